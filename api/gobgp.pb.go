@@ -0,0 +1,1881 @@
+// Copyright (C) 2015 Nippon Telegraph and Telephone Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: gobgp.proto
+
+package api
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Resource int32
+
+const (
+	Resource_GLOBAL  Resource = 0
+	Resource_LOCAL   Resource = 1
+	Resource_ADJ_IN  Resource = 2
+	Resource_ADJ_OUT Resource = 3
+)
+
+// Enum value maps for Resource.
+var (
+	Resource_name = map[int32]string{
+		0: "GLOBAL",
+		1: "LOCAL",
+		2: "ADJ_IN",
+		3: "ADJ_OUT",
+	}
+	Resource_value = map[string]int32{
+		"GLOBAL":  0,
+		"LOCAL":   1,
+		"ADJ_IN":  2,
+		"ADJ_OUT": 3,
+	}
+)
+
+func (x Resource) Enum() *Resource {
+	p := new(Resource)
+	*p = x
+	return p
+}
+
+func (x Resource) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Resource) Descriptor() protoreflect.EnumDescriptor {
+	return file_gobgp_proto_enumTypes[0].Descriptor()
+}
+
+func (Resource) Type() protoreflect.EnumType {
+	return &file_gobgp_proto_enumTypes[0]
+}
+
+func (x Resource) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Resource.Descriptor instead.
+func (Resource) EnumDescriptor() ([]byte, []int) {
+	return file_gobgp_proto_rawDescGZIP(), []int{0}
+}
+
+type AFI int32
+
+const (
+	AFI_UNKNOWN_AFI AFI = 0
+	AFI_IP          AFI = 1
+	AFI_IP6         AFI = 2
+	AFI_L2VPN       AFI = 25
+)
+
+// Enum value maps for AFI.
+var (
+	AFI_name = map[int32]string{
+		0:  "UNKNOWN_AFI",
+		1:  "IP",
+		2:  "IP6",
+		25: "L2VPN",
+	}
+	AFI_value = map[string]int32{
+		"UNKNOWN_AFI": 0,
+		"IP":          1,
+		"IP6":         2,
+		"L2VPN":       25,
+	}
+)
+
+func (x AFI) Enum() *AFI {
+	p := new(AFI)
+	*p = x
+	return p
+}
+
+func (x AFI) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (AFI) Descriptor() protoreflect.EnumDescriptor {
+	return file_gobgp_proto_enumTypes[1].Descriptor()
+}
+
+func (AFI) Type() protoreflect.EnumType {
+	return &file_gobgp_proto_enumTypes[1]
+}
+
+func (x AFI) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use AFI.Descriptor instead.
+func (AFI) EnumDescriptor() ([]byte, []int) {
+	return file_gobgp_proto_rawDescGZIP(), []int{1}
+}
+
+type SAFI int32
+
+const (
+	SAFI_UNKNOWN_SAFI             SAFI = 0
+	SAFI_UNICAST                  SAFI = 1
+	SAFI_MULTICAST                SAFI = 2
+	SAFI_MPLS_LABEL               SAFI = 4
+	SAFI_VPLS                     SAFI = 65
+	SAFI_EVPN                     SAFI = 70
+	SAFI_MPLS_VPN                 SAFI = 128
+	SAFI_MPLS_VPN_MULTICAST       SAFI = 129
+	SAFI_ROUTE_TARGET_CONSTRAINTS SAFI = 132
+)
+
+// Enum value maps for SAFI.
+var (
+	SAFI_name = map[int32]string{
+		0:   "UNKNOWN_SAFI",
+		1:   "UNICAST",
+		2:   "MULTICAST",
+		4:   "MPLS_LABEL",
+		65:  "VPLS",
+		70:  "EVPN",
+		128: "MPLS_VPN",
+		129: "MPLS_VPN_MULTICAST",
+		132: "ROUTE_TARGET_CONSTRAINTS",
+	}
+	SAFI_value = map[string]int32{
+		"UNKNOWN_SAFI":             0,
+		"UNICAST":                  1,
+		"MULTICAST":                2,
+		"MPLS_LABEL":               4,
+		"VPLS":                     65,
+		"EVPN":                     70,
+		"MPLS_VPN":                 128,
+		"MPLS_VPN_MULTICAST":       129,
+		"ROUTE_TARGET_CONSTRAINTS": 132,
+	}
+)
+
+func (x SAFI) Enum() *SAFI {
+	p := new(SAFI)
+	*p = x
+	return p
+}
+
+func (x SAFI) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (SAFI) Descriptor() protoreflect.EnumDescriptor {
+	return file_gobgp_proto_enumTypes[2].Descriptor()
+}
+
+func (SAFI) Type() protoreflect.EnumType {
+	return &file_gobgp_proto_enumTypes[2]
+}
+
+func (x SAFI) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use SAFI.Descriptor instead.
+func (SAFI) EnumDescriptor() ([]byte, []int) {
+	return file_gobgp_proto_rawDescGZIP(), []int{2}
+}
+
+type Origin int32
+
+const (
+	Origin_ORIGIN_IGP        Origin = 0
+	Origin_ORIGIN_EGP        Origin = 1
+	Origin_ORIGIN_INCOMPLETE Origin = 2
+)
+
+// Enum value maps for Origin.
+var (
+	Origin_name = map[int32]string{
+		0: "ORIGIN_IGP",
+		1: "ORIGIN_EGP",
+		2: "ORIGIN_INCOMPLETE",
+	}
+	Origin_value = map[string]int32{
+		"ORIGIN_IGP":        0,
+		"ORIGIN_EGP":        1,
+		"ORIGIN_INCOMPLETE": 2,
+	}
+)
+
+func (x Origin) Enum() *Origin {
+	p := new(Origin)
+	*p = x
+	return p
+}
+
+func (x Origin) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Origin) Descriptor() protoreflect.EnumDescriptor {
+	return file_gobgp_proto_enumTypes[3].Descriptor()
+}
+
+func (Origin) Type() protoreflect.EnumType {
+	return &file_gobgp_proto_enumTypes[3]
+}
+
+func (x Origin) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Origin.Descriptor instead.
+func (Origin) EnumDescriptor() ([]byte, []int) {
+	return file_gobgp_proto_rawDescGZIP(), []int{3}
+}
+
+type ValidationResult int32
+
+const (
+	ValidationResult_VALIDATION_NONE      ValidationResult = 0
+	ValidationResult_VALIDATION_NOT_FOUND ValidationResult = 1
+	ValidationResult_VALIDATION_VALID     ValidationResult = 2
+	ValidationResult_VALIDATION_INVALID   ValidationResult = 3
+)
+
+// Enum value maps for ValidationResult.
+var (
+	ValidationResult_name = map[int32]string{
+		0: "VALIDATION_NONE",
+		1: "VALIDATION_NOT_FOUND",
+		2: "VALIDATION_VALID",
+		3: "VALIDATION_INVALID",
+	}
+	ValidationResult_value = map[string]int32{
+		"VALIDATION_NONE":      0,
+		"VALIDATION_NOT_FOUND": 1,
+		"VALIDATION_VALID":     2,
+		"VALIDATION_INVALID":   3,
+	}
+)
+
+func (x ValidationResult) Enum() *ValidationResult {
+	p := new(ValidationResult)
+	*p = x
+	return p
+}
+
+func (x ValidationResult) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ValidationResult) Descriptor() protoreflect.EnumDescriptor {
+	return file_gobgp_proto_enumTypes[4].Descriptor()
+}
+
+func (ValidationResult) Type() protoreflect.EnumType {
+	return &file_gobgp_proto_enumTypes[4]
+}
+
+func (x ValidationResult) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ValidationResult.Descriptor instead.
+func (ValidationResult) EnumDescriptor() ([]byte, []int) {
+	return file_gobgp_proto_rawDescGZIP(), []int{4}
+}
+
+type Error_ErrorCode int32
+
+const (
+	Error_SUCCESS Error_ErrorCode = 0
+	Error_FAIL    Error_ErrorCode = 1
+)
+
+// Enum value maps for Error_ErrorCode.
+var (
+	Error_ErrorCode_name = map[int32]string{
+		0: "SUCCESS",
+		1: "FAIL",
+	}
+	Error_ErrorCode_value = map[string]int32{
+		"SUCCESS": 0,
+		"FAIL":    1,
+	}
+)
+
+func (x Error_ErrorCode) Enum() *Error_ErrorCode {
+	p := new(Error_ErrorCode)
+	*p = x
+	return p
+}
+
+func (x Error_ErrorCode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Error_ErrorCode) Descriptor() protoreflect.EnumDescriptor {
+	return file_gobgp_proto_enumTypes[5].Descriptor()
+}
+
+func (Error_ErrorCode) Type() protoreflect.EnumType {
+	return &file_gobgp_proto_enumTypes[5]
+}
+
+func (x Error_ErrorCode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Error_ErrorCode.Descriptor instead.
+func (Error_ErrorCode) EnumDescriptor() ([]byte, []int) {
+	return file_gobgp_proto_rawDescGZIP(), []int{2, 0}
+}
+
+type AddressFamily struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Afi           AFI                    `protobuf:"varint,1,opt,name=afi,proto3,enum=gobgpapi.v1.AFI" json:"afi,omitempty"`
+	Safi          SAFI                   `protobuf:"varint,2,opt,name=safi,proto3,enum=gobgpapi.v1.SAFI" json:"safi,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddressFamily) Reset() {
+	*x = AddressFamily{}
+	mi := &file_gobgp_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddressFamily) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddressFamily) ProtoMessage() {}
+
+func (x *AddressFamily) ProtoReflect() protoreflect.Message {
+	mi := &file_gobgp_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddressFamily.ProtoReflect.Descriptor instead.
+func (*AddressFamily) Descriptor() ([]byte, []int) {
+	return file_gobgp_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *AddressFamily) GetAfi() AFI {
+	if x != nil {
+		return x.Afi
+	}
+	return AFI_UNKNOWN_AFI
+}
+
+func (x *AddressFamily) GetSafi() SAFI {
+	if x != nil {
+		return x.Safi
+	}
+	return SAFI_UNKNOWN_SAFI
+}
+
+type Arguments struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Resource Resource               `protobuf:"varint,1,opt,name=resource,proto3,enum=gobgpapi.v1.Resource" json:"resource,omitempty"`
+	// ipv4 unicast if not specified
+	Af              *AddressFamily `protobuf:"bytes,2,opt,name=af,proto3" json:"af,omitempty"`
+	NeighborAddress string         `protobuf:"bytes,3,opt,name=neighbor_address,json=neighborAddress,proto3" json:"neighbor_address,omitempty"`
+	// the name of the policy
+	Name string `protobuf:"bytes,4,opt,name=name,proto3" json:"name,omitempty"`
+	// send the current best paths before the changes
+	Snapshot      bool `protobuf:"varint,5,opt,name=snapshot,proto3" json:"snapshot,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Arguments) Reset() {
+	*x = Arguments{}
+	mi := &file_gobgp_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Arguments) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Arguments) ProtoMessage() {}
+
+func (x *Arguments) ProtoReflect() protoreflect.Message {
+	mi := &file_gobgp_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Arguments.ProtoReflect.Descriptor instead.
+func (*Arguments) Descriptor() ([]byte, []int) {
+	return file_gobgp_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Arguments) GetResource() Resource {
+	if x != nil {
+		return x.Resource
+	}
+	return Resource_GLOBAL
+}
+
+func (x *Arguments) GetAf() *AddressFamily {
+	if x != nil {
+		return x.Af
+	}
+	return nil
+}
+
+func (x *Arguments) GetNeighborAddress() string {
+	if x != nil {
+		return x.NeighborAddress
+	}
+	return ""
+}
+
+func (x *Arguments) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Arguments) GetSnapshot() bool {
+	if x != nil {
+		return x.Snapshot
+	}
+	return false
+}
+
+type Error struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Code          Error_ErrorCode        `protobuf:"varint,1,opt,name=code,proto3,enum=gobgpapi.v1.Error_ErrorCode" json:"code,omitempty"`
+	Msg           string                 `protobuf:"bytes,2,opt,name=msg,proto3" json:"msg,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Error) Reset() {
+	*x = Error{}
+	mi := &file_gobgp_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Error) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Error) ProtoMessage() {}
+
+func (x *Error) ProtoReflect() protoreflect.Message {
+	mi := &file_gobgp_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Error.ProtoReflect.Descriptor instead.
+func (*Error) Descriptor() ([]byte, []int) {
+	return file_gobgp_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Error) GetCode() Error_ErrorCode {
+	if x != nil {
+		return x.Code
+	}
+	return Error_SUCCESS
+}
+
+func (x *Error) GetMsg() string {
+	if x != nil {
+		return x.Msg
+	}
+	return ""
+}
+
+type PeerConf struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RemoteIp      string                 `protobuf:"bytes,1,opt,name=remote_ip,json=remoteIp,proto3" json:"remote_ip,omitempty"`
+	Id            string                 `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	RemoteAs      uint32                 `protobuf:"varint,3,opt,name=remote_as,json=remoteAs,proto3" json:"remote_as,omitempty"`
+	RemoteCap     []uint32               `protobuf:"varint,4,rep,packed,name=remote_cap,json=remoteCap,proto3" json:"remote_cap,omitempty"`
+	LocalCap      []uint32               `protobuf:"varint,5,rep,packed,name=local_cap,json=localCap,proto3" json:"local_cap,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PeerConf) Reset() {
+	*x = PeerConf{}
+	mi := &file_gobgp_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PeerConf) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeerConf) ProtoMessage() {}
+
+func (x *PeerConf) ProtoReflect() protoreflect.Message {
+	mi := &file_gobgp_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeerConf.ProtoReflect.Descriptor instead.
+func (*PeerConf) Descriptor() ([]byte, []int) {
+	return file_gobgp_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *PeerConf) GetRemoteIp() string {
+	if x != nil {
+		return x.RemoteIp
+	}
+	return ""
+}
+
+func (x *PeerConf) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *PeerConf) GetRemoteAs() uint32 {
+	if x != nil {
+		return x.RemoteAs
+	}
+	return 0
+}
+
+func (x *PeerConf) GetRemoteCap() []uint32 {
+	if x != nil {
+		return x.RemoteCap
+	}
+	return nil
+}
+
+func (x *PeerConf) GetLocalCap() []uint32 {
+	if x != nil {
+		return x.LocalCap
+	}
+	return nil
+}
+
+type PeerInfo struct {
+	state                     protoimpl.MessageState `protogen:"open.v1"`
+	BgpState                  string                 `protobuf:"bytes,1,opt,name=bgp_state,json=bgpState,proto3" json:"bgp_state,omitempty"`
+	AdminState                string                 `protobuf:"bytes,2,opt,name=admin_state,json=adminState,proto3" json:"admin_state,omitempty"`
+	FsmEstablishedTransitions uint32                 `protobuf:"varint,3,opt,name=fsm_established_transitions,json=fsmEstablishedTransitions,proto3" json:"fsm_established_transitions,omitempty"`
+	TotalMessageOut           uint32                 `protobuf:"varint,4,opt,name=total_message_out,json=totalMessageOut,proto3" json:"total_message_out,omitempty"`
+	TotalMessageIn            uint32                 `protobuf:"varint,5,opt,name=total_message_in,json=totalMessageIn,proto3" json:"total_message_in,omitempty"`
+	UpdateMessageOut          uint32                 `protobuf:"varint,6,opt,name=update_message_out,json=updateMessageOut,proto3" json:"update_message_out,omitempty"`
+	UpdateMessageIn           uint32                 `protobuf:"varint,7,opt,name=update_message_in,json=updateMessageIn,proto3" json:"update_message_in,omitempty"`
+	KeepaliveMessageOut       uint32                 `protobuf:"varint,8,opt,name=keepalive_message_out,json=keepaliveMessageOut,proto3" json:"keepalive_message_out,omitempty"`
+	KeepaliveMessageIn        uint32                 `protobuf:"varint,9,opt,name=keepalive_message_in,json=keepaliveMessageIn,proto3" json:"keepalive_message_in,omitempty"`
+	OpenMessageOut            uint32                 `protobuf:"varint,10,opt,name=open_message_out,json=openMessageOut,proto3" json:"open_message_out,omitempty"`
+	OpenMessageIn             uint32                 `protobuf:"varint,11,opt,name=open_message_in,json=openMessageIn,proto3" json:"open_message_in,omitempty"`
+	NotificationOut           uint32                 `protobuf:"varint,12,opt,name=notification_out,json=notificationOut,proto3" json:"notification_out,omitempty"`
+	NotificationIn            uint32                 `protobuf:"varint,13,opt,name=notification_in,json=notificationIn,proto3" json:"notification_in,omitempty"`
+	RefreshMessageOut         uint32                 `protobuf:"varint,14,opt,name=refresh_message_out,json=refreshMessageOut,proto3" json:"refresh_message_out,omitempty"`
+	RefreshMessageIn          uint32                 `protobuf:"varint,15,opt,name=refresh_message_in,json=refreshMessageIn,proto3" json:"refresh_message_in,omitempty"`
+	DiscardedOut              uint32                 `protobuf:"varint,16,opt,name=discarded_out,json=discardedOut,proto3" json:"discarded_out,omitempty"`
+	DiscardedIn               uint32                 `protobuf:"varint,17,opt,name=discarded_in,json=discardedIn,proto3" json:"discarded_in,omitempty"`
+	// seconds since the session was established or went down
+	Uptime        int64  `protobuf:"varint,18,opt,name=uptime,proto3" json:"uptime,omitempty"`
+	Downtime      int64  `protobuf:"varint,19,opt,name=downtime,proto3" json:"downtime,omitempty"`
+	LastError     string `protobuf:"bytes,20,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+	Received      uint32 `protobuf:"varint,21,opt,name=received,proto3" json:"received,omitempty"`
+	Accepted      uint32 `protobuf:"varint,22,opt,name=accepted,proto3" json:"accepted,omitempty"`
+	Advertized    uint32 `protobuf:"varint,23,opt,name=advertized,proto3" json:"advertized,omitempty"`
+	OutQ          uint32 `protobuf:"varint,24,opt,name=out_q,json=outQ,proto3" json:"out_q,omitempty"`
+	Flops         uint32 `protobuf:"varint,25,opt,name=flops,proto3" json:"flops,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PeerInfo) Reset() {
+	*x = PeerInfo{}
+	mi := &file_gobgp_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PeerInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeerInfo) ProtoMessage() {}
+
+func (x *PeerInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_gobgp_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeerInfo.ProtoReflect.Descriptor instead.
+func (*PeerInfo) Descriptor() ([]byte, []int) {
+	return file_gobgp_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *PeerInfo) GetBgpState() string {
+	if x != nil {
+		return x.BgpState
+	}
+	return ""
+}
+
+func (x *PeerInfo) GetAdminState() string {
+	if x != nil {
+		return x.AdminState
+	}
+	return ""
+}
+
+func (x *PeerInfo) GetFsmEstablishedTransitions() uint32 {
+	if x != nil {
+		return x.FsmEstablishedTransitions
+	}
+	return 0
+}
+
+func (x *PeerInfo) GetTotalMessageOut() uint32 {
+	if x != nil {
+		return x.TotalMessageOut
+	}
+	return 0
+}
+
+func (x *PeerInfo) GetTotalMessageIn() uint32 {
+	if x != nil {
+		return x.TotalMessageIn
+	}
+	return 0
+}
+
+func (x *PeerInfo) GetUpdateMessageOut() uint32 {
+	if x != nil {
+		return x.UpdateMessageOut
+	}
+	return 0
+}
+
+func (x *PeerInfo) GetUpdateMessageIn() uint32 {
+	if x != nil {
+		return x.UpdateMessageIn
+	}
+	return 0
+}
+
+func (x *PeerInfo) GetKeepaliveMessageOut() uint32 {
+	if x != nil {
+		return x.KeepaliveMessageOut
+	}
+	return 0
+}
+
+func (x *PeerInfo) GetKeepaliveMessageIn() uint32 {
+	if x != nil {
+		return x.KeepaliveMessageIn
+	}
+	return 0
+}
+
+func (x *PeerInfo) GetOpenMessageOut() uint32 {
+	if x != nil {
+		return x.OpenMessageOut
+	}
+	return 0
+}
+
+func (x *PeerInfo) GetOpenMessageIn() uint32 {
+	if x != nil {
+		return x.OpenMessageIn
+	}
+	return 0
+}
+
+func (x *PeerInfo) GetNotificationOut() uint32 {
+	if x != nil {
+		return x.NotificationOut
+	}
+	return 0
+}
+
+func (x *PeerInfo) GetNotificationIn() uint32 {
+	if x != nil {
+		return x.NotificationIn
+	}
+	return 0
+}
+
+func (x *PeerInfo) GetRefreshMessageOut() uint32 {
+	if x != nil {
+		return x.RefreshMessageOut
+	}
+	return 0
+}
+
+func (x *PeerInfo) GetRefreshMessageIn() uint32 {
+	if x != nil {
+		return x.RefreshMessageIn
+	}
+	return 0
+}
+
+func (x *PeerInfo) GetDiscardedOut() uint32 {
+	if x != nil {
+		return x.DiscardedOut
+	}
+	return 0
+}
+
+func (x *PeerInfo) GetDiscardedIn() uint32 {
+	if x != nil {
+		return x.DiscardedIn
+	}
+	return 0
+}
+
+func (x *PeerInfo) GetUptime() int64 {
+	if x != nil {
+		return x.Uptime
+	}
+	return 0
+}
+
+func (x *PeerInfo) GetDowntime() int64 {
+	if x != nil {
+		return x.Downtime
+	}
+	return 0
+}
+
+func (x *PeerInfo) GetLastError() string {
+	if x != nil {
+		return x.LastError
+	}
+	return ""
+}
+
+func (x *PeerInfo) GetReceived() uint32 {
+	if x != nil {
+		return x.Received
+	}
+	return 0
+}
+
+func (x *PeerInfo) GetAccepted() uint32 {
+	if x != nil {
+		return x.Accepted
+	}
+	return 0
+}
+
+func (x *PeerInfo) GetAdvertized() uint32 {
+	if x != nil {
+		return x.Advertized
+	}
+	return 0
+}
+
+func (x *PeerInfo) GetOutQ() uint32 {
+	if x != nil {
+		return x.OutQ
+	}
+	return 0
+}
+
+func (x *PeerInfo) GetFlops() uint32 {
+	if x != nil {
+		return x.Flops
+	}
+	return 0
+}
+
+type Peer struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Conf          *PeerConf              `protobuf:"bytes,1,opt,name=conf,proto3" json:"conf,omitempty"`
+	Info          *PeerInfo              `protobuf:"bytes,2,opt,name=info,proto3" json:"info,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Peer) Reset() {
+	*x = Peer{}
+	mi := &file_gobgp_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Peer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Peer) ProtoMessage() {}
+
+func (x *Peer) ProtoReflect() protoreflect.Message {
+	mi := &file_gobgp_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Peer.ProtoReflect.Descriptor instead.
+func (*Peer) Descriptor() ([]byte, []int) {
+	return file_gobgp_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Peer) GetConf() *PeerConf {
+	if x != nil {
+		return x.Conf
+	}
+	return nil
+}
+
+func (x *Peer) GetInfo() *PeerInfo {
+	if x != nil {
+		return x.Info
+	}
+	return nil
+}
+
+type AsPathSegment struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// AS_SET(1) or AS_SEQUENCE(2)
+	Type          uint32   `protobuf:"varint,1,opt,name=type,proto3" json:"type,omitempty"`
+	Asns          []uint32 `protobuf:"varint,2,rep,packed,name=asns,proto3" json:"asns,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AsPathSegment) Reset() {
+	*x = AsPathSegment{}
+	mi := &file_gobgp_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AsPathSegment) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AsPathSegment) ProtoMessage() {}
+
+func (x *AsPathSegment) ProtoReflect() protoreflect.Message {
+	mi := &file_gobgp_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AsPathSegment.ProtoReflect.Descriptor instead.
+func (*AsPathSegment) Descriptor() ([]byte, []int) {
+	return file_gobgp_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *AsPathSegment) GetType() uint32 {
+	if x != nil {
+		return x.Type
+	}
+	return 0
+}
+
+func (x *AsPathSegment) GetAsns() []uint32 {
+	if x != nil {
+		return x.Asns
+	}
+	return nil
+}
+
+type Path struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Prefix     string                 `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	Family     *AddressFamily         `protobuf:"bytes,2,opt,name=family,proto3" json:"family,omitempty"`
+	Nexthop    string                 `protobuf:"bytes,3,opt,name=nexthop,proto3" json:"nexthop,omitempty"`
+	IsWithdraw bool                   `protobuf:"varint,4,opt,name=is_withdraw,json=isWithdraw,proto3" json:"is_withdraw,omitempty"`
+	// seconds since the path was received
+	Age           int64            `protobuf:"varint,5,opt,name=age,proto3" json:"age,omitempty"`
+	SourceAddress string           `protobuf:"bytes,6,opt,name=source_address,json=sourceAddress,proto3" json:"source_address,omitempty"`
+	SourceAs      uint32           `protobuf:"varint,7,opt,name=source_as,json=sourceAs,proto3" json:"source_as,omitempty"`
+	Validation    ValidationResult `protobuf:"varint,8,opt,name=validation,proto3,enum=gobgpapi.v1.ValidationResult" json:"validation,omitempty"`
+	Origin        Origin           `protobuf:"varint,9,opt,name=origin,proto3,enum=gobgpapi.v1.Origin" json:"origin,omitempty"`
+	AsPath        []*AsPathSegment `protobuf:"bytes,10,rep,name=as_path,json=asPath,proto3" json:"as_path,omitempty"`
+	Med           *uint32          `protobuf:"varint,11,opt,name=med,proto3,oneof" json:"med,omitempty"`
+	LocalPref     *uint32          `protobuf:"varint,12,opt,name=local_pref,json=localPref,proto3,oneof" json:"local_pref,omitempty"`
+	Communities   []uint32         `protobuf:"varint,13,rep,packed,name=communities,proto3" json:"communities,omitempty"`
+	// the nlri and all the path attributes in the wire format for the
+	// attributes not decoded above
+	Nlri          []byte   `protobuf:"bytes,14,opt,name=nlri,proto3" json:"nlri,omitempty"`
+	Pattrs        [][]byte `protobuf:"bytes,15,rep,name=pattrs,proto3" json:"pattrs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Path) Reset() {
+	*x = Path{}
+	mi := &file_gobgp_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Path) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Path) ProtoMessage() {}
+
+func (x *Path) ProtoReflect() protoreflect.Message {
+	mi := &file_gobgp_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Path.ProtoReflect.Descriptor instead.
+func (*Path) Descriptor() ([]byte, []int) {
+	return file_gobgp_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Path) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *Path) GetFamily() *AddressFamily {
+	if x != nil {
+		return x.Family
+	}
+	return nil
+}
+
+func (x *Path) GetNexthop() string {
+	if x != nil {
+		return x.Nexthop
+	}
+	return ""
+}
+
+func (x *Path) GetIsWithdraw() bool {
+	if x != nil {
+		return x.IsWithdraw
+	}
+	return false
+}
+
+func (x *Path) GetAge() int64 {
+	if x != nil {
+		return x.Age
+	}
+	return 0
+}
+
+func (x *Path) GetSourceAddress() string {
+	if x != nil {
+		return x.SourceAddress
+	}
+	return ""
+}
+
+func (x *Path) GetSourceAs() uint32 {
+	if x != nil {
+		return x.SourceAs
+	}
+	return 0
+}
+
+func (x *Path) GetValidation() ValidationResult {
+	if x != nil {
+		return x.Validation
+	}
+	return ValidationResult_VALIDATION_NONE
+}
+
+func (x *Path) GetOrigin() Origin {
+	if x != nil {
+		return x.Origin
+	}
+	return Origin_ORIGIN_IGP
+}
+
+func (x *Path) GetAsPath() []*AsPathSegment {
+	if x != nil {
+		return x.AsPath
+	}
+	return nil
+}
+
+func (x *Path) GetMed() uint32 {
+	if x != nil && x.Med != nil {
+		return *x.Med
+	}
+	return 0
+}
+
+func (x *Path) GetLocalPref() uint32 {
+	if x != nil && x.LocalPref != nil {
+		return *x.LocalPref
+	}
+	return 0
+}
+
+func (x *Path) GetCommunities() []uint32 {
+	if x != nil {
+		return x.Communities
+	}
+	return nil
+}
+
+func (x *Path) GetNlri() []byte {
+	if x != nil {
+		return x.Nlri
+	}
+	return nil
+}
+
+func (x *Path) GetPattrs() [][]byte {
+	if x != nil {
+		return x.Pattrs
+	}
+	return nil
+}
+
+type Destination struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Prefix string                 `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	Paths  []*Path                `protobuf:"bytes,2,rep,name=paths,proto3" json:"paths,omitempty"`
+	// -1 if none of the paths has a reachable nexthop
+	BestPathIdx   int32 `protobuf:"varint,3,opt,name=best_path_idx,json=bestPathIdx,proto3" json:"best_path_idx,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Destination) Reset() {
+	*x = Destination{}
+	mi := &file_gobgp_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Destination) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Destination) ProtoMessage() {}
+
+func (x *Destination) ProtoReflect() protoreflect.Message {
+	mi := &file_gobgp_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Destination.ProtoReflect.Descriptor instead.
+func (*Destination) Descriptor() ([]byte, []int) {
+	return file_gobgp_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Destination) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *Destination) GetPaths() []*Path {
+	if x != nil {
+		return x.Paths
+	}
+	return nil
+}
+
+func (x *Destination) GetBestPathIdx() int32 {
+	if x != nil {
+		return x.BestPathIdx
+	}
+	return 0
+}
+
+type Statement struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Hits          uint64                 `protobuf:"varint,2,opt,name=hits,proto3" json:"hits,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Statement) Reset() {
+	*x = Statement{}
+	mi := &file_gobgp_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Statement) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Statement) ProtoMessage() {}
+
+func (x *Statement) ProtoReflect() protoreflect.Message {
+	mi := &file_gobgp_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Statement.ProtoReflect.Descriptor instead.
+func (*Statement) Descriptor() ([]byte, []int) {
+	return file_gobgp_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Statement) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Statement) GetHits() uint64 {
+	if x != nil {
+		return x.Hits
+	}
+	return 0
+}
+
+type Policy struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Statements    []*Statement           `protobuf:"bytes,2,rep,name=statements,proto3" json:"statements,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Policy) Reset() {
+	*x = Policy{}
+	mi := &file_gobgp_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Policy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Policy) ProtoMessage() {}
+
+func (x *Policy) ProtoReflect() protoreflect.Message {
+	mi := &file_gobgp_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Policy.ProtoReflect.Descriptor instead.
+func (*Policy) Descriptor() ([]byte, []int) {
+	return file_gobgp_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Policy) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Policy) GetStatements() []*Statement {
+	if x != nil {
+		return x.Statements
+	}
+	return nil
+}
+
+type BestPathEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// add, withdraw or best-changed
+	Type   string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Prefix string `protobuf:"bytes,2,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	// the withdrawn best path for the withdraw event
+	Path          *Path `protobuf:"bytes,3,opt,name=path,proto3" json:"path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BestPathEvent) Reset() {
+	*x = BestPathEvent{}
+	mi := &file_gobgp_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BestPathEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BestPathEvent) ProtoMessage() {}
+
+func (x *BestPathEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_gobgp_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BestPathEvent.ProtoReflect.Descriptor instead.
+func (*BestPathEvent) Descriptor() ([]byte, []int) {
+	return file_gobgp_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *BestPathEvent) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *BestPathEvent) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *BestPathEvent) GetPath() *Path {
+	if x != nil {
+		return x.Path
+	}
+	return nil
+}
+
+type NotificationEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Code          uint32                 `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	Subcode       uint32                 `protobuf:"varint,2,opt,name=subcode,proto3" json:"subcode,omitempty"`
+	CodeName      string                 `protobuf:"bytes,3,opt,name=code_name,json=codeName,proto3" json:"code_name,omitempty"`
+	SubcodeName   string                 `protobuf:"bytes,4,opt,name=subcode_name,json=subcodeName,proto3" json:"subcode_name,omitempty"`
+	Data          []byte                 `protobuf:"bytes,5,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NotificationEvent) Reset() {
+	*x = NotificationEvent{}
+	mi := &file_gobgp_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NotificationEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NotificationEvent) ProtoMessage() {}
+
+func (x *NotificationEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_gobgp_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NotificationEvent.ProtoReflect.Descriptor instead.
+func (*NotificationEvent) Descriptor() ([]byte, []int) {
+	return file_gobgp_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *NotificationEvent) GetCode() uint32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *NotificationEvent) GetSubcode() uint32 {
+	if x != nil {
+		return x.Subcode
+	}
+	return 0
+}
+
+func (x *NotificationEvent) GetCodeName() string {
+	if x != nil {
+		return x.CodeName
+	}
+	return ""
+}
+
+func (x *NotificationEvent) GetSubcodeName() string {
+	if x != nil {
+		return x.SubcodeName
+	}
+	return ""
+}
+
+func (x *NotificationEvent) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type PrefixLimitEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RouteFamily   string                 `protobuf:"bytes,1,opt,name=route_family,json=routeFamily,proto3" json:"route_family,omitempty"`
+	Prefixes      uint32                 `protobuf:"varint,2,opt,name=prefixes,proto3" json:"prefixes,omitempty"`
+	MaxPrefixes   uint32                 `protobuf:"varint,3,opt,name=max_prefixes,json=maxPrefixes,proto3" json:"max_prefixes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PrefixLimitEvent) Reset() {
+	*x = PrefixLimitEvent{}
+	mi := &file_gobgp_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PrefixLimitEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PrefixLimitEvent) ProtoMessage() {}
+
+func (x *PrefixLimitEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_gobgp_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PrefixLimitEvent.ProtoReflect.Descriptor instead.
+func (*PrefixLimitEvent) Descriptor() ([]byte, []int) {
+	return file_gobgp_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *PrefixLimitEvent) GetRouteFamily() string {
+	if x != nil {
+		return x.RouteFamily
+	}
+	return ""
+}
+
+func (x *PrefixLimitEvent) GetPrefixes() uint32 {
+	if x != nil {
+		return x.Prefixes
+	}
+	return 0
+}
+
+func (x *PrefixLimitEvent) GetMaxPrefixes() uint32 {
+	if x != nil {
+		return x.MaxPrefixes
+	}
+	return 0
+}
+
+type PeerEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// peer-up, peer-down, notification-sent, notification-received,
+	// admin-state-changed, prefix-limit-warning or prefix-limit-exceeded
+	Type string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	// unix time of the event
+	Time            int64              `protobuf:"varint,2,opt,name=time,proto3" json:"time,omitempty"`
+	NeighborAddress string             `protobuf:"bytes,3,opt,name=neighbor_address,json=neighborAddress,proto3" json:"neighbor_address,omitempty"`
+	PeerAs          uint32             `protobuf:"varint,4,opt,name=peer_as,json=peerAs,proto3" json:"peer_as,omitempty"`
+	OldState        string             `protobuf:"bytes,5,opt,name=old_state,json=oldState,proto3" json:"old_state,omitempty"`
+	State           string             `protobuf:"bytes,6,opt,name=state,proto3" json:"state,omitempty"`
+	AdminState      string             `protobuf:"bytes,7,opt,name=admin_state,json=adminState,proto3" json:"admin_state,omitempty"`
+	Notification    *NotificationEvent `protobuf:"bytes,8,opt,name=notification,proto3" json:"notification,omitempty"`
+	PrefixLimit     *PrefixLimitEvent  `protobuf:"bytes,9,opt,name=prefix_limit,json=prefixLimit,proto3" json:"prefix_limit,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *PeerEvent) Reset() {
+	*x = PeerEvent{}
+	mi := &file_gobgp_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PeerEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeerEvent) ProtoMessage() {}
+
+func (x *PeerEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_gobgp_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeerEvent.ProtoReflect.Descriptor instead.
+func (*PeerEvent) Descriptor() ([]byte, []int) {
+	return file_gobgp_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *PeerEvent) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *PeerEvent) GetTime() int64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+func (x *PeerEvent) GetNeighborAddress() string {
+	if x != nil {
+		return x.NeighborAddress
+	}
+	return ""
+}
+
+func (x *PeerEvent) GetPeerAs() uint32 {
+	if x != nil {
+		return x.PeerAs
+	}
+	return 0
+}
+
+func (x *PeerEvent) GetOldState() string {
+	if x != nil {
+		return x.OldState
+	}
+	return ""
+}
+
+func (x *PeerEvent) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *PeerEvent) GetAdminState() string {
+	if x != nil {
+		return x.AdminState
+	}
+	return ""
+}
+
+func (x *PeerEvent) GetNotification() *NotificationEvent {
+	if x != nil {
+		return x.Notification
+	}
+	return nil
+}
+
+func (x *PeerEvent) GetPrefixLimit() *PrefixLimitEvent {
+	if x != nil {
+		return x.PrefixLimit
+	}
+	return nil
+}
+
+var File_gobgp_proto protoreflect.FileDescriptor
+
+const file_gobgp_proto_rawDesc = "" +
+	"\n" +
+	"\vgobgp.proto\x12\vgobgpapi.v1\"Z\n" +
+	"\rAddressFamily\x12\"\n" +
+	"\x03afi\x18\x01 \x01(\x0e2\x10.gobgpapi.v1.AFIR\x03afi\x12%\n" +
+	"\x04safi\x18\x02 \x01(\x0e2\x11.gobgpapi.v1.SAFIR\x04safi\"\xc5\x01\n" +
+	"\tArguments\x121\n" +
+	"\bresource\x18\x01 \x01(\x0e2\x15.gobgpapi.v1.ResourceR\bresource\x12*\n" +
+	"\x02af\x18\x02 \x01(\v2\x1a.gobgpapi.v1.AddressFamilyR\x02af\x12)\n" +
+	"\x10neighbor_address\x18\x03 \x01(\tR\x0fneighborAddress\x12\x12\n" +
+	"\x04name\x18\x04 \x01(\tR\x04name\x12\x1a\n" +
+	"\bsnapshot\x18\x05 \x01(\bR\bsnapshot\"o\n" +
+	"\x05Error\x120\n" +
+	"\x04code\x18\x01 \x01(\x0e2\x1c.gobgpapi.v1.Error.ErrorCodeR\x04code\x12\x10\n" +
+	"\x03msg\x18\x02 \x01(\tR\x03msg\"\"\n" +
+	"\tErrorCode\x12\v\n" +
+	"\aSUCCESS\x10\x00\x12\b\n" +
+	"\x04FAIL\x10\x01\"\x90\x01\n" +
+	"\bPeerConf\x12\x1b\n" +
+	"\tremote_ip\x18\x01 \x01(\tR\bremoteIp\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\x12\x1b\n" +
+	"\tremote_as\x18\x03 \x01(\rR\bremoteAs\x12\x1d\n" +
+	"\n" +
+	"remote_cap\x18\x04 \x03(\rR\tremoteCap\x12\x1b\n" +
+	"\tlocal_cap\x18\x05 \x03(\rR\blocalCap\"\xc0\a\n" +
+	"\bPeerInfo\x12\x1b\n" +
+	"\tbgp_state\x18\x01 \x01(\tR\bbgpState\x12\x1f\n" +
+	"\vadmin_state\x18\x02 \x01(\tR\n" +
+	"adminState\x12>\n" +
+	"\x1bfsm_established_transitions\x18\x03 \x01(\rR\x19fsmEstablishedTransitions\x12*\n" +
+	"\x11total_message_out\x18\x04 \x01(\rR\x0ftotalMessageOut\x12(\n" +
+	"\x10total_message_in\x18\x05 \x01(\rR\x0etotalMessageIn\x12,\n" +
+	"\x12update_message_out\x18\x06 \x01(\rR\x10updateMessageOut\x12*\n" +
+	"\x11update_message_in\x18\a \x01(\rR\x0fupdateMessageIn\x122\n" +
+	"\x15keepalive_message_out\x18\b \x01(\rR\x13keepaliveMessageOut\x120\n" +
+	"\x14keepalive_message_in\x18\t \x01(\rR\x12keepaliveMessageIn\x12(\n" +
+	"\x10open_message_out\x18\n" +
+	" \x01(\rR\x0eopenMessageOut\x12&\n" +
+	"\x0fopen_message_in\x18\v \x01(\rR\ropenMessageIn\x12)\n" +
+	"\x10notification_out\x18\f \x01(\rR\x0fnotificationOut\x12'\n" +
+	"\x0fnotification_in\x18\r \x01(\rR\x0enotificationIn\x12.\n" +
+	"\x13refresh_message_out\x18\x0e \x01(\rR\x11refreshMessageOut\x12,\n" +
+	"\x12refresh_message_in\x18\x0f \x01(\rR\x10refreshMessageIn\x12#\n" +
+	"\rdiscarded_out\x18\x10 \x01(\rR\fdiscardedOut\x12!\n" +
+	"\fdiscarded_in\x18\x11 \x01(\rR\vdiscardedIn\x12\x16\n" +
+	"\x06uptime\x18\x12 \x01(\x03R\x06uptime\x12\x1a\n" +
+	"\bdowntime\x18\x13 \x01(\x03R\bdowntime\x12\x1d\n" +
+	"\n" +
+	"last_error\x18\x14 \x01(\tR\tlastError\x12\x1a\n" +
+	"\breceived\x18\x15 \x01(\rR\breceived\x12\x1a\n" +
+	"\baccepted\x18\x16 \x01(\rR\baccepted\x12\x1e\n" +
+	"\n" +
+	"advertized\x18\x17 \x01(\rR\n" +
+	"advertized\x12\x13\n" +
+	"\x05out_q\x18\x18 \x01(\rR\x04outQ\x12\x14\n" +
+	"\x05flops\x18\x19 \x01(\rR\x05flops\"\\\n" +
+	"\x04Peer\x12)\n" +
+	"\x04conf\x18\x01 \x01(\v2\x15.gobgpapi.v1.PeerConfR\x04conf\x12)\n" +
+	"\x04info\x18\x02 \x01(\v2\x15.gobgpapi.v1.PeerInfoR\x04info\"7\n" +
+	"\rAsPathSegment\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\rR\x04type\x12\x12\n" +
+	"\x04asns\x18\x02 \x03(\rR\x04asns\"\xa4\x04\n" +
+	"\x04Path\x12\x16\n" +
+	"\x06prefix\x18\x01 \x01(\tR\x06prefix\x122\n" +
+	"\x06family\x18\x02 \x01(\v2\x1a.gobgpapi.v1.AddressFamilyR\x06family\x12\x18\n" +
+	"\anexthop\x18\x03 \x01(\tR\anexthop\x12\x1f\n" +
+	"\vis_withdraw\x18\x04 \x01(\bR\n" +
+	"isWithdraw\x12\x10\n" +
+	"\x03age\x18\x05 \x01(\x03R\x03age\x12%\n" +
+	"\x0esource_address\x18\x06 \x01(\tR\rsourceAddress\x12\x1b\n" +
+	"\tsource_as\x18\a \x01(\rR\bsourceAs\x12=\n" +
+	"\n" +
+	"validation\x18\b \x01(\x0e2\x1d.gobgpapi.v1.ValidationResultR\n" +
+	"validation\x12+\n" +
+	"\x06origin\x18\t \x01(\x0e2\x13.gobgpapi.v1.OriginR\x06origin\x123\n" +
+	"\aas_path\x18\n" +
+	" \x03(\v2\x1a.gobgpapi.v1.AsPathSegmentR\x06asPath\x12\x15\n" +
+	"\x03med\x18\v \x01(\rH\x00R\x03med\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"local_pref\x18\f \x01(\rH\x01R\tlocalPref\x88\x01\x01\x12 \n" +
+	"\vcommunities\x18\r \x03(\rR\vcommunities\x12\x12\n" +
+	"\x04nlri\x18\x0e \x01(\fR\x04nlri\x12\x16\n" +
+	"\x06pattrs\x18\x0f \x03(\fR\x06pattrsB\x06\n" +
+	"\x04_medB\r\n" +
+	"\v_local_pref\"r\n" +
+	"\vDestination\x12\x16\n" +
+	"\x06prefix\x18\x01 \x01(\tR\x06prefix\x12'\n" +
+	"\x05paths\x18\x02 \x03(\v2\x11.gobgpapi.v1.PathR\x05paths\x12\"\n" +
+	"\rbest_path_idx\x18\x03 \x01(\x05R\vbestPathIdx\"3\n" +
+	"\tStatement\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04hits\x18\x02 \x01(\x04R\x04hits\"T\n" +
+	"\x06Policy\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x126\n" +
+	"\n" +
+	"statements\x18\x02 \x03(\v2\x16.gobgpapi.v1.StatementR\n" +
+	"statements\"b\n" +
+	"\rBestPathEvent\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x16\n" +
+	"\x06prefix\x18\x02 \x01(\tR\x06prefix\x12%\n" +
+	"\x04path\x18\x03 \x01(\v2\x11.gobgpapi.v1.PathR\x04path\"\x95\x01\n" +
+	"\x11NotificationEvent\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\rR\x04code\x12\x18\n" +
+	"\asubcode\x18\x02 \x01(\rR\asubcode\x12\x1b\n" +
+	"\tcode_name\x18\x03 \x01(\tR\bcodeName\x12!\n" +
+	"\fsubcode_name\x18\x04 \x01(\tR\vsubcodeName\x12\x12\n" +
+	"\x04data\x18\x05 \x01(\fR\x04data\"t\n" +
+	"\x10PrefixLimitEvent\x12!\n" +
+	"\froute_family\x18\x01 \x01(\tR\vrouteFamily\x12\x1a\n" +
+	"\bprefixes\x18\x02 \x01(\rR\bprefixes\x12!\n" +
+	"\fmax_prefixes\x18\x03 \x01(\rR\vmaxPrefixes\"\xd1\x02\n" +
+	"\tPeerEvent\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x12\n" +
+	"\x04time\x18\x02 \x01(\x03R\x04time\x12)\n" +
+	"\x10neighbor_address\x18\x03 \x01(\tR\x0fneighborAddress\x12\x17\n" +
+	"\apeer_as\x18\x04 \x01(\rR\x06peerAs\x12\x1b\n" +
+	"\told_state\x18\x05 \x01(\tR\boldState\x12\x14\n" +
+	"\x05state\x18\x06 \x01(\tR\x05state\x12\x1f\n" +
+	"\vadmin_state\x18\a \x01(\tR\n" +
+	"adminState\x12B\n" +
+	"\fnotification\x18\b \x01(\v2\x1e.gobgpapi.v1.NotificationEventR\fnotification\x12@\n" +
+	"\fprefix_limit\x18\t \x01(\v2\x1d.gobgpapi.v1.PrefixLimitEventR\vprefixLimit*:\n" +
+	"\bResource\x12\n" +
+	"\n" +
+	"\x06GLOBAL\x10\x00\x12\t\n" +
+	"\x05LOCAL\x10\x01\x12\n" +
+	"\n" +
+	"\x06ADJ_IN\x10\x02\x12\v\n" +
+	"\aADJ_OUT\x10\x03*2\n" +
+	"\x03AFI\x12\x0f\n" +
+	"\vUNKNOWN_AFI\x10\x00\x12\x06\n" +
+	"\x02IP\x10\x01\x12\a\n" +
+	"\x03IP6\x10\x02\x12\t\n" +
+	"\x05L2VPN\x10\x19*\x9f\x01\n" +
+	"\x04SAFI\x12\x10\n" +
+	"\fUNKNOWN_SAFI\x10\x00\x12\v\n" +
+	"\aUNICAST\x10\x01\x12\r\n" +
+	"\tMULTICAST\x10\x02\x12\x0e\n" +
+	"\n" +
+	"MPLS_LABEL\x10\x04\x12\b\n" +
+	"\x04VPLS\x10A\x12\b\n" +
+	"\x04EVPN\x10F\x12\r\n" +
+	"\bMPLS_VPN\x10\x80\x01\x12\x17\n" +
+	"\x12MPLS_VPN_MULTICAST\x10\x81\x01\x12\x1d\n" +
+	"\x18ROUTE_TARGET_CONSTRAINTS\x10\x84\x01*?\n" +
+	"\x06Origin\x12\x0e\n" +
+	"\n" +
+	"ORIGIN_IGP\x10\x00\x12\x0e\n" +
+	"\n" +
+	"ORIGIN_EGP\x10\x01\x12\x15\n" +
+	"\x11ORIGIN_INCOMPLETE\x10\x02*o\n" +
+	"\x10ValidationResult\x12\x13\n" +
+	"\x0fVALIDATION_NONE\x10\x00\x12\x18\n" +
+	"\x14VALIDATION_NOT_FOUND\x10\x01\x12\x14\n" +
+	"\x10VALIDATION_VALID\x10\x02\x12\x16\n" +
+	"\x12VALIDATION_INVALID\x10\x032\xa1\a\n" +
+	"\bGobgpApi\x12=\n" +
+	"\fGetNeighbors\x12\x16.gobgpapi.v1.Arguments\x1a\x11.gobgpapi.v1.Peer\"\x000\x01\x12:\n" +
+	"\vGetNeighbor\x12\x16.gobgpapi.v1.Arguments\x1a\x11.gobgpapi.v1.Peer\"\x00\x12>\n" +
+	"\x06GetRib\x12\x16.gobgpapi.v1.Arguments\x1a\x18.gobgpapi.v1.Destination\"\x000\x01\x12:\n" +
+	"\tGetAdjRib\x12\x16.gobgpapi.v1.Arguments\x1a\x11.gobgpapi.v1.Path\"\x000\x01\x128\n" +
+	"\bShutdown\x12\x16.gobgpapi.v1.Arguments\x1a\x12.gobgpapi.v1.Error\"\x00\x125\n" +
+	"\x05Reset\x12\x16.gobgpapi.v1.Arguments\x1a\x12.gobgpapi.v1.Error\"\x00\x129\n" +
+	"\tSoftReset\x12\x16.gobgpapi.v1.Arguments\x1a\x12.gobgpapi.v1.Error\"\x00\x12;\n" +
+	"\vSoftResetIn\x12\x16.gobgpapi.v1.Arguments\x1a\x12.gobgpapi.v1.Error\"\x00\x12<\n" +
+	"\fSoftResetOut\x12\x16.gobgpapi.v1.Arguments\x1a\x12.gobgpapi.v1.Error\"\x00\x126\n" +
+	"\x06Enable\x12\x16.gobgpapi.v1.Arguments\x1a\x12.gobgpapi.v1.Error\"\x00\x127\n" +
+	"\aDisable\x12\x16.gobgpapi.v1.Arguments\x1a\x12.gobgpapi.v1.Error\"\x00\x12>\n" +
+	"\vGetPolicies\x12\x16.gobgpapi.v1.Arguments\x1a\x13.gobgpapi.v1.Policy\"\x000\x01\x12:\n" +
+	"\tGetPolicy\x12\x16.gobgpapi.v1.Arguments\x1a\x13.gobgpapi.v1.Policy\"\x00\x12G\n" +
+	"\rWatchBestPath\x12\x16.gobgpapi.v1.Arguments\x1a\x1a.gobgpapi.v1.BestPathEvent\"\x000\x01\x12A\n" +
+	"\vWatchEvents\x12\x16.gobgpapi.v1.Arguments\x1a\x16.gobgpapi.v1.PeerEvent\"\x000\x01B\x1fZ\x1dgithub.com/osrg/gobgp/api;apib\x06proto3"
+
+var (
+	file_gobgp_proto_rawDescOnce sync.Once
+	file_gobgp_proto_rawDescData []byte
+)
+
+func file_gobgp_proto_rawDescGZIP() []byte {
+	file_gobgp_proto_rawDescOnce.Do(func() {
+		file_gobgp_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_gobgp_proto_rawDesc), len(file_gobgp_proto_rawDesc)))
+	})
+	return file_gobgp_proto_rawDescData
+}
+
+var file_gobgp_proto_enumTypes = make([]protoimpl.EnumInfo, 6)
+var file_gobgp_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
+var file_gobgp_proto_goTypes = []any{
+	(Resource)(0),             // 0: gobgpapi.v1.Resource
+	(AFI)(0),                  // 1: gobgpapi.v1.AFI
+	(SAFI)(0),                 // 2: gobgpapi.v1.SAFI
+	(Origin)(0),               // 3: gobgpapi.v1.Origin
+	(ValidationResult)(0),     // 4: gobgpapi.v1.ValidationResult
+	(Error_ErrorCode)(0),      // 5: gobgpapi.v1.Error.ErrorCode
+	(*AddressFamily)(nil),     // 6: gobgpapi.v1.AddressFamily
+	(*Arguments)(nil),         // 7: gobgpapi.v1.Arguments
+	(*Error)(nil),             // 8: gobgpapi.v1.Error
+	(*PeerConf)(nil),          // 9: gobgpapi.v1.PeerConf
+	(*PeerInfo)(nil),          // 10: gobgpapi.v1.PeerInfo
+	(*Peer)(nil),              // 11: gobgpapi.v1.Peer
+	(*AsPathSegment)(nil),     // 12: gobgpapi.v1.AsPathSegment
+	(*Path)(nil),              // 13: gobgpapi.v1.Path
+	(*Destination)(nil),       // 14: gobgpapi.v1.Destination
+	(*Statement)(nil),         // 15: gobgpapi.v1.Statement
+	(*Policy)(nil),            // 16: gobgpapi.v1.Policy
+	(*BestPathEvent)(nil),     // 17: gobgpapi.v1.BestPathEvent
+	(*NotificationEvent)(nil), // 18: gobgpapi.v1.NotificationEvent
+	(*PrefixLimitEvent)(nil),  // 19: gobgpapi.v1.PrefixLimitEvent
+	(*PeerEvent)(nil),         // 20: gobgpapi.v1.PeerEvent
+}
+var file_gobgp_proto_depIdxs = []int32{
+	1,  // 0: gobgpapi.v1.AddressFamily.afi:type_name -> gobgpapi.v1.AFI
+	2,  // 1: gobgpapi.v1.AddressFamily.safi:type_name -> gobgpapi.v1.SAFI
+	0,  // 2: gobgpapi.v1.Arguments.resource:type_name -> gobgpapi.v1.Resource
+	6,  // 3: gobgpapi.v1.Arguments.af:type_name -> gobgpapi.v1.AddressFamily
+	5,  // 4: gobgpapi.v1.Error.code:type_name -> gobgpapi.v1.Error.ErrorCode
+	9,  // 5: gobgpapi.v1.Peer.conf:type_name -> gobgpapi.v1.PeerConf
+	10, // 6: gobgpapi.v1.Peer.info:type_name -> gobgpapi.v1.PeerInfo
+	6,  // 7: gobgpapi.v1.Path.family:type_name -> gobgpapi.v1.AddressFamily
+	4,  // 8: gobgpapi.v1.Path.validation:type_name -> gobgpapi.v1.ValidationResult
+	3,  // 9: gobgpapi.v1.Path.origin:type_name -> gobgpapi.v1.Origin
+	12, // 10: gobgpapi.v1.Path.as_path:type_name -> gobgpapi.v1.AsPathSegment
+	13, // 11: gobgpapi.v1.Destination.paths:type_name -> gobgpapi.v1.Path
+	15, // 12: gobgpapi.v1.Policy.statements:type_name -> gobgpapi.v1.Statement
+	13, // 13: gobgpapi.v1.BestPathEvent.path:type_name -> gobgpapi.v1.Path
+	18, // 14: gobgpapi.v1.PeerEvent.notification:type_name -> gobgpapi.v1.NotificationEvent
+	19, // 15: gobgpapi.v1.PeerEvent.prefix_limit:type_name -> gobgpapi.v1.PrefixLimitEvent
+	7,  // 16: gobgpapi.v1.GobgpApi.GetNeighbors:input_type -> gobgpapi.v1.Arguments
+	7,  // 17: gobgpapi.v1.GobgpApi.GetNeighbor:input_type -> gobgpapi.v1.Arguments
+	7,  // 18: gobgpapi.v1.GobgpApi.GetRib:input_type -> gobgpapi.v1.Arguments
+	7,  // 19: gobgpapi.v1.GobgpApi.GetAdjRib:input_type -> gobgpapi.v1.Arguments
+	7,  // 20: gobgpapi.v1.GobgpApi.Shutdown:input_type -> gobgpapi.v1.Arguments
+	7,  // 21: gobgpapi.v1.GobgpApi.Reset:input_type -> gobgpapi.v1.Arguments
+	7,  // 22: gobgpapi.v1.GobgpApi.SoftReset:input_type -> gobgpapi.v1.Arguments
+	7,  // 23: gobgpapi.v1.GobgpApi.SoftResetIn:input_type -> gobgpapi.v1.Arguments
+	7,  // 24: gobgpapi.v1.GobgpApi.SoftResetOut:input_type -> gobgpapi.v1.Arguments
+	7,  // 25: gobgpapi.v1.GobgpApi.Enable:input_type -> gobgpapi.v1.Arguments
+	7,  // 26: gobgpapi.v1.GobgpApi.Disable:input_type -> gobgpapi.v1.Arguments
+	7,  // 27: gobgpapi.v1.GobgpApi.GetPolicies:input_type -> gobgpapi.v1.Arguments
+	7,  // 28: gobgpapi.v1.GobgpApi.GetPolicy:input_type -> gobgpapi.v1.Arguments
+	7,  // 29: gobgpapi.v1.GobgpApi.WatchBestPath:input_type -> gobgpapi.v1.Arguments
+	7,  // 30: gobgpapi.v1.GobgpApi.WatchEvents:input_type -> gobgpapi.v1.Arguments
+	11, // 31: gobgpapi.v1.GobgpApi.GetNeighbors:output_type -> gobgpapi.v1.Peer
+	11, // 32: gobgpapi.v1.GobgpApi.GetNeighbor:output_type -> gobgpapi.v1.Peer
+	14, // 33: gobgpapi.v1.GobgpApi.GetRib:output_type -> gobgpapi.v1.Destination
+	13, // 34: gobgpapi.v1.GobgpApi.GetAdjRib:output_type -> gobgpapi.v1.Path
+	8,  // 35: gobgpapi.v1.GobgpApi.Shutdown:output_type -> gobgpapi.v1.Error
+	8,  // 36: gobgpapi.v1.GobgpApi.Reset:output_type -> gobgpapi.v1.Error
+	8,  // 37: gobgpapi.v1.GobgpApi.SoftReset:output_type -> gobgpapi.v1.Error
+	8,  // 38: gobgpapi.v1.GobgpApi.SoftResetIn:output_type -> gobgpapi.v1.Error
+	8,  // 39: gobgpapi.v1.GobgpApi.SoftResetOut:output_type -> gobgpapi.v1.Error
+	8,  // 40: gobgpapi.v1.GobgpApi.Enable:output_type -> gobgpapi.v1.Error
+	8,  // 41: gobgpapi.v1.GobgpApi.Disable:output_type -> gobgpapi.v1.Error
+	16, // 42: gobgpapi.v1.GobgpApi.GetPolicies:output_type -> gobgpapi.v1.Policy
+	16, // 43: gobgpapi.v1.GobgpApi.GetPolicy:output_type -> gobgpapi.v1.Policy
+	17, // 44: gobgpapi.v1.GobgpApi.WatchBestPath:output_type -> gobgpapi.v1.BestPathEvent
+	20, // 45: gobgpapi.v1.GobgpApi.WatchEvents:output_type -> gobgpapi.v1.PeerEvent
+	31, // [31:46] is the sub-list for method output_type
+	16, // [16:31] is the sub-list for method input_type
+	16, // [16:16] is the sub-list for extension type_name
+	16, // [16:16] is the sub-list for extension extendee
+	0,  // [0:16] is the sub-list for field type_name
+}
+
+func init() { file_gobgp_proto_init() }
+func file_gobgp_proto_init() {
+	if File_gobgp_proto != nil {
+		return
+	}
+	file_gobgp_proto_msgTypes[7].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_gobgp_proto_rawDesc), len(file_gobgp_proto_rawDesc)),
+			NumEnums:      6,
+			NumMessages:   15,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_gobgp_proto_goTypes,
+		DependencyIndexes: file_gobgp_proto_depIdxs,
+		EnumInfos:         file_gobgp_proto_enumTypes,
+		MessageInfos:      file_gobgp_proto_msgTypes,
+	}.Build()
+	File_gobgp_proto = out.File
+	file_gobgp_proto_goTypes = nil
+	file_gobgp_proto_depIdxs = nil
+}
@@ -0,0 +1,705 @@
+// Copyright (C) 2015 Nippon Telegraph and Telephone Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: gobgp.proto
+
+package api
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	GobgpApi_GetNeighbors_FullMethodName  = "/gobgpapi.v1.GobgpApi/GetNeighbors"
+	GobgpApi_GetNeighbor_FullMethodName   = "/gobgpapi.v1.GobgpApi/GetNeighbor"
+	GobgpApi_GetRib_FullMethodName        = "/gobgpapi.v1.GobgpApi/GetRib"
+	GobgpApi_GetAdjRib_FullMethodName     = "/gobgpapi.v1.GobgpApi/GetAdjRib"
+	GobgpApi_Shutdown_FullMethodName      = "/gobgpapi.v1.GobgpApi/Shutdown"
+	GobgpApi_Reset_FullMethodName         = "/gobgpapi.v1.GobgpApi/Reset"
+	GobgpApi_SoftReset_FullMethodName     = "/gobgpapi.v1.GobgpApi/SoftReset"
+	GobgpApi_SoftResetIn_FullMethodName   = "/gobgpapi.v1.GobgpApi/SoftResetIn"
+	GobgpApi_SoftResetOut_FullMethodName  = "/gobgpapi.v1.GobgpApi/SoftResetOut"
+	GobgpApi_Enable_FullMethodName        = "/gobgpapi.v1.GobgpApi/Enable"
+	GobgpApi_Disable_FullMethodName       = "/gobgpapi.v1.GobgpApi/Disable"
+	GobgpApi_GetPolicies_FullMethodName   = "/gobgpapi.v1.GobgpApi/GetPolicies"
+	GobgpApi_GetPolicy_FullMethodName     = "/gobgpapi.v1.GobgpApi/GetPolicy"
+	GobgpApi_WatchBestPath_FullMethodName = "/gobgpapi.v1.GobgpApi/WatchBestPath"
+	GobgpApi_WatchEvents_FullMethodName   = "/gobgpapi.v1.GobgpApi/WatchEvents"
+)
+
+// GobgpApiClient is the client API for GobgpApi service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// management api of gobgpd. the messages are only extended with new
+// fields and the field numbers are never reused.
+type GobgpApiClient interface {
+	GetNeighbors(ctx context.Context, in *Arguments, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Peer], error)
+	GetNeighbor(ctx context.Context, in *Arguments, opts ...grpc.CallOption) (*Peer, error)
+	// the destinations of the global rib or the local rib of a route
+	// server client in the order of the prefixes
+	GetRib(ctx context.Context, in *Arguments, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Destination], error)
+	GetAdjRib(ctx context.Context, in *Arguments, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Path], error)
+	Shutdown(ctx context.Context, in *Arguments, opts ...grpc.CallOption) (*Error, error)
+	Reset(ctx context.Context, in *Arguments, opts ...grpc.CallOption) (*Error, error)
+	SoftReset(ctx context.Context, in *Arguments, opts ...grpc.CallOption) (*Error, error)
+	SoftResetIn(ctx context.Context, in *Arguments, opts ...grpc.CallOption) (*Error, error)
+	SoftResetOut(ctx context.Context, in *Arguments, opts ...grpc.CallOption) (*Error, error)
+	Enable(ctx context.Context, in *Arguments, opts ...grpc.CallOption) (*Error, error)
+	Disable(ctx context.Context, in *Arguments, opts ...grpc.CallOption) (*Error, error)
+	GetPolicies(ctx context.Context, in *Arguments, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Policy], error)
+	GetPolicy(ctx context.Context, in *Arguments, opts ...grpc.CallOption) (*Policy, error)
+	// the best path changes of the address family in the global rib. the
+	// current best paths are sent as the add events first if the snapshot
+	// is requested.
+	WatchBestPath(ctx context.Context, in *Arguments, opts ...grpc.CallOption) (grpc.ServerStreamingClient[BestPathEvent], error)
+	// the events of all the neighbors, or the neighbor in the arguments
+	WatchEvents(ctx context.Context, in *Arguments, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PeerEvent], error)
+}
+
+type gobgpApiClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGobgpApiClient(cc grpc.ClientConnInterface) GobgpApiClient {
+	return &gobgpApiClient{cc}
+}
+
+func (c *gobgpApiClient) GetNeighbors(ctx context.Context, in *Arguments, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Peer], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &GobgpApi_ServiceDesc.Streams[0], GobgpApi_GetNeighbors_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Arguments, Peer]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GobgpApi_GetNeighborsClient = grpc.ServerStreamingClient[Peer]
+
+func (c *gobgpApiClient) GetNeighbor(ctx context.Context, in *Arguments, opts ...grpc.CallOption) (*Peer, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Peer)
+	err := c.cc.Invoke(ctx, GobgpApi_GetNeighbor_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gobgpApiClient) GetRib(ctx context.Context, in *Arguments, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Destination], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &GobgpApi_ServiceDesc.Streams[1], GobgpApi_GetRib_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Arguments, Destination]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GobgpApi_GetRibClient = grpc.ServerStreamingClient[Destination]
+
+func (c *gobgpApiClient) GetAdjRib(ctx context.Context, in *Arguments, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Path], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &GobgpApi_ServiceDesc.Streams[2], GobgpApi_GetAdjRib_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Arguments, Path]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GobgpApi_GetAdjRibClient = grpc.ServerStreamingClient[Path]
+
+func (c *gobgpApiClient) Shutdown(ctx context.Context, in *Arguments, opts ...grpc.CallOption) (*Error, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Error)
+	err := c.cc.Invoke(ctx, GobgpApi_Shutdown_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gobgpApiClient) Reset(ctx context.Context, in *Arguments, opts ...grpc.CallOption) (*Error, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Error)
+	err := c.cc.Invoke(ctx, GobgpApi_Reset_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gobgpApiClient) SoftReset(ctx context.Context, in *Arguments, opts ...grpc.CallOption) (*Error, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Error)
+	err := c.cc.Invoke(ctx, GobgpApi_SoftReset_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gobgpApiClient) SoftResetIn(ctx context.Context, in *Arguments, opts ...grpc.CallOption) (*Error, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Error)
+	err := c.cc.Invoke(ctx, GobgpApi_SoftResetIn_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gobgpApiClient) SoftResetOut(ctx context.Context, in *Arguments, opts ...grpc.CallOption) (*Error, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Error)
+	err := c.cc.Invoke(ctx, GobgpApi_SoftResetOut_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gobgpApiClient) Enable(ctx context.Context, in *Arguments, opts ...grpc.CallOption) (*Error, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Error)
+	err := c.cc.Invoke(ctx, GobgpApi_Enable_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gobgpApiClient) Disable(ctx context.Context, in *Arguments, opts ...grpc.CallOption) (*Error, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Error)
+	err := c.cc.Invoke(ctx, GobgpApi_Disable_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gobgpApiClient) GetPolicies(ctx context.Context, in *Arguments, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Policy], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &GobgpApi_ServiceDesc.Streams[3], GobgpApi_GetPolicies_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Arguments, Policy]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GobgpApi_GetPoliciesClient = grpc.ServerStreamingClient[Policy]
+
+func (c *gobgpApiClient) GetPolicy(ctx context.Context, in *Arguments, opts ...grpc.CallOption) (*Policy, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Policy)
+	err := c.cc.Invoke(ctx, GobgpApi_GetPolicy_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gobgpApiClient) WatchBestPath(ctx context.Context, in *Arguments, opts ...grpc.CallOption) (grpc.ServerStreamingClient[BestPathEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &GobgpApi_ServiceDesc.Streams[4], GobgpApi_WatchBestPath_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Arguments, BestPathEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GobgpApi_WatchBestPathClient = grpc.ServerStreamingClient[BestPathEvent]
+
+func (c *gobgpApiClient) WatchEvents(ctx context.Context, in *Arguments, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PeerEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &GobgpApi_ServiceDesc.Streams[5], GobgpApi_WatchEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Arguments, PeerEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GobgpApi_WatchEventsClient = grpc.ServerStreamingClient[PeerEvent]
+
+// GobgpApiServer is the server API for GobgpApi service.
+// All implementations must embed UnimplementedGobgpApiServer
+// for forward compatibility.
+//
+// management api of gobgpd. the messages are only extended with new
+// fields and the field numbers are never reused.
+type GobgpApiServer interface {
+	GetNeighbors(*Arguments, grpc.ServerStreamingServer[Peer]) error
+	GetNeighbor(context.Context, *Arguments) (*Peer, error)
+	// the destinations of the global rib or the local rib of a route
+	// server client in the order of the prefixes
+	GetRib(*Arguments, grpc.ServerStreamingServer[Destination]) error
+	GetAdjRib(*Arguments, grpc.ServerStreamingServer[Path]) error
+	Shutdown(context.Context, *Arguments) (*Error, error)
+	Reset(context.Context, *Arguments) (*Error, error)
+	SoftReset(context.Context, *Arguments) (*Error, error)
+	SoftResetIn(context.Context, *Arguments) (*Error, error)
+	SoftResetOut(context.Context, *Arguments) (*Error, error)
+	Enable(context.Context, *Arguments) (*Error, error)
+	Disable(context.Context, *Arguments) (*Error, error)
+	GetPolicies(*Arguments, grpc.ServerStreamingServer[Policy]) error
+	GetPolicy(context.Context, *Arguments) (*Policy, error)
+	// the best path changes of the address family in the global rib. the
+	// current best paths are sent as the add events first if the snapshot
+	// is requested.
+	WatchBestPath(*Arguments, grpc.ServerStreamingServer[BestPathEvent]) error
+	// the events of all the neighbors, or the neighbor in the arguments
+	WatchEvents(*Arguments, grpc.ServerStreamingServer[PeerEvent]) error
+	mustEmbedUnimplementedGobgpApiServer()
+}
+
+// UnimplementedGobgpApiServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedGobgpApiServer struct{}
+
+func (UnimplementedGobgpApiServer) GetNeighbors(*Arguments, grpc.ServerStreamingServer[Peer]) error {
+	return status.Errorf(codes.Unimplemented, "method GetNeighbors not implemented")
+}
+func (UnimplementedGobgpApiServer) GetNeighbor(context.Context, *Arguments) (*Peer, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNeighbor not implemented")
+}
+func (UnimplementedGobgpApiServer) GetRib(*Arguments, grpc.ServerStreamingServer[Destination]) error {
+	return status.Errorf(codes.Unimplemented, "method GetRib not implemented")
+}
+func (UnimplementedGobgpApiServer) GetAdjRib(*Arguments, grpc.ServerStreamingServer[Path]) error {
+	return status.Errorf(codes.Unimplemented, "method GetAdjRib not implemented")
+}
+func (UnimplementedGobgpApiServer) Shutdown(context.Context, *Arguments) (*Error, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Shutdown not implemented")
+}
+func (UnimplementedGobgpApiServer) Reset(context.Context, *Arguments) (*Error, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Reset not implemented")
+}
+func (UnimplementedGobgpApiServer) SoftReset(context.Context, *Arguments) (*Error, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SoftReset not implemented")
+}
+func (UnimplementedGobgpApiServer) SoftResetIn(context.Context, *Arguments) (*Error, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SoftResetIn not implemented")
+}
+func (UnimplementedGobgpApiServer) SoftResetOut(context.Context, *Arguments) (*Error, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SoftResetOut not implemented")
+}
+func (UnimplementedGobgpApiServer) Enable(context.Context, *Arguments) (*Error, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Enable not implemented")
+}
+func (UnimplementedGobgpApiServer) Disable(context.Context, *Arguments) (*Error, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Disable not implemented")
+}
+func (UnimplementedGobgpApiServer) GetPolicies(*Arguments, grpc.ServerStreamingServer[Policy]) error {
+	return status.Errorf(codes.Unimplemented, "method GetPolicies not implemented")
+}
+func (UnimplementedGobgpApiServer) GetPolicy(context.Context, *Arguments) (*Policy, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPolicy not implemented")
+}
+func (UnimplementedGobgpApiServer) WatchBestPath(*Arguments, grpc.ServerStreamingServer[BestPathEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchBestPath not implemented")
+}
+func (UnimplementedGobgpApiServer) WatchEvents(*Arguments, grpc.ServerStreamingServer[PeerEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchEvents not implemented")
+}
+func (UnimplementedGobgpApiServer) mustEmbedUnimplementedGobgpApiServer() {}
+func (UnimplementedGobgpApiServer) testEmbeddedByValue()                  {}
+
+// UnsafeGobgpApiServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GobgpApiServer will
+// result in compilation errors.
+type UnsafeGobgpApiServer interface {
+	mustEmbedUnimplementedGobgpApiServer()
+}
+
+func RegisterGobgpApiServer(s grpc.ServiceRegistrar, srv GobgpApiServer) {
+	// If the following call pancis, it indicates UnimplementedGobgpApiServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&GobgpApi_ServiceDesc, srv)
+}
+
+func _GobgpApi_GetNeighbors_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Arguments)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GobgpApiServer).GetNeighbors(m, &grpc.GenericServerStream[Arguments, Peer]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GobgpApi_GetNeighborsServer = grpc.ServerStreamingServer[Peer]
+
+func _GobgpApi_GetNeighbor_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Arguments)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GobgpApiServer).GetNeighbor(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GobgpApi_GetNeighbor_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GobgpApiServer).GetNeighbor(ctx, req.(*Arguments))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GobgpApi_GetRib_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Arguments)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GobgpApiServer).GetRib(m, &grpc.GenericServerStream[Arguments, Destination]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GobgpApi_GetRibServer = grpc.ServerStreamingServer[Destination]
+
+func _GobgpApi_GetAdjRib_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Arguments)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GobgpApiServer).GetAdjRib(m, &grpc.GenericServerStream[Arguments, Path]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GobgpApi_GetAdjRibServer = grpc.ServerStreamingServer[Path]
+
+func _GobgpApi_Shutdown_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Arguments)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GobgpApiServer).Shutdown(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GobgpApi_Shutdown_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GobgpApiServer).Shutdown(ctx, req.(*Arguments))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GobgpApi_Reset_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Arguments)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GobgpApiServer).Reset(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GobgpApi_Reset_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GobgpApiServer).Reset(ctx, req.(*Arguments))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GobgpApi_SoftReset_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Arguments)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GobgpApiServer).SoftReset(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GobgpApi_SoftReset_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GobgpApiServer).SoftReset(ctx, req.(*Arguments))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GobgpApi_SoftResetIn_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Arguments)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GobgpApiServer).SoftResetIn(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GobgpApi_SoftResetIn_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GobgpApiServer).SoftResetIn(ctx, req.(*Arguments))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GobgpApi_SoftResetOut_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Arguments)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GobgpApiServer).SoftResetOut(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GobgpApi_SoftResetOut_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GobgpApiServer).SoftResetOut(ctx, req.(*Arguments))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GobgpApi_Enable_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Arguments)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GobgpApiServer).Enable(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GobgpApi_Enable_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GobgpApiServer).Enable(ctx, req.(*Arguments))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GobgpApi_Disable_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Arguments)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GobgpApiServer).Disable(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GobgpApi_Disable_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GobgpApiServer).Disable(ctx, req.(*Arguments))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GobgpApi_GetPolicies_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Arguments)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GobgpApiServer).GetPolicies(m, &grpc.GenericServerStream[Arguments, Policy]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GobgpApi_GetPoliciesServer = grpc.ServerStreamingServer[Policy]
+
+func _GobgpApi_GetPolicy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Arguments)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GobgpApiServer).GetPolicy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GobgpApi_GetPolicy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GobgpApiServer).GetPolicy(ctx, req.(*Arguments))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GobgpApi_WatchBestPath_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Arguments)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GobgpApiServer).WatchBestPath(m, &grpc.GenericServerStream[Arguments, BestPathEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GobgpApi_WatchBestPathServer = grpc.ServerStreamingServer[BestPathEvent]
+
+func _GobgpApi_WatchEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Arguments)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GobgpApiServer).WatchEvents(m, &grpc.GenericServerStream[Arguments, PeerEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GobgpApi_WatchEventsServer = grpc.ServerStreamingServer[PeerEvent]
+
+// GobgpApi_ServiceDesc is the grpc.ServiceDesc for GobgpApi service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var GobgpApi_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gobgpapi.v1.GobgpApi",
+	HandlerType: (*GobgpApiServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetNeighbor",
+			Handler:    _GobgpApi_GetNeighbor_Handler,
+		},
+		{
+			MethodName: "Shutdown",
+			Handler:    _GobgpApi_Shutdown_Handler,
+		},
+		{
+			MethodName: "Reset",
+			Handler:    _GobgpApi_Reset_Handler,
+		},
+		{
+			MethodName: "SoftReset",
+			Handler:    _GobgpApi_SoftReset_Handler,
+		},
+		{
+			MethodName: "SoftResetIn",
+			Handler:    _GobgpApi_SoftResetIn_Handler,
+		},
+		{
+			MethodName: "SoftResetOut",
+			Handler:    _GobgpApi_SoftResetOut_Handler,
+		},
+		{
+			MethodName: "Enable",
+			Handler:    _GobgpApi_Enable_Handler,
+		},
+		{
+			MethodName: "Disable",
+			Handler:    _GobgpApi_Disable_Handler,
+		},
+		{
+			MethodName: "GetPolicy",
+			Handler:    _GobgpApi_GetPolicy_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "GetNeighbors",
+			Handler:       _GobgpApi_GetNeighbors_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetRib",
+			Handler:       _GobgpApi_GetRib_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetAdjRib",
+			Handler:       _GobgpApi_GetAdjRib_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetPolicies",
+			Handler:       _GobgpApi_GetPolicies_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchBestPath",
+			Handler:       _GobgpApi_WatchBestPath_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchEvents",
+			Handler:       _GobgpApi_WatchEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "gobgp.proto",
+}
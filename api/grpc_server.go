@@ -0,0 +1,279 @@
+// Copyright (C) 2015 Nippon Telegraph and Telephone Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/osrg/gobgp/packet"
+	"google.golang.org/grpc"
+	"net"
+)
+
+const GRPC_PORT = 50051
+
+// the request sent to the bgp server for a grpc call. the server
+// sends one response for a unary call and any number of responses
+// for a streaming call, then closes ResponseCh.
+type GrpcRequest struct {
+	RequestType int
+	RemoteAddr  string
+	RouteFamily bgp.RouteFamily
+	Name        string
+	// send the current best paths before the changes
+	Snapshot   bool
+	ResponseCh chan *GrpcResponse
+	// closed when the client watching the events goes away
+	Done chan struct{}
+}
+
+func NewGrpcRequest(reqType int, remoteAddr string, rf bgp.RouteFamily) *GrpcRequest {
+	return &GrpcRequest{
+		RequestType: reqType,
+		RouteFamily: rf,
+		RemoteAddr:  remoteAddr,
+		ResponseCh:  make(chan *GrpcResponse),
+	}
+}
+
+type GrpcResponse struct {
+	ResponseErr error
+	Data        interface{}
+}
+
+func (r *GrpcResponse) Err() error {
+	return r.ResponseErr
+}
+
+type Server struct {
+	UnimplementedGobgpApiServer
+	grpcServer  *grpc.Server
+	port        int
+	bgpServerCh chan *GrpcRequest
+}
+
+func NewGrpcServer(port int, bgpServerCh chan *GrpcRequest) *Server {
+	s := &Server{
+		grpcServer:  grpc.NewServer(),
+		port:        port,
+		bgpServerCh: bgpServerCh,
+	}
+	RegisterGobgpApiServer(s.grpcServer, s)
+	return s
+}
+
+func (s *Server) Serve() error {
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", s.port))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"Topic": "Grpc",
+			"Key":   s.port,
+		}).Error("failed to listen: ", err)
+		return err
+	}
+	return s.grpcServer.Serve(l)
+}
+
+func routeFamily(arg *Arguments) bgp.RouteFamily {
+	af := arg.GetAf()
+	if af == nil {
+		return bgp.RF_IPv4_UC
+	}
+	return bgp.AfiSafiToRouteFamily(uint16(af.Afi), uint8(af.Safi))
+}
+
+func (s *Server) newRequest(reqType int, arg *Arguments) *GrpcRequest {
+	req := NewGrpcRequest(reqType, arg.NeighborAddress, routeFamily(arg))
+	req.Name = arg.Name
+	req.Snapshot = arg.Snapshot
+	return req
+}
+
+func (s *Server) request(reqType int, arg *Arguments) *GrpcRequest {
+	req := s.newRequest(reqType, arg)
+	s.bgpServerCh <- req
+	return req
+}
+
+func (s *Server) get(reqType int, arg *Arguments) (interface{}, error) {
+	res := <-s.request(reqType, arg).ResponseCh
+	if err := res.Err(); err != nil {
+		log.Debug(err.Error())
+		return nil, err
+	}
+	return res.Data, nil
+}
+
+// the responses left after the client goes away are discarded not to
+// block the bgp server
+func (s *Server) stream(reqType int, arg *Arguments, send func(interface{}) error) error {
+	req := s.request(reqType, arg)
+	for res := range req.ResponseCh {
+		err := res.Err()
+		if err == nil {
+			err = send(res.Data)
+		}
+		if err != nil {
+			log.Debug(err.Error())
+			go func() {
+				for range req.ResponseCh {
+				}
+			}()
+			return err
+		}
+	}
+	return nil
+}
+
+// send the events until the client goes away. the bgp server never
+// blocks on the client; it closes the response channel if the client
+// can't keep up with the events.
+func (s *Server) watch(ctx context.Context, reqType int, arg *Arguments, send func(interface{}) error) error {
+	req := s.newRequest(reqType, arg)
+	req.ResponseCh = make(chan *GrpcResponse, WATCH_QUEUE_SIZE)
+	req.Done = make(chan struct{})
+	defer close(req.Done)
+	s.bgpServerCh <- req
+	for {
+		select {
+		case res, ok := <-req.ResponseCh:
+			if !ok {
+				return fmt.Errorf("the events can't be sent fast enough")
+			}
+			if err := res.Err(); err != nil {
+				return err
+			}
+			if err := send(res.Data); err != nil {
+				log.Debug(err.Error())
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *Server) neighborOp(reqType int, arg *Arguments) (*Error, error) {
+	d, err := s.get(reqType, arg)
+	if err != nil {
+		return &Error{Code: Error_FAIL, Msg: err.Error()}, nil
+	}
+	return d.(*Error), nil
+}
+
+func (s *Server) GetNeighbors(arg *Arguments, stream GobgpApi_GetNeighborsServer) error {
+	return s.stream(REQ_NEIGHBORS, arg, func(d interface{}) error {
+		return stream.Send(d.(*Peer))
+	})
+}
+
+func (s *Server) GetNeighbor(ctx context.Context, arg *Arguments) (*Peer, error) {
+	d, err := s.get(REQ_NEIGHBOR, arg)
+	if err != nil {
+		return nil, err
+	}
+	return d.(*Peer), nil
+}
+
+func (s *Server) GetRib(arg *Arguments, stream GobgpApi_GetRibServer) error {
+	var reqType int
+	switch arg.Resource {
+	case Resource_GLOBAL:
+		reqType = REQ_GLOBAL_RIB
+	case Resource_LOCAL:
+		reqType = REQ_LOCAL_RIB
+	default:
+		return fmt.Errorf("unsupported resource type: %v", arg.Resource)
+	}
+	return s.stream(reqType, arg, func(d interface{}) error {
+		return stream.Send(d.(*Destination))
+	})
+}
+
+func (s *Server) GetAdjRib(arg *Arguments, stream GobgpApi_GetAdjRibServer) error {
+	var reqType int
+	switch arg.Resource {
+	case Resource_ADJ_IN:
+		reqType = REQ_ADJ_RIB_IN
+	case Resource_ADJ_OUT:
+		reqType = REQ_ADJ_RIB_OUT
+	default:
+		return fmt.Errorf("unsupported resource type: %v", arg.Resource)
+	}
+	return s.stream(reqType, arg, func(d interface{}) error {
+		return stream.Send(d.(*Path))
+	})
+}
+
+func (s *Server) Shutdown(ctx context.Context, arg *Arguments) (*Error, error) {
+	return s.neighborOp(REQ_NEIGHBOR_SHUTDOWN, arg)
+}
+
+func (s *Server) Reset(ctx context.Context, arg *Arguments) (*Error, error) {
+	return s.neighborOp(REQ_NEIGHBOR_RESET, arg)
+}
+
+func (s *Server) SoftReset(ctx context.Context, arg *Arguments) (*Error, error) {
+	return s.neighborOp(REQ_NEIGHBOR_SOFT_RESET, arg)
+}
+
+func (s *Server) SoftResetIn(ctx context.Context, arg *Arguments) (*Error, error) {
+	return s.neighborOp(REQ_NEIGHBOR_SOFT_RESET_IN, arg)
+}
+
+func (s *Server) SoftResetOut(ctx context.Context, arg *Arguments) (*Error, error) {
+	return s.neighborOp(REQ_NEIGHBOR_SOFT_RESET_OUT, arg)
+}
+
+func (s *Server) Enable(ctx context.Context, arg *Arguments) (*Error, error) {
+	return s.neighborOp(REQ_NEIGHBOR_ENABLE, arg)
+}
+
+func (s *Server) Disable(ctx context.Context, arg *Arguments) (*Error, error) {
+	return s.neighborOp(REQ_NEIGHBOR_DISABLE, arg)
+}
+
+func (s *Server) GetPolicies(arg *Arguments, stream GobgpApi_GetPoliciesServer) error {
+	return s.stream(REQ_POLICIES, arg, func(d interface{}) error {
+		return stream.Send(d.(*Policy))
+	})
+}
+
+func (s *Server) GetPolicy(ctx context.Context, arg *Arguments) (*Policy, error) {
+	d, err := s.get(REQ_POLICY, arg)
+	if err != nil {
+		return nil, err
+	}
+	return d.(*Policy), nil
+}
+
+func (s *Server) WatchBestPath(arg *Arguments, stream GobgpApi_WatchBestPathServer) error {
+	return s.watch(stream.Context(), REQ_GLOBAL_RIB_WATCH, arg, func(d interface{}) error {
+		for _, e := range d.([]*BestPathEvent) {
+			if err := stream.Send(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Server) WatchEvents(arg *Arguments, stream GobgpApi_WatchEventsServer) error {
+	return s.watch(stream.Context(), REQ_EVENTS, arg, func(d interface{}) error {
+		return stream.Send(d.(*PeerEvent))
+	})
+}
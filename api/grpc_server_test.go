@@ -0,0 +1,140 @@
+// Copyright (C) 2015 Nippon Telegraph and Telephone Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"fmt"
+	"github.com/osrg/gobgp/packet"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// answers the requests in place of the bgp server
+func grpcTestBgpServer(ch chan *GrpcRequest, done chan int) {
+	for req := range ch {
+		switch req.RequestType {
+		case REQ_NEIGHBORS:
+			for _, addr := range []string{"10.0.0.1", "10.0.0.2"} {
+				req.ResponseCh <- &GrpcResponse{Data: &Peer{Conf: &PeerConf{RemoteIp: addr}}}
+			}
+		case REQ_NEIGHBOR:
+			req.ResponseCh <- &GrpcResponse{ResponseErr: fmt.Errorf("Neighbor that has %v does not exist.", req.RemoteAddr)}
+		case REQ_GLOBAL_RIB:
+			req.ResponseCh <- &GrpcResponse{Data: &Destination{Prefix: req.RouteFamily.String()}}
+		case REQ_NEIGHBOR_ENABLE:
+			req.ResponseCh <- &GrpcResponse{Data: &Error{Msg: "ADMIN_STATE_UP"}}
+		case REQ_POLICIES:
+			for i := 0; i < 100; i++ {
+				req.ResponseCh <- &GrpcResponse{Data: &Policy{Name: fmt.Sprintf("policy%d", i)}}
+			}
+			done <- 1
+		case REQ_EVENTS:
+			req.ResponseCh <- &GrpcResponse{Data: &PeerEvent{Type: "peer-up", NeighborAddress: req.RemoteAddr}}
+			// the stream is kept open until the client goes away
+			go func(req *GrpcRequest) {
+				<-req.Done
+				done <- 1
+			}(req)
+			continue
+		}
+		close(req.ResponseCh)
+	}
+}
+
+func TestGrpcServer(t *testing.T) {
+	assert := assert.New(t)
+	ch := make(chan *GrpcRequest)
+	done := make(chan int)
+	go grpcTestBgpServer(ch, done)
+
+	s := NewGrpcServer(0, ch)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(err)
+	go s.grpcServer.Serve(l)
+	defer s.grpcServer.Stop()
+
+	conn, err := grpc.NewClient(l.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.Nil(err)
+	defer conn.Close()
+	client := NewGobgpApiClient(conn)
+
+	stream, err := client.GetNeighbors(context.Background(), &Arguments{})
+	assert.Nil(err)
+	addrs := []string{}
+	for {
+		p, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(err)
+		addrs = append(addrs, p.Conf.RemoteIp)
+	}
+	assert.Equal(addrs, []string{"10.0.0.1", "10.0.0.2"})
+
+	_, err = client.GetNeighbor(context.Background(), &Arguments{NeighborAddress: "10.0.0.3"})
+	assert.NotNil(err)
+
+	r, err := client.Enable(context.Background(), &Arguments{NeighborAddress: "10.0.0.1"})
+	assert.Nil(err)
+	assert.Equal(r.Code, Error_SUCCESS)
+	assert.Equal(r.Msg, "ADMIN_STATE_UP")
+
+	// the route family of the arguments
+	rib, err := client.GetRib(context.Background(), &Arguments{
+		Resource: Resource_GLOBAL,
+		Af:       &AddressFamily{Afi: AFI_IP6, Safi: SAFI_UNICAST},
+	})
+	assert.Nil(err)
+	d, err := rib.Recv()
+	assert.Nil(err)
+	assert.Equal(d.Prefix, bgp.RF_IPv6_UC.String())
+
+	_, err = client.GetAdjRib(context.Background(), &Arguments{Resource: Resource_GLOBAL})
+	assert.Nil(err)
+
+	// the bgp server isn't blocked by the client going away
+	ctx, cancel := context.WithCancel(context.Background())
+	policies, err := client.GetPolicies(ctx, &Arguments{})
+	assert.Nil(err)
+	_, err = policies.Recv()
+	assert.Nil(err)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fatal("the responses aren't discarded")
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	events, err := client.WatchEvents(ctx, &Arguments{NeighborAddress: "10.0.0.1"})
+	assert.Nil(err)
+	e, err := events.Recv()
+	assert.Nil(err)
+	assert.Equal(e.Type, "peer-up")
+	assert.Equal(e.NeighborAddress, "10.0.0.1")
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fatal("the watch isn't canceled")
+	}
+}
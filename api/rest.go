@@ -16,10 +16,12 @@
 package api
 
 import (
+	"fmt"
 	log "github.com/Sirupsen/logrus"
 	"github.com/fukata/golang-stats-api-handler"
 	"github.com/gorilla/mux"
 	"github.com/osrg/gobgp/packet"
+	"io/ioutil"
 	"net/http"
 	"strconv"
 )
@@ -39,6 +41,21 @@ const (
 	REQ_NEIGHBOR_ENABLE
 	REQ_NEIGHBOR_DISABLE
 	REQ_GLOBAL_RIB
+	REQ_GLOBAL_RIB_EXPLAIN
+	REQ_LOCAL_RIB_EXPLAIN
+	REQ_POLICY_TEST
+	REQ_NEIGHBOR_POLICY_TEST_IMPORT
+	REQ_NEIGHBOR_POLICY_TEST_EXPORT
+	REQ_POLICIES
+	REQ_POLICY
+	REQ_NEIGHBOR_POLICY_COUNTERS
+	REQ_ADJ_RIB_IN_FILTERED
+	REQ_RPKI
+	REQ_NEIGHBOR_ADD
+	REQ_NEIGHBOR_UPDATE
+	REQ_NEIGHBOR_DELETE
+	REQ_GLOBAL_RIB_WATCH
+	REQ_EVENTS
 )
 
 const (
@@ -46,17 +63,51 @@ const (
 	GLOBAL       = "/bgp/global"
 	NEIGHBOR     = "/bgp/neighbor"
 	NEIGHBORS    = "/bgp/neighbors"
+	POLICY       = "/bgp/policy"
+	POLICIES     = "/bgp/policies"
+	RPKI         = "/bgp/rpki"
+	EVENTS       = "/bgp/events"
 
 	PARAM_REMOTE_PEER_ADDR = "remotePeerAddr"
 	PARAM_SHOW_OBJECT      = "showObject"
 	PARAM_OPERATION        = "operation"
 	PARAM_ROUTE_FAMILY     = "routeFamily"
+	PARAM_PREFIX           = "prefix"
+	PARAM_POLICY_NAME      = "policyName"
+	PARAM_DIRECTION        = "direction"
+	PARAM_PERSIST          = "persist"
+	PARAM_SNAPSHOT         = "snapshot"
+	PARAM_LOOKUP           = "lookup"
+	PARAM_CURSOR           = "cursor"
+	PARAM_LIMIT            = "limit"
+	PARAM_NEIGHBOR         = "neighbor"
+	PARAM_COMMUNITY        = "community"
+	PARAM_AS_PATH          = "aspath"
+	PARAM_NEXTHOP          = "nexthop"
+	PARAM_BEST             = "best"
 
 	STATS = "/stats"
 )
 
 const REST_PORT = 8080
 
+// the number of the events queued for a streaming client
+const WATCH_QUEUE_SIZE = 64
+
+// the page and the filters of the paths in a rib dump. the page starts
+// after the prefix of the cursor and has up to Limit prefixes; all the
+// prefixes if Limit is zero. AsPath is a regular expression matched
+// against the AS path.
+type RibDumpOptions struct {
+	Cursor    string
+	Limit     int
+	Neighbor  string
+	Community string
+	AsPath    string
+	Nexthop   string
+	BestOnly  bool
+}
+
 // trigger struct for exchanging information in the rest and peer.
 // rest and peer operated at different thread.
 
@@ -64,8 +115,20 @@ type RestRequest struct {
 	RequestType int
 	RemoteAddr  string
 	RouteFamily bgp.RouteFamily
-	ResponseCh  chan *RestResponse
-	Err         error
+	Prefix      string
+	Name        string
+	Data        []byte
+	// write the configuration changed at runtime to the config file
+	Persist bool
+	// send the current best paths before the changes
+	Snapshot bool
+	// how the prefix is looked up in the rib; exact, longest, longer or shorter
+	Lookup     string
+	Dump       RibDumpOptions
+	ResponseCh chan *RestResponse
+	Err        error
+	// closed when the client streaming the events goes away
+	Done chan struct{}
 }
 
 func NewRestRequest(reqType int, remoteAddr string, rf bgp.RouteFamily) *RestRequest {
@@ -90,12 +153,15 @@ func (r *RestResponse) Err() error {
 type RestServer struct {
 	port        int
 	bgpServerCh chan *RestRequest
+	configCh    chan *RestRequest
 }
 
-func NewRestServer(port int, bgpServerCh chan *RestRequest) *RestServer {
+// the requests changing the configuration are sent to configCh
+func NewRestServer(port int, bgpServerCh chan *RestRequest, configCh chan *RestRequest) *RestServer {
 	rs := &RestServer{
 		port:        port,
-		bgpServerCh: bgpServerCh}
+		bgpServerCh: bgpServerCh,
+		configCh:    configCh}
 	return rs
 }
 
@@ -111,22 +177,73 @@ func NewRestServer(port int, bgpServerCh chan *RestRequest) *RestServer {
 //     -- curl -i -X GET http://<ownIP>:8080/v1/bgp/neighbor/<remote address of target neighbor>/adj-rib-out/<rf>
 //   get local-rib of each neighbor.
 //     -- curl -i -X GET http://<ownIP>:8080/v1/bgp/neighbor/<remote address of target neighbor>/local-rib/<rf>
+//   look up a prefix or an address in the global rib, local-rib, adj-rib-in or adj-rib-out. the prefix itself is looked up by default,
+//   the most specific prefix covering it with lookup=longest, and the prefix with its more-/less-specifics with lookup=longer/shorter.
+//     -- curl -i -X GET http://<ownIP>:8080/v1/bgp/global/rib/<rf>/<prefix or address>[?lookup=<exact|longest|longer|shorter>]
+//     -- curl -i -X GET http://<ownIP>:8080/v1/bgp/neighbor/<remote address of target neighbor>/<local-rib|adj-rib-in|adj-rib-out>/<rf>/<prefix or address>[?lookup=<exact|longest|longer|shorter>]
+//   get the global rib, local-rib, adj-rib-in or adj-rib-out by pages of up to <limit> prefixes. "Next" in the response is the cursor of the next page
+//   and missing in the last page. the paths are filtered by the source neighbor, a community, an AS path regular expression, the nexthop and the best paths.
+//     -- curl -i -X GET 'http://<ownIP>:8080/v1/bgp/global/rib/<rf>?limit=<limit>[&cursor=<Next>][&neighbor=<address>][&community=<as:value>][&aspath=<regexp>][&nexthop=<address>][&best=true]'
+//   explain the best path selection of a prefix in the global rib.
+//     -- curl -i -X GET http://<ownIP>:8080/v1/bgp/global/explain/<rf>/<prefix>
+//   explain the best path selection of a prefix in the local-rib of each neighbor.
+//     -- curl -i -X GET http://<ownIP>:8080/v1/bgp/neighbor/<remote address of target neighbor>/explain/<rf>/<prefix>
+//   get the paths in adj-rib-in of each neighbor filtered by the policies.
+//     -- curl -i -X GET http://<ownIP>:8080/v1/bgp/neighbor/<remote address of target neighbor>/filtered-routes/<rf>
+//   get the number of the paths accepted, rejected and modified by the policies of each neighbor.
+//     -- curl -i -X GET http://<ownIP>:8080/v1/bgp/neighbor/<remote address of target neighbor>/policy-counters
+//   get the number of the paths matching each statement of the policies.
+//     -- curl -i -X GET http://<ownIP>:8080/v1/bgp/policies
+//     -- curl -i -X GET http://<ownIP>:8080/v1/bgp/policy/<policy name>
+//   apply a policy to the path in the request body.
+//     -- curl -i -X POST -d '{"Prefix": "10.0.0.0/24", "AsPath": [65001]}' http://<ownIP>:8080/v1/bgp/policy/<policy name>/test
+//   apply the import or export policies of each neighbor to the path in the request body.
+//     -- curl -i -X POST -d '{"Prefix": "10.0.0.0/24", "AsPath": [65001]}' http://<ownIP>:8080/v1/bgp/neighbor/<remote address of target neighbor>/policy-test/<import|export>
+//   get the state of the RPKI caches and the number of the ROAs.
+//     -- curl -i -X GET http://<ownIP>:8080/v1/bgp/rpki
+//   add a neighbor with the configuration in the request body. the running configuration is written to the config file with persist=true.
+//     -- curl -i -X POST -d '{"NeighborAddress": "10.0.0.2", "PeerAs": 65002}' http://<ownIP>:8080/v1/bgp/neighbors[?persist=true]
+//   replace the configuration of a neighbor. the session is reset if the configuration is changed.
+//     -- curl -i -X PUT -d '{"PeerAs": 65002, "Description": "peer2"}' http://<ownIP>:8080/v1/bgp/neighbor/<remote address of target neighbor>[?persist=true]
+//   delete a neighbor.
+//     -- curl -i -X DELETE http://<ownIP>:8080/v1/bgp/neighbor/<remote address of target neighbor>[?persist=true]
+//   watch the best path changes in the global rib as server-sent events. the current best paths are sent first with snapshot=true.
+//     -- curl -i -N -X GET http://<ownIP>:8080/v1/bgp/global/watch/<rf>[?snapshot=true]
+//   stream the events of the neighbors such as state and admin state changes, notifications and prefix limits as server-sent events.
+//     -- curl -i -N -X GET http://<ownIP>:8080/v1/bgp/events
+//     -- curl -i -N -X GET http://<ownIP>:8080/v1/bgp/neighbor/<remote address of target neighbor>/events
 func (rs *RestServer) Serve() {
 	global := BASE_VERSION + GLOBAL
 	neighbor := BASE_VERSION + NEIGHBOR
 	neighbors := BASE_VERSION + NEIGHBORS
+	policy := BASE_VERSION + POLICY
+	policies := BASE_VERSION + POLICIES
+	rpki := BASE_VERSION + RPKI
+	events := BASE_VERSION + EVENTS
 
 	r := mux.NewRouter()
 	perPeerURL := "/{" + PARAM_REMOTE_PEER_ADDR + "}"
 	showObjectURL := "/{" + PARAM_SHOW_OBJECT + "}"
 	operationURL := "/{" + PARAM_OPERATION + "}"
 	routeFamilyURL := "/{" + PARAM_ROUTE_FAMILY + "}"
+	prefixURL := "/{" + PARAM_PREFIX + ":.+}"
 	r.HandleFunc(global+showObjectURL+routeFamilyURL, rs.GlobalGET).Methods("GET")
+	r.HandleFunc(global+showObjectURL+routeFamilyURL+prefixURL, rs.GlobalGET).Methods("GET")
 	r.HandleFunc(neighbors, rs.NeighborGET).Methods("GET")
+	r.HandleFunc(neighbors, rs.NeighborConfig).Methods("POST")
+	r.HandleFunc(neighbor+perPeerURL, rs.NeighborConfig).Methods("PUT", "DELETE")
 	r.HandleFunc(neighbor+perPeerURL, rs.NeighborGET).Methods("GET")
+	r.HandleFunc(neighbor+perPeerURL+showObjectURL, rs.NeighborGET).Methods("GET")
 	r.HandleFunc(neighbor+perPeerURL+showObjectURL+routeFamilyURL, rs.NeighborGET).Methods("GET")
+	r.HandleFunc(neighbor+perPeerURL+showObjectURL+routeFamilyURL+prefixURL, rs.NeighborGET).Methods("GET")
+	r.HandleFunc(neighbor+perPeerURL+"/policy-test/{"+PARAM_DIRECTION+"}", rs.NeighborPolicyTest).Methods("POST")
 	r.HandleFunc(neighbor+perPeerURL+operationURL, rs.NeighborPOST).Methods("POST")
 	r.HandleFunc(neighbor+perPeerURL+operationURL+routeFamilyURL, rs.NeighborPOST).Methods("POST")
+	r.HandleFunc(policies, rs.PolicyGET).Methods("GET")
+	r.HandleFunc(policy+"/{"+PARAM_POLICY_NAME+"}", rs.PolicyGET).Methods("GET")
+	r.HandleFunc(policy+"/{"+PARAM_POLICY_NAME+"}/test", rs.PolicyTest).Methods("POST")
+	r.HandleFunc(rpki, rs.RpkiGET).Methods("GET")
+	r.HandleFunc(events, rs.EventsGET).Methods("GET")
 
 	// stats
 	r.HandleFunc(STATS, stats_api.Handler).Methods("GET")
@@ -160,6 +277,25 @@ func (rs *RestServer) neighbor(w http.ResponseWriter, r *http.Request, reqType i
 
 	//Send channel of request parameter.
 	req := NewRestRequest(reqType, remoteAddr, rf)
+	req.Prefix = params[PARAM_PREFIX]
+	query := r.URL.Query()
+	req.Lookup = query.Get(PARAM_LOOKUP)
+	req.Dump = RibDumpOptions{
+		Cursor:    query.Get(PARAM_CURSOR),
+		Neighbor:  query.Get(PARAM_NEIGHBOR),
+		Community: query.Get(PARAM_COMMUNITY),
+		AsPath:    query.Get(PARAM_AS_PATH),
+		Nexthop:   query.Get(PARAM_NEXTHOP),
+		BestOnly:  query.Get(PARAM_BEST) == "true",
+	}
+	if limit := query.Get(PARAM_LIMIT); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid limit: "+limit, http.StatusBadRequest)
+			return
+		}
+		req.Dump.Limit = n
+	}
 	rs.bgpServerCh <- req
 
 	//Wait response
@@ -211,6 +347,14 @@ func (rs *RestServer) NeighborGET(w http.ResponseWriter, r *http.Request) {
 			rs.neighbor(w, r, REQ_ADJ_RIB_IN)
 		case "adj-rib-out":
 			rs.neighbor(w, r, REQ_ADJ_RIB_OUT)
+		case "explain":
+			rs.neighbor(w, r, REQ_LOCAL_RIB_EXPLAIN)
+		case "filtered-routes":
+			rs.neighbor(w, r, REQ_ADJ_RIB_IN_FILTERED)
+		case "policy-counters":
+			rs.neighbor(w, r, REQ_NEIGHBOR_POLICY_COUNTERS)
+		case "events":
+			rs.EventsGET(w, r)
 		default:
 			NotFoundHandler(w, r)
 		}
@@ -219,12 +363,50 @@ func (rs *RestServer) NeighborGET(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (rs *RestServer) NeighborConfig(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	var reqType int
+	switch r.Method {
+	case "POST":
+		reqType = REQ_NEIGHBOR_ADD
+	case "PUT":
+		reqType = REQ_NEIGHBOR_UPDATE
+	case "DELETE":
+		reqType = REQ_NEIGHBOR_DELETE
+	}
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req := NewRestRequest(reqType, params[PARAM_REMOTE_PEER_ADDR], 0)
+	req.Data = data
+	req.Persist = r.URL.Query().Get(PARAM_PERSIST) == "true"
+	rs.configCh <- req
+
+	// nothing is changed on error
+	res := <-req.ResponseCh
+	if e := res.Err(); e != nil {
+		log.Debug(e.Error())
+		http.Error(w, e.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(res.Data)
+}
+
 func (rs *RestServer) GlobalGET(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	if showObject, ok := params[PARAM_SHOW_OBJECT]; ok {
 		switch showObject {
 		case "rib":
 			rs.neighbor(w, r, REQ_GLOBAL_RIB)
+		case "explain":
+			rs.neighbor(w, r, REQ_GLOBAL_RIB_EXPLAIN)
+		case "watch":
+			rs.watch(w, r)
 		default:
 			NotFoundHandler(w, r)
 		}
@@ -232,6 +414,151 @@ func (rs *RestServer) GlobalGET(w http.ResponseWriter, r *http.Request) {
 
 }
 
+// send the data of each response from the bgp server as a server-sent
+// event until the client goes away. the bgp server sends an empty
+// response first if nothing is sent when the stream starts, and closes
+// the response channel if the client can't keep up with the events.
+func (rs *RestServer) stream(w http.ResponseWriter, req *RestRequest) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming isn't supported", http.StatusInternalServerError)
+		return
+	}
+
+	req.ResponseCh = make(chan *RestResponse, WATCH_QUEUE_SIZE)
+	req.Done = make(chan struct{})
+	defer close(req.Done)
+	rs.bgpServerCh <- req
+
+	res := <-req.ResponseCh
+	if e := res.Err(); e != nil {
+		log.Debug(e.Error())
+		http.Error(w, e.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	closed := w.(http.CloseNotifier).CloseNotify()
+	for {
+		if res.Data != nil {
+			fmt.Fprintf(w, "data: %s\n\n", res.Data)
+		}
+		flusher.Flush()
+		select {
+		case res, ok = <-req.ResponseCh:
+			if !ok {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+// each event is sent as a JSON array of the best path changes
+func (rs *RestServer) watch(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	var rf bgp.RouteFamily
+	switch params[PARAM_ROUTE_FAMILY] {
+	case "ipv4":
+		rf = bgp.RF_IPv4_UC
+	case "ipv6":
+		rf = bgp.RF_IPv6_UC
+	case "evpn":
+		rf = bgp.RF_EVPN
+	default:
+		NotFoundHandler(w, r)
+		return
+	}
+	req := NewRestRequest(REQ_GLOBAL_RIB_WATCH, "", rf)
+	req.Snapshot = r.URL.Query().Get(PARAM_SNAPSHOT) == "true"
+	rs.stream(w, req)
+}
+
+// each event of the neighbors, or the neighbor in the url, is sent as
+// a JSON object
+func (rs *RestServer) EventsGET(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	rs.stream(w, NewRestRequest(REQ_EVENTS, params[PARAM_REMOTE_PEER_ADDR], 0))
+}
+
+func (rs *RestServer) PolicyGET(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	req := NewRestRequest(REQ_POLICIES, "", 0)
+	if name, ok := params[PARAM_POLICY_NAME]; ok {
+		req.RequestType = REQ_POLICY
+		req.Name = name
+	}
+	rs.bgpServerCh <- req
+
+	res := <-req.ResponseCh
+	if e := res.Err(); e != nil {
+		log.Debug(e.Error())
+		http.Error(w, e.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(res.Data)
+}
+
+func (rs *RestServer) RpkiGET(w http.ResponseWriter, r *http.Request) {
+	req := NewRestRequest(REQ_RPKI, "", 0)
+	rs.bgpServerCh <- req
+
+	res := <-req.ResponseCh
+	if e := res.Err(); e != nil {
+		log.Debug(e.Error())
+		http.Error(w, e.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(res.Data)
+}
+
+func (rs *RestServer) policyTest(w http.ResponseWriter, r *http.Request, reqType int) {
+	params := mux.Vars(r)
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req := NewRestRequest(reqType, params[PARAM_REMOTE_PEER_ADDR], 0)
+	req.Name = params[PARAM_POLICY_NAME]
+	req.Data = data
+	rs.bgpServerCh <- req
+
+	res := <-req.ResponseCh
+	if e := res.Err(); e != nil {
+		log.Debug(e.Error())
+		http.Error(w, e.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(res.Data)
+}
+
+func (rs *RestServer) PolicyTest(w http.ResponseWriter, r *http.Request) {
+	rs.policyTest(w, r, REQ_POLICY_TEST)
+}
+
+func (rs *RestServer) NeighborPolicyTest(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	switch params[PARAM_DIRECTION] {
+	case "import":
+		rs.policyTest(w, r, REQ_NEIGHBOR_POLICY_TEST_IMPORT)
+	case "export":
+		rs.policyTest(w, r, REQ_NEIGHBOR_POLICY_TEST_EXPORT)
+	default:
+		NotFoundHandler(w, r)
+	}
+}
+
 func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
 }
@@ -16,6 +16,8 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
 	log "github.com/Sirupsen/logrus"
 	"github.com/Sirupsen/logrus/hooks/syslog"
 	"github.com/jessevdk/go-flags"
@@ -25,6 +27,7 @@ import (
 	"github.com/osrg/gobgp/server"
 	"io/ioutil"
 	"log/syslog"
+	"net"
 	"os"
 	"os/signal"
 	"runtime"
@@ -144,9 +147,14 @@ func main() {
 	go bgpServer.Serve()
 
 	// start Rest Server
-	restServer := api.NewRestServer(api.REST_PORT, bgpServer.RestReqCh)
+	configReqCh := make(chan *api.RestRequest)
+	restServer := api.NewRestServer(api.REST_PORT, bgpServer.RestReqCh, configReqCh)
 	go restServer.Serve()
 
+	// start grpc Server
+	grpcServer := api.NewGrpcServer(api.GRPC_PORT, bgpServer.GrpcReqCh)
+	go grpcServer.Serve()
+
 	var bgpConfig *config.Bgp = nil
 	var policyConfig *config.RoutingPolicy = nil
 	for {
@@ -154,23 +162,50 @@ func main() {
 		case newConfig := <-configCh:
 			var added []config.Neighbor
 			var deleted []config.Neighbor
+			var addedNetworks []config.Network
+			var deletedNetworks []config.Network
+			var updateGlobalPolicy bool
 
 			if bgpConfig == nil {
 				bgpServer.SetGlobalType(newConfig.Bgp.Global)
 				bgpConfig = &newConfig.Bgp
 				added = newConfig.Bgp.NeighborList
 				deleted = []config.Neighbor{}
+				addedNetworks, deletedNetworks = config.UpdateNetworkConfig(nil, &newConfig.Bgp.Global)
 			} else {
+				addedNetworks, deletedNetworks = config.UpdateNetworkConfig(&bgpConfig.Global, &newConfig.Bgp.Global)
+				updateAggregates := config.CheckAggregateDifference(&bgpConfig.Global, &newConfig.Bgp.Global)
+				updateRouteSelection := config.CheckRouteSelectionDifference(&bgpConfig.Global, &newConfig.Bgp.Global)
+				updateEventWebhooks := config.CheckEventWebhookDifference(&bgpConfig.Global, &newConfig.Bgp.Global)
+				updateGlobalPolicy = config.CheckGlobalPolicyDifference(bgpConfig, &newConfig.Bgp)
 				bgpConfig, added, deleted = config.UpdateConfig(bgpConfig, &newConfig.Bgp)
+				if updateAggregates {
+					log.Info("Aggregate address config is updated")
+					bgpServer.UpdateAggregates(bgpConfig.Global)
+				}
+				if updateRouteSelection {
+					log.Info("Route selection config is updated")
+					bgpServer.UpdateRouteSelection(bgpConfig.Global)
+				}
+				if updateEventWebhooks {
+					log.Info("Event webhook config is updated")
+					bgpServer.UpdateEventWebhooks(bgpConfig.Global)
+				}
 			}
 
 			if policyConfig == nil {
 				policyConfig = &newConfig.Policy
 				bgpServer.SetPolicy(newConfig.Policy)
+				bgpServer.UpdateGlobalPolicy(bgpConfig.ApplyPolicy)
 			} else {
 				if res := config.CheckPolicyDifference(policyConfig, &newConfig.Policy); res {
 					log.Info("Policy config is updated")
 					bgpServer.UpdatePolicy(newConfig.Policy)
+					policyConfig = &newConfig.Policy
+				}
+				if updateGlobalPolicy {
+					log.Info("Global policy config is updated")
+					bgpServer.UpdateGlobalPolicy(bgpConfig.ApplyPolicy)
 				}
 			}
 
@@ -182,6 +217,16 @@ func main() {
 				log.Infof("Peer %v is deleted", p.NeighborAddress)
 				bgpServer.PeerDelete(p)
 			}
+			for _, n := range addedNetworks {
+				log.Infof("Network %v/%v is added", n.Address, n.Masklength)
+				bgpServer.NetworkAdd(n)
+			}
+			for _, n := range deletedNetworks {
+				log.Infof("Network %v/%v is deleted", n.Address, n.Masklength)
+				bgpServer.NetworkDelete(n)
+			}
+		case req := <-configReqCh:
+			bgpConfig = handleNeighborRequest(bgpServer, bgpConfig, policyConfig, opts.ConfigFile, req)
 		case sig := <-sigCh:
 			switch sig {
 			case syscall.SIGHUP:
@@ -191,3 +236,88 @@ func main() {
 		}
 	}
 }
+
+// apply the neighbor configuration changed through the rest api. the
+// whole running configuration is written to the config file before the
+// change is applied if the request asks to persist it. an updated
+// neighbor is reset only if the change needs a new session, see
+// config.NeighborNeedsReset.
+func handleNeighborRequest(bgpServer *server.BgpServer, bgpConfig *config.Bgp, policyConfig *config.RoutingPolicy, path string, req *api.RestRequest) *config.Bgp {
+	result := &api.RestResponse{}
+	defer func() {
+		req.ResponseCh <- result
+		close(req.ResponseCh)
+	}()
+
+	if bgpConfig == nil {
+		result.ResponseErr = fmt.Errorf("the config file isn't loaded yet")
+		return bgpConfig
+	}
+
+	var n config.Neighbor
+	var newConfig *config.Bgp
+	var added, deleted, updated []config.Neighbor
+	var err error
+	if req.RequestType != api.REQ_NEIGHBOR_DELETE {
+		if err := json.Unmarshal(req.Data, &n); err != nil {
+			result.ResponseErr = err
+			return bgpConfig
+		}
+	}
+	switch req.RequestType {
+	case api.REQ_NEIGHBOR_ADD:
+		newConfig, err = config.AddNeighbor(bgpConfig, &n, policyConfig)
+		added = []config.Neighbor{n}
+	case api.REQ_NEIGHBOR_UPDATE:
+		addr := net.ParseIP(req.RemoteAddr)
+		if n.NeighborAddress == nil {
+			n.NeighborAddress = addr
+		} else if !n.NeighborAddress.Equal(addr) {
+			result.ResponseErr = fmt.Errorf("neighbor address %s can't be changed", req.RemoteAddr)
+			return bgpConfig
+		}
+		var old *config.Neighbor
+		newConfig, old, err = config.UpdateNeighbor(bgpConfig, &n, policyConfig)
+		if old != nil {
+			if config.NeighborNeedsReset(old, &n) {
+				deleted = []config.Neighbor{*old}
+				added = []config.Neighbor{n}
+			} else {
+				updated = []config.Neighbor{n}
+			}
+		}
+	case api.REQ_NEIGHBOR_DELETE:
+		newConfig, n, err = config.DeleteNeighbor(bgpConfig, net.ParseIP(req.RemoteAddr))
+		deleted = []config.Neighbor{n}
+	}
+	if err != nil {
+		result.ResponseErr = err
+		return bgpConfig
+	}
+
+	if req.Persist {
+		c := config.BgpConfigSet{Bgp: *newConfig}
+		if policyConfig != nil {
+			c.Policy = *policyConfig
+		}
+		if err := config.WriteConfigfile(path, c); err != nil {
+			result.ResponseErr = fmt.Errorf("can't write the config file %s: %s", path, err)
+			return bgpConfig
+		}
+	}
+
+	for _, p := range deleted {
+		log.Infof("Peer %v is deleted", p.NeighborAddress)
+		bgpServer.PeerDelete(p)
+	}
+	for _, p := range added {
+		log.Infof("Peer %v is added", p.NeighborAddress)
+		bgpServer.PeerAdd(p)
+	}
+	for _, p := range updated {
+		log.Infof("Peer %v is updated", p.NeighborAddress)
+		bgpServer.PeerUpdate(p)
+	}
+	result.Data, _ = json.Marshal(n)
+	return newConfig
+}
@@ -35,6 +35,15 @@ const (
 	COMMUNITY_TYPE_NONE
 )
 
+// typedef for typedef gobgp:nexthop-resolver-type
+type NexthopResolverType int
+
+const (
+	NEXTHOP_RESOLVER_TYPE_NONE = iota
+	NEXTHOP_RESOLVER_TYPE_STATIC
+	NEXTHOP_RESOLVER_TYPE_ZEBRA
+)
+
 // typedef for typedef bgp:rr-cluster-id-type
 type RrClusterIdType string
 
@@ -71,14 +80,40 @@ const (
 )
 
 // typedef for typedef rpol:install-protocol-type
+// the zero value means no install protocol condition
 type InstallProtocolType int
 
 const (
-	INSTALL_PROTOCOL_TYPE_ISIS = iota
+	INSTALL_PROTOCOL_TYPE_NONE = iota
+	INSTALL_PROTOCOL_TYPE_BGP
+	INSTALL_PROTOCOL_TYPE_ISIS
 	INSTALL_PROTOCOL_TYPE_OSPF
 	INSTALL_PROTOCOL_TYPE_OSPF3
 	INSTALL_PROTOCOL_TYPE_STATIC
 	INSTALL_PROTOCOL_TYPE_DIRECTLY_CONNECTED
+	INSTALL_PROTOCOL_TYPE_LOCAL_AGGREGATE
+)
+
+// typedef for typedef gobgp:rpki-validation-result-type
+// the zero value means not validated
+type RpkiValidationResultType int
+
+const (
+	RPKI_VALIDATION_RESULT_TYPE_NONE = iota
+	RPKI_VALIDATION_RESULT_TYPE_NOT_FOUND
+	RPKI_VALIDATION_RESULT_TYPE_VALID
+	RPKI_VALIDATION_RESULT_TYPE_INVALID
+)
+
+// typedef for typedef gobgp:match-origin-type
+// the zero value means no origin condition
+type MatchOriginType int
+
+const (
+	MATCH_ORIGIN_TYPE_NONE = iota
+	MATCH_ORIGIN_TYPE_IGP
+	MATCH_ORIGIN_TYPE_EGP
+	MATCH_ORIGIN_TYPE_INCOMPLETE
 )
 
 // typedef for typedef rpol:default-policy-type
@@ -89,12 +124,78 @@ const (
 	DEFAULT_POLICY_TYPE_REJECT_ROUTE
 )
 
+// typedef for typedef bgp-pol:bgp-set-community-option-type
+type BgpSetCommunityOptionType int
+
+const (
+	BGP_SET_COMMUNITY_OPTION_TYPE_ADD = iota
+	BGP_SET_COMMUNITY_OPTION_TYPE_REMOVE
+	BGP_SET_COMMUNITY_OPTION_TYPE_REPLACE
+)
+
+// typedef for typedef ptypes:attribute-comparison
+type AttributeComparison int
+
+const (
+	ATTRIBUTE_COMPARISON_EQ = iota
+	ATTRIBUTE_COMPARISON_GE
+	ATTRIBUTE_COMPARISON_LE
+)
+
 //struct for container rpol:igp-actions
 type IgpActions struct {
 	// original -> rpol:set-tag
 	SetTag IgpTagType
 }
 
+//struct for container bgp-pol:set-community
+type SetCommunity struct {
+	// original -> bgp-pol:communities
+	//original type is list of union
+	Communities []string
+	// original -> bgp-pol:options
+	Options BgpSetCommunityOptionType
+}
+
+//struct for container bgp-pol:set-ext-community
+type SetExtCommunity struct {
+	// original -> bgp-pol:communities
+	//original type is list of union
+	//route targets and route origins like "route-target:65000:100"
+	Communities []string
+	// original -> bgp-pol:options
+	Options BgpSetCommunityOptionType
+}
+
+//struct for container bgp-pol:set-as-path-prepend
+type SetAsPathPrepend struct {
+	// original -> bgp-pol:repeat-n
+	RepeatN uint8
+	// original -> gobgp:as
+	// the local AS is prepended if not specified
+	As uint32
+}
+
+//struct for container bgp-pol:bgp-actions
+type BgpActions struct {
+	// original -> bgp-pol:set-as-path-prepend
+	SetAsPathPrepend SetAsPathPrepend
+	// original -> bgp-pol:set-community
+	SetCommunity SetCommunity
+	// original -> bgp-pol:set-ext-community
+	SetExtCommunity SetExtCommunity
+	// original -> bgp-pol:set-local-pref
+	SetLocalPref uint32
+	// original -> bgp-pol:set-next-hop
+	//original type is union
+	//an IP address, "self" or "peer-address"
+	SetNextHop string
+	// original -> bgp-pol:set-med
+	//original type is union
+	//a value, or an increment or decrement like "+10" and "-10"
+	SetMed string
+}
+
 //struct for container rpol:actions
 type Actions struct {
 	// original -> rpol:accept-route
@@ -105,6 +206,10 @@ type Actions struct {
 	RejectRoute bool
 	// original -> rpol:igp-actions
 	IgpActions IgpActions
+	// original -> bgp-pol:bgp-actions
+	BgpActions BgpActions
+	// original -> gobgp:set-weight
+	SetWeight uint32
 }
 
 //struct for container rpol:igp-conditions
@@ -113,6 +218,45 @@ type IgpConditions struct {
 	TagEq IgpTagType
 }
 
+//struct for container bgp-pol:as-path-length
+type AsPathLength struct {
+	// original -> ptypes:operator
+	Operator AttributeComparison
+	// original -> ptypes:value
+	Value uint32
+}
+
+//struct for container bgp-pol:bgp-conditions
+type BgpConditions struct {
+	// original -> bgp-pol:match-community-set
+	MatchCommunitySet string
+	// original -> bgp-pol:match-ext-community-set
+	MatchExtCommunitySet string
+	// original -> bgp-pol:match-as-path-set
+	MatchAsPathSet string
+	// original -> bgp-pol:as-path-length
+	AsPathLength AsPathLength
+	// original -> bgp-pol:origin-eq
+	OriginEq MatchOriginType
+	// original -> gobgp:med-range
+	//a range of MED like "0..100", "100.." or "..100"
+	MedRange string
+	// original -> bgp-pol:local-pref-eq
+	LocalPrefEq uint32
+	// original -> bgp-pol:next-hop-in
+	//original type is list of inet:ip-address
+	//"peer-address" stands for the address of the neighbor that the
+	//path is received from
+	NextHopIn []string
+	// original -> gobgp:match-next-hop-set
+	//the name of the prefix set containing the next-hop
+	MatchNextHopSet string
+	// original -> bgp-pol:afi-safi-in
+	AfiSafiIn []string
+	// original -> gobgp:rpki-validation-result
+	RpkiValidationResult RpkiValidationResultType
+}
+
 //struct for container rpol:conditions
 type Conditions struct {
 	// original -> rpol:call-policy
@@ -127,6 +271,8 @@ type Conditions struct {
 	InstallProtocolEq InstallProtocolType
 	// original -> rpol:igp-conditions
 	IgpConditions IgpConditions
+	// original -> bgp-pol:bgp-conditions
+	BgpConditions BgpConditions
 }
 
 //struct for container rpol:statement
@@ -181,12 +327,50 @@ type PrefixSet struct {
 	PrefixList []Prefix
 }
 
+//struct for container bgp-pol:community-set
+type CommunitySet struct {
+	// original -> bgp-pol:community-set-name
+	CommunitySetName string
+	// original -> bgp-pol:community-member
+	//original type is list of union
+	CommunityMemberList []string
+}
+
+//struct for container bgp-pol:ext-community-set
+type ExtCommunitySet struct {
+	// original -> bgp-pol:ext-community-set-name
+	ExtCommunitySetName string
+	// original -> bgp-pol:ext-community-member
+	//original type is list of union
+	ExtCommunityMemberList []string
+}
+
+//struct for container bgp-pol:as-path-set
+type AsPathSet struct {
+	// original -> bgp-pol:as-path-set-name
+	AsPathSetName string
+	// original -> bgp-pol:as-path-set-member
+	AsPathSetMemberList []string
+}
+
+//struct for container bgp-pol:bgp-defined-sets
+type BgpDefinedSets struct {
+	// original -> bgp-pol:community-set
+	CommunitySetList []CommunitySet
+	// original -> bgp-pol:ext-community-set
+	ExtCommunitySetList []ExtCommunitySet
+	// original -> bgp-pol:as-path-set
+	AsPathSetList []AsPathSet
+}
+
 //struct for container rpol:defined-sets
 type DefinedSets struct {
 	// original -> rpol:prefix-set
 	PrefixSetList []PrefixSet
 	// original -> rpol:neighbor-set
 	NeighborSetList []NeighborSet
+	// original -> bgp-pol:bgp-defined-sets
+	BgpDefinedSets BgpDefinedSets
 }
 
 //struct for container rpol:routing-policy
@@ -209,6 +393,16 @@ type ApplyPolicy struct {
 	DefaultExportPolicy DefaultPolicyType
 }
 
+//struct for container gobgp:conditional-advertisement
+type ConditionalAdvertisement struct {
+	// original -> gobgp:advertise-policy
+	AdvertisePolicy string
+	// original -> gobgp:exist-policy
+	ExistPolicy string
+	// original -> gobgp:non-exist-policy
+	NonExistPolicy string
+}
+
 //struct for container bgp-op:bgp-neighbor-common-state
 type BgpNeighborCommonState struct {
 	// original -> bgp-op:state
@@ -324,6 +518,49 @@ type EbgpMultihop struct {
 	MultihopTtl uint8
 }
 
+//struct for container gobgp:nexthop-resolver
+type NexthopResolver struct {
+	// original -> gobgp:type
+	Type NexthopResolverType
+	// original -> gobgp:path
+	Path string
+	// original -> gobgp:scan-interval
+	//gobgp:scan-interval's original type is decimal64
+	ScanInterval float64
+}
+
+//struct for container gobgp:rpki-server
+type RpkiServer struct {
+	// original -> gobgp:address
+	//gobgp:address's original type is inet:ip-address
+	Address net.IP
+	// original -> gobgp:port
+	Port uint32
+	// original -> gobgp:refresh-time
+	//the interval of the serial queries in seconds when the cache
+	//doesn't tell
+	RefreshTime uint32
+}
+
+//struct for container gobgp:rpki-validation
+type RpkiValidation struct {
+	// original -> gobgp:rpki-server
+	RpkiServerList []RpkiServer
+	// original -> gobgp:roa-file
+	//the TOML file of the static ROAs used instead of the caches
+	RoaFile string
+}
+
+//struct for container gobgp:event-webhook
+type EventWebhook struct {
+	// original -> gobgp:url
+	Url string
+	// original -> gobgp:event-types
+	//the types of the neighbor events posted. all the events are
+	//posted if not specified
+	EventTypes []string
+}
+
 //struct for container bgp:timers
 type Timers struct {
 	// original -> bgp:connect-retry
@@ -535,6 +772,50 @@ type RouteSelectionOptions struct {
 	// original -> bgp-mp:ignore-next-hop-igp-metric
 	//bgp-mp:ignore-next-hop-igp-metric's original type is boolean
 	IgnoreNextHopIgpMetric bool
+	// original -> gobgp:deterministic-med
+	//gobgp:deterministic-med's original type is boolean
+	DeterministicMed bool
+}
+
+//struct for container gobgp:network
+type Network struct {
+	// original -> gobgp:address
+	//gobgp:address's original type is inet:ip-address
+	Address net.IP
+	// original -> gobgp:masklength
+	Masklength uint8
+	// original -> gobgp:next-hop
+	//gobgp:next-hop's original type is inet:ip-address
+	NextHop net.IP
+	// original -> gobgp:origin
+	Origin BgpOriginAttrType
+	// original -> gobgp:med
+	Med uint32
+	// original -> gobgp:local-pref
+	LocalPref uint32
+	// original -> gobgp:community
+	//original type is list of bgp-types:bgp-std-community-type
+	CommunityList []string
+	// original -> gobgp:tag
+	//the IGP tag matched by the igp-conditions of the policies
+	Tag IgpTagType
+	// original -> rpol:apply-policy
+	ApplyPolicy ApplyPolicy
+}
+
+//struct for container gobgp:aggregate-address
+type AggregateAddress struct {
+	// original -> gobgp:address
+	//gobgp:address's original type is inet:ip-address
+	Address net.IP
+	// original -> gobgp:masklength
+	Masklength uint8
+	// original -> gobgp:as-set
+	//gobgp:as-set's original type is boolean
+	AsSet bool
+	// original -> gobgp:summary-only
+	//gobgp:summary-only's original type is boolean
+	SummaryOnly bool
 }
 
 //struct for container bgp-mp:afi-safi
@@ -569,6 +850,10 @@ type AfiSafi struct {
 	L2vpnVpls L2vpnVpls
 	// original -> bgp-mp:l2vpn-evpn
 	L2vpnEvpn L2vpnEvpn
+	// original -> gobgp:network
+	NetworkList []Network
+	// original -> gobgp:aggregate-address
+	AggregateAddressList []AggregateAddress
 }
 
 //struct for container bgp:graceful-restart
@@ -594,6 +879,8 @@ type Neighbor struct {
 	GracefulRestart GracefulRestart
 	// original -> rpol:apply-policy
 	ApplyPolicy ApplyPolicy
+	// original -> gobgp:conditional-advertisement
+	ConditionalAdvertisementList []ConditionalAdvertisement
 	// original -> bgp-mp:use-multiple-paths
 	UseMultiplePaths UseMultiplePaths
 	// original -> bgp-mp:afi-safi
@@ -630,6 +917,10 @@ type Neighbor struct {
 	AsPathOptions AsPathOptions
 	// original -> bgp:add-paths
 	AddPaths AddPaths
+	// original -> gobgp:weight
+	Weight uint32
+	// original -> gobgp:default-local-pref
+	DefaultLocalPref uint32
 	// original -> bgp-op:bgp-neighbor-common-state
 	BgpNeighborCommonState BgpNeighborCommonState
 }
@@ -728,6 +1019,12 @@ type Global struct {
 	UseMultiplePaths UseMultiplePaths
 	// original -> bgp-mp:afi-safi
 	AfiSafiList []AfiSafi
+	// original -> gobgp:nexthop-resolver
+	NexthopResolver NexthopResolver
+	// original -> gobgp:rpki-validation
+	RpkiValidation RpkiValidation
+	// original -> gobgp:event-webhook
+	EventWebhookList []EventWebhook
 	// original -> bgp-op:bgp-global-state
 	BgpGlobalState BgpGlobalState
 }
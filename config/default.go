@@ -10,6 +10,8 @@ const (
 	DEFAULT_HOLDTIME                  = 90
 	DEFAULT_IDLE_HOLDTIME_AFTER_RESET = 30
 	DEFAULT_CONNECT_RETRY             = 120
+	DEFAULT_LOCAL_PREF                = 100
+	DEFAULT_NEXTHOP_SCAN_INTERVAL     = 60
 )
 
 type neighbor struct {
@@ -37,6 +39,10 @@ func SetDefaultConfigValues(md toml.MetaData, bt *Bgp) error {
 		}
 	}
 
+	if _, ok := global["Global.NexthopResolver.ScanInterval"]; !ok {
+		bt.Global.NexthopResolver.ScanInterval = float64(DEFAULT_NEXTHOP_SCAN_INTERVAL)
+	}
+
 	nidx := 0
 	for _, key := range md.Keys() {
 		if !strings.HasPrefix(key.String(), "NeighborList") {
@@ -51,7 +57,7 @@ func SetDefaultConfigValues(md toml.MetaData, bt *Bgp) error {
 	}
 	for i, n := range neighbors {
 		if _, ok := n.attributes["NeighborList.Timers.ConnectRetry"]; !ok {
-			bt.NeighborList[i].Timers.HoldTime = float64(DEFAULT_CONNECT_RETRY)
+			bt.NeighborList[i].Timers.ConnectRetry = float64(DEFAULT_CONNECT_RETRY)
 		}
 		if _, ok := n.attributes["NeighborList.Timers.HoldTime"]; !ok {
 			bt.NeighborList[i].Timers.HoldTime = float64(DEFAULT_HOLDTIME)
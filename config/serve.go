@@ -1,9 +1,16 @@
 package config
 
 import (
+	"bytes"
+	"fmt"
 	"github.com/BurntSushi/toml"
 	log "github.com/Sirupsen/logrus"
+	"github.com/osrg/gobgp/packet"
+	"io/ioutil"
+	"net"
+	"os"
 	"reflect"
+	"strings"
 )
 
 type BgpConfigSet struct {
@@ -50,8 +57,26 @@ func UpdateConfig(curC *Bgp, newC *Bgp) (*Bgp, []Neighbor, []Neighbor) {
 		bgpConfig.Global = newC.Global
 		curC = &bgpConfig
 	} else {
-		// can't update the global config
+		// can't update the global config except the network and
+		// aggregate address lists, the route selection options and
+		// the event webhooks
 		bgpConfig.Global = curC.Global
+		bgpConfig.Global.EventWebhookList = newC.Global.EventWebhookList
+		afiSafiList := make([]AfiSafi, len(curC.Global.AfiSafiList))
+		for i, a := range curC.Global.AfiSafiList {
+			afiSafiList[i] = a
+			afiSafiList[i].NetworkList = nil
+			afiSafiList[i].AggregateAddressList = nil
+			afiSafiList[i].RouteSelectionOptions = RouteSelectionOptions{}
+			for _, b := range newC.Global.AfiSafiList {
+				if a.AfiSafiName == b.AfiSafiName {
+					afiSafiList[i].NetworkList = b.NetworkList
+					afiSafiList[i].AggregateAddressList = b.AggregateAddressList
+					afiSafiList[i].RouteSelectionOptions = b.RouteSelectionOptions
+				}
+			}
+		}
+		bgpConfig.Global.AfiSafiList = afiSafiList
 	}
 	added := []Neighbor{}
 	deleted := []Neighbor{}
@@ -69,9 +94,153 @@ func UpdateConfig(curC *Bgp, newC *Bgp) (*Bgp, []Neighbor, []Neighbor) {
 	}
 
 	bgpConfig.NeighborList = newC.NeighborList
+	bgpConfig.ApplyPolicy = newC.ApplyPolicy
 	return &bgpConfig, added, deleted
 }
 
+// the key identifying the network statement, like "10.0.0.0/24". the
+// host bits of the address are masked as the prefix advertised.
+func NetworkKey(n Network) string {
+	addr := n.Address
+	if a := addr.To4(); a != nil {
+		addr = a
+	}
+	if masked := addr.Mask(net.CIDRMask(int(n.Masklength), len(addr)*8)); masked != nil {
+		addr = masked
+	}
+	return fmt.Sprintf("%s/%d", addr, n.Masklength)
+}
+
+func networkMap(g *Global) map[string]Network {
+	m := make(map[string]Network)
+	for _, a := range g.AfiSafiList {
+		for _, n := range a.NetworkList {
+			m[NetworkKey(n)] = n
+		}
+	}
+	return m
+}
+
+// return the networks to be announced and the ones to be withdrawn.
+// a network whose attributes are changed is announced again.
+func UpdateNetworkConfig(curC *Global, newC *Global) ([]Network, []Network) {
+	curMap := map[string]Network{}
+	if curC != nil {
+		curMap = networkMap(curC)
+	}
+	newMap := networkMap(newC)
+
+	added := []Network{}
+	deleted := []Network{}
+
+	for k, n := range newMap {
+		if c, ok := curMap[k]; !ok || !reflect.DeepEqual(c, n) {
+			added = append(added, n)
+		}
+	}
+
+	for k, n := range curMap {
+		if _, ok := newMap[k]; !ok {
+			deleted = append(deleted, n)
+		}
+	}
+	return added, deleted
+}
+
+func CheckAggregateDifference(curC *Global, newC *Global) bool {
+	aggregates := func(g *Global) map[string][]AggregateAddress {
+		m := make(map[string][]AggregateAddress)
+		for _, a := range g.AfiSafiList {
+			if len(a.AggregateAddressList) > 0 {
+				m[a.AfiSafiName] = a.AggregateAddressList
+			}
+		}
+		return m
+	}
+	return !reflect.DeepEqual(aggregates(curC), aggregates(newC))
+}
+
+func CheckRouteSelectionDifference(curC *Global, newC *Global) bool {
+	options := func(g *Global) map[string]RouteSelectionOptions {
+		m := make(map[string]RouteSelectionOptions)
+		for _, a := range g.AfiSafiList {
+			m[a.AfiSafiName] = a.RouteSelectionOptions
+		}
+		return m
+	}
+	return !reflect.DeepEqual(options(curC), options(newC))
+}
+
+func CheckEventWebhookDifference(curC *Global, newC *Global) bool {
+	return !reflect.DeepEqual(curC.EventWebhookList, newC.EventWebhookList)
+}
+
+func CheckGlobalPolicyDifference(curC *Bgp, newC *Bgp) bool {
+	return !reflect.DeepEqual(curC.ApplyPolicy, newC.ApplyPolicy)
+}
+
+// return the apply-policy in the container of the address family
+func AfiSafiApplyPolicy(a AfiSafi) ApplyPolicy {
+	switch a.AfiSafiName {
+	case "ipv4-unicast":
+		return a.Ipv4Unicast.ApplyPolicy
+	case "ipv6-unicast":
+		return a.Ipv6Unicast.ApplyPolicy
+	case "ipv4-multicast":
+		return a.Ipv4Multicast.ApplyPolicy
+	case "ipv6-multicast":
+		return a.Ipv6Multicast.ApplyPolicy
+	case "ipv4-labelled-unicast":
+		return a.Ipv4LabelledUnicast.ApplyPolicy
+	case "ipv6-labelled-unicast":
+		return a.Ipv6LabelledUnicast.ApplyPolicy
+	case "l3vpn-ipv4-unicast":
+		return a.L3vpnIpv4Unicast.ApplyPolicy
+	case "l3vpn-ipv6-unicast":
+		return a.L3vpnIpv6Unicast.ApplyPolicy
+	case "l3vpn-ipv4-multicast":
+		return a.L3vpnIpv4Multicast.ApplyPolicy
+	case "l3vpn-ipv6-multicast":
+		return a.L3vpnIpv6Multicast.ApplyPolicy
+	case "l2vpn-vpls":
+		return a.L2vpnVpls.ApplyPolicy
+	case "l2vpn-evpn":
+		return a.L2vpnEvpn.ApplyPolicy
+	}
+	return ApplyPolicy{}
+}
+
+// return the prefix-limit in the container of the address family
+func AfiSafiPrefixLimit(a AfiSafi) PrefixLimit {
+	switch a.AfiSafiName {
+	case "ipv4-unicast":
+		return a.Ipv4Unicast.PrefixLimit
+	case "ipv6-unicast":
+		return a.Ipv6Unicast.PrefixLimit
+	case "ipv4-multicast":
+		return a.Ipv4Multicast.PrefixLimit
+	case "ipv6-multicast":
+		return a.Ipv6Multicast.PrefixLimit
+	case "ipv4-labelled-unicast":
+		return a.Ipv4LabelledUnicast.PrefixLimit
+	case "ipv6-labelled-unicast":
+		return a.Ipv6LabelledUnicast.PrefixLimit
+	case "l3vpn-ipv4-unicast":
+		return a.L3vpnIpv4Unicast.PrefixLimit
+	case "l3vpn-ipv6-unicast":
+		return a.L3vpnIpv6Unicast.PrefixLimit
+	case "l3vpn-ipv4-multicast":
+		return a.L3vpnIpv4Multicast.PrefixLimit
+	case "l3vpn-ipv6-multicast":
+		return a.L3vpnIpv6Multicast.PrefixLimit
+	case "l2vpn-vpls":
+		return a.L2vpnVpls.PrefixLimit
+	case "l2vpn-evpn":
+		return a.L2vpnEvpn.PrefixLimit
+	}
+	return PrefixLimit{}
+}
+
 func CheckPolicyDifference(currentPolicy *RoutingPolicy, newPolicy *RoutingPolicy) bool {
 
 	log.Debug("current policy : ", currentPolicy)
@@ -91,3 +260,239 @@ func CheckPolicyDifference(currentPolicy *RoutingPolicy, newPolicy *RoutingPolic
 	}
 	return result
 }
+
+func neighborIndex(c *Bgp, addr net.IP) int {
+	for i, n := range c.NeighborList {
+		if n.NeighborAddress.Equal(addr) {
+			return i
+		}
+	}
+	return -1
+}
+
+// the default values of the neighbor configured at runtime. the zero
+// values are taken as not specified and the peer type is decided by
+// the peer AS.
+func SetNeighborDefaultValues(g *Global, n *Neighbor) {
+	if n.Timers.ConnectRetry == 0 {
+		n.Timers.ConnectRetry = float64(DEFAULT_CONNECT_RETRY)
+	}
+	if n.Timers.HoldTime == 0 {
+		n.Timers.HoldTime = float64(DEFAULT_HOLDTIME)
+	}
+	if n.Timers.KeepaliveInterval == 0 {
+		n.Timers.KeepaliveInterval = n.Timers.HoldTime / 3
+	}
+	if n.Timers.IdleHoldTimeAfterReset == 0 {
+		n.Timers.IdleHoldTimeAfterReset = float64(DEFAULT_IDLE_HOLDTIME_AFTER_RESET)
+	}
+	if len(n.AfiSafiList) == 0 {
+		if n.NeighborAddress.To4() != nil {
+			n.AfiSafiList = []AfiSafi{AfiSafi{AfiSafiName: "ipv4-unicast"}}
+		} else {
+			n.AfiSafiList = []AfiSafi{AfiSafi{AfiSafiName: "ipv6-unicast"}}
+		}
+	}
+	if n.PeerAs != g.As {
+		n.PeerType = PEER_TYPE_EXTERNAL
+	} else {
+		n.PeerType = PEER_TYPE_INTERNAL
+	}
+}
+
+func ValidateNeighbor(n *Neighbor, p *RoutingPolicy) error {
+	if n.NeighborAddress == nil {
+		return fmt.Errorf("neighbor address isn't specified")
+	}
+	if n.PeerAs == 0 {
+		return fmt.Errorf("peer AS of %s isn't specified", n.NeighborAddress)
+	}
+	if n.Timers.HoldTime < 3 {
+		return fmt.Errorf("hold time %v of %s is shorter than 3 seconds", n.Timers.HoldTime, n.NeighborAddress)
+	}
+	if n.Timers.KeepaliveInterval > n.Timers.HoldTime {
+		return fmt.Errorf("keepalive interval %v of %s is longer than the hold time", n.Timers.KeepaliveInterval, n.NeighborAddress)
+	}
+
+	defined := make(map[string]bool)
+	if p != nil {
+		for _, d := range p.PolicyDefinitionList {
+			defined[d.Name] = true
+		}
+	}
+	names := make([]string, 0)
+	applyPolicies := []ApplyPolicy{n.ApplyPolicy}
+	for _, a := range n.AfiSafiList {
+		if _, err := bgp.GetRouteFamily(a.AfiSafiName); err != nil {
+			return err
+		}
+		applyPolicies = append(applyPolicies, AfiSafiApplyPolicy(a))
+	}
+	for _, a := range applyPolicies {
+		names = append(names, a.ImportPolicies...)
+		names = append(names, a.ExportPolicies...)
+	}
+	for _, c := range n.ConditionalAdvertisementList {
+		names = append(names, c.AdvertisePolicy, c.ExistPolicy, c.NonExistPolicy)
+	}
+	for _, name := range names {
+		if name != "" && !defined[name] {
+			return fmt.Errorf("policy %s of %s isn't defined", name, n.NeighborAddress)
+		}
+	}
+	return nil
+}
+
+// add the neighbor configured at runtime to the current configuration.
+// the default values are set to the neighbor.
+func AddNeighbor(curC *Bgp, n *Neighbor, p *RoutingPolicy) (*Bgp, error) {
+	SetNeighborDefaultValues(&curC.Global, n)
+	if err := ValidateNeighbor(n, p); err != nil {
+		return nil, err
+	}
+	if neighborIndex(curC, n.NeighborAddress) >= 0 {
+		return nil, fmt.Errorf("neighbor %s already exists", n.NeighborAddress)
+	}
+	bgpConfig := *curC
+	bgpConfig.NeighborList = append(append([]Neighbor{}, curC.NeighborList...), *n)
+	return &bgpConfig, nil
+}
+
+// replace the configuration of the neighbor. the old configuration is
+// returned if it's changed.
+func UpdateNeighbor(curC *Bgp, n *Neighbor, p *RoutingPolicy) (*Bgp, *Neighbor, error) {
+	SetNeighborDefaultValues(&curC.Global, n)
+	if err := ValidateNeighbor(n, p); err != nil {
+		return nil, nil, err
+	}
+	idx := neighborIndex(curC, n.NeighborAddress)
+	if idx < 0 {
+		return nil, nil, fmt.Errorf("neighbor %s doesn't exist", n.NeighborAddress)
+	}
+	old := curC.NeighborList[idx]
+	if reflect.DeepEqual(old, *n) {
+		return curC, nil, nil
+	}
+	bgpConfig := *curC
+	bgpConfig.NeighborList = append([]Neighbor{}, curC.NeighborList...)
+	bgpConfig.NeighborList[idx] = *n
+	return &bgpConfig, &old, nil
+}
+
+// the fields of the neighbor applied to the running peer in place: the
+// description, the policies, the conditional advertisements and the
+// containers of the address families, i.e. their policies and prefix
+// limits. the other fields, like the AS, the address, the transport and
+// the names of the address families, need a new session. true if the
+// neighbor has to be reset for the new configuration.
+func NeighborNeedsReset(old, n *Neighbor) bool {
+	if len(old.AfiSafiList) != len(n.AfiSafiList) {
+		return true
+	}
+	for i, a := range n.AfiSafiList {
+		if a.AfiSafiName != old.AfiSafiList[i].AfiSafiName {
+			return true
+		}
+	}
+	c := *n
+	c.Description = old.Description
+	c.ApplyPolicy = old.ApplyPolicy
+	c.ConditionalAdvertisementList = old.ConditionalAdvertisementList
+	c.AfiSafiList = old.AfiSafiList
+	return !reflect.DeepEqual(*old, c)
+}
+
+func DeleteNeighbor(curC *Bgp, addr net.IP) (*Bgp, Neighbor, error) {
+	idx := neighborIndex(curC, addr)
+	if idx < 0 {
+		return nil, Neighbor{}, fmt.Errorf("neighbor %s doesn't exist", addr)
+	}
+	bgpConfig := *curC
+	bgpConfig.NeighborList = append(append([]Neighbor{}, curC.NeighborList[:idx]...), curC.NeighborList[idx+1:]...)
+	return &bgpConfig, curC.NeighborList[idx], nil
+}
+
+// the fields of the value which differ from the default value, as the
+// tables of the config file. the operational state isn't included. nil
+// if nothing differs.
+func configuredValue(v, def reflect.Value) interface{} {
+	if reflect.DeepEqual(v.Interface(), def.Interface()) {
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		m := make(map[string]interface{})
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.Type.Kind() == reflect.Struct && strings.HasSuffix(f.Type.Name(), "State") {
+				continue
+			}
+			if c := configuredValue(v.Field(i), def.Field(i)); c != nil {
+				m[f.Name] = c
+			}
+		}
+		if len(m) == 0 {
+			return nil
+		}
+		return m
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Struct {
+			return v.Interface()
+		}
+		zero := reflect.Zero(v.Type().Elem())
+		l := make([]map[string]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c, _ := configuredValue(v.Index(i), zero).(map[string]interface{})
+			if c == nil {
+				c = make(map[string]interface{})
+			}
+			l = append(l, c)
+		}
+		return l
+	}
+	return v.Interface()
+}
+
+// write the running configuration to the config file. only the values
+// configured are written; the default values and the state are
+// omitted. the file is replaced at once not to leave a broken one.
+func WriteConfigfile(path string, c BgpConfigSet) error {
+	d := Bgp{}
+	SetDefaultConfigValues(toml.MetaData{}, &d)
+	values, _ := configuredValue(reflect.ValueOf(c.Bgp), reflect.ValueOf(d)).(map[string]interface{})
+	if values == nil {
+		values = make(map[string]interface{})
+	}
+	neighbors := make([]map[string]interface{}, 0, len(c.Bgp.NeighborList))
+	for _, n := range c.Bgp.NeighborList {
+		def := Neighbor{NeighborAddress: n.NeighborAddress, PeerAs: n.PeerAs}
+		SetNeighborDefaultValues(&c.Bgp.Global, &def)
+		m, _ := configuredValue(reflect.ValueOf(n), reflect.ValueOf(def)).(map[string]interface{})
+		if m == nil {
+			m = make(map[string]interface{})
+		}
+		m["NeighborAddress"] = n.NeighborAddress
+		m["PeerAs"] = n.PeerAs
+		neighbors = append(neighbors, m)
+	}
+	delete(values, "NeighborList")
+	if len(neighbors) > 0 {
+		values["NeighborList"] = neighbors
+	}
+	if p, ok := configuredValue(reflect.ValueOf(c.Policy), reflect.ValueOf(RoutingPolicy{})).(map[string]interface{}); ok {
+		for k, v := range p {
+			values[k] = v
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(values); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
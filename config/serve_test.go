@@ -0,0 +1,235 @@
+package config
+
+import (
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readConfigfile(path string) (BgpConfigSet, error) {
+	c := BgpConfigSet{}
+	md, err := toml.DecodeFile(path, &c.Bgp)
+	if err != nil {
+		return c, err
+	}
+	if err := SetDefaultConfigValues(md, &c.Bgp); err != nil {
+		return c, err
+	}
+	_, err = toml.DecodeFile(path, &c.Policy)
+	return c, err
+}
+
+func testConfigSet() BgpConfigSet {
+	return BgpConfigSet{
+		Bgp: Bgp{
+			Global: Global{
+				As:       65000,
+				RouterId: net.ParseIP("10.0.0.1"),
+			},
+			NeighborList: []Neighbor{
+				Neighbor{
+					NeighborAddress: net.ParseIP("10.0.0.2"),
+					PeerAs:          65001,
+					Description:     "peer",
+					Timers:          Timers{HoldTime: 30},
+					ApplyPolicy: ApplyPolicy{
+						ImportPolicies:      []string{"pd1"},
+						DefaultImportPolicy: DEFAULT_POLICY_TYPE_REJECT_ROUTE,
+					},
+				},
+				Neighbor{
+					NeighborAddress: net.ParseIP("2001:db8::2"),
+					PeerAs:          65000,
+					AfiSafiList: []AfiSafi{
+						AfiSafi{AfiSafiName: "ipv6-unicast"},
+						AfiSafi{AfiSafiName: "ipv4-unicast"},
+					},
+				},
+			},
+		},
+		Policy: RoutingPolicy{
+			DefinedSets: DefinedSets{
+				PrefixSetList: []PrefixSet{
+					PrefixSet{
+						PrefixSetName: "ps1",
+						PrefixList: []Prefix{
+							Prefix{Address: net.ParseIP("10.0.0.0"), Masklength: 8},
+						},
+					},
+				},
+			},
+			PolicyDefinitionList: []PolicyDefinition{
+				PolicyDefinition{Name: "pd1"},
+			},
+		},
+	}
+}
+
+func TestWriteConfigfile(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "gobgp")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "gobgpd.conf")
+
+	c := testConfigSet()
+	for i := range c.Bgp.NeighborList {
+		SetNeighborDefaultValues(&c.Bgp.Global, &c.Bgp.NeighborList[i])
+	}
+	d := Bgp{}
+	SetDefaultConfigValues(toml.MetaData{}, &d)
+	c.Bgp.Global.AfiSafiList = d.Global.AfiSafiList
+	c.Bgp.Global.NexthopResolver = d.Global.NexthopResolver
+
+	// the operational state isn't written
+	written := c
+	written.Bgp.NeighborList = append([]Neighbor{}, c.Bgp.NeighborList...)
+	written.Bgp.NeighborList[0].BgpNeighborCommonState.State = 6
+
+	assert.Nil(WriteConfigfile(path, written))
+	r, err := readConfigfile(path)
+	assert.Nil(err)
+	assert.Equal(r.Bgp, c.Bgp)
+	assert.Equal(r.Policy, c.Policy)
+
+	// the temporary file isn't left
+	_, err = os.Stat(path + ".tmp")
+	assert.True(os.IsNotExist(err))
+}
+
+func TestValidateNeighbor(t *testing.T) {
+	assert := assert.New(t)
+	p := testConfigSet().Policy
+	valid := func() Neighbor {
+		n := Neighbor{NeighborAddress: net.ParseIP("10.0.0.3"), PeerAs: 65003}
+		SetNeighborDefaultValues(&Global{As: 65000}, &n)
+		return n
+	}
+
+	n := valid()
+	assert.Nil(ValidateNeighbor(&n, &p))
+
+	n = valid()
+	n.NeighborAddress = nil
+	assert.NotNil(ValidateNeighbor(&n, &p))
+
+	n = valid()
+	n.PeerAs = 0
+	assert.NotNil(ValidateNeighbor(&n, &p))
+
+	n = valid()
+	n.Timers.HoldTime = 2
+	assert.NotNil(ValidateNeighbor(&n, &p))
+
+	n = valid()
+	n.Timers.KeepaliveInterval = n.Timers.HoldTime + 1
+	assert.NotNil(ValidateNeighbor(&n, &p))
+
+	n = valid()
+	n.AfiSafiList = []AfiSafi{AfiSafi{AfiSafiName: "unknown"}}
+	assert.NotNil(ValidateNeighbor(&n, &p))
+
+	n = valid()
+	n.ApplyPolicy.ExportPolicies = []string{"pd2"}
+	assert.NotNil(ValidateNeighbor(&n, &p))
+
+	n = valid()
+	n.AfiSafiList[0].Ipv4Unicast.ApplyPolicy.ImportPolicies = []string{"pd2"}
+	assert.NotNil(ValidateNeighbor(&n, &p))
+
+	n = valid()
+	n.ConditionalAdvertisementList = []ConditionalAdvertisement{
+		ConditionalAdvertisement{AdvertisePolicy: "pd1", ExistPolicy: "pd2"},
+	}
+	assert.NotNil(ValidateNeighbor(&n, &p))
+}
+
+func TestAddUpdateDeleteNeighbor(t *testing.T) {
+	assert := assert.New(t)
+	c := testConfigSet()
+	cur := &c.Bgp
+
+	n := Neighbor{NeighborAddress: net.ParseIP("10.0.0.3"), PeerAs: 65003}
+	added, err := AddNeighbor(cur, &n, &c.Policy)
+	assert.Nil(err)
+	assert.Equal(len(added.NeighborList), 3)
+	assert.Equal(len(cur.NeighborList), 2)
+	assert.Equal(added.NeighborList[2].Timers.HoldTime, float64(DEFAULT_HOLDTIME))
+	assert.Equal(added.NeighborList[2].PeerType, PeerTypeDef(PEER_TYPE_EXTERNAL))
+
+	dup := Neighbor{NeighborAddress: net.ParseIP("10.0.0.3"), PeerAs: 65003}
+	_, err = AddNeighbor(added, &dup, &c.Policy)
+	assert.NotNil(err)
+
+	invalid := Neighbor{NeighborAddress: net.ParseIP("10.0.0.4")}
+	_, err = AddNeighbor(added, &invalid, &c.Policy)
+	assert.NotNil(err)
+
+	// the old configuration is returned only if it's changed
+	same := Neighbor{NeighborAddress: net.ParseIP("10.0.0.3"), PeerAs: 65003}
+	updated, old, err := UpdateNeighbor(added, &same, &c.Policy)
+	assert.Nil(err)
+	assert.Nil(old)
+	assert.Equal(updated, added)
+
+	changed := Neighbor{NeighborAddress: net.ParseIP("10.0.0.3"), PeerAs: 65003, Description: "updated"}
+	updated, old, err = UpdateNeighbor(added, &changed, &c.Policy)
+	assert.Nil(err)
+	assert.Equal(old.Description, "")
+	assert.Equal(updated.NeighborList[2].Description, "updated")
+	assert.Equal(added.NeighborList[2].Description, "")
+
+	missing := Neighbor{NeighborAddress: net.ParseIP("10.0.0.5"), PeerAs: 65005}
+	_, _, err = UpdateNeighbor(added, &missing, &c.Policy)
+	assert.NotNil(err)
+
+	deleted, d, err := DeleteNeighbor(updated, net.ParseIP("10.0.0.3"))
+	assert.Nil(err)
+	assert.Equal(d.Description, "updated")
+	assert.Equal(deleted.NeighborList, cur.NeighborList)
+	assert.Equal(len(updated.NeighborList), 3)
+
+	_, _, err = DeleteNeighbor(deleted, net.ParseIP("10.0.0.3"))
+	assert.NotNil(err)
+}
+
+func TestNeighborNeedsReset(t *testing.T) {
+	assert := assert.New(t)
+	old := testConfigSet().Bgp.NeighborList[1]
+	SetNeighborDefaultValues(&Global{As: 65000}, &old)
+
+	n := old
+	n.Description = "updated"
+	n.ApplyPolicy.ImportPolicies = []string{"pd1"}
+	n.ConditionalAdvertisementList = []ConditionalAdvertisement{
+		ConditionalAdvertisement{AdvertisePolicy: "pd1", ExistPolicy: "pd1"},
+	}
+	n.AfiSafiList = []AfiSafi{
+		AfiSafi{AfiSafiName: "ipv6-unicast", Ipv6Unicast: Ipv6Unicast{PrefixLimit: PrefixLimit{MaxPrefixes: 10}}},
+		AfiSafi{AfiSafiName: "ipv4-unicast"},
+	}
+	assert.False(NeighborNeedsReset(&old, &n))
+
+	n = old
+	n.PeerAs = 65001
+	assert.True(NeighborNeedsReset(&old, &n))
+
+	n = old
+	n.AuthPassword = "password"
+	assert.True(NeighborNeedsReset(&old, &n))
+
+	n = old
+	n.AfiSafiList = []AfiSafi{AfiSafi{AfiSafiName: "ipv6-unicast"}}
+	assert.True(NeighborNeedsReset(&old, &n))
+
+	n = old
+	n.AfiSafiList = []AfiSafi{
+		AfiSafi{AfiSafiName: "ipv4-unicast"},
+		AfiSafi{AfiSafiName: "ipv6-unicast"},
+	}
+	assert.True(NeighborNeedsReset(&old, &n))
+}
@@ -1481,6 +1481,77 @@ const (
 	BGP_ERROR_SUB_OUT_OF_RESOURCES
 )
 
+var errorCodeNames = map[uint8]string{
+	BGP_ERROR_MESSAGE_HEADER_ERROR: "MESSAGE_HEADER_ERROR",
+	BGP_ERROR_OPEN_MESSAGE_ERROR:   "OPEN_MESSAGE_ERROR",
+	BGP_ERROR_UPDATE_MESSAGE_ERROR: "UPDATE_MESSAGE_ERROR",
+	BGP_ERROR_HOLD_TIMER_EXPIRED:   "HOLD_TIMER_EXPIRED",
+	BGP_ERROR_FSM_ERROR:            "FSM_ERROR",
+	BGP_ERROR_CEASE:                "CEASE",
+}
+
+var errorSubcodeNames = map[uint8]map[uint8]string{
+	BGP_ERROR_MESSAGE_HEADER_ERROR: {
+		BGP_ERROR_SUB_CONNECTION_NOT_SYNCHRONIZED: "CONNECTION_NOT_SYNCHRONIZED",
+		BGP_ERROR_SUB_BAD_MESSAGE_LENGTH:          "BAD_MESSAGE_LENGTH",
+		BGP_ERROR_SUB_BAD_MESSAGE_TYPE:            "BAD_MESSAGE_TYPE",
+	},
+	BGP_ERROR_OPEN_MESSAGE_ERROR: {
+		BGP_ERROR_SUB_UNSUPPORTED_VERSION_NUMBER:     "UNSUPPORTED_VERSION_NUMBER",
+		BGP_ERROR_SUB_BAD_PEER_AS:                    "BAD_PEER_AS",
+		BGP_ERROR_SUB_BAD_BGP_IDENTIFIER:             "BAD_BGP_IDENTIFIER",
+		BGP_ERROR_SUB_UNSUPPORTED_OPTIONAL_PARAMETER: "UNSUPPORTED_OPTIONAL_PARAMETER",
+		BGP_ERROR_SUB_AUTHENTICATION_FAILURE:         "AUTHENTICATION_FAILURE",
+		BGP_ERROR_SUB_UNACCEPTABLE_HOLD_TIME:         "UNACCEPTABLE_HOLD_TIME",
+	},
+	BGP_ERROR_UPDATE_MESSAGE_ERROR: {
+		BGP_ERROR_SUB_MALFORMED_ATTRIBUTE_LIST:          "MALFORMED_ATTRIBUTE_LIST",
+		BGP_ERROR_SUB_UNRECOGNIZED_WELL_KNOWN_ATTRIBUTE: "UNRECOGNIZED_WELL_KNOWN_ATTRIBUTE",
+		BGP_ERROR_SUB_MISSING_WELL_KNOWN_ATTRIBUTE:      "MISSING_WELL_KNOWN_ATTRIBUTE",
+		BGP_ERROR_SUB_ATTRIBUTE_FLAGS_ERROR:             "ATTRIBUTE_FLAGS_ERROR",
+		BGP_ERROR_SUB_ATTRIBUTE_LENGTH_ERROR:            "ATTRIBUTE_LENGTH_ERROR",
+		BGP_ERROR_SUB_INVALID_ORIGIN_ATTRIBUTE:          "INVALID_ORIGIN_ATTRIBUTE",
+		BGP_ERROR_SUB_ROUTING_LOOP:                      "ROUTING_LOOP",
+		BGP_ERROR_SUB_INVALID_NEXT_HOP_ATTRIBUTE:        "INVALID_NEXT_HOP_ATTRIBUTE",
+		BGP_ERROR_SUB_OPTIONAL_ATTRIBUTE_ERROR:          "OPTIONAL_ATTRIBUTE_ERROR",
+		BGP_ERROR_SUB_INVALID_NETWORK_FIELD:             "INVALID_NETWORK_FIELD",
+		BGP_ERROR_SUB_MALFORMED_AS_PATH:                 "MALFORMED_AS_PATH",
+	},
+	BGP_ERROR_HOLD_TIMER_EXPIRED: {
+		BGP_ERROR_SUB_HOLD_TIMER_EXPIRED: "HOLD_TIMER_EXPIRED",
+	},
+	BGP_ERROR_FSM_ERROR: {
+		BGP_ERROR_SUB_FSM_ERROR: "FSM_ERROR",
+	},
+	BGP_ERROR_CEASE: {
+		BGP_ERROR_SUB_MAXIMUM_NUMBER_OF_PREFIXES_REACHED: "MAXIMUM_NUMBER_OF_PREFIXES_REACHED",
+		BGP_ERROR_SUB_ADMINISTRATIVE_SHUTDOWN:            "ADMINISTRATIVE_SHUTDOWN",
+		BGP_ERROR_SUB_PEER_DECONFIGURED:                  "PEER_DECONFIGURED",
+		BGP_ERROR_SUB_ADMINISTRATIVE_RESET:               "ADMINISTRATIVE_RESET",
+		BGP_ERROR_SUB_CONNECTION_RESET:                   "CONNECTION_RESET",
+		BGP_ERROR_SUB_OTHER_CONFIGURATION_CHANGE:         "OTHER_CONFIGURATION_CHANGE",
+		BGP_ERROR_SUB_CONNECTION_COLLISION_RESOLUTION:    "CONNECTION_COLLISION_RESOLUTION",
+		BGP_ERROR_SUB_OUT_OF_RESOURCES:                   "OUT_OF_RESOURCES",
+	},
+}
+
+// the names of the error code and subcode of a NOTIFICATION message
+func NotificationErrorNames(code, subcode uint8) (string, string) {
+	codeName, ok := errorCodeNames[code]
+	if !ok {
+		codeName = fmt.Sprintf("UNKNOWN(%d)", code)
+	}
+	subcodeName, ok := errorSubcodeNames[code][subcode]
+	if !ok {
+		if subcode == 0 {
+			subcodeName = "UNSPECIFIC"
+		} else {
+			subcodeName = fmt.Sprintf("UNKNOWN(%d)", subcode)
+		}
+	}
+	return codeName, subcodeName
+}
+
 var pathAttrFlags map[BGPAttrType]uint8 = map[BGPAttrType]uint8{
 	BGP_ATTR_TYPE_ORIGIN:               BGP_ATTR_FLAG_TRANSITIVE,
 	BGP_ATTR_TYPE_AS_PATH:              BGP_ATTR_FLAG_TRANSITIVE,
@@ -2484,6 +2555,13 @@ type ExtendedCommunityInterface interface {
 	String() string
 }
 
+// the sub-types of the AS specific and IPv4 address specific extended
+// communities (RFC 4360)
+const (
+	EC_SUBTYPE_ROUTE_TARGET = 0x02
+	EC_SUBTYPE_ROUTE_ORIGIN = 0x03
+)
+
 type TwoOctetAsSpecificExtended struct {
 	SubType    uint8
 	AS         uint16
@@ -2770,7 +2848,7 @@ func NewPathAttributeAs4Aggregator(as uint32, address string) *PathAttributeAs4A
 		},
 		Value: PathAttributeAggregatorParam{
 			AS:      as,
-			Address: net.ParseIP(address),
+			Address: net.ParseIP(address).To4(),
 		},
 	}
 }
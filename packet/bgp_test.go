@@ -195,3 +195,16 @@ func Test_RouteTargetMembershipNLRIString(t *testing.T) {
 	assert.Equal("65546:281479272677952/96", r.String())
 
 }
+
+func Test_NotificationErrorNames(t *testing.T) {
+	assert := assert.New(t)
+	code, subcode := NotificationErrorNames(BGP_ERROR_CEASE, BGP_ERROR_SUB_ADMINISTRATIVE_SHUTDOWN)
+	assert.Equal("CEASE", code)
+	assert.Equal("ADMINISTRATIVE_SHUTDOWN", subcode)
+	code, subcode = NotificationErrorNames(BGP_ERROR_HOLD_TIMER_EXPIRED, 0)
+	assert.Equal("HOLD_TIMER_EXPIRED", code)
+	assert.Equal("UNSPECIFIC", subcode)
+	code, subcode = NotificationErrorNames(10, 1)
+	assert.Equal("UNKNOWN(10)", code)
+	assert.Equal("UNKNOWN(1)", subcode)
+}
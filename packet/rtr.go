@@ -0,0 +1,420 @@
+// Copyright (C) 2015 Nippon Telegraph and Telephone Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bgp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// RPKI-to-Router protocol (RFC 6810 and RFC 8210)
+
+const (
+	RTR_PORT              = 323
+	RTR_MIN_LEN           = 8
+	RTR_MAX_LEN           = 65535
+	RTR_PROTOCOL_VERSION0 = 0
+	RTR_PROTOCOL_VERSION1 = 1
+)
+
+const (
+	RTR_SERIAL_NOTIFY  = 0
+	RTR_SERIAL_QUERY   = 1
+	RTR_RESET_QUERY    = 2
+	RTR_CACHE_RESPONSE = 3
+	RTR_IPV4_PREFIX    = 4
+	RTR_IPV6_PREFIX    = 6
+	RTR_END_OF_DATA    = 7
+	RTR_CACHE_RESET    = 8
+	RTR_ROUTER_KEY     = 9
+	RTR_ERROR_REPORT   = 10
+)
+
+const (
+	RTR_SERIAL_NOTIFY_LEN        = 12
+	RTR_SERIAL_QUERY_LEN         = 12
+	RTR_RESET_QUERY_LEN          = 8
+	RTR_CACHE_RESPONSE_LEN       = 8
+	RTR_IPV4_PREFIX_LEN          = 20
+	RTR_IPV6_PREFIX_LEN          = 32
+	RTR_END_OF_DATA_LEN_V0       = 12
+	RTR_END_OF_DATA_LEN_V1       = 24
+	RTR_CACHE_RESET_LEN          = 8
+	RTR_ERROR_REPORT_MIN_LEN     = 16
+	RTR_PREFIX_FLAG_ANNOUNCE     = 0x01
+	RTR_PREFIX_FLAG_WITHDRAWAL   = 0x00
+	RTR_DEFAULT_REFRESH_INTERVAL = 3600
+	RTR_DEFAULT_RETRY_INTERVAL   = 600
+	RTR_DEFAULT_EXPIRE_INTERVAL  = 7200
+)
+
+// error codes of the error report
+const (
+	RTR_CORRUPT_DATA                 = 0
+	RTR_INTERNAL_ERROR               = 1
+	RTR_NO_DATA_AVAILABLE            = 2
+	RTR_INVALID_REQUEST              = 3
+	RTR_UNSUPPORTED_PROTOCOL_VERSION = 4
+	RTR_UNSUPPORTED_PDU_TYPE         = 5
+	RTR_WITHDRAWAL_OF_UNKNOWN_RECORD = 6
+	RTR_DUPLICATE_ANNOUNCEMENT       = 7
+	RTR_UNEXPECTED_PROTOCOL_VERSION  = 8
+)
+
+type RTRMessage interface {
+	DecodeFromBytes([]byte) error
+	Serialize() ([]byte, error)
+}
+
+// the common header. the session id is zero for some PDUs, and the
+// error code for the error report.
+type RTRCommon struct {
+	Version   uint8
+	Type      uint8
+	SessionID uint16
+	Len       uint32
+}
+
+func (m *RTRCommon) DecodeFromBytes(data []byte) error {
+	if len(data) < RTR_MIN_LEN {
+		return fmt.Errorf("not all RTR header bytes available")
+	}
+	m.Version = data[0]
+	m.Type = data[1]
+	m.SessionID = binary.BigEndian.Uint16(data[2:4])
+	m.Len = binary.BigEndian.Uint32(data[4:8])
+	if int(m.Len) > len(data) {
+		return fmt.Errorf("not all RTR message bytes available")
+	}
+	return nil
+}
+
+func (m *RTRCommon) serialize(length int) []byte {
+	buf := make([]byte, length)
+	buf[0] = m.Version
+	buf[1] = m.Type
+	binary.BigEndian.PutUint16(buf[2:4], m.SessionID)
+	binary.BigEndian.PutUint32(buf[4:8], uint32(length))
+	return buf
+}
+
+// serial notify and serial query
+type RTRSerial struct {
+	RTRCommon
+	Serial uint32
+}
+
+func (m *RTRSerial) DecodeFromBytes(data []byte) error {
+	if err := m.RTRCommon.DecodeFromBytes(data); err != nil {
+		return err
+	}
+	if m.Len != RTR_SERIAL_NOTIFY_LEN {
+		return fmt.Errorf("invalid RTR serial PDU length: %d", m.Len)
+	}
+	m.Serial = binary.BigEndian.Uint32(data[8:12])
+	return nil
+}
+
+func (m *RTRSerial) Serialize() ([]byte, error) {
+	buf := m.serialize(RTR_SERIAL_NOTIFY_LEN)
+	binary.BigEndian.PutUint32(buf[8:12], m.Serial)
+	return buf, nil
+}
+
+func NewRTRSerialNotify(version uint8, sessionID uint16, serial uint32) *RTRSerial {
+	return &RTRSerial{
+		RTRCommon: RTRCommon{Version: version, Type: RTR_SERIAL_NOTIFY, SessionID: sessionID},
+		Serial:    serial,
+	}
+}
+
+func NewRTRSerialQuery(version uint8, sessionID uint16, serial uint32) *RTRSerial {
+	return &RTRSerial{
+		RTRCommon: RTRCommon{Version: version, Type: RTR_SERIAL_QUERY, SessionID: sessionID},
+		Serial:    serial,
+	}
+}
+
+// reset query, cache response and cache reset, which have the header
+// only
+type RTRHeaderOnly struct {
+	RTRCommon
+}
+
+func (m *RTRHeaderOnly) DecodeFromBytes(data []byte) error {
+	if err := m.RTRCommon.DecodeFromBytes(data); err != nil {
+		return err
+	}
+	if m.Len != RTR_MIN_LEN {
+		return fmt.Errorf("invalid RTR PDU length: %d", m.Len)
+	}
+	return nil
+}
+
+func (m *RTRHeaderOnly) Serialize() ([]byte, error) {
+	return m.serialize(RTR_MIN_LEN), nil
+}
+
+func NewRTRResetQuery(version uint8) *RTRHeaderOnly {
+	return &RTRHeaderOnly{RTRCommon{Version: version, Type: RTR_RESET_QUERY}}
+}
+
+func NewRTRCacheResponse(version uint8, sessionID uint16) *RTRHeaderOnly {
+	return &RTRHeaderOnly{RTRCommon{Version: version, Type: RTR_CACHE_RESPONSE, SessionID: sessionID}}
+}
+
+func NewRTRCacheReset(version uint8) *RTRHeaderOnly {
+	return &RTRHeaderOnly{RTRCommon{Version: version, Type: RTR_CACHE_RESET}}
+}
+
+// IPv4 prefix and IPv6 prefix
+type RTRIPPrefix struct {
+	RTRCommon
+	Flags     uint8
+	PrefixLen uint8
+	MaxLen    uint8
+	Prefix    net.IP
+	AS        uint32
+}
+
+func (m *RTRIPPrefix) DecodeFromBytes(data []byte) error {
+	if err := m.RTRCommon.DecodeFromBytes(data); err != nil {
+		return err
+	}
+	addrLen := net.IPv4len
+	if m.Type == RTR_IPV6_PREFIX {
+		addrLen = net.IPv6len
+	}
+	if int(m.Len) != 16+addrLen {
+		return fmt.Errorf("invalid RTR prefix PDU length: %d", m.Len)
+	}
+	m.Flags = data[8]
+	m.PrefixLen = data[9]
+	m.MaxLen = data[10]
+	if int(m.PrefixLen) > addrLen*8 || m.PrefixLen > m.MaxLen || int(m.MaxLen) > addrLen*8 {
+		return fmt.Errorf("invalid RTR prefix length %d and max length %d", m.PrefixLen, m.MaxLen)
+	}
+	m.Prefix = net.IP(append([]byte{}, data[12:12+addrLen]...))
+	m.AS = binary.BigEndian.Uint32(data[12+addrLen : 16+addrLen])
+	return nil
+}
+
+func (m *RTRIPPrefix) Serialize() ([]byte, error) {
+	addr := []byte(m.Prefix.To4())
+	if m.Type == RTR_IPV6_PREFIX {
+		addr = []byte(m.Prefix.To16())
+	}
+	if addr == nil {
+		return nil, fmt.Errorf("invalid RTR prefix: %s", m.Prefix)
+	}
+	buf := m.serialize(16 + len(addr))
+	buf[8] = m.Flags
+	buf[9] = m.PrefixLen
+	buf[10] = m.MaxLen
+	copy(buf[12:], addr)
+	binary.BigEndian.PutUint32(buf[12+len(addr):], m.AS)
+	return buf, nil
+}
+
+func NewRTRIPPrefix(version uint8, prefix net.IP, prefixLen, maxLen uint8, as uint32, announce bool) *RTRIPPrefix {
+	t := uint8(RTR_IPV4_PREFIX)
+	if prefix.To4() == nil {
+		t = RTR_IPV6_PREFIX
+	}
+	flags := uint8(RTR_PREFIX_FLAG_WITHDRAWAL)
+	if announce {
+		flags = RTR_PREFIX_FLAG_ANNOUNCE
+	}
+	return &RTRIPPrefix{
+		RTRCommon: RTRCommon{Version: version, Type: t},
+		Flags:     flags,
+		PrefixLen: prefixLen,
+		MaxLen:    maxLen,
+		Prefix:    prefix,
+		AS:        as,
+	}
+}
+
+// end of data. the intervals are carried in version 1 only.
+type RTREndOfData struct {
+	RTRCommon
+	Serial          uint32
+	RefreshInterval uint32
+	RetryInterval   uint32
+	ExpireInterval  uint32
+}
+
+func (m *RTREndOfData) DecodeFromBytes(data []byte) error {
+	if err := m.RTRCommon.DecodeFromBytes(data); err != nil {
+		return err
+	}
+	switch {
+	case m.Version == RTR_PROTOCOL_VERSION0 && m.Len == RTR_END_OF_DATA_LEN_V0:
+		m.Serial = binary.BigEndian.Uint32(data[8:12])
+		m.RefreshInterval = RTR_DEFAULT_REFRESH_INTERVAL
+		m.RetryInterval = RTR_DEFAULT_RETRY_INTERVAL
+		m.ExpireInterval = RTR_DEFAULT_EXPIRE_INTERVAL
+	case m.Version != RTR_PROTOCOL_VERSION0 && m.Len == RTR_END_OF_DATA_LEN_V1:
+		m.Serial = binary.BigEndian.Uint32(data[8:12])
+		m.RefreshInterval = binary.BigEndian.Uint32(data[12:16])
+		m.RetryInterval = binary.BigEndian.Uint32(data[16:20])
+		m.ExpireInterval = binary.BigEndian.Uint32(data[20:24])
+	default:
+		return fmt.Errorf("invalid RTR end of data PDU length: %d", m.Len)
+	}
+	return nil
+}
+
+func (m *RTREndOfData) Serialize() ([]byte, error) {
+	if m.Version == RTR_PROTOCOL_VERSION0 {
+		buf := m.serialize(RTR_END_OF_DATA_LEN_V0)
+		binary.BigEndian.PutUint32(buf[8:12], m.Serial)
+		return buf, nil
+	}
+	buf := m.serialize(RTR_END_OF_DATA_LEN_V1)
+	binary.BigEndian.PutUint32(buf[8:12], m.Serial)
+	binary.BigEndian.PutUint32(buf[12:16], m.RefreshInterval)
+	binary.BigEndian.PutUint32(buf[16:20], m.RetryInterval)
+	binary.BigEndian.PutUint32(buf[20:24], m.ExpireInterval)
+	return buf, nil
+}
+
+func NewRTREndOfData(version uint8, sessionID uint16, serial uint32) *RTREndOfData {
+	return &RTREndOfData{
+		RTRCommon:       RTRCommon{Version: version, Type: RTR_END_OF_DATA, SessionID: sessionID},
+		Serial:          serial,
+		RefreshInterval: RTR_DEFAULT_REFRESH_INTERVAL,
+		RetryInterval:   RTR_DEFAULT_RETRY_INTERVAL,
+		ExpireInterval:  RTR_DEFAULT_EXPIRE_INTERVAL,
+	}
+}
+
+// error report. the session id field of the header is the error code.
+type RTRErrorReport struct {
+	RTRCommon
+	PDU  []byte
+	Text []byte
+}
+
+func (m *RTRErrorReport) DecodeFromBytes(data []byte) error {
+	if err := m.RTRCommon.DecodeFromBytes(data); err != nil {
+		return err
+	}
+	if m.Len < RTR_ERROR_REPORT_MIN_LEN {
+		return fmt.Errorf("invalid RTR error report length: %d", m.Len)
+	}
+	data = data[8:m.Len]
+	pduLen := binary.BigEndian.Uint32(data[:4])
+	if int(pduLen)+8 > len(data) {
+		return fmt.Errorf("invalid RTR error report PDU length: %d", pduLen)
+	}
+	m.PDU = data[4 : 4+pduLen]
+	data = data[4+pduLen:]
+	textLen := binary.BigEndian.Uint32(data[:4])
+	if int(textLen)+4 > len(data) {
+		return fmt.Errorf("invalid RTR error report text length: %d", textLen)
+	}
+	m.Text = data[4 : 4+textLen]
+	return nil
+}
+
+func (m *RTRErrorReport) Serialize() ([]byte, error) {
+	buf := m.serialize(RTR_ERROR_REPORT_MIN_LEN + len(m.PDU) + len(m.Text))
+	binary.BigEndian.PutUint32(buf[8:12], uint32(len(m.PDU)))
+	copy(buf[12:], m.PDU)
+	binary.BigEndian.PutUint32(buf[12+len(m.PDU):], uint32(len(m.Text)))
+	copy(buf[16+len(m.PDU):], m.Text)
+	return buf, nil
+}
+
+func (m *RTRErrorReport) ErrorCode() uint16 {
+	return m.SessionID
+}
+
+func NewRTRErrorReport(version uint8, errorCode uint16, pdu []byte, text string) *RTRErrorReport {
+	return &RTRErrorReport{
+		RTRCommon: RTRCommon{Version: version, Type: RTR_ERROR_REPORT, SessionID: errorCode},
+		PDU:       pdu,
+		Text:      []byte(text),
+	}
+}
+
+// the router key PDUs of version 1 are decoded but not used
+type RTRRouterKey struct {
+	RTRCommon
+	Data []byte
+}
+
+func (m *RTRRouterKey) DecodeFromBytes(data []byte) error {
+	if err := m.RTRCommon.DecodeFromBytes(data); err != nil {
+		return err
+	}
+	m.Data = data[8:m.Len]
+	return nil
+}
+
+func (m *RTRRouterKey) Serialize() ([]byte, error) {
+	buf := m.serialize(RTR_MIN_LEN + len(m.Data))
+	copy(buf[8:], m.Data)
+	return buf, nil
+}
+
+// return the length of the PDU at the head of the data, which can be
+// used to split the stream into PDUs.
+func SplitRTR(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if len(data) < RTR_MIN_LEN {
+		return 0, nil, nil
+	}
+	length := binary.BigEndian.Uint32(data[4:8])
+	if length < RTR_MIN_LEN || length > RTR_MAX_LEN {
+		return 0, nil, fmt.Errorf("invalid RTR PDU length: %d", length)
+	}
+	if len(data) < int(length) {
+		return 0, nil, nil
+	}
+	return int(length), data[:length], nil
+}
+
+func ParseRTR(data []byte) (RTRMessage, error) {
+	if len(data) < RTR_MIN_LEN {
+		return nil, fmt.Errorf("not all RTR header bytes available")
+	}
+	var msg RTRMessage
+	switch data[1] {
+	case RTR_SERIAL_NOTIFY, RTR_SERIAL_QUERY:
+		msg = &RTRSerial{}
+	case RTR_RESET_QUERY, RTR_CACHE_RESPONSE, RTR_CACHE_RESET:
+		msg = &RTRHeaderOnly{}
+	case RTR_IPV4_PREFIX, RTR_IPV6_PREFIX:
+		msg = &RTRIPPrefix{}
+	case RTR_END_OF_DATA:
+		msg = &RTREndOfData{}
+	case RTR_ROUTER_KEY:
+		msg = &RTRRouterKey{}
+	case RTR_ERROR_REPORT:
+		msg = &RTRErrorReport{}
+	default:
+		return nil, fmt.Errorf("unknown RTR PDU type: %d", data[1])
+	}
+	if err := msg.DecodeFromBytes(data); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
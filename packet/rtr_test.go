@@ -0,0 +1,78 @@
+// Copyright (C) 2015 Nippon Telegraph and Telephone Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bgp
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+)
+
+func TestRTRMessages(t *testing.T) {
+	msgs := []RTRMessage{
+		NewRTRSerialNotify(1, 10, 100),
+		NewRTRSerialQuery(1, 10, 100),
+		NewRTRResetQuery(1),
+		NewRTRCacheResponse(1, 10),
+		NewRTRIPPrefix(1, net.ParseIP("10.0.0.0").To4(), 16, 24, 65001, true),
+		NewRTRIPPrefix(1, net.ParseIP("2001:db8::"), 32, 48, 65002, false),
+		NewRTREndOfData(1, 10, 100),
+		NewRTREndOfData(0, 10, 100),
+		NewRTRCacheReset(1),
+		NewRTRErrorReport(1, RTR_UNSUPPORTED_PROTOCOL_VERSION, []byte{1, 2, 0, 0, 0, 0, 0, 8}, "unsupported"),
+	}
+	var buf bytes.Buffer
+	for _, m := range msgs {
+		b, err := m.Serialize()
+		assert.Nil(t, err)
+		buf.Write(b)
+	}
+
+	// all the PDUs are read back from the stream
+	scanner := bufio.NewScanner(&buf)
+	scanner.Split(SplitRTR)
+	for _, m := range msgs {
+		assert.True(t, scanner.Scan())
+		parsed, err := ParseRTR(scanner.Bytes())
+		assert.Nil(t, err)
+		b1, _ := m.Serialize()
+		b2, _ := parsed.Serialize()
+		assert.Equal(t, b1, b2)
+	}
+	assert.False(t, scanner.Scan())
+	assert.Nil(t, scanner.Err())
+
+	b, _ := NewRTRErrorReport(0, RTR_NO_DATA_AVAILABLE, nil, "").Serialize()
+	m, _ := ParseRTR(b)
+	assert.Equal(t, uint16(RTR_NO_DATA_AVAILABLE), m.(*RTRErrorReport).ErrorCode())
+
+	// version 0 end of data has the default intervals
+	b, _ = NewRTREndOfData(0, 10, 100).Serialize()
+	m, _ = ParseRTR(b)
+	assert.Equal(t, uint32(RTR_DEFAULT_REFRESH_INTERVAL), m.(*RTREndOfData).RefreshInterval)
+
+	// max length shorter than prefix length
+	b, _ = NewRTRIPPrefix(1, net.ParseIP("10.0.0.0").To4(), 24, 16, 65001, true).Serialize()
+	_, err := ParseRTR(b)
+	assert.NotNil(t, err)
+
+	b, _ = NewRTRResetQuery(1).Serialize()
+	b[1] = 99
+	_, err = ParseRTR(b)
+	assert.NotNil(t, err)
+}
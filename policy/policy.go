@@ -16,14 +16,20 @@
 package policy
 
 import (
+	"errors"
 	"fmt"
 	log "github.com/Sirupsen/logrus"
 	"github.com/osrg/gobgp/config"
 	"github.com/osrg/gobgp/packet"
 	"github.com/osrg/gobgp/table"
+	"github.com/tchap/go-patricia/patricia"
+	"math"
 	"net"
+	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync/atomic"
 )
 
 type RouteType int
@@ -53,39 +59,70 @@ func NewPolicy(name string, pd config.PolicyDefinition, ds config.DefinedSets) *
 		Name: name,
 	}
 	for _, statement := range stmtList {
-		prefixSetName := statement.Conditions.MatchPrefixSet
-
-		prefixList := make([]Prefix, 0)
-		for _, ps := range ds.PrefixSetList {
-			if ps.PrefixSetName == prefixSetName {
-				for _, pl := range ps.PrefixList {
-					prefix, e := NewPrefix(pl.Address, pl.Masklength, pl.MasklengthRange)
-					if e != nil {
-						log.WithFields(log.Fields{
-							"Topic":  "Policy",
-							"prefix": prefix,
-							"msg":    e,
-						}).Warn("failed to generate a NewPrefix from configration.")
-						continue
-					}
-					prefixList = append(prefixList, prefix)
-				}
-			}
+		// the match set options are applied to each defined set
+		options := statement.Conditions.MatchSetOptions
+		conditions := make([]Conditions, 0)
+		if name := statement.Conditions.MatchPrefixSet; name != "" {
+			conditions = append(conditions, NewPrefixConditions(name, options, ds))
 		}
-
-		neighborSetName := statement.Conditions.MatchNeighborSet
-		neighborList := make([]net.IP, 0)
-		for _, neighborSet := range ds.NeighborSetList {
-			if neighborSet.NeighborSetName == neighborSetName {
-				for _, nl := range neighborSet.NeighborInfoList {
-					neighborList = append(neighborList, nl.Address)
-				}
+		if name := statement.Conditions.MatchNeighborSet; name != "" {
+			conditions = append(conditions, NewNeighborConditions(name, options, ds))
+		}
+		bgpConditions := statement.Conditions.BgpConditions
+		if bgpConditions.MatchCommunitySet != "" {
+			conditions = append(conditions, NewCommunityConditions(bgpConditions.MatchCommunitySet, options, ds))
+		}
+		if bgpConditions.MatchExtCommunitySet != "" {
+			conditions = append(conditions, NewExtCommunityConditions(bgpConditions.MatchExtCommunitySet, options, ds))
+		}
+		if bgpConditions.MatchAsPathSet != "" {
+			conditions = append(conditions, NewAsPathConditions(bgpConditions.MatchAsPathSet, options, ds))
+		}
+		// the zero value means no length condition. the empty AS path
+		// can be matched with the AS path set member "^$".
+		if c := bgpConditions.AsPathLength; c.Operator != config.ATTRIBUTE_COMPARISON_EQ || c.Value != 0 {
+			conditions = append(conditions, &AsPathLengthConditions{
+				Operator: c.Operator,
+				Value:    c.Value,
+			})
+		}
+		if o := bgpConditions.OriginEq; o != config.MATCH_ORIGIN_TYPE_NONE {
+			conditions = append(conditions, newOriginConditions(o))
+		}
+		if r := bgpConditions.MedRange; r != "" {
+			c, err := NewMedConditions(r)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"Topic": "Policy",
+					"Key":   statement.Name,
+					"Error": err,
+				}).Warn("failed to parse the MED range")
+				// the statement never matches
+				conditions = append(conditions, &DefaultConditions{})
+			} else {
+				conditions = append(conditions, c)
 			}
 		}
-		con := &PrefixConditions{
-			PrefixList:      prefixList,
-			NeighborList:    neighborList,
-			MatchSetOptions: statement.Conditions.MatchSetOptions,
+		if bgpConditions.LocalPrefEq != 0 {
+			conditions = append(conditions, &LocalPrefConditions{LocalPref: bgpConditions.LocalPrefEq})
+		}
+		if len(bgpConditions.NextHopIn) > 0 {
+			conditions = append(conditions, NewNextHopConditions(bgpConditions.NextHopIn))
+		}
+		if name := bgpConditions.MatchNextHopSet; name != "" {
+			conditions = append(conditions, NewNextHopSetConditions(name, options, ds))
+		}
+		if len(bgpConditions.AfiSafiIn) > 0 {
+			conditions = append(conditions, NewRouteFamilyConditions(bgpConditions.AfiSafiIn))
+		}
+		if v := bgpConditions.RpkiValidationResult; v != config.RPKI_VALIDATION_RESULT_TYPE_NONE {
+			conditions = append(conditions, &RpkiValidationConditions{Result: v})
+		}
+		if protocol := statement.Conditions.InstallProtocolEq; protocol != config.INSTALL_PROTOCOL_TYPE_NONE {
+			conditions = append(conditions, &InstallProtocolConditions{InstallProtocol: protocol})
+		}
+		if tag := statement.Conditions.IgpConditions.TagEq; tag != "" {
+			conditions = append(conditions, &IgpTagConditions{Tag: string(tag)})
 		}
 
 		act := &RoutingActions{
@@ -95,10 +132,38 @@ func NewPolicy(name string, pd config.PolicyDefinition, ds config.DefinedSets) *
 			act.AcceptRoute = true
 		}
 
+		modActions := make([]Actions, 0)
+		if statement.Actions.SetWeight != 0 {
+			modActions = append(modActions, &WeightAction{Weight: statement.Actions.SetWeight})
+		}
+		if len(statement.Actions.BgpActions.SetCommunity.Communities) > 0 ||
+			statement.Actions.BgpActions.SetCommunity.Options == config.BGP_SET_COMMUNITY_OPTION_TYPE_REPLACE {
+			modActions = append(modActions, NewCommunityAction(statement.Actions.BgpActions.SetCommunity))
+		}
+		if len(statement.Actions.BgpActions.SetExtCommunity.Communities) > 0 ||
+			statement.Actions.BgpActions.SetExtCommunity.Options == config.BGP_SET_COMMUNITY_OPTION_TYPE_REPLACE {
+			modActions = append(modActions, NewExtCommunityAction(statement.Actions.BgpActions.SetExtCommunity))
+		}
+		for _, a := range newModificationActions(statement.Actions.BgpActions) {
+			mod, err := NewModificationActions(a.AttrType, a.Value)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"Topic": "Policy",
+					"Key":   statement.Name,
+					"Error": err,
+				}).Warn("failed to parse the modification action")
+				continue
+			}
+			modActions = append(modActions, mod)
+		}
+
 		s := Statement{
 			Name:       statement.Name,
-			Conditions: con,
+			CallPolicy: statement.Conditions.CallPolicy,
+			Conditions: conditions,
 			Actions:    act,
+			ModActions: modActions,
+			hits:       new(uint64),
 		}
 		st = append(st, s)
 	}
@@ -106,10 +171,149 @@ func NewPolicy(name string, pd config.PolicyDefinition, ds config.DefinedSets) *
 	return p
 }
 
+// create the policies of the routing policy configuration and resolve
+// the policies called by the statements. the calls making a loop are
+// left unresolved, and the statements never match.
+func NewPolicyMap(pl config.RoutingPolicy) map[string]*Policy {
+	pMap := make(map[string]*Policy)
+	for _, pd := range pl.PolicyDefinitionList {
+		pMap[pd.Name] = NewPolicy(pd.Name, pd, pl.DefinedSets)
+	}
+	for _, p := range pMap {
+		for i := range p.Statements {
+			s := &p.Statements[i]
+			if s.CallPolicy == "" {
+				continue
+			}
+			called, ok := pMap[s.CallPolicy]
+			if !ok {
+				log.WithFields(log.Fields{
+					"Topic":      "Policy",
+					"PolicyName": p.Name,
+					"Key":        s.CallPolicy,
+				}).Warn("called policy isn't defined")
+				continue
+			}
+			if called.calls(p.Name, pMap, map[string]bool{}) {
+				log.WithFields(log.Fields{
+					"Topic":      "Policy",
+					"PolicyName": p.Name,
+					"Key":        s.CallPolicy,
+				}).Warn("policy calls make a loop")
+				continue
+			}
+			s.callPolicy = called
+		}
+	}
+	return pMap
+}
+
+// UpdatePolicyMap creates the policies of the new configuration like
+// NewPolicyMap. The policies whose definitions and called policies
+// aren't changed are taken over from the old map with their hit
+// counts, so the users of a policy can tell whether it's updated by
+// comparing the pointers. All the policies are updated when the
+// defined sets are changed.
+func UpdatePolicyMap(old map[string]*Policy, oldConfig, newConfig config.RoutingPolicy) map[string]*Policy {
+	pMap := NewPolicyMap(newConfig)
+	if !reflect.DeepEqual(oldConfig.DefinedSets, newConfig.DefinedSets) {
+		return pMap
+	}
+	oldDefs := make(map[string]config.PolicyDefinition)
+	for _, pd := range oldConfig.PolicyDefinitionList {
+		oldDefs[pd.Name] = pd
+	}
+	updated := make(map[string]bool)
+	for _, pd := range newConfig.PolicyDefinitionList {
+		if oldPd, ok := oldDefs[pd.Name]; !ok || old[pd.Name] == nil || !reflect.DeepEqual(oldPd, pd) {
+			updated[pd.Name] = true
+		}
+	}
+	for name, _ := range oldDefs {
+		if _, ok := pMap[name]; !ok {
+			updated[name] = true
+		}
+	}
+	// the policies calling the updated ones are updated too
+	for changed := true; changed; {
+		changed = false
+		for name, p := range pMap {
+			if updated[name] {
+				continue
+			}
+			for _, s := range p.Statements {
+				if s.CallPolicy != "" && updated[s.CallPolicy] {
+					updated[name] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	for name, _ := range pMap {
+		if !updated[name] {
+			pMap[name] = old[name]
+		}
+	}
+	for name, p := range pMap {
+		if !updated[name] {
+			continue
+		}
+		for i := range p.Statements {
+			if s := &p.Statements[i]; s.callPolicy != nil {
+				s.callPolicy = pMap[s.CallPolicy]
+			}
+		}
+	}
+	return pMap
+}
+
+// return true if the policy calls the named policy directly or
+// indirectly
+func (p *Policy) calls(name string, pMap map[string]*Policy, visited map[string]bool) bool {
+	if p.Name == name {
+		return true
+	}
+	if visited[p.Name] {
+		return false
+	}
+	visited[p.Name] = true
+	for _, s := range p.Statements {
+		if called, ok := pMap[s.CallPolicy]; ok && called.calls(name, pMap, visited) {
+			return true
+		}
+	}
+	return false
+}
+
 type Statement struct {
-	Name       string
-	Conditions Conditions
+	Name string
+	// the name of the policy called as a subroutine. the statement
+	// matches only when the called policy accepts the path.
+	CallPolicy string
+	callPolicy *Policy
+	// all the conditions need to be met
+	Conditions []Conditions
 	Actions    Actions
+	// applied to the accepted paths in order
+	ModActions []Actions
+	// the number of the paths matching the statement. shared by the
+	// goroutines of the peers.
+	hits *uint64
+}
+
+func (s *Statement) hit(options *PolicyOptions) {
+	if s.hits != nil && (options == nil || !options.DryRun) {
+		atomic.AddUint64(s.hits, 1)
+	}
+}
+
+// the number of the paths matching the statement
+func (s *Statement) Hits() uint64 {
+	if s.hits == nil {
+		return 0
+	}
+	return atomic.LoadUint64(s.hits)
 }
 
 type Conditions interface {
@@ -117,84 +321,656 @@ type Conditions interface {
 }
 
 type DefaultConditions struct {
-	CallPolicy string
 }
 
 func (c *DefaultConditions) evaluate(path table.Path) bool {
 	return false
 }
 
+// apply the match set options to the results of matching the path
+// against each member of the set. ANY matches when any member matches,
+// ALL when all the members match and INVERT when no member matches.
+func evaluateSet(options config.MatchSetOptionsType, size int, match func(int) bool) bool {
+	switch options {
+	case config.MATCH_SET_OPTIONS_TYPE_ANY:
+		for i := 0; i < size; i++ {
+			if match(i) {
+				return true
+			}
+		}
+		return false
+	case config.MATCH_SET_OPTIONS_TYPE_ALL:
+		for i := 0; i < size; i++ {
+			if !match(i) {
+				return false
+			}
+		}
+		return size > 0
+	case config.MATCH_SET_OPTIONS_TYPE_INVERT:
+		for i := 0; i < size; i++ {
+			if match(i) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// a path has only one prefix and one neighbor, so ALL is handled as
+// ANY for the prefix and neighbor sets like OpenConfig restricts them.
+func restrictMatchSetOptions(options config.MatchSetOptionsType) config.MatchSetOptionsType {
+	if options == config.MATCH_SET_OPTIONS_TYPE_ALL {
+		return config.MATCH_SET_OPTIONS_TYPE_ANY
+	}
+	return options
+}
+
+// stop visiting the trie
+var errPrefixMatched = errors.New("prefix matched")
+
 type PrefixConditions struct {
 	DefaultConditions
 	PrefixList      []Prefix
-	NeighborList    []net.IP
 	MatchSetOptions config.MatchSetOptionsType
+	// PrefixList indexed for the lookup of the prefixes covering paths
+	tries map[bgp.RouteFamily]*patricia.Trie
+}
+
+func NewPrefixConditions(name string, options config.MatchSetOptionsType, ds config.DefinedSets) *PrefixConditions {
+	c := &PrefixConditions{
+		PrefixList:      make([]Prefix, 0),
+		MatchSetOptions: restrictMatchSetOptions(options),
+	}
+	for _, ps := range ds.PrefixSetList {
+		if ps.PrefixSetName != name {
+			continue
+		}
+		for _, pl := range ps.PrefixList {
+			prefix, e := NewPrefix(pl.Address, pl.Masklength, pl.MasklengthRange)
+			if e != nil {
+				log.WithFields(log.Fields{
+					"Topic":  "Policy",
+					"prefix": prefix,
+					"msg":    e,
+				}).Warn("failed to generate a NewPrefix from configration.")
+				continue
+			}
+			c.PrefixList = append(c.PrefixList, prefix)
+		}
+		c.tries = newPrefixTries(c.PrefixList)
+		return c
+	}
+	log.WithFields(log.Fields{
+		"Topic": "Policy",
+		"Key":   name,
+	}).Warn("prefix set isn't defined")
+	return c
 }
 
-// evaluate path's prefix and neighbor's address with conditions
-// return value depends on MatchSetOptions
+// compare prefixes in this condition and nlri of path.
+// If PrefixList's length is zero, return true.
+// Otherwise return value depends on MatchSetOptions
 func (c *PrefixConditions) evaluate(path table.Path) bool {
-	pref := c.evaluatePrefix(path)
-	log.Debug("evaluate prefix : ", pref)
-	neigh := c.evaluateNeighbor(path)
-	log.Debug("evaluate neighbor : ", neigh)
+	if len(c.PrefixList) == 0 {
+		return true
+	}
+	if c.tries == nil {
+		result := evaluateSet(c.MatchSetOptions, len(c.PrefixList), func(i int) bool {
+			return IpPrefixCalculate(path, c.PrefixList[i])
+		})
+		log.Debug("evaluate prefix : ", result)
+		return result
+	}
 
-	switch c.MatchSetOptions {
-	case config.MATCH_SET_OPTIONS_TYPE_ALL:
-		return pref && neigh
-	case config.MATCH_SET_OPTIONS_TYPE_ANY:
-		return pref || neigh
-	case config.MATCH_SET_OPTIONS_TYPE_INVERT:
-		return !(pref || neigh)
-	default:
-		return false
+	// look up the prefixes covering the address of the path instead
+	// of comparing the path with each prefix
+	matched := false
+	if trie, ok := c.tries[path.GetRouteFamily()]; ok {
+		if addr, masklen, ok := pathPrefix(path); ok {
+			// all the bits of the address
+			trie.VisitPrefixes(table.PrefixKey(addr, 128), func(_ patricia.Prefix, item patricia.Item) error {
+				for _, prefix := range item.([]*Prefix) {
+					if prefix.matchMasklength(addr, masklen) {
+						matched = true
+						return errPrefixMatched
+					}
+				}
+				return nil
+			})
+		}
+	}
+	// ALL is handled as ANY
+	result := matched
+	if c.MatchSetOptions == config.MATCH_SET_OPTIONS_TYPE_INVERT {
+		result = !matched
 	}
+	log.Debug("evaluate prefix : ", result)
+	return result
 }
 
-// compare prefixes in this condition and nlri of path and
-// subsequent comparison is skipped if that matches the conditions.
-// If PrefixList's length is zero, return true.
-func (c *PrefixConditions) evaluatePrefix(path table.Path) bool {
+type NeighborConditions struct {
+	DefaultConditions
+	NeighborList    []net.IP
+	MatchSetOptions config.MatchSetOptionsType
+}
 
-	if len(c.PrefixList) == 0 {
+func NewNeighborConditions(name string, options config.MatchSetOptionsType, ds config.DefinedSets) *NeighborConditions {
+	c := &NeighborConditions{
+		NeighborList:    make([]net.IP, 0),
+		MatchSetOptions: restrictMatchSetOptions(options),
+	}
+	for _, neighborSet := range ds.NeighborSetList {
+		if neighborSet.NeighborSetName != name {
+			continue
+		}
+		for _, nl := range neighborSet.NeighborInfoList {
+			c.NeighborList = append(c.NeighborList, nl.Address)
+		}
+		return c
+	}
+	log.WithFields(log.Fields{
+		"Topic": "Policy",
+		"Key":   name,
+	}).Warn("neighbor set isn't defined")
+	return c
+}
+
+// compare neighbor ipaddress of this condition and source address of path.
+// locally originated paths don't match any neighbor.
+// If NeighborList's length is zero, return true.
+// Otherwise return value depends on MatchSetOptions
+func (c *NeighborConditions) evaluate(path table.Path) bool {
+	if len(c.NeighborList) == 0 {
 		return true
 	}
+	result := evaluateSet(c.MatchSetOptions, len(c.NeighborList), func(i int) bool {
+		return path.GetSource() != nil && path.GetSource().Address.Equal(c.NeighborList[i])
+	})
+	log.Debug("evaluate neighbor : ", result)
+	return result
+}
 
-	for _, cp := range c.PrefixList {
-		if IpPrefixCalculate(path, cp) {
-			return true
+// community set member; a community or a regular expression matched
+// against the "<as>:<value>" form of the communities.
+type communityMember struct {
+	value  uint32
+	regexp *regexp.Regexp
+}
+
+func parseCommunityMember(s string) (*communityMember, error) {
+	if v, err := table.ParseCommunity(s); err == nil {
+		return &communityMember{value: v}, nil
+	}
+	r, err := regexp.Compile(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid community member: %s", s)
+	}
+	return &communityMember{regexp: r}, nil
+}
+
+func (m *communityMember) match(community uint32) bool {
+	if m.regexp == nil {
+		return m.value == community
+	}
+	return m.regexp.MatchString(fmt.Sprintf("%d:%d", community>>16, community&0xffff))
+}
+
+func parseCommunityMembers(members []string) []*communityMember {
+	list := make([]*communityMember, 0, len(members))
+	for _, s := range members {
+		m, err := parseCommunityMember(s)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"Topic": "Policy",
+				"Error": err,
+			}).Warn("failed to parse the community member")
+			continue
+		}
+		list = append(list, m)
+	}
+	return list
+}
+
+type CommunityConditions struct {
+	DefaultConditions
+	CommunityList   []*communityMember
+	MatchSetOptions config.MatchSetOptionsType
+}
+
+func NewCommunityConditions(name string, options config.MatchSetOptionsType, ds config.DefinedSets) *CommunityConditions {
+	c := &CommunityConditions{
+		CommunityList:   make([]*communityMember, 0),
+		MatchSetOptions: options,
+	}
+	for _, cs := range ds.BgpDefinedSets.CommunitySetList {
+		if cs.CommunitySetName == name {
+			c.CommunityList = parseCommunityMembers(cs.CommunityMemberList)
+			return c
 		}
 	}
+	log.WithFields(log.Fields{
+		"Topic": "Policy",
+		"Key":   name,
+	}).Warn("community set isn't defined")
+	return c
+}
+
+// a member of the set matches when the path has a community matching
+// the member. return value depends on MatchSetOptions
+func (c *CommunityConditions) evaluate(path table.Path) bool {
+	communities := path.GetCommunities()
+	return evaluateSet(c.MatchSetOptions, len(c.CommunityList), func(i int) bool {
+		for _, community := range communities {
+			if c.CommunityList[i].match(community) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// extended community set member; a route target or route origin like
+// "route-target:65000:100", the string representation of an extended
+// community like "65000:100", or a regular expression matched against
+// the both forms.
+type extCommunityMember struct {
+	community bgp.ExtendedCommunityInterface
+	value     string
+	regexp    *regexp.Regexp
+}
+
+func parseExtCommunityMember(s string) (*extCommunityMember, error) {
+	if c, err := table.ParseExtCommunity(s); err == nil {
+		return &extCommunityMember{community: c}, nil
+	}
+	if regexp.QuoteMeta(s) == s {
+		return &extCommunityMember{value: s}, nil
+	}
+	r, err := regexp.Compile(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid extended community member: %s", s)
+	}
+	return &extCommunityMember{regexp: r}, nil
+}
+
+func (m *extCommunityMember) match(community bgp.ExtendedCommunityInterface) bool {
+	if m.community != nil {
+		return table.EqualExtCommunity(m.community, community)
+	}
+	if m.regexp == nil {
+		return m.value == community.String()
+	}
+	return m.regexp.MatchString(community.String()) || m.regexp.MatchString(table.ExtCommunityString(community))
+}
+
+func parseExtCommunityMembers(members []string) []*extCommunityMember {
+	list := make([]*extCommunityMember, 0, len(members))
+	for _, s := range members {
+		m, err := parseExtCommunityMember(s)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"Topic": "Policy",
+				"Error": err,
+			}).Warn("failed to parse the extended community member")
+			continue
+		}
+		list = append(list, m)
+	}
+	return list
+}
+
+type ExtCommunityConditions struct {
+	DefaultConditions
+	ExtCommunityList []*extCommunityMember
+	MatchSetOptions  config.MatchSetOptionsType
+}
+
+func NewExtCommunityConditions(name string, options config.MatchSetOptionsType, ds config.DefinedSets) *ExtCommunityConditions {
+	c := &ExtCommunityConditions{
+		ExtCommunityList: make([]*extCommunityMember, 0),
+		MatchSetOptions:  options,
+	}
+	for _, es := range ds.BgpDefinedSets.ExtCommunitySetList {
+		if es.ExtCommunitySetName == name {
+			c.ExtCommunityList = parseExtCommunityMembers(es.ExtCommunityMemberList)
+			return c
+		}
+	}
+	log.WithFields(log.Fields{
+		"Topic": "Policy",
+		"Key":   name,
+	}).Warn("extended community set isn't defined")
+	return c
+}
+
+// a member of the set matches when the path has an extended community
+// matching the member. return value depends on MatchSetOptions
+func (c *ExtCommunityConditions) evaluate(path table.Path) bool {
+	communities := path.GetExtCommunities()
+	return evaluateSet(c.MatchSetOptions, len(c.ExtCommunityList), func(i int) bool {
+		for _, community := range communities {
+			if c.ExtCommunityList[i].match(community) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+type AsPathConditions struct {
+	DefaultConditions
+	AsPathList      []*regexp.Regexp
+	MatchSetOptions config.MatchSetOptionsType
+}
+
+func NewAsPathConditions(name string, options config.MatchSetOptionsType, ds config.DefinedSets) *AsPathConditions {
+	c := &AsPathConditions{
+		AsPathList:      make([]*regexp.Regexp, 0),
+		MatchSetOptions: options,
+	}
+	for _, as := range ds.BgpDefinedSets.AsPathSetList {
+		if as.AsPathSetName != name {
+			continue
+		}
+		for _, s := range as.AsPathSetMemberList {
+			r, err := table.ParseAsPathRegexp(s)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"Topic": "Policy",
+					"Key":   name,
+					"Error": err,
+				}).Warn("failed to parse the AS path set member")
+				continue
+			}
+			c.AsPathList = append(c.AsPathList, r)
+		}
+		return c
+	}
+	log.WithFields(log.Fields{
+		"Topic": "Policy",
+		"Key":   name,
+	}).Warn("AS path set isn't defined")
+	return c
+}
+
+// the regular expressions in the set are matched against the AS path
+// like "65001 65002 {65003,65004}". return value depends on
+// MatchSetOptions
+func (c *AsPathConditions) evaluate(path table.Path) bool {
+	aspath := path.GetAsString()
+	return evaluateSet(c.MatchSetOptions, len(c.AsPathList), func(i int) bool {
+		return c.AsPathList[i].MatchString(aspath)
+	})
+}
+
+// AsPathLengthConditions compares the length of the AS path. AS_SET is
+// counted as one AS.
+type AsPathLengthConditions struct {
+	DefaultConditions
+	Operator config.AttributeComparison
+	Value    uint32
+}
+
+func (c *AsPathLengthConditions) evaluate(path table.Path) bool {
+	length := uint32(path.GetAsPathLen())
+	switch c.Operator {
+	case config.ATTRIBUTE_COMPARISON_EQ:
+		return length == c.Value
+	case config.ATTRIBUTE_COMPARISON_GE:
+		return length >= c.Value
+	case config.ATTRIBUTE_COMPARISON_LE:
+		return length <= c.Value
+	}
 	return false
 }
 
-// compare neighbor ipaddress of this condition and source address of path
-// and, subsequent comparisons are skipped if that matches the conditions.
-// If NeighborList's length is zero, return true.
-func (c *PrefixConditions) evaluateNeighbor(path table.Path) bool {
+// OriginConditions compares the ORIGIN attribute.
+type OriginConditions struct {
+	DefaultConditions
+	Origin uint8
+}
 
-	if len(c.NeighborList) == 0 {
+func newOriginConditions(o config.MatchOriginType) Conditions {
+	switch o {
+	case config.MATCH_ORIGIN_TYPE_IGP:
+		return &OriginConditions{Origin: bgp.BGP_ORIGIN_ATTR_TYPE_IGP}
+	case config.MATCH_ORIGIN_TYPE_EGP:
+		return &OriginConditions{Origin: bgp.BGP_ORIGIN_ATTR_TYPE_EGP}
+	case config.MATCH_ORIGIN_TYPE_INCOMPLETE:
+		return &OriginConditions{Origin: bgp.BGP_ORIGIN_ATTR_TYPE_INCOMPLETE}
+	}
+	log.WithFields(log.Fields{
+		"Topic": "Policy",
+		"Key":   o,
+	}).Warn("unknown origin type")
+	return &DefaultConditions{}
+}
+
+func (c *OriginConditions) evaluate(path table.Path) bool {
+	origin, err := path.GetOrigin()
+	return err == nil && origin == c.Origin
+}
+
+// MedConditions matches the paths whose MED is in the range. The path
+// without MED is handled as MED 0.
+type MedConditions struct {
+	DefaultConditions
+	Min uint32
+	Max uint32
+}
+
+// create the MED condition from the range like "0..100". the minimum
+// or the maximum can be omitted.
+func NewMedConditions(medRange string) (*MedConditions, error) {
+	c := &MedConditions{Max: math.MaxUint32}
+	idx := strings.Index(medRange, "..")
+	if idx == -1 {
+		return nil, fmt.Errorf("invalid MED range: %s", medRange)
+	}
+	if idx != 0 {
+		min, err := strconv.ParseUint(medRange[:idx], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MED range: %s", medRange)
+		}
+		c.Min = uint32(min)
+	}
+	if idx+2 != len(medRange) {
+		max, err := strconv.ParseUint(medRange[idx+2:], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MED range: %s", medRange)
+		}
+		c.Max = uint32(max)
+	}
+	if c.Min > c.Max {
+		return nil, fmt.Errorf("invalid MED range: %s", medRange)
+	}
+	return c, nil
+}
+
+func (c *MedConditions) evaluate(path table.Path) bool {
+	med, _ := path.GetMed()
+	return c.Min <= med && med <= c.Max
+}
+
+type LocalPrefConditions struct {
+	DefaultConditions
+	LocalPref uint32
+}
+
+func (c *LocalPrefConditions) evaluate(path table.Path) bool {
+	localPref, err := path.GetLocalPref()
+	return err == nil && localPref == c.LocalPref
+}
+
+// NextHopConditions matches the paths whose next-hop is one of the
+// addresses, or the address of the neighbor that the path is received
+// from if PeerAddress is true.
+type NextHopConditions struct {
+	DefaultConditions
+	NextHopList []net.IP
+	PeerAddress bool
+}
+
+func NewNextHopConditions(nexthops []string) *NextHopConditions {
+	c := &NextHopConditions{
+		NextHopList: make([]net.IP, 0, len(nexthops)),
+	}
+	for _, s := range nexthops {
+		if s == NEXTHOP_PEER_ADDRESS {
+			c.PeerAddress = true
+		} else if ip := net.ParseIP(s); ip != nil {
+			c.NextHopList = append(c.NextHopList, ip)
+		} else {
+			log.WithFields(log.Fields{
+				"Topic": "Policy",
+				"Key":   s,
+			}).Warn("invalid next-hop")
+		}
+	}
+	return c
+}
+
+func (c *NextHopConditions) evaluate(path table.Path) bool {
+	nexthop := path.GetNexthop()
+	if c.PeerAddress && path.GetSource() != nil && path.GetSource().Address.Equal(nexthop) {
 		return true
 	}
+	for _, ip := range c.NextHopList {
+		if ip.Equal(nexthop) {
+			return true
+		}
+	}
+	return false
+}
 
-	for _, neighbor := range c.NeighborList {
-		cAddr := neighbor
-		pAddr := path.GetSource().Address
-		if pAddr.Equal(cAddr) {
+// NextHopSetConditions matches the paths whose next-hop is contained
+// in the prefixes of the prefix set. The mask length ranges of the
+// prefixes are ignored.
+type NextHopSetConditions struct {
+	DefaultConditions
+	PrefixList      []*net.IPNet
+	MatchSetOptions config.MatchSetOptionsType
+}
+
+func NewNextHopSetConditions(name string, options config.MatchSetOptionsType, ds config.DefinedSets) *NextHopSetConditions {
+	c := &NextHopSetConditions{
+		PrefixList:      make([]*net.IPNet, 0),
+		MatchSetOptions: restrictMatchSetOptions(options),
+	}
+	for _, ps := range ds.PrefixSetList {
+		if ps.PrefixSetName != name {
+			continue
+		}
+		for _, pl := range ps.PrefixList {
+			_, n, err := net.ParseCIDR(fmt.Sprintf("%s/%d", pl.Address, pl.Masklength))
+			if err != nil {
+				log.WithFields(log.Fields{
+					"Topic": "Policy",
+					"Key":   name,
+					"Error": err,
+				}).Warn("failed to parse the prefix of the next-hop set")
+				continue
+			}
+			c.PrefixList = append(c.PrefixList, n)
+		}
+		return c
+	}
+	log.WithFields(log.Fields{
+		"Topic": "Policy",
+		"Key":   name,
+	}).Warn("prefix set isn't defined")
+	return c
+}
+
+func (c *NextHopSetConditions) evaluate(path table.Path) bool {
+	nexthop := path.GetNexthop()
+	return evaluateSet(c.MatchSetOptions, len(c.PrefixList), func(i int) bool {
+		return nexthop != nil && c.PrefixList[i].Contains(nexthop)
+	})
+}
+
+type RouteFamilyConditions struct {
+	DefaultConditions
+	RouteFamilyList []bgp.RouteFamily
+}
+
+func NewRouteFamilyConditions(names []string) *RouteFamilyConditions {
+	c := &RouteFamilyConditions{
+		RouteFamilyList: make([]bgp.RouteFamily, 0, len(names)),
+	}
+	for _, name := range names {
+		rf, err := bgp.GetRouteFamily(name)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"Topic": "Policy",
+				"Key":   name,
+				"Error": err,
+			}).Warn("invalid route family")
+			continue
+		}
+		c.RouteFamilyList = append(c.RouteFamilyList, rf)
+	}
+	return c
+}
+
+func (c *RouteFamilyConditions) evaluate(path table.Path) bool {
+	for _, rf := range c.RouteFamilyList {
+		if rf == path.GetRouteFamily() {
 			return true
 		}
 	}
 	return false
 }
 
+type InstallProtocolConditions struct {
+	DefaultConditions
+	InstallProtocol config.InstallProtocolType
+}
+
+func (c *InstallProtocolConditions) evaluate(path table.Path) bool {
+	return path.GetInstallProtocol() == c.InstallProtocol
+}
+
+// IgpTagConditions matches the locally originated paths with the tag.
+type IgpTagConditions struct {
+	DefaultConditions
+	Tag string
+}
+
+func (c *IgpTagConditions) evaluate(path table.Path) bool {
+	return path.GetIgpTag() == c.Tag
+}
+
+// RpkiValidationConditions matches the paths with the result of the
+// RPKI origin validation. the paths not validated never match.
+type RpkiValidationConditions struct {
+	DefaultConditions
+	Result config.RpkiValidationResultType
+}
+
+func (c *RpkiValidationConditions) evaluate(path table.Path) bool {
+	return path.GetValidation() == c.Result
+}
+
+// PolicyOptions is the information on the session that the policy is
+// applied to. The actions like next-hop-self refer to it.
+type PolicyOptions struct {
+	LocalAs         uint32
+	LocalAddress    net.IP
+	NeighborAddress net.IP
+	// don't count the hits of the statements
+	DryRun bool
+}
+
 type Actions interface {
-	apply(table.Path) table.Path
+	apply(table.Path, *PolicyOptions) table.Path
 }
 
 type DefaultActions struct {
 }
 
-func (a *DefaultActions) apply(path table.Path) table.Path {
+func (a *DefaultActions) apply(path table.Path, options *PolicyOptions) table.Path {
 	return path
 }
 
@@ -203,7 +979,7 @@ type RoutingActions struct {
 	AcceptRoute bool
 }
 
-func (r *RoutingActions) apply(path table.Path) table.Path {
+func (r *RoutingActions) apply(path table.Path, options *PolicyOptions) table.Path {
 	if r.AcceptRoute {
 		return path
 	} else {
@@ -211,12 +987,288 @@ func (r *RoutingActions) apply(path table.Path) table.Path {
 	}
 }
 
+type WeightAction struct {
+	DefaultActions
+	Weight uint32
+}
+
+func (a *WeightAction) apply(path table.Path, options *PolicyOptions) table.Path {
+	newPath := path.Clone(path.IsWithdraw())
+	newPath.SetWeight(a.Weight)
+	return newPath
+}
+
+// CommunityAction adds, removes or replaces the communities of the
+// path. The communities to remove can be regular expressions.
+type CommunityAction struct {
+	DefaultActions
+	Communities []*communityMember
+	Options     config.BgpSetCommunityOptionType
+}
+
+func NewCommunityAction(c config.SetCommunity) *CommunityAction {
+	a := &CommunityAction{
+		Options: c.Options,
+	}
+	if c.Options == config.BGP_SET_COMMUNITY_OPTION_TYPE_REMOVE {
+		a.Communities = parseCommunityMembers(c.Communities)
+		return a
+	}
+	a.Communities = make([]*communityMember, 0, len(c.Communities))
+	for _, s := range c.Communities {
+		v, err := table.ParseCommunity(s)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"Topic": "Policy",
+				"Error": err,
+			}).Warn("failed to parse the community to set")
+			continue
+		}
+		a.Communities = append(a.Communities, &communityMember{value: v})
+	}
+	return a
+}
+
+func (a *CommunityAction) apply(path table.Path, options *PolicyOptions) table.Path {
+	newPath := path.Clone(path.IsWithdraw())
+	switch a.Options {
+	case config.BGP_SET_COMMUNITY_OPTION_TYPE_ADD, config.BGP_SET_COMMUNITY_OPTION_TYPE_REPLACE:
+		communities := make([]uint32, 0, len(a.Communities))
+		for _, m := range a.Communities {
+			communities = append(communities, m.value)
+		}
+		newPath.SetCommunities(communities, a.Options == config.BGP_SET_COMMUNITY_OPTION_TYPE_REPLACE)
+	case config.BGP_SET_COMMUNITY_OPTION_TYPE_REMOVE:
+		communities := make([]uint32, 0)
+		for _, community := range newPath.GetCommunities() {
+			removed := false
+			for _, m := range a.Communities {
+				if m.match(community) {
+					removed = true
+					break
+				}
+			}
+			if !removed {
+				communities = append(communities, community)
+			}
+		}
+		newPath.SetCommunities(communities, true)
+	}
+	return newPath
+}
+
+// ExtCommunityAction adds, removes or replaces the route targets and
+// route origins of the path. The extended communities to remove can be
+// regular expressions.
+type ExtCommunityAction struct {
+	DefaultActions
+	ExtCommunities []*extCommunityMember
+	Options        config.BgpSetCommunityOptionType
+}
+
+func NewExtCommunityAction(c config.SetExtCommunity) *ExtCommunityAction {
+	a := &ExtCommunityAction{
+		Options: c.Options,
+	}
+	if c.Options == config.BGP_SET_COMMUNITY_OPTION_TYPE_REMOVE {
+		a.ExtCommunities = parseExtCommunityMembers(c.Communities)
+		return a
+	}
+	a.ExtCommunities = make([]*extCommunityMember, 0, len(c.Communities))
+	for _, s := range c.Communities {
+		community, err := table.ParseExtCommunity(s)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"Topic": "Policy",
+				"Error": err,
+			}).Warn("failed to parse the extended community to set")
+			continue
+		}
+		a.ExtCommunities = append(a.ExtCommunities, &extCommunityMember{community: community})
+	}
+	return a
+}
+
+func (a *ExtCommunityAction) apply(path table.Path, options *PolicyOptions) table.Path {
+	newPath := path.Clone(path.IsWithdraw())
+	switch a.Options {
+	case config.BGP_SET_COMMUNITY_OPTION_TYPE_ADD, config.BGP_SET_COMMUNITY_OPTION_TYPE_REPLACE:
+		communities := make([]bgp.ExtendedCommunityInterface, 0, len(a.ExtCommunities))
+		for _, m := range a.ExtCommunities {
+			communities = append(communities, m.community)
+		}
+		newPath.SetExtCommunities(communities, a.Options == config.BGP_SET_COMMUNITY_OPTION_TYPE_REPLACE)
+	case config.BGP_SET_COMMUNITY_OPTION_TYPE_REMOVE:
+		communities := make([]bgp.ExtendedCommunityInterface, 0)
+		for _, community := range newPath.GetExtCommunities() {
+			removed := false
+			for _, m := range a.ExtCommunities {
+				if m.match(community) {
+					removed = true
+					break
+				}
+			}
+			if !removed {
+				communities = append(communities, community)
+			}
+		}
+		newPath.SetExtCommunities(communities, true)
+	}
+	return newPath
+}
+
+// ModificationActions modifies an attribute of the path. Value of
+// MULTI_EXIT_DISC is a value, or an increment or decrement like "+10".
+// Value of NEXT_HOP is an IP address, "self" or "peer-address". Value
+// of AS_PATH is "<as> <repeat>" to prepend the AS repeat times, where
+// the AS "self" stands for the local AS.
 type ModificationActions struct {
 	DefaultActions
 	AttrType bgp.BGPAttrType
 	Value    string
 }
 
+const (
+	NEXTHOP_SELF         = "self"
+	NEXTHOP_PEER_ADDRESS = "peer-address"
+	AS_PATH_LOCAL_AS     = "self"
+)
+
+// convert the bgp actions of the statement to the modification actions
+func newModificationActions(c config.BgpActions) []ModificationActions {
+	l := make([]ModificationActions, 0)
+	if c.SetMed != "" {
+		l = append(l, ModificationActions{AttrType: bgp.BGP_ATTR_TYPE_MULTI_EXIT_DISC, Value: c.SetMed})
+	}
+	if c.SetLocalPref != 0 {
+		l = append(l, ModificationActions{AttrType: bgp.BGP_ATTR_TYPE_LOCAL_PREF, Value: strconv.FormatUint(uint64(c.SetLocalPref), 10)})
+	}
+	if c.SetNextHop != "" {
+		l = append(l, ModificationActions{AttrType: bgp.BGP_ATTR_TYPE_NEXT_HOP, Value: c.SetNextHop})
+	}
+	if c.SetAsPathPrepend.RepeatN != 0 {
+		as := AS_PATH_LOCAL_AS
+		if c.SetAsPathPrepend.As != 0 {
+			as = strconv.FormatUint(uint64(c.SetAsPathPrepend.As), 10)
+		}
+		l = append(l, ModificationActions{AttrType: bgp.BGP_ATTR_TYPE_AS_PATH, Value: fmt.Sprintf("%s %d", as, c.SetAsPathPrepend.RepeatN)})
+	}
+	return l
+}
+
+func NewModificationActions(attrType bgp.BGPAttrType, value string) (*ModificationActions, error) {
+	a := &ModificationActions{
+		AttrType: attrType,
+		Value:    value,
+	}
+	var err error
+	switch attrType {
+	case bgp.BGP_ATTR_TYPE_MULTI_EXIT_DISC:
+		_, _, err = a.med()
+	case bgp.BGP_ATTR_TYPE_LOCAL_PREF:
+		_, err = strconv.ParseUint(value, 10, 32)
+	case bgp.BGP_ATTR_TYPE_NEXT_HOP:
+		if value != NEXTHOP_SELF && value != NEXTHOP_PEER_ADDRESS && net.ParseIP(value) == nil {
+			err = fmt.Errorf("invalid next-hop: %s", value)
+		}
+	case bgp.BGP_ATTR_TYPE_AS_PATH:
+		_, _, err = a.prepend()
+	default:
+		err = fmt.Errorf("unsupported attribute: %s", attrType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *ModificationActions) med() (int64, bool, error) {
+	if a.Value == "" {
+		return 0, false, fmt.Errorf("invalid MED: %s", a.Value)
+	}
+	doReplace := a.Value[0] != '+' && a.Value[0] != '-'
+	med, err := strconv.ParseInt(strings.TrimPrefix(a.Value, "+"), 10, 64)
+	if err != nil || med > math.MaxUint32 || med < -math.MaxUint32 || (doReplace && med < 0) {
+		return 0, false, fmt.Errorf("invalid MED: %s", a.Value)
+	}
+	return med, doReplace, nil
+}
+
+// return the AS to prepend and how many times. zero AS stands for the
+// local AS.
+func (a *ModificationActions) prepend() (uint32, uint8, error) {
+	elems := strings.Fields(a.Value)
+	if len(elems) != 2 {
+		return 0, 0, fmt.Errorf("invalid AS path prepend: %s", a.Value)
+	}
+	var as uint64
+	var err error
+	if elems[0] != AS_PATH_LOCAL_AS {
+		if as, err = strconv.ParseUint(elems[0], 10, 32); err != nil || as == 0 {
+			return 0, 0, fmt.Errorf("invalid AS path prepend: %s", a.Value)
+		}
+	}
+	repeat, err := strconv.ParseUint(elems[1], 10, 8)
+	if err != nil || repeat == 0 {
+		return 0, 0, fmt.Errorf("invalid AS path prepend: %s", a.Value)
+	}
+	return uint32(as), uint8(repeat), nil
+}
+
+func (a *ModificationActions) apply(path table.Path, options *PolicyOptions) table.Path {
+	if options == nil {
+		options = &PolicyOptions{}
+	}
+	newPath := path.Clone(path.IsWithdraw())
+	switch a.AttrType {
+	case bgp.BGP_ATTR_TYPE_MULTI_EXIT_DISC:
+		med, doReplace, _ := a.med()
+		if err := newPath.SetMed(med, doReplace); err != nil {
+			log.WithFields(log.Fields{
+				"Topic": "Policy",
+				"Key":   path.GetNlri().String(),
+				"Error": err,
+			}).Warn("failed to set MED")
+			return path
+		}
+	case bgp.BGP_ATTR_TYPE_LOCAL_PREF:
+		localPref, _ := strconv.ParseUint(a.Value, 10, 32)
+		newPath.SetLocalPref(uint32(localPref))
+	case bgp.BGP_ATTR_TYPE_NEXT_HOP:
+		var nexthop net.IP
+		switch a.Value {
+		case NEXTHOP_SELF:
+			nexthop = options.LocalAddress
+		case NEXTHOP_PEER_ADDRESS:
+			nexthop = options.NeighborAddress
+		default:
+			nexthop = net.ParseIP(a.Value)
+		}
+		if nexthop == nil {
+			log.WithFields(log.Fields{
+				"Topic": "Policy",
+				"Key":   path.GetNlri().String(),
+			}).Warnf("can't resolve the next-hop %s", a.Value)
+			return path
+		}
+		newPath.SetNexthop(nexthop)
+	case bgp.BGP_ATTR_TYPE_AS_PATH:
+		as, repeat, _ := a.prepend()
+		if as == 0 {
+			as = options.LocalAs
+		}
+		if as == 0 {
+			log.WithFields(log.Fields{
+				"Topic": "Policy",
+				"Key":   path.GetNlri().String(),
+			}).Warn("can't prepend the local AS")
+			return path
+		}
+		newPath.PrependAsn(as, repeat)
+	}
+	return newPath
+}
+
 type Prefix struct {
 	Address         net.IP
 	AddressFamily   bgp.RouteFamily
@@ -266,61 +1318,102 @@ func NewPrefix(addr net.IP, maskLen uint8, maskRange string) (Prefix, error) {
 
 //compare path and condition of policy
 //and, subsequent comparison skip if that matches the conditions.
-func (p *Policy) Apply(path table.Path) (bool, RouteType, table.Path) {
-	for _, statement := range p.Statements {
+func (p *Policy) Apply(path table.Path, options *PolicyOptions) (bool, RouteType, table.Path) {
+	statement, routeType, newPath := p.ApplyStatement(path, options)
+	return statement != nil, routeType, newPath
+}
+
+// same as Apply but return the statement matching the path instead, or
+// nil if no statement matches.
+func (p *Policy) ApplyStatement(path table.Path, options *PolicyOptions) (*Statement, RouteType, table.Path) {
+	for i := range p.Statements {
+		statement := &p.Statements[i]
 
-		result := statement.Conditions.evaluate(path)
+		result := true
+		for _, c := range statement.Conditions {
+			if !c.evaluate(path) {
+				result = false
+				break
+			}
+		}
 		log.WithFields(log.Fields{
 			"Topic":      "Policy",
 			"Path":       path,
 			"PolicyName": p.Name,
 		}).Debug("statement.Conditions.evaluate : ", result)
 
+		// the statement calling a policy matches when the called policy
+		// accepts the path, and the actions are applied to the path
+		// modified by the called policy.
+		target := path
+		if result && statement.CallPolicy != "" {
+			result = false
+			if statement.callPolicy == nil {
+				log.WithFields(log.Fields{
+					"Topic":      "Policy",
+					"PolicyName": p.Name,
+					"Key":        statement.CallPolicy,
+				}).Warn("called policy isn't resolved")
+			} else if matched, routeType, newPath := statement.callPolicy.Apply(path, options); matched && routeType == ROUTE_TYPE_ACCEPT {
+				result = true
+				target = newPath
+			}
+		}
+
 		var p table.Path
 		if result {
-			p = statement.Actions.apply(path)
+			statement.hit(options)
+			p = statement.Actions.apply(target, options)
 			if p != nil {
-				return true, ROUTE_TYPE_ACCEPT, p
+				for _, action := range statement.ModActions {
+					p = action.apply(p, options)
+				}
+				return statement, ROUTE_TYPE_ACCEPT, p
 			} else {
-				return true, ROUTE_TYPE_REJECT, nil
+				return statement, ROUTE_TYPE_REJECT, nil
 			}
 		}
 	}
-	return false, ROUTE_TYPE_NONE, nil
+	return nil, ROUTE_TYPE_NONE, nil
+}
+
+// the address and the mask length of the path of ipv4 or ipv6 unicast
+func pathPrefix(path table.Path) (net.IP, uint8, bool) {
+	switch path.GetRouteFamily() {
+	case bgp.RF_IPv4_UC:
+		prefix := path.GetNlri().(*bgp.NLRInfo).IPAddrPrefix
+		return prefix.Prefix, prefix.Length, true
+	case bgp.RF_IPv6_UC:
+		prefix := path.GetNlri().(*bgp.IPv6AddrPrefix)
+		return prefix.Prefix, prefix.Length, true
+	}
+	return nil, 0, false
+}
+
+// compare the mask length range of the prefix and the path covered by
+// the prefix. the path needs to be equal to the prefix when the range
+// isn't specified.
+func (p *Prefix) matchMasklength(addr net.IP, masklen uint8) bool {
+	rMin, okMin := p.MasklengthRange[MASK_LENGTH_RANGE_MIN]
+	rMax, okMax := p.MasklengthRange[MASK_LENGTH_RANGE_MAX]
+	if !okMin && !okMax {
+		return addr.Equal(p.Address) && masklen == p.Masklength
+	}
+	return rMin <= masklen && masklen <= rMax
 }
 
 func IpPrefixCalculate(path table.Path, cPrefix Prefix) bool {
 	rf := path.GetRouteFamily()
 	log.Debug("path routefamily : ", rf.String())
-	var pAddr net.IP
-	var pMasklen uint8
-
 	if rf != cPrefix.AddressFamily {
 		return false
 	}
-
-	switch rf {
-	case bgp.RF_IPv4_UC:
-		pAddr = path.GetNlri().(*bgp.NLRInfo).IPAddrPrefix.Prefix
-		pMasklen = path.GetNlri().(*bgp.NLRInfo).IPAddrPrefix.Length
-	case bgp.RF_IPv6_UC:
-		pAddr = path.GetNlri().(*bgp.IPv6AddrPrefix).Prefix
-		pMasklen = path.GetNlri().(*bgp.IPv6AddrPrefix).Length
-	default:
+	pAddr, pMasklen, ok := pathPrefix(path)
+	if !ok {
 		return false
 	}
 
 	cp := fmt.Sprintf("%s/%d", cPrefix.Address, cPrefix.Masklength)
-	rMin, okMin := cPrefix.MasklengthRange[MASK_LENGTH_RANGE_MIN]
-	rMax, okMax := cPrefix.MasklengthRange[MASK_LENGTH_RANGE_MAX]
-	if !okMin && !okMax {
-		if pAddr.Equal(cPrefix.Address) && pMasklen == cPrefix.Masklength {
-			return true
-		} else {
-			return false
-		}
-	}
-
 	_, ipNet, e := net.ParseCIDR(cp)
 	if e != nil {
 		log.WithFields(log.Fields{
@@ -330,8 +1423,22 @@ func IpPrefixCalculate(path table.Path, cPrefix Prefix) bool {
 		}).Error("failed to parse the prefix of condition")
 		return false
 	}
-	if ipNet.Contains(pAddr) && (rMin <= pMasklen && pMasklen <= rMax) {
-		return true
+	return ipNet.Contains(pAddr) && cPrefix.matchMasklength(pAddr, pMasklen)
+}
+
+// index the prefixes by the address bits for each address family
+func newPrefixTries(prefixList []Prefix) map[bgp.RouteFamily]*patricia.Trie {
+	tries := make(map[bgp.RouteFamily]*patricia.Trie)
+	for i := range prefixList {
+		prefix := &prefixList[i]
+		trie, ok := tries[prefix.AddressFamily]
+		if !ok {
+			trie = patricia.NewTrie()
+			tries[prefix.AddressFamily] = trie
+		}
+		key := table.PrefixKey(prefix.Address, prefix.Masklength)
+		l, _ := trie.Get(key).([]*Prefix)
+		trie.Set(key, append(l, prefix))
 	}
-	return false
+	return tries
 }
@@ -23,8 +23,36 @@ import (
 	"github.com/stretchr/testify/assert"
 	"net"
 	"testing"
+	"time"
 )
 
+// the path received from 10.0.0.1 in AS 65001. the IPv4 NLRI is sent
+// with NEXT_HOP and the others with MP_REACH_NLRI. the AS path in the
+// attributes replaces the default one.
+func testPath(nlri bgp.AddrPrefixInterface, attrs ...bgp.PathAttributeInterface) table.Path {
+	peer := &table.PeerInfo{AS: 65001, Address: net.ParseIP("10.0.0.1")}
+	aspath := bgp.NewPathAttributeAsPath([]bgp.AsPathParamInterface{bgp.NewAsPathParam(2, []uint16{65001})})
+	others := make([]bgp.PathAttributeInterface, 0, len(attrs))
+	for _, a := range attrs {
+		if p, ok := a.(*bgp.PathAttributeAsPath); ok {
+			aspath = p
+		} else {
+			others = append(others, a)
+		}
+	}
+	pathAttributes := []bgp.PathAttributeInterface{bgp.NewPathAttributeOrigin(0), aspath}
+	nlriList := []bgp.NLRInfo{}
+	if n, ok := nlri.(*bgp.NLRInfo); ok {
+		pathAttributes = append(pathAttributes, bgp.NewPathAttributeNextHop("10.0.0.1"))
+		nlriList = append(nlriList, *n)
+	} else {
+		pathAttributes = append(pathAttributes, bgp.NewPathAttributeMpReachNLRI("2001::1", []bgp.AddrPrefixInterface{nlri}))
+	}
+	pathAttributes = append(pathAttributes, others...)
+	updateMsg := bgp.NewBGPUpdateMessage([]bgp.WithdrawnRoute{}, pathAttributes, nlriList)
+	return table.NewProcessMessage(updateMsg, peer).ToPathList()[0]
+}
+
 func TestPrefixCalcurateNoRange(t *testing.T) {
 	log.SetLevel(log.DebugLevel)
 	// creatae path
@@ -280,7 +308,7 @@ func TestPolicyNotMatch(t *testing.T) {
 	pName := "pd1"
 	df := pl.DefinedSets
 	p := NewPolicy(pName, pl.PolicyDefinitionList[0], df)
-	match, pType, newPath := p.Apply(path)
+	match, pType, newPath := p.Apply(path, nil)
 	assert.Equal(t, match, false)
 	assert.Equal(t, pType, ROUTE_TYPE_NONE)
 	assert.Equal(t, newPath, nil)
@@ -339,7 +367,7 @@ func TestPolicyMatchAndReject(t *testing.T) {
 	pName := "pd1"
 	df := pl.DefinedSets
 	p := NewPolicy(pName, pl.PolicyDefinitionList[0], df)
-	match, pType, newPath := p.Apply(path)
+	match, pType, newPath := p.Apply(path, nil)
 	assert.Equal(t, match, true)
 	assert.Equal(t, pType, ROUTE_TYPE_REJECT)
 	assert.Equal(t, newPath, nil)
@@ -398,7 +426,7 @@ func TestPolicyMatchAndAccept(t *testing.T) {
 	pName := "pd1"
 	df := pl.DefinedSets
 	p := NewPolicy(pName, pl.PolicyDefinitionList[0], df)
-	match, pType, newPath := p.Apply(path)
+	match, pType, newPath := p.Apply(path, nil)
 	assert.Equal(t, match, true)
 	assert.Equal(t, pType, ROUTE_TYPE_ACCEPT)
 	assert.Equal(t, newPath, path)
@@ -464,12 +492,12 @@ func TestPolicyRejectOnlyPrefixSet(t *testing.T) {
 	pName := "pd1"
 	df := pl.DefinedSets
 	p := NewPolicy(pName, pl.PolicyDefinitionList[0], df)
-	match, pType, newPath := p.Apply(path1)
+	match, pType, newPath := p.Apply(path1, nil)
 	assert.Equal(t, match, true)
 	assert.Equal(t, pType, ROUTE_TYPE_REJECT)
 	assert.Equal(t, newPath, nil)
 
-	match2, pType2, newPath2 := p.Apply(path2)
+	match2, pType2, newPath2 := p.Apply(path2, nil)
 	assert.Equal(t, match2, false)
 	assert.Equal(t, pType2, ROUTE_TYPE_NONE)
 	assert.Equal(t, newPath2, nil)
@@ -533,12 +561,12 @@ func TestPolicyRejectOnlyNeighborSet(t *testing.T) {
 	pName := "pd1"
 	df := pl.DefinedSets
 	p := NewPolicy(pName, pl.PolicyDefinitionList[0], df)
-	match, pType, newPath := p.Apply(path1)
+	match, pType, newPath := p.Apply(path1, nil)
 	assert.Equal(t, match, true)
 	assert.Equal(t, pType, ROUTE_TYPE_REJECT)
 	assert.Equal(t, newPath, nil)
 
-	match2, pType2, newPath2 := p.Apply(path2)
+	match2, pType2, newPath2 := p.Apply(path2, nil)
 	assert.Equal(t, match2, false)
 	assert.Equal(t, pType2, ROUTE_TYPE_NONE)
 	assert.Equal(t, newPath2, nil)
@@ -639,13 +667,890 @@ func TestPolicyDifferentRoutefamilyOfPathAndPolicy(t *testing.T) {
 	pName := "pd1"
 	df := pl.DefinedSets
 	p := NewPolicy(pName, pl.PolicyDefinitionList[0], df)
-	match1, pType1, newPath1 := p.Apply(pathIPv4)
+	match1, pType1, newPath1 := p.Apply(pathIPv4, nil)
 	assert.Equal(t, match1, true)
 	assert.Equal(t, pType1, ROUTE_TYPE_REJECT)
 	assert.Equal(t, newPath1, nil)
 
-	match2, pType2, newPath2 := p.Apply(pathIPv6)
+	match2, pType2, newPath2 := p.Apply(pathIPv6, nil)
 	assert.Equal(t, match2, true)
 	assert.Equal(t, pType2, ROUTE_TYPE_REJECT)
 	assert.Equal(t, newPath2, nil)
 }
+
+func TestPolicySetWeight(t *testing.T) {
+	path := testPath(bgp.NewNLRInfo(24, "10.10.0.101"))
+	// create policy
+	s := config.Statement{
+		Name: "statement1",
+		Conditions: config.Conditions{
+			MatchSetOptions: config.MATCH_SET_OPTIONS_TYPE_ALL,
+		},
+		Actions: config.Actions{
+			AcceptRoute: true,
+			SetWeight:   200,
+		},
+	}
+	pd := config.PolicyDefinition{Name: "pd1", StatementList: []config.Statement{s}}
+	p := NewPolicy("pd1", pd, config.DefinedSets{})
+	match, pType, newPath := p.Apply(path, nil)
+	assert.Equal(t, match, true)
+	assert.Equal(t, pType, ROUTE_TYPE_ACCEPT)
+	assert.Equal(t, newPath.GetWeight(), uint32(200))
+	// the original path must not be modified
+	assert.Equal(t, path.GetWeight(), uint32(0))
+}
+
+func TestPolicyMatchCommunity(t *testing.T) {
+	path1 := testPath(bgp.NewNLRInfo(24, "10.10.0.101"), bgp.NewPathAttributeCommunities([]uint32{65001<<16 | 100, 65001<<16 | 200}))
+	path2 := testPath(bgp.NewNLRInfo(24, "10.10.0.101"), bgp.NewPathAttributeCommunities([]uint32{65002<<16 | 100}))
+	path3 := testPath(bgp.NewNLRInfo(24, "10.10.0.101"))
+	path4 := testPath(bgp.NewNLRInfo(24, "10.10.0.101"), bgp.NewPathAttributeCommunities([]uint32{0xffffff01}))
+	// create policy
+	ds := config.DefinedSets{
+		BgpDefinedSets: config.BgpDefinedSets{
+			CommunitySetList: []config.CommunitySet{
+				config.CommunitySet{
+					CommunitySetName:    "cs1",
+					CommunityMemberList: []string{"65001:200", "no-export"},
+				},
+				config.CommunitySet{
+					CommunitySetName:    "cs2",
+					CommunityMemberList: []string{"^6500[0-9]:1.*$"},
+				},
+			},
+		},
+	}
+	s := config.Statement{
+		Name: "statement1",
+		Conditions: config.Conditions{
+			BgpConditions: config.BgpConditions{
+				MatchCommunitySet: "cs1",
+			},
+			MatchSetOptions: config.MATCH_SET_OPTIONS_TYPE_ANY,
+		},
+		Actions: config.Actions{
+			RejectRoute: true,
+		},
+	}
+	pd := config.PolicyDefinition{Name: "pd1", StatementList: []config.Statement{s}}
+	p := NewPolicy("pd1", pd, ds)
+	match, pType, _ := p.Apply(path1, nil)
+	assert.Equal(t, match, true)
+	assert.Equal(t, pType, ROUTE_TYPE_REJECT)
+	match, _, _ = p.Apply(path2, nil)
+	assert.Equal(t, match, false)
+	match, _, _ = p.Apply(path3, nil)
+	assert.Equal(t, match, false)
+	match, _, _ = p.Apply(path4, nil)
+	assert.Equal(t, match, true)
+
+	// regular expression
+	s.Conditions.BgpConditions.MatchCommunitySet = "cs2"
+	pd = config.PolicyDefinition{Name: "pd2", StatementList: []config.Statement{s}}
+	p = NewPolicy("pd2", pd, ds)
+	match, _, _ = p.Apply(path1, nil)
+	assert.Equal(t, match, true)
+	match, _, _ = p.Apply(path2, nil)
+	assert.Equal(t, match, true)
+	match, _, _ = p.Apply(path4, nil)
+	assert.Equal(t, match, false)
+
+	// undefined set never matches
+	s.Conditions.BgpConditions.MatchCommunitySet = "cs3"
+	pd = config.PolicyDefinition{Name: "pd3", StatementList: []config.Statement{s}}
+	p = NewPolicy("pd3", pd, ds)
+	match, _, _ = p.Apply(path1, nil)
+	assert.Equal(t, match, false)
+}
+
+func TestPolicyMatchExtCommunity(t *testing.T) {
+	rt := &bgp.TwoOctetAsSpecificExtended{SubType: 0x02, AS: 65001, LocalAdmin: 100}
+	path1 := testPath(bgp.NewNLRInfo(24, "10.10.0.101"), bgp.NewPathAttributeExtendedCommunities([]bgp.ExtendedCommunityInterface{rt}))
+	path2 := testPath(bgp.NewNLRInfo(24, "10.10.0.101"), bgp.NewPathAttributeCommunities([]uint32{65001<<16 | 100}))
+	soo := &bgp.TwoOctetAsSpecificExtended{SubType: 0x03, AS: 65001, LocalAdmin: 100}
+	path3 := testPath(bgp.NewNLRInfo(24, "10.10.0.101"), bgp.NewPathAttributeExtendedCommunities([]bgp.ExtendedCommunityInterface{soo}))
+	// create policy
+	ds := config.DefinedSets{
+		BgpDefinedSets: config.BgpDefinedSets{
+			ExtCommunitySetList: []config.ExtCommunitySet{
+				config.ExtCommunitySet{
+					ExtCommunitySetName:    "es1",
+					ExtCommunityMemberList: []string{"65001:100"},
+				},
+				config.ExtCommunitySet{
+					ExtCommunitySetName:    "es2",
+					ExtCommunityMemberList: []string{"^65001:.*$"},
+				},
+				config.ExtCommunitySet{
+					ExtCommunitySetName:    "es3",
+					ExtCommunityMemberList: []string{"65001:1"},
+				},
+				config.ExtCommunitySet{
+					ExtCommunitySetName:    "es4",
+					ExtCommunityMemberList: []string{"route-target:65001:100"},
+				},
+				config.ExtCommunitySet{
+					ExtCommunitySetName:    "es5",
+					ExtCommunityMemberList: []string{"^route-target:65001:.*$"},
+				},
+				config.ExtCommunitySet{
+					ExtCommunitySetName:    "es6",
+					ExtCommunityMemberList: []string{"route-origin:65001:100"},
+				},
+			},
+		},
+	}
+	for _, c := range []struct {
+		set   string
+		match bool
+	}{{"es1", true}, {"es2", true}, {"es3", false}, {"es4", true}, {"es5", true}, {"es6", false}} {
+		s := config.Statement{
+			Name: "statement1",
+			Conditions: config.Conditions{
+				BgpConditions: config.BgpConditions{
+					MatchExtCommunitySet: c.set,
+				},
+				MatchSetOptions: config.MATCH_SET_OPTIONS_TYPE_ALL,
+			},
+			Actions: config.Actions{
+				RejectRoute: true,
+			},
+		}
+		pd := config.PolicyDefinition{Name: "pd1", StatementList: []config.Statement{s}}
+		p := NewPolicy("pd1", pd, ds)
+		match, _, _ := p.Apply(path1, nil)
+		assert.Equal(t, match, c.match, c.set)
+		match, _, _ = p.Apply(path2, nil)
+		assert.Equal(t, match, false, c.set)
+	}
+
+	// the route origin is distinguished from the route target
+	s := config.Statement{
+		Name: "statement1",
+		Conditions: config.Conditions{
+			BgpConditions: config.BgpConditions{
+				MatchExtCommunitySet: "es6",
+			},
+			MatchSetOptions: config.MATCH_SET_OPTIONS_TYPE_ANY,
+		},
+		Actions: config.Actions{
+			RejectRoute: true,
+		},
+	}
+	pd := config.PolicyDefinition{Name: "pd1", StatementList: []config.Statement{s}}
+	match, _, _ := NewPolicy("pd1", pd, ds).Apply(path3, nil)
+	assert.Equal(t, match, true)
+}
+
+func TestPolicySetExtCommunity(t *testing.T) {
+	rt1 := &bgp.TwoOctetAsSpecificExtended{SubType: 0x02, AS: 65001, LocalAdmin: 100}
+	rt2 := &bgp.TwoOctetAsSpecificExtended{SubType: 0x02, AS: 65001, LocalAdmin: 200}
+	soo := &bgp.IPv4AddressSpecificExtended{SubType: 0x03, IPv4: net.ParseIP("10.0.0.1").To4(), LocalAdmin: 1}
+	path := testPath(bgp.NewNLRInfo(24, "10.10.0.101"), bgp.NewPathAttributeExtendedCommunities([]bgp.ExtendedCommunityInterface{rt1, soo}))
+	apply := func(c config.SetExtCommunity) []string {
+		s := config.Statement{
+			Name: "statement1",
+			Conditions: config.Conditions{
+				MatchSetOptions: config.MATCH_SET_OPTIONS_TYPE_ALL,
+			},
+			Actions: config.Actions{
+				AcceptRoute: true,
+				BgpActions: config.BgpActions{
+					SetExtCommunity: c,
+				},
+			},
+		}
+		pd := config.PolicyDefinition{Name: "pd1", StatementList: []config.Statement{s}}
+		p := NewPolicy("pd1", pd, config.DefinedSets{})
+		match, pType, newPath := p.Apply(path, nil)
+		assert.Equal(t, match, true)
+		assert.Equal(t, pType, ROUTE_TYPE_ACCEPT)
+		l := make([]string, 0)
+		for _, c := range newPath.GetExtCommunities() {
+			l = append(l, table.ExtCommunityString(c))
+		}
+		return l
+	}
+
+	l := apply(config.SetExtCommunity{
+		Communities: []string{"route-target:65001:200", "route-target:65001:100"},
+		Options:     config.BGP_SET_COMMUNITY_OPTION_TYPE_ADD,
+	})
+	assert.Equal(t, l, []string{"route-target:65001:100", "route-origin:10.0.0.1:1", "route-target:65001:200"})
+
+	l = apply(config.SetExtCommunity{
+		Communities: []string{"^route-target:.*$"},
+		Options:     config.BGP_SET_COMMUNITY_OPTION_TYPE_REMOVE,
+	})
+	assert.Equal(t, l, []string{"route-origin:10.0.0.1:1"})
+
+	l = apply(config.SetExtCommunity{
+		Communities: []string{"route-target:65001:200"},
+		Options:     config.BGP_SET_COMMUNITY_OPTION_TYPE_REPLACE,
+	})
+	assert.Equal(t, l, []string{"route-target:65001:200"})
+
+	// the original path must not be modified
+	assert.Equal(t, len(path.GetExtCommunities()), 2)
+	assert.True(t, table.EqualExtCommunity(path.GetExtCommunities()[0], rt1))
+	assert.False(t, table.EqualExtCommunity(path.GetExtCommunities()[0], rt2))
+}
+
+func TestPolicySetCommunity(t *testing.T) {
+	path := testPath(bgp.NewNLRInfo(24, "10.10.0.101"), bgp.NewPathAttributeCommunities([]uint32{65001<<16 | 100, 65001<<16 | 200, 65002<<16 | 100}))
+	apply := func(c config.SetCommunity) table.Path {
+		s := config.Statement{
+			Name: "statement1",
+			Conditions: config.Conditions{
+				MatchSetOptions: config.MATCH_SET_OPTIONS_TYPE_ALL,
+			},
+			Actions: config.Actions{
+				AcceptRoute: true,
+				BgpActions: config.BgpActions{
+					SetCommunity: c,
+				},
+			},
+		}
+		pd := config.PolicyDefinition{Name: "pd1", StatementList: []config.Statement{s}}
+		p := NewPolicy("pd1", pd, config.DefinedSets{})
+		match, pType, newPath := p.Apply(path, nil)
+		assert.Equal(t, match, true)
+		assert.Equal(t, pType, ROUTE_TYPE_ACCEPT)
+		return newPath
+	}
+
+	newPath := apply(config.SetCommunity{
+		Communities: []string{"65003:300", "65001:100"},
+		Options:     config.BGP_SET_COMMUNITY_OPTION_TYPE_ADD,
+	})
+	assert.Equal(t, newPath.GetCommunities(), []uint32{65001<<16 | 100, 65001<<16 | 200, 65002<<16 | 100, 65003<<16 | 300})
+
+	newPath = apply(config.SetCommunity{
+		Communities: []string{"^65001:.*$"},
+		Options:     config.BGP_SET_COMMUNITY_OPTION_TYPE_REMOVE,
+	})
+	assert.Equal(t, newPath.GetCommunities(), []uint32{65002<<16 | 100})
+
+	newPath = apply(config.SetCommunity{
+		Communities: []string{"no-advertise"},
+		Options:     config.BGP_SET_COMMUNITY_OPTION_TYPE_REPLACE,
+	})
+	assert.Equal(t, newPath.GetCommunities(), []uint32{0xffffff02})
+
+	// replacing with nothing removes the attribute
+	newPath = apply(config.SetCommunity{
+		Options: config.BGP_SET_COMMUNITY_OPTION_TYPE_REPLACE,
+	})
+	assert.Equal(t, len(newPath.GetCommunities()), 0)
+
+	// the original path must not be modified
+	assert.Equal(t, path.GetCommunities(), []uint32{65001<<16 | 100, 65001<<16 | 200, 65002<<16 | 100})
+}
+
+func TestPolicyMatchAsPath(t *testing.T) {
+	path1 := testPath(bgp.NewNLRInfo(24, "10.10.0.101"), bgp.NewPathAttributeAsPath([]bgp.AsPathParamInterface{
+		bgp.NewAs4PathParam(bgp.BGP_ASPATH_ATTR_TYPE_SEQ, []uint32{65001, 65100, 65002}),
+	}))
+	path2 := testPath(bgp.NewNLRInfo(24, "10.10.0.101"), bgp.NewPathAttributeAsPath([]bgp.AsPathParamInterface{
+		bgp.NewAs4PathParam(bgp.BGP_ASPATH_ATTR_TYPE_SEQ, []uint32{65001, 165002}),
+	}))
+	path3 := testPath(bgp.NewNLRInfo(24, "10.10.0.101"), bgp.NewPathAttributeAsPath([]bgp.AsPathParamInterface{
+		bgp.NewAs4PathParam(bgp.BGP_ASPATH_ATTR_TYPE_SEQ, []uint32{65003}),
+		bgp.NewAs4PathParam(bgp.BGP_ASPATH_ATTR_TYPE_SET, []uint32{65100, 65200}),
+	}))
+	path4 := testPath(bgp.NewNLRInfo(24, "10.10.0.101"), bgp.NewPathAttributeAsPath([]bgp.AsPathParamInterface{}))
+	ds := config.DefinedSets{
+		BgpDefinedSets: config.BgpDefinedSets{
+			AsPathSetList: []config.AsPathSet{
+				config.AsPathSet{
+					AsPathSetName:       "originAs",
+					AsPathSetMemberList: []string{"_65002$"},
+				},
+				config.AsPathSet{
+					AsPathSetName:       "neighborAs",
+					AsPathSetMemberList: []string{"^65001_"},
+				},
+				config.AsPathSet{
+					AsPathSetName:       "transitAs",
+					AsPathSetMemberList: []string{"_65100_"},
+				},
+				config.AsPathSet{
+					AsPathSetName:       "empty",
+					AsPathSetMemberList: []string{"^$"},
+				},
+			},
+		},
+	}
+	for _, c := range []struct {
+		set   string
+		paths []table.Path
+		match []bool
+	}{
+		{"originAs", []table.Path{path1, path2, path3, path4}, []bool{true, false, false, false}},
+		{"neighborAs", []table.Path{path1, path2, path3, path4}, []bool{true, true, false, false}},
+		{"transitAs", []table.Path{path1, path2, path3, path4}, []bool{true, false, true, false}},
+		{"empty", []table.Path{path1, path2, path3, path4}, []bool{false, false, false, true}},
+	} {
+		s := config.Statement{
+			Name: "statement1",
+			Conditions: config.Conditions{
+				BgpConditions: config.BgpConditions{
+					MatchAsPathSet: c.set,
+				},
+				MatchSetOptions: config.MATCH_SET_OPTIONS_TYPE_ALL,
+			},
+			Actions: config.Actions{
+				RejectRoute: true,
+			},
+		}
+		pd := config.PolicyDefinition{Name: "pd1", StatementList: []config.Statement{s}}
+		p := NewPolicy("pd1", pd, ds)
+		for i, path := range c.paths {
+			match, _, _ := p.Apply(path, nil)
+			assert.Equal(t, match, c.match[i], c.set)
+		}
+	}
+}
+
+func TestPolicyMatchAsPathLength(t *testing.T) {
+	path := testPath(bgp.NewNLRInfo(24, "10.10.0.101"), bgp.NewPathAttributeAsPath([]bgp.AsPathParamInterface{
+		bgp.NewAs4PathParam(bgp.BGP_ASPATH_ATTR_TYPE_SEQ, []uint32{65001, 65002}),
+		bgp.NewAs4PathParam(bgp.BGP_ASPATH_ATTR_TYPE_SET, []uint32{65003, 65004}),
+	}))
+	for _, c := range []struct {
+		length config.AsPathLength
+		match  bool
+	}{
+		{config.AsPathLength{Operator: config.ATTRIBUTE_COMPARISON_EQ, Value: 3}, true},
+		{config.AsPathLength{Operator: config.ATTRIBUTE_COMPARISON_EQ, Value: 4}, false},
+		{config.AsPathLength{Operator: config.ATTRIBUTE_COMPARISON_GE, Value: 3}, true},
+		{config.AsPathLength{Operator: config.ATTRIBUTE_COMPARISON_GE, Value: 4}, false},
+		{config.AsPathLength{Operator: config.ATTRIBUTE_COMPARISON_LE, Value: 2}, false},
+		{config.AsPathLength{Operator: config.ATTRIBUTE_COMPARISON_LE, Value: 3}, true},
+	} {
+		s := config.Statement{
+			Name: "statement1",
+			Conditions: config.Conditions{
+				BgpConditions: config.BgpConditions{
+					AsPathLength: c.length,
+				},
+				MatchSetOptions: config.MATCH_SET_OPTIONS_TYPE_ALL,
+			},
+			Actions: config.Actions{
+				RejectRoute: true,
+			},
+		}
+		pd := config.PolicyDefinition{Name: "pd1", StatementList: []config.Statement{s}}
+		p := NewPolicy("pd1", pd, config.DefinedSets{})
+		match, _, _ := p.Apply(path, nil)
+		assert.Equal(t, match, c.match)
+	}
+}
+
+func TestPolicyMatchAttributeConditions(t *testing.T) {
+	peer := &table.PeerInfo{AS: 65001, Address: net.ParseIP("10.0.0.1")}
+	pathAttributes := []bgp.PathAttributeInterface{
+		bgp.NewPathAttributeOrigin(bgp.BGP_ORIGIN_ATTR_TYPE_EGP),
+		bgp.NewPathAttributeAsPath([]bgp.AsPathParamInterface{}),
+		bgp.NewPathAttributeNextHop("10.0.0.1"),
+		bgp.NewPathAttributeMultiExitDisc(100),
+		bgp.NewPathAttributeLocalPref(200),
+	}
+	nlri := []bgp.NLRInfo{*bgp.NewNLRInfo(24, "10.10.0.101")}
+	updateMsg := bgp.NewBGPUpdateMessage([]bgp.WithdrawnRoute{}, pathAttributes, nlri)
+	path := table.NewProcessMessage(updateMsg, peer).ToPathList()[0]
+	path.SetValidation(config.RPKI_VALIDATION_RESULT_TYPE_VALID)
+	network, _ := table.CreateNetworkPath(config.Network{
+		Address:    net.ParseIP("10.20.0.0"),
+		Masklength: 16,
+		NextHop:    net.ParseIP("10.0.0.2"),
+		Tag:        "100",
+	}, false, time.Now())
+
+	ds := config.DefinedSets{
+		PrefixSetList: []config.PrefixSet{
+			config.PrefixSet{
+				PrefixSetName: "ps1",
+				PrefixList: []config.Prefix{
+					config.Prefix{Address: net.ParseIP("10.0.0.0"), Masklength: 30},
+				},
+			},
+		},
+	}
+	for i, c := range []struct {
+		conditions config.Conditions
+		match      bool
+		matchLocal bool
+	}{
+		{config.Conditions{BgpConditions: config.BgpConditions{OriginEq: config.MATCH_ORIGIN_TYPE_EGP}}, true, false},
+		{config.Conditions{BgpConditions: config.BgpConditions{OriginEq: config.MATCH_ORIGIN_TYPE_IGP}}, false, true},
+		{config.Conditions{BgpConditions: config.BgpConditions{MedRange: "50..100"}}, true, false},
+		{config.Conditions{BgpConditions: config.BgpConditions{MedRange: "..99"}}, false, true},
+		{config.Conditions{BgpConditions: config.BgpConditions{MedRange: "100"}}, false, false},
+		{config.Conditions{BgpConditions: config.BgpConditions{LocalPrefEq: 200}}, true, false},
+		{config.Conditions{BgpConditions: config.BgpConditions{LocalPrefEq: 100}}, false, false},
+		{config.Conditions{BgpConditions: config.BgpConditions{NextHopIn: []string{"peer-address"}}}, true, false},
+		{config.Conditions{BgpConditions: config.BgpConditions{NextHopIn: []string{"10.0.0.2"}}}, false, true},
+		{config.Conditions{BgpConditions: config.BgpConditions{MatchNextHopSet: "ps1"}}, true, true},
+		{config.Conditions{BgpConditions: config.BgpConditions{MatchNextHopSet: "ps1"}, MatchSetOptions: config.MATCH_SET_OPTIONS_TYPE_INVERT}, false, false},
+		{config.Conditions{BgpConditions: config.BgpConditions{AfiSafiIn: []string{"ipv4-unicast"}}}, true, true},
+		{config.Conditions{BgpConditions: config.BgpConditions{AfiSafiIn: []string{"ipv6-unicast"}}}, false, false},
+		{config.Conditions{InstallProtocolEq: config.INSTALL_PROTOCOL_TYPE_BGP}, true, false},
+		{config.Conditions{InstallProtocolEq: config.INSTALL_PROTOCOL_TYPE_STATIC}, false, true},
+		{config.Conditions{IgpConditions: config.IgpConditions{TagEq: "100"}}, false, true},
+		{config.Conditions{BgpConditions: config.BgpConditions{RpkiValidationResult: config.RPKI_VALIDATION_RESULT_TYPE_VALID}}, true, false},
+		{config.Conditions{BgpConditions: config.BgpConditions{RpkiValidationResult: config.RPKI_VALIDATION_RESULT_TYPE_INVALID}}, false, false},
+	} {
+		s := config.Statement{
+			Name:       "statement1",
+			Conditions: c.conditions,
+			Actions: config.Actions{
+				RejectRoute: true,
+			},
+		}
+		pd := config.PolicyDefinition{Name: "pd1", StatementList: []config.Statement{s}}
+		p := NewPolicy("pd1", pd, ds)
+		match, _, _ := p.Apply(path, nil)
+		assert.Equal(t, match, c.match, "%d", i)
+		match, _, _ = p.Apply(network, nil)
+		assert.Equal(t, match, c.matchLocal, "%d", i)
+	}
+}
+
+func TestPolicyModificationActions(t *testing.T) {
+	path := testPath(bgp.NewNLRInfo(24, "10.10.0.101"),
+		bgp.NewPathAttributeAsPath([]bgp.AsPathParamInterface{bgp.NewAs4PathParam(bgp.BGP_ASPATH_ATTR_TYPE_SEQ, []uint32{65001})}),
+		bgp.NewPathAttributeMultiExitDisc(100))
+	options := &PolicyOptions{
+		LocalAs:         65000,
+		LocalAddress:    net.ParseIP("10.0.0.100"),
+		NeighborAddress: net.ParseIP("10.0.0.2"),
+	}
+	apply := func(a config.BgpActions) table.Path {
+		s := config.Statement{
+			Name: "statement1",
+			Conditions: config.Conditions{
+				MatchSetOptions: config.MATCH_SET_OPTIONS_TYPE_ALL,
+			},
+			Actions: config.Actions{
+				AcceptRoute: true,
+				BgpActions:  a,
+			},
+		}
+		pd := config.PolicyDefinition{Name: "pd1", StatementList: []config.Statement{s}}
+		p := NewPolicy("pd1", pd, config.DefinedSets{})
+		match, pType, newPath := p.Apply(path, options)
+		assert.Equal(t, match, true)
+		assert.Equal(t, pType, ROUTE_TYPE_ACCEPT)
+		return newPath
+	}
+	getMed := func(p table.Path) uint32 {
+		med, err := p.GetMed()
+		assert.Nil(t, err)
+		return med
+	}
+
+	// MED
+	assert.Equal(t, getMed(apply(config.BgpActions{SetMed: "200"})), uint32(200))
+	assert.Equal(t, getMed(apply(config.BgpActions{SetMed: "+10"})), uint32(110))
+	assert.Equal(t, getMed(apply(config.BgpActions{SetMed: "-10"})), uint32(90))
+	// underflow leaves the path as is
+	assert.Equal(t, getMed(apply(config.BgpActions{SetMed: "-200"})), uint32(100))
+	// invalid value is ignored
+	assert.Equal(t, getMed(apply(config.BgpActions{SetMed: "abc"})), uint32(100))
+
+	// next-hop
+	assert.Equal(t, apply(config.BgpActions{SetNextHop: "192.168.0.1"}).GetNexthop().String(), "192.168.0.1")
+	assert.Equal(t, apply(config.BgpActions{SetNextHop: "self"}).GetNexthop().String(), "10.0.0.100")
+	assert.Equal(t, apply(config.BgpActions{SetNextHop: "peer-address"}).GetNexthop().String(), "10.0.0.2")
+
+	// AS path prepend
+	newPath := apply(config.BgpActions{SetAsPathPrepend: config.SetAsPathPrepend{RepeatN: 2}})
+	assert.Equal(t, newPath.GetAsString(), "65000 65000 65001")
+	newPath = apply(config.BgpActions{SetAsPathPrepend: config.SetAsPathPrepend{RepeatN: 1, As: 65100}})
+	assert.Equal(t, newPath.GetAsString(), "65100 65001")
+
+	// all together
+	newPath = apply(config.BgpActions{SetMed: "+1", SetLocalPref: 200, SetNextHop: "self"})
+	assert.Equal(t, getMed(newPath), uint32(101))
+	assert.Equal(t, newPath.GetNexthop().String(), "10.0.0.100")
+	localPref, err := newPath.GetLocalPref()
+	assert.Nil(t, err)
+	assert.Equal(t, localPref, uint32(200))
+
+	// the original path must not be modified
+	assert.Equal(t, getMed(path), uint32(100))
+	assert.Equal(t, path.GetNexthop().String(), "10.0.0.1")
+	assert.Equal(t, path.GetAsString(), "65001")
+}
+
+func TestPolicyMatchSetOptions(t *testing.T) {
+	path1 := testPath(bgp.NewNLRInfo(24, "10.10.0.101"), bgp.NewPathAttributeCommunities([]uint32{65001<<16 | 100, 65001<<16 | 200}))
+	path2 := testPath(bgp.NewNLRInfo(24, "10.10.0.101"), bgp.NewPathAttributeCommunities([]uint32{65001<<16 | 100}))
+	path3 := testPath(bgp.NewNLRInfo(24, "10.10.0.101"), bgp.NewPathAttributeCommunities([]uint32{65002<<16 | 100}))
+	ds := config.DefinedSets{
+		PrefixSetList: []config.PrefixSet{
+			config.PrefixSet{
+				PrefixSetName: "ps1",
+				PrefixList: []config.Prefix{
+					config.Prefix{
+						Address:         net.ParseIP("10.10.0.0"),
+						Masklength:      16,
+						MasklengthRange: "21..24",
+					}},
+			},
+		},
+		BgpDefinedSets: config.BgpDefinedSets{
+			CommunitySetList: []config.CommunitySet{
+				config.CommunitySet{
+					CommunitySetName:    "cs1",
+					CommunityMemberList: []string{"65001:100", "65001:200"},
+				},
+			},
+		},
+	}
+	for _, c := range []struct {
+		options   config.MatchSetOptionsType
+		prefixSet string
+		match     []bool
+	}{
+		{config.MATCH_SET_OPTIONS_TYPE_ANY, "", []bool{true, true, false}},
+		{config.MATCH_SET_OPTIONS_TYPE_ALL, "", []bool{true, false, false}},
+		{config.MATCH_SET_OPTIONS_TYPE_INVERT, "", []bool{false, false, true}},
+		// each set is inverted, so the paths in the prefix set never match
+		{config.MATCH_SET_OPTIONS_TYPE_INVERT, "ps1", []bool{false, false, false}},
+		// ALL is handled as ANY for the prefix set
+		{config.MATCH_SET_OPTIONS_TYPE_ALL, "ps1", []bool{true, false, false}},
+	} {
+		s := config.Statement{
+			Name: "statement1",
+			Conditions: config.Conditions{
+				MatchPrefixSet: c.prefixSet,
+				BgpConditions: config.BgpConditions{
+					MatchCommunitySet: "cs1",
+				},
+				MatchSetOptions: c.options,
+			},
+			Actions: config.Actions{
+				RejectRoute: true,
+			},
+		}
+		pd := config.PolicyDefinition{Name: "pd1", StatementList: []config.Statement{s}}
+		p := NewPolicy("pd1", pd, ds)
+		for i, path := range []table.Path{path1, path2, path3} {
+			match, _, _ := p.Apply(path, nil)
+			assert.Equal(t, match, c.match[i], "options %d, prefix set %q, path%d", c.options, c.prefixSet, i+1)
+		}
+	}
+}
+
+func TestPolicyCallPolicy(t *testing.T) {
+	path1 := testPath(bgp.NewNLRInfo(24, "10.10.0.101"), bgp.NewPathAttributeCommunities([]uint32{65001<<16 | 100}))
+	path2 := testPath(bgp.NewNLRInfo(24, "10.10.0.101"), bgp.NewPathAttributeCommunities([]uint32{65002<<16 | 100}))
+	ds := config.DefinedSets{
+		BgpDefinedSets: config.BgpDefinedSets{
+			CommunitySetList: []config.CommunitySet{
+				config.CommunitySet{
+					CommunitySetName:    "cs1",
+					CommunityMemberList: []string{"65001:100"},
+				},
+			},
+		},
+	}
+	// the called policy accepts the paths with 65001:100 and sets the weight
+	called := config.PolicyDefinition{
+		Name: "called",
+		StatementList: []config.Statement{
+			config.Statement{
+				Name: "statement1",
+				Conditions: config.Conditions{
+					BgpConditions: config.BgpConditions{
+						MatchCommunitySet: "cs1",
+					},
+				},
+				Actions: config.Actions{
+					AcceptRoute: true,
+					SetWeight:   100,
+				},
+			},
+		},
+	}
+	caller := config.PolicyDefinition{
+		Name: "caller",
+		StatementList: []config.Statement{
+			config.Statement{
+				Name: "statement1",
+				Conditions: config.Conditions{
+					CallPolicy: "called",
+				},
+				Actions: config.Actions{
+					AcceptRoute: true,
+					BgpActions: config.BgpActions{
+						SetLocalPref: 200,
+					},
+				},
+			},
+		},
+	}
+	// policies calling each other
+	loop1 := config.PolicyDefinition{
+		Name: "loop1",
+		StatementList: []config.Statement{
+			config.Statement{
+				Name:       "statement1",
+				Conditions: config.Conditions{CallPolicy: "loop2"},
+				Actions:    config.Actions{AcceptRoute: true},
+			},
+		},
+	}
+	loop2 := config.PolicyDefinition{
+		Name: "loop2",
+		StatementList: []config.Statement{
+			config.Statement{
+				Name:       "statement1",
+				Conditions: config.Conditions{CallPolicy: "loop1"},
+				Actions:    config.Actions{AcceptRoute: true},
+			},
+		},
+	}
+	pMap := NewPolicyMap(config.RoutingPolicy{
+		DefinedSets:          ds,
+		PolicyDefinitionList: []config.PolicyDefinition{called, caller, loop1, loop2},
+	})
+
+	match, pType, newPath := pMap["caller"].Apply(path1, nil)
+	assert.Equal(t, match, true)
+	assert.Equal(t, pType, ROUTE_TYPE_ACCEPT)
+	// the modifications of both policies are applied
+	assert.Equal(t, newPath.GetWeight(), uint32(100))
+	localPref, err := newPath.GetLocalPref()
+	assert.Nil(t, err)
+	assert.Equal(t, localPref, uint32(200))
+
+	match, _, _ = pMap["caller"].Apply(path2, nil)
+	assert.Equal(t, match, false)
+
+	match, _, _ = pMap["loop1"].Apply(path1, nil)
+	assert.Equal(t, match, false)
+}
+
+func TestPolicyStatementHits(t *testing.T) {
+	path1 := testPath(bgp.NewNLRInfo(24, "10.10.0.101"), bgp.NewPathAttributeCommunities([]uint32{65001<<16 | 100}))
+	path2 := testPath(bgp.NewNLRInfo(24, "10.10.0.101"), bgp.NewPathAttributeCommunities([]uint32{65002<<16 | 100}))
+	ds := config.DefinedSets{
+		BgpDefinedSets: config.BgpDefinedSets{
+			CommunitySetList: []config.CommunitySet{
+				config.CommunitySet{
+					CommunitySetName:    "cs1",
+					CommunityMemberList: []string{"65001:100"},
+				},
+			},
+		},
+	}
+	pd := config.PolicyDefinition{
+		Name: "policy1",
+		StatementList: []config.Statement{
+			config.Statement{
+				Name: "statement1",
+				Conditions: config.Conditions{
+					BgpConditions: config.BgpConditions{
+						MatchCommunitySet: "cs1",
+					},
+				},
+				Actions: config.Actions{RejectRoute: true},
+			},
+			config.Statement{
+				Name:    "statement2",
+				Actions: config.Actions{AcceptRoute: true},
+			},
+		},
+	}
+	p := NewPolicy(pd.Name, pd, ds)
+
+	p.Apply(path1, nil)
+	p.Apply(path1, &PolicyOptions{})
+	p.Apply(path2, nil)
+	assert.Equal(t, p.Statements[0].Hits(), uint64(2))
+	assert.Equal(t, p.Statements[1].Hits(), uint64(1))
+
+	// the dry runs aren't counted
+	statement, pType, _ := p.ApplyStatement(path1, &PolicyOptions{DryRun: true})
+	assert.Equal(t, statement.Name, "statement1")
+	assert.Equal(t, pType, ROUTE_TYPE_REJECT)
+	assert.Equal(t, p.Statements[0].Hits(), uint64(2))
+}
+
+func TestPrefixConditionsLookup(t *testing.T) {
+	ds := config.DefinedSets{
+		PrefixSetList: []config.PrefixSet{
+			config.PrefixSet{
+				PrefixSetName: "ps1",
+				PrefixList: []config.Prefix{
+					config.Prefix{Address: net.ParseIP("0.0.0.0"), Masklength: 0, MasklengthRange: "0..0"},
+					config.Prefix{Address: net.ParseIP("10.10.0.0"), Masklength: 16, MasklengthRange: "21..24"},
+					config.Prefix{Address: net.ParseIP("10.10.0.0"), Masklength: 16, MasklengthRange: "16..16"},
+					config.Prefix{Address: net.ParseIP("2001:123:123::"), Masklength: 48, MasklengthRange: "48..64"},
+				},
+			},
+		},
+	}
+	for _, c := range []struct {
+		nlri  bgp.AddrPrefixInterface
+		match bool
+	}{
+		{bgp.NewNLRInfo(0, "0.0.0.0"), true},
+		{bgp.NewNLRInfo(16, "10.10.0.0"), true},
+		{bgp.NewNLRInfo(20, "10.10.0.0"), false},
+		{bgp.NewNLRInfo(24, "10.10.1.0"), true},
+		{bgp.NewNLRInfo(25, "10.10.1.0"), false},
+		{bgp.NewNLRInfo(24, "10.11.1.0"), false},
+		{bgp.NewIPv6AddrPrefix(64, "2001:123:123:1::"), true},
+		{bgp.NewIPv6AddrPrefix(80, "2001:123:123:1::"), false},
+		{bgp.NewIPv6AddrPrefix(48, "2001:123:124::"), false},
+	} {
+		path := testPath(c.nlri)
+		for _, options := range []config.MatchSetOptionsType{config.MATCH_SET_OPTIONS_TYPE_ANY, config.MATCH_SET_OPTIONS_TYPE_INVERT} {
+			pc := NewPrefixConditions("ps1", options, ds)
+			// the same result as comparing the path with each prefix
+			linear := &PrefixConditions{PrefixList: pc.PrefixList, MatchSetOptions: pc.MatchSetOptions}
+			match := c.match != (options == config.MATCH_SET_OPTIONS_TYPE_INVERT)
+			assert.Equal(t, pc.evaluate(path), match, "%s options %d", c.nlri, options)
+			assert.Equal(t, linear.evaluate(path), match, "%s options %d", c.nlri, options)
+		}
+	}
+}
+
+// the prefix set of n /24 prefixes from 10.0.0.0/24 accepting the
+// more specifics up to /28
+func benchmarkPrefixSet(n int) config.DefinedSets {
+	prefixList := make([]config.Prefix, 0, n)
+	for i := 0; i < n; i++ {
+		a := uint32(10<<24) + uint32(i)<<8
+		prefixList = append(prefixList, config.Prefix{
+			Address:         net.IPv4(byte(a>>24), byte(a>>16), byte(a>>8), 0),
+			Masklength:      24,
+			MasklengthRange: "24..28",
+		})
+	}
+	return config.DefinedSets{
+		PrefixSetList: []config.PrefixSet{
+			config.PrefixSet{PrefixSetName: "ps1", PrefixList: prefixList},
+		},
+	}
+}
+
+// the full table of n paths, the half of which are in the prefix set
+func benchmarkPaths(n int) []table.Path {
+	paths := make([]table.Path, 0, n)
+	for i := 0; i < n; i++ {
+		a := uint32(10<<24) + uint32(i)<<9
+		paths = append(paths, testPath(bgp.NewNLRInfo(24, net.IPv4(byte(a>>24), byte(a>>16), byte(a>>8), 0).String())))
+	}
+	return paths
+}
+
+func benchmarkPrefixSetImport(b *testing.B, setSize int, linear bool) {
+	log.SetLevel(log.InfoLevel)
+	ds := benchmarkPrefixSet(setSize)
+	pd := config.PolicyDefinition{
+		Name: "pd1",
+		StatementList: []config.Statement{
+			config.Statement{
+				Name:       "statement1",
+				Conditions: config.Conditions{MatchPrefixSet: "ps1"},
+				Actions:    config.Actions{AcceptRoute: true},
+			},
+		},
+	}
+	p := NewPolicy("pd1", pd, ds)
+	if linear {
+		p.Statements[0].Conditions[0].(*PrefixConditions).tries = nil
+	}
+	paths := benchmarkPaths(500000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Apply(paths[i%len(paths)], nil)
+	}
+}
+
+// import of the full table against the prefix set of 100k entries
+func BenchmarkPrefixSetImport100k(b *testing.B) {
+	benchmarkPrefixSetImport(b, 100000, false)
+}
+
+func BenchmarkPrefixSetImport10k(b *testing.B) {
+	benchmarkPrefixSetImport(b, 10000, false)
+}
+
+// comparing the paths with each prefix for reference
+func BenchmarkPrefixSetImportLinear10k(b *testing.B) {
+	benchmarkPrefixSetImport(b, 10000, true)
+}
+
+func TestUpdatePolicyMap(t *testing.T) {
+	ds := config.DefinedSets{
+		PrefixSetList: []config.PrefixSet{
+			config.PrefixSet{
+				PrefixSetName: "ps1",
+				PrefixList: []config.Prefix{
+					config.Prefix{Address: net.ParseIP("10.10.0.0"), Masklength: 16, MasklengthRange: "21..24"},
+				},
+			},
+		},
+	}
+	statement := func(name, callPolicy string) config.Statement {
+		return config.Statement{
+			Name:       name,
+			Conditions: config.Conditions{MatchPrefixSet: "ps1", CallPolicy: callPolicy},
+			Actions:    config.Actions{AcceptRoute: true},
+		}
+	}
+	oldConfig := config.RoutingPolicy{
+		DefinedSets: ds,
+		PolicyDefinitionList: []config.PolicyDefinition{
+			config.PolicyDefinition{Name: "pd1", StatementList: []config.Statement{statement("st1", "")}},
+			config.PolicyDefinition{Name: "pd2", StatementList: []config.Statement{statement("st2", "pd3")}},
+			config.PolicyDefinition{Name: "pd3", StatementList: []config.Statement{statement("st3", "")}},
+			config.PolicyDefinition{Name: "pd4", StatementList: []config.Statement{statement("st4", "pd1")}},
+		},
+	}
+	old := UpdatePolicyMap(nil, config.RoutingPolicy{}, oldConfig)
+	assert.Equal(t, 4, len(old))
+
+	// pd2 calling pd3 is updated too
+	newConfig := config.RoutingPolicy{
+		DefinedSets: ds,
+		PolicyDefinitionList: []config.PolicyDefinition{
+			config.PolicyDefinition{Name: "pd1", StatementList: []config.Statement{statement("st1", "")}},
+			config.PolicyDefinition{Name: "pd2", StatementList: []config.Statement{statement("st2", "pd3")}},
+			config.PolicyDefinition{Name: "pd3", StatementList: []config.Statement{statement("st3-1", "")}},
+			config.PolicyDefinition{Name: "pd4", StatementList: []config.Statement{statement("st4-1", "pd1")}},
+		},
+	}
+	pMap := UpdatePolicyMap(old, oldConfig, newConfig)
+	assert.True(t, pMap["pd1"] == old["pd1"])
+	assert.False(t, pMap["pd2"] == old["pd2"])
+	assert.False(t, pMap["pd3"] == old["pd3"])
+	assert.False(t, pMap["pd4"] == old["pd4"])
+	assert.True(t, pMap["pd2"].Statements[0].callPolicy == pMap["pd3"])
+	// the policy taken over is called
+	assert.True(t, pMap["pd4"].Statements[0].callPolicy == old["pd1"])
+
+	// pd2 calls the deleted policy
+	newConfig2 := config.RoutingPolicy{
+		DefinedSets:          ds,
+		PolicyDefinitionList: newConfig.PolicyDefinitionList[:2],
+	}
+	pMap2 := UpdatePolicyMap(pMap, newConfig, newConfig2)
+	assert.Equal(t, 2, len(pMap2))
+	assert.True(t, pMap2["pd1"] == pMap["pd1"])
+	assert.False(t, pMap2["pd2"] == pMap["pd2"])
+	assert.Nil(t, pMap2["pd2"].Statements[0].callPolicy)
+
+	// all the policies are updated with the defined sets
+	newConfig3 := newConfig2
+	newConfig3.DefinedSets = config.DefinedSets{}
+	pMap3 := UpdatePolicyMap(pMap2, newConfig2, newConfig3)
+	assert.False(t, pMap3["pd1"] == pMap2["pd1"])
+}
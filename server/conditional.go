@@ -0,0 +1,219 @@
+// Copyright (C) 2015 Nippon Telegraph and Telephone Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/osrg/gobgp/config"
+	"github.com/osrg/gobgp/policy"
+	"github.com/osrg/gobgp/table"
+	"reflect"
+)
+
+// conditionalAdvertisement advertises the paths matching the
+// advertise policy only when a path matching the exist policy is in
+// the RIB, or when no path matching the non-exist policy is.
+type conditionalAdvertisement struct {
+	config    config.ConditionalAdvertisement
+	advertise *policy.Policy
+	exist     *policy.Policy
+	nonExist  *policy.Policy
+	// the prefixes in the RIB matching the exist or non-exist policy
+	watched map[string]bool
+	// the best paths matching the advertise policy
+	paths map[string]table.Path
+}
+
+func newConditionalAdvertisement(c config.ConditionalAdvertisement, policyMap map[string]*policy.Policy) (*conditionalAdvertisement, error) {
+	advertise, ok := policyMap[c.AdvertisePolicy]
+	if !ok {
+		return nil, fmt.Errorf("advertise policy %s isn't defined", c.AdvertisePolicy)
+	}
+	ca := &conditionalAdvertisement{
+		config:    c,
+		advertise: advertise,
+		watched:   make(map[string]bool),
+		paths:     make(map[string]table.Path),
+	}
+	switch {
+	case c.ExistPolicy != "" && c.NonExistPolicy != "":
+		return nil, fmt.Errorf("both exist and non-exist policies are specified")
+	case c.ExistPolicy != "":
+		if ca.exist, ok = policyMap[c.ExistPolicy]; !ok {
+			return nil, fmt.Errorf("exist policy %s isn't defined", c.ExistPolicy)
+		}
+	case c.NonExistPolicy != "":
+		if ca.nonExist, ok = policyMap[c.NonExistPolicy]; !ok {
+			return nil, fmt.Errorf("non-exist policy %s isn't defined", c.NonExistPolicy)
+		}
+	default:
+		return nil, fmt.Errorf("neither exist nor non-exist policy is specified")
+	}
+	return ca, nil
+}
+
+// return true if the policy accepts the path. the hits of the
+// statements aren't counted as the path isn't filtered.
+func matchPolicy(pol *policy.Policy, path table.Path) bool {
+	matched, action, _ := pol.Apply(path, &policy.PolicyOptions{DryRun: true})
+	return matched && action == policy.ROUTE_TYPE_ACCEPT
+}
+
+// the key of the paths of the same prefix
+func pathKey(path table.Path) string {
+	return path.GetRouteFamily().String() + ":" + path.GetNlri().String()
+}
+
+// return true if the path is subject to the condition
+func (c *conditionalAdvertisement) match(path table.Path) bool {
+	return !path.IsWithdraw() && matchPolicy(c.advertise, path)
+}
+
+// return true if the paths matching the advertise policy can be
+// advertised
+func (c *conditionalAdvertisement) isActive() bool {
+	if c.exist != nil {
+		return len(c.watched) > 0
+	}
+	return len(c.watched) == 0
+}
+
+// update the watched prefixes with the best path changes. return
+// true if the condition changes.
+func (c *conditionalAdvertisement) watch(pathList []table.Path) bool {
+	active := c.isActive()
+	pol := c.exist
+	if pol == nil {
+		pol = c.nonExist
+	}
+	for _, path := range pathList {
+		key := pathKey(path)
+		if !path.IsWithdraw() && matchPolicy(pol, path) {
+			c.watched[key] = true
+		} else {
+			delete(c.watched, key)
+		}
+	}
+	return active != c.isActive()
+}
+
+func (c *conditionalAdvertisement) pathList() []table.Path {
+	paths := make([]table.Path, 0, len(c.paths))
+	for _, path := range c.paths {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func (peer *Peer) setConditionalAdvertisements(policyMap map[string]*policy.Policy) {
+	conditionals := make([]*conditionalAdvertisement, 0)
+	for _, c := range peer.peerConfig.ConditionalAdvertisementList {
+		ca, err := newConditionalAdvertisement(c, policyMap)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"Topic": "Peer",
+				"Key":   peer.peerConfig.NeighborAddress,
+				"Error": err,
+			}).Error("failed to install conditional advertisement")
+			continue
+		}
+		// keep the state learned from the RIB so far
+		for _, old := range peer.conditionals {
+			if reflect.DeepEqual(old.config, c) {
+				ca.watched = old.watched
+				ca.paths = old.paths
+				break
+			}
+		}
+		log.WithFields(log.Fields{
+			"Topic":           "Peer",
+			"Key":             peer.peerConfig.NeighborAddress,
+			"AdvertisePolicy": c.AdvertisePolicy,
+			"ExistPolicy":     c.ExistPolicy,
+			"NonExistPolicy":  c.NonExistPolicy,
+		}).Info("conditional advertisement installed")
+		conditionals = append(conditionals, ca)
+	}
+	peer.conditionals = conditionals
+}
+
+// update the conditions with the best path changes in the RIB and
+// return the conditional advertisements whose condition changed.
+func (peer *Peer) updateConditions(pathList []table.Path) []*conditionalAdvertisement {
+	changed := make([]*conditionalAdvertisement, 0)
+	for _, c := range peer.conditionals {
+		if c.watch(pathList) {
+			log.WithFields(log.Fields{
+				"Topic":           "Peer",
+				"Key":             peer.peerConfig.NeighborAddress,
+				"AdvertisePolicy": c.config.AdvertisePolicy,
+				"Active":          c.isActive(),
+			}).Info("advertise condition changed")
+			changed = append(changed, c)
+		}
+	}
+	return changed
+}
+
+// remember the paths subject to conditional advertisement and turn
+// them into withdrawals while their condition isn't met.
+func (peer *Peer) applyConditions(pathList []table.Path) []table.Path {
+	if len(peer.conditionals) == 0 {
+		return pathList
+	}
+	paths := make([]table.Path, 0, len(pathList))
+	for _, path := range pathList {
+		key := pathKey(path)
+		suppressed := false
+		for _, c := range peer.conditionals {
+			if c.match(path) {
+				c.paths[key] = path
+				if !c.isActive() {
+					suppressed = true
+				}
+			} else {
+				delete(c.paths, key)
+			}
+		}
+		if suppressed {
+			path = path.Clone(true)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// return true if applyConditions() turns the path into a withdrawal.
+// unlike applyConditions(), the path isn't remembered.
+func (peer *Peer) suppressedByConditions(path table.Path) bool {
+	for _, c := range peer.conditionals {
+		if c.match(path) && !c.isActive() {
+			return true
+		}
+	}
+	return false
+}
+
+// send the best path changes to the peer. watchList is the changes
+// in the RIB used to evaluate the advertise conditions.
+func (peer *Peer) advertisePaths(watchList []table.Path, pathList []table.Path) {
+	changed := peer.updateConditions(watchList)
+	peer.sendUpdateMsgFromPaths(pathList)
+	for _, c := range changed {
+		peer.sendUpdateMsgFromPaths(c.pathList())
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright (C) 2015 Nippon Telegraph and Telephone Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"github.com/osrg/gobgp/config"
+	"github.com/osrg/gobgp/packet"
+	"github.com/osrg/gobgp/policy"
+	"github.com/osrg/gobgp/table"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+)
+
+func conditionalPolicyMap() map[string]*policy.Policy {
+	ds := config.DefinedSets{
+		PrefixSetList: []config.PrefixSet{
+			config.PrefixSet{
+				PrefixSetName: "backup",
+				PrefixList: []config.Prefix{
+					config.Prefix{Address: net.ParseIP("10.10.0.0"), Masklength: 16, MasklengthRange: "16..24"},
+				},
+			},
+			config.PrefixSet{
+				PrefixSetName: "primary",
+				PrefixList: []config.Prefix{
+					config.Prefix{Address: net.ParseIP("0.0.0.0"), Masklength: 0, MasklengthRange: "0..0"},
+				},
+			},
+		},
+	}
+	policyMap := make(map[string]*policy.Policy)
+	for _, name := range []string{"backup", "primary"} {
+		pd := config.PolicyDefinition{
+			Name: name,
+			StatementList: []config.Statement{
+				config.Statement{
+					Name: name,
+					Conditions: config.Conditions{
+						MatchPrefixSet:  name,
+						MatchSetOptions: config.MATCH_SET_OPTIONS_TYPE_ALL,
+					},
+					Actions: config.Actions{AcceptRoute: true},
+				},
+			},
+		}
+		policyMap[name] = policy.NewPolicy(name, pd, ds)
+	}
+	return policyMap
+}
+
+func TestConditionalAdvertisementNonExist(t *testing.T) {
+	peer := &Peer{
+		peerConfig: config.Neighbor{
+			NeighborAddress: net.ParseIP("10.0.0.2"),
+			ConditionalAdvertisementList: []config.ConditionalAdvertisement{
+				config.ConditionalAdvertisement{
+					AdvertisePolicy: "backup",
+					NonExistPolicy:  "primary",
+				},
+			},
+		},
+	}
+	peer.setConditionalAdvertisements(conditionalPolicyMap())
+	assert.Equal(t, len(peer.conditionals), 1)
+
+	// the primary path isn't in the rib so the backup is advertised
+	backup := testPath(bgp.NewNLRInfo(24, "10.10.1.0"))
+	paths := peer.applyConditions([]table.Path{backup})
+	assert.Equal(t, paths[0].IsWithdraw(), false)
+
+	primary := testPath(bgp.NewNLRInfo(0, "0.0.0.0"))
+	changed := peer.updateConditions([]table.Path{primary})
+	assert.Equal(t, len(changed), 1)
+	paths = peer.applyConditions(changed[0].pathList())
+	assert.Equal(t, len(paths), 1)
+	assert.Equal(t, paths[0].IsWithdraw(), true)
+
+	// the other paths don't change the condition
+	changed = peer.updateConditions([]table.Path{testPath(bgp.NewNLRInfo(24, "20.20.20.0"))})
+	assert.Equal(t, len(changed), 0)
+
+	changed = peer.updateConditions([]table.Path{testPath(bgp.NewNLRInfo(0, "0.0.0.0")).Clone(true)})
+	assert.Equal(t, len(changed), 1)
+	paths = peer.applyConditions(changed[0].pathList())
+	assert.Equal(t, paths[0].IsWithdraw(), false)
+
+	// the withdrawn backup path is forgotten
+	peer.applyConditions([]table.Path{testPath(bgp.NewNLRInfo(24, "10.10.1.0")).Clone(true)})
+	assert.Equal(t, len(changed[0].pathList()), 0)
+
+	// evaluating the conditions doesn't count the hits
+	for _, c := range peer.conditionals {
+		assert.Equal(t, c.advertise.Statements[0].Hits(), uint64(0))
+		assert.Equal(t, c.nonExist.Statements[0].Hits(), uint64(0))
+	}
+}
+
+func TestConditionalAdvertisementInvalid(t *testing.T) {
+	policyMap := conditionalPolicyMap()
+	_, err := newConditionalAdvertisement(config.ConditionalAdvertisement{
+		AdvertisePolicy: "backup",
+	}, policyMap)
+	assert.Error(t, err)
+	_, err = newConditionalAdvertisement(config.ConditionalAdvertisement{
+		AdvertisePolicy: "backup",
+		ExistPolicy:     "primary",
+		NonExistPolicy:  "primary",
+	}, policyMap)
+	assert.Error(t, err)
+	_, err = newConditionalAdvertisement(config.ConditionalAdvertisement{
+		AdvertisePolicy: "undefined",
+		ExistPolicy:     "primary",
+	}, policyMap)
+	assert.Error(t, err)
+}
@@ -0,0 +1,396 @@
+// Copyright (C) 2015 Nippon Telegraph and Telephone Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	log "github.com/Sirupsen/logrus"
+	"github.com/osrg/gobgp/api"
+	"github.com/osrg/gobgp/config"
+	"github.com/osrg/gobgp/packet"
+	"net/http"
+	"time"
+)
+
+const (
+	PEER_EVENT_UP                    = "peer-up"
+	PEER_EVENT_DOWN                  = "peer-down"
+	PEER_EVENT_NOTIFICATION_SENT     = "notification-sent"
+	PEER_EVENT_NOTIFICATION_RECEIVED = "notification-received"
+	PEER_EVENT_ADMIN_STATE_CHANGED   = "admin-state-changed"
+	PEER_EVENT_PREFIX_LIMIT_WARNING  = "prefix-limit-warning"
+	PEER_EVENT_PREFIX_LIMIT_EXCEEDED = "prefix-limit-exceeded"
+)
+
+const (
+	EVENT_QUEUE_SIZE   = 1024
+	WEBHOOK_QUEUE_SIZE = 256
+	WEBHOOK_TIMEOUT    = time.Second * 5
+)
+
+type notificationEvent struct {
+	Code        uint8
+	Subcode     uint8
+	CodeName    string
+	SubcodeName string
+	Data        []byte
+}
+
+type prefixLimitEvent struct {
+	RouteFamily string
+	Prefixes    int
+	MaxPrefixes uint32
+}
+
+// the event of a neighbor published to the clients and the webhooks
+type peerEvent struct {
+	Type         string
+	Time         int64
+	Neighbor     string
+	PeerAs       uint32
+	OldState     string             `json:",omitempty"`
+	State        string             `json:",omitempty"`
+	AdminState   string             `json:",omitempty"`
+	Notification *notificationEvent `json:",omitempty"`
+	PrefixLimit  *prefixLimitEvent  `json:",omitempty"`
+}
+
+func newPeerEvent(eventType string, conf *config.Neighbor) *peerEvent {
+	return &peerEvent{
+		Type:     eventType,
+		Time:     time.Now().Unix(),
+		Neighbor: conf.NeighborAddress.String(),
+		PeerAs:   conf.PeerAs,
+	}
+}
+
+func newNotificationEvent(eventType string, conf *config.Neighbor, code, subcode uint8, data []byte) *peerEvent {
+	e := newPeerEvent(eventType, conf)
+	codeName, subcodeName := bgp.NotificationErrorNames(code, subcode)
+	e.Notification = &notificationEvent{
+		Code:        code,
+		Subcode:     subcode,
+		CodeName:    codeName,
+		SubcodeName: subcodeName,
+		Data:        data,
+	}
+	return e
+}
+
+func newPrefixLimitEvent(eventType string, conf *config.Neighbor, rf bgp.RouteFamily, prefixes int, limit config.PrefixLimit) *peerEvent {
+	e := newPeerEvent(eventType, conf)
+	e.PrefixLimit = &prefixLimitEvent{
+		RouteFamily: rf.String(),
+		Prefixes:    prefixes,
+		MaxPrefixes: limit.MaxPrefixes,
+	}
+	return e
+}
+
+func (e *peerEvent) ToApiStruct() *api.PeerEvent {
+	event := &api.PeerEvent{
+		Type:            e.Type,
+		Time:            e.Time,
+		NeighborAddress: e.Neighbor,
+		PeerAs:          e.PeerAs,
+		OldState:        e.OldState,
+		State:           e.State,
+		AdminState:      e.AdminState,
+	}
+	if n := e.Notification; n != nil {
+		event.Notification = &api.NotificationEvent{
+			Code:        uint32(n.Code),
+			Subcode:     uint32(n.Subcode),
+			CodeName:    n.CodeName,
+			SubcodeName: n.SubcodeName,
+			Data:        n.Data,
+		}
+	}
+	if l := e.PrefixLimit; l != nil {
+		event.PrefixLimit = &api.PrefixLimitEvent{
+			RouteFamily: l.RouteFamily,
+			Prefixes:    uint32(l.Prefixes),
+			MaxPrefixes: l.MaxPrefixes,
+		}
+	}
+	return event
+}
+
+// the client streaming the events through the rest or the grpc api.
+// the events are sent without blocking; false is returned if the client
+// went away or can't keep up with the events.
+type streamClient interface {
+	neighbor() string
+	// nothing but the stream is started
+	start()
+	send(v interface{}) bool
+	fail(err error)
+	canceled() bool
+	close()
+}
+
+// the events are sent in JSON
+type restClient struct {
+	req *api.RestRequest
+}
+
+func (c *restClient) neighbor() string {
+	return c.req.RemoteAddr
+}
+
+func (c *restClient) start() {
+	c.req.ResponseCh <- &api.RestResponse{}
+}
+
+func (c *restClient) send(v interface{}) bool {
+	if c.canceled() {
+		return false
+	}
+	j, _ := json.Marshal(v)
+	select {
+	case c.req.ResponseCh <- &api.RestResponse{Data: j}:
+		return true
+	default:
+		log.WithFields(log.Fields{
+			"Topic": "Event",
+			"Key":   c.req.RequestType,
+		}).Warn("client is dropped since it can't keep up with the events")
+		return false
+	}
+}
+
+func (c *restClient) fail(err error) {
+	c.req.ResponseCh <- &api.RestResponse{ResponseErr: err}
+	close(c.req.ResponseCh)
+}
+
+func (c *restClient) canceled() bool {
+	select {
+	case <-c.req.Done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *restClient) close() {
+	close(c.req.ResponseCh)
+}
+
+// the events are sent as the messages of the grpc api
+type grpcClient struct {
+	req *api.GrpcRequest
+}
+
+func (c *grpcClient) neighbor() string {
+	return c.req.RemoteAddr
+}
+
+func (c *grpcClient) start() {
+}
+
+func (c *grpcClient) send(v interface{}) bool {
+	if c.canceled() {
+		return false
+	}
+	var data interface{}
+	switch v := v.(type) {
+	case *peerEvent:
+		data = v.ToApiStruct()
+	case []*watchEvent:
+		events := make([]*api.BestPathEvent, 0, len(v))
+		for _, e := range v {
+			events = append(events, e.ToApiStruct())
+		}
+		data = events
+	}
+	select {
+	case c.req.ResponseCh <- &api.GrpcResponse{Data: data}:
+		return true
+	default:
+		log.WithFields(log.Fields{
+			"Topic": "Event",
+			"Key":   c.req.RequestType,
+		}).Warn("client is dropped since it can't keep up with the events")
+		return false
+	}
+}
+
+func (c *grpcClient) fail(err error) {
+	c.req.ResponseCh <- &api.GrpcResponse{ResponseErr: err}
+	close(c.req.ResponseCh)
+}
+
+func (c *grpcClient) canceled() bool {
+	select {
+	case <-c.req.Done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *grpcClient) close() {
+	close(c.req.ResponseCh)
+}
+
+// the events are posted to the url in JSON in the order published
+type webhook struct {
+	config  config.EventWebhook
+	types   map[string]bool
+	eventCh chan *peerEvent
+	client  *http.Client
+}
+
+func newWebhook(c config.EventWebhook) *webhook {
+	w := &webhook{
+		config:  c,
+		types:   make(map[string]bool),
+		eventCh: make(chan *peerEvent, WEBHOOK_QUEUE_SIZE),
+		client:  &http.Client{Timeout: WEBHOOK_TIMEOUT},
+	}
+	for _, t := range c.EventTypes {
+		w.types[t] = true
+	}
+	return w
+}
+
+func (w *webhook) post(e *peerEvent) {
+	if len(w.types) > 0 && !w.types[e.Type] {
+		return
+	}
+	select {
+	case w.eventCh <- e:
+	default:
+		log.WithFields(log.Fields{
+			"Topic": "Event",
+			"Key":   w.config.Url,
+		}).Warn("webhook is too slow, event is dropped")
+	}
+}
+
+func (w *webhook) loop() {
+	for e := range w.eventCh {
+		j, _ := json.Marshal(e)
+		res, err := w.client.Post(w.config.Url, "application/json", bytes.NewReader(j))
+		if err != nil {
+			log.WithFields(log.Fields{
+				"Topic": "Event",
+				"Key":   w.config.Url,
+				"Error": err,
+			}).Warn("failed to post event")
+			continue
+		}
+		res.Body.Close()
+		if res.StatusCode/100 != 2 {
+			log.WithFields(log.Fields{
+				"Topic":  "Event",
+				"Key":    w.config.Url,
+				"Status": res.Status,
+			}).Warn("webhook rejected event")
+		}
+	}
+}
+
+// the events of all the neighbors are published to the bus from the
+// peers and the fsms and distributed in its own goroutine.
+type eventBus struct {
+	eventCh     chan *peerEvent
+	subscribeCh chan streamClient
+	subscribers []streamClient
+	webhookCh   chan []config.EventWebhook
+	webhooks    []*webhook
+}
+
+func newEventBus(hooks []config.EventWebhook) *eventBus {
+	b := &eventBus{
+		eventCh:     make(chan *peerEvent, EVENT_QUEUE_SIZE),
+		subscribeCh: make(chan streamClient),
+		webhookCh:   make(chan []config.EventWebhook),
+	}
+	b.setWebhooks(hooks)
+	go b.loop()
+	return b
+}
+
+// replace the webhooks. the events queued for the old ones are still
+// posted.
+func (b *eventBus) setWebhooks(hooks []config.EventWebhook) {
+	for _, w := range b.webhooks {
+		close(w.eventCh)
+	}
+	b.webhooks = make([]*webhook, 0, len(hooks))
+	for _, c := range hooks {
+		w := newWebhook(c)
+		b.webhooks = append(b.webhooks, w)
+		go w.loop()
+	}
+}
+
+// the events published after this returns are posted to the new
+// webhooks
+func (b *eventBus) updateWebhooks(hooks []config.EventWebhook) {
+	b.webhookCh <- hooks
+}
+
+// never blocks the caller. the peers without the bus don't publish
+// anything.
+func (b *eventBus) publish(e *peerEvent) {
+	if b == nil {
+		return
+	}
+	select {
+	case b.eventCh <- e:
+	default:
+		log.WithFields(log.Fields{
+			"Topic": "Event",
+			"Key":   e.Neighbor,
+			"Type":  e.Type,
+		}).Warn("too many events, event is dropped")
+	}
+}
+
+func (b *eventBus) loop() {
+	for {
+		select {
+		case c := <-b.subscribeCh:
+			c.start()
+			b.subscribers = append(b.subscribers, c)
+		case hooks := <-b.webhookCh:
+			b.setWebhooks(hooks)
+		case e := <-b.eventCh:
+			subscribers := make([]streamClient, 0, len(b.subscribers))
+			for _, c := range b.subscribers {
+				alive := false
+				if c.neighbor() == "" || c.neighbor() == e.Neighbor {
+					alive = c.send(e)
+				} else {
+					alive = !c.canceled()
+				}
+				if alive {
+					subscribers = append(subscribers, c)
+				} else {
+					c.close()
+				}
+			}
+			b.subscribers = subscribers
+			for _, w := range b.webhooks {
+				w.post(e)
+			}
+		}
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright (C) 2015 Nippon Telegraph and Telephone Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"github.com/osrg/gobgp/api"
+	"github.com/osrg/gobgp/config"
+	"github.com/osrg/gobgp/packet"
+	"github.com/osrg/gobgp/table"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func recvEvent(t *testing.T, ch chan *api.RestResponse) *peerEvent {
+	select {
+	case res := <-ch:
+		e := &peerEvent{}
+		assert.Nil(t, json.Unmarshal(res.Data, e))
+		return e
+	case <-time.After(time.Second * 5):
+		t.Fatal("no event")
+	}
+	return nil
+}
+
+func TestEventBus(t *testing.T) {
+	assert := assert.New(t)
+	posted := make(chan *peerEvent, 8)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		e := &peerEvent{}
+		json.Unmarshal(b, e)
+		posted <- e
+	}))
+	defer hook.Close()
+
+	bus := newEventBus([]config.EventWebhook{
+		config.EventWebhook{Url: hook.URL, EventTypes: []string{PEER_EVENT_DOWN}},
+	})
+	all := api.NewRestRequest(api.REQ_EVENTS, "", 0)
+	all.ResponseCh = make(chan *api.RestResponse, api.WATCH_QUEUE_SIZE)
+	all.Done = make(chan struct{})
+	bus.subscribeCh <- &restClient{all}
+	assert.Nil((<-all.ResponseCh).Data)
+	one := api.NewRestRequest(api.REQ_EVENTS, "10.0.0.2", 0)
+	one.ResponseCh = make(chan *api.RestResponse, api.WATCH_QUEUE_SIZE)
+	one.Done = make(chan struct{})
+	bus.subscribeCh <- &restClient{one}
+	<-one.ResponseCh
+
+	conf1 := &config.Neighbor{NeighborAddress: net.ParseIP("10.0.0.1"), PeerAs: 65001}
+	conf2 := &config.Neighbor{NeighborAddress: net.ParseIP("10.0.0.2"), PeerAs: 65002}
+	fsm := &FSM{peerConfig: conf1, state: bgp.BGP_FSM_OPENCONFIRM, events: bus}
+	fsm.StateChange(bgp.BGP_FSM_ESTABLISHED)
+	bus.publish(newNotificationEvent(PEER_EVENT_NOTIFICATION_RECEIVED, conf2, bgp.BGP_ERROR_CEASE, bgp.BGP_ERROR_SUB_ADMINISTRATIVE_RESET, nil))
+	fsm.StateChange(bgp.BGP_FSM_IDLE)
+
+	e := recvEvent(t, all.ResponseCh)
+	assert.Equal(e.Type, PEER_EVENT_UP)
+	assert.Equal(e.Neighbor, "10.0.0.1")
+	assert.Equal(e.OldState, bgp.BGP_FSM_OPENCONFIRM.String())
+	assert.Equal(e.State, bgp.BGP_FSM_ESTABLISHED.String())
+	e = recvEvent(t, all.ResponseCh)
+	assert.Equal(e.Type, PEER_EVENT_NOTIFICATION_RECEIVED)
+	assert.Equal(e.Notification.CodeName, "CEASE")
+	assert.Equal(e.Notification.SubcodeName, "ADMINISTRATIVE_RESET")
+	e = recvEvent(t, all.ResponseCh)
+	assert.Equal(e.Type, PEER_EVENT_DOWN)
+
+	// only the events of the neighbor
+	e = recvEvent(t, one.ResponseCh)
+	assert.Equal(e.Type, PEER_EVENT_NOTIFICATION_RECEIVED)
+	assert.Equal(len(one.ResponseCh), 0)
+
+	// only the types configured are posted
+	select {
+	case e = <-posted:
+		assert.Equal(e.Type, PEER_EVENT_DOWN)
+		assert.Equal(e.Neighbor, "10.0.0.1")
+	case <-time.After(time.Second * 5):
+		t.Fatal("no event posted")
+	}
+
+	// the client going away is removed
+	close(all.Done)
+	fsm.StateChange(bgp.BGP_FSM_ACTIVE)
+	fsm.StateChange(bgp.BGP_FSM_ESTABLISHED)
+	select {
+	case _, ok := <-all.ResponseCh:
+		assert.False(ok)
+	case <-time.After(time.Second * 5):
+		t.Fatal("client isn't removed")
+	}
+}
+
+func TestEventBusUpdateWebhooks(t *testing.T) {
+	assert := assert.New(t)
+	posted := make(chan *peerEvent, 8)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		e := &peerEvent{}
+		json.Unmarshal(b, e)
+		posted <- e
+	}))
+	defer hook.Close()
+
+	// the webhooks configured at reload
+	bus := newEventBus(nil)
+	bus.updateWebhooks([]config.EventWebhook{config.EventWebhook{Url: hook.URL}})
+	conf := &config.Neighbor{NeighborAddress: net.ParseIP("10.0.0.1"), PeerAs: 65001}
+	bus.publish(newPeerEvent(PEER_EVENT_UP, conf))
+	select {
+	case e := <-posted:
+		assert.Equal(e.Type, PEER_EVENT_UP)
+	case <-time.After(time.Second * 5):
+		t.Fatal("no event posted")
+	}
+
+	bus.updateWebhooks(nil)
+	bus.publish(newPeerEvent(PEER_EVENT_DOWN, conf))
+	select {
+	case e := <-posted:
+		t.Fatalf("event %s is posted to the removed webhook", e.Type)
+	case <-time.After(time.Millisecond * 100):
+	}
+}
+
+func TestEventBusGrpc(t *testing.T) {
+	assert := assert.New(t)
+	bus := newEventBus(nil)
+	req := api.NewGrpcRequest(api.REQ_EVENTS, "10.0.0.1", 0)
+	req.ResponseCh = make(chan *api.GrpcResponse, api.WATCH_QUEUE_SIZE)
+	req.Done = make(chan struct{})
+	bus.subscribeCh <- &grpcClient{req}
+
+	conf := &config.Neighbor{NeighborAddress: net.ParseIP("10.0.0.1"), PeerAs: 65001}
+	bus.publish(newNotificationEvent(PEER_EVENT_NOTIFICATION_SENT, conf, bgp.BGP_ERROR_CEASE, bgp.BGP_ERROR_SUB_ADMINISTRATIVE_SHUTDOWN, nil))
+	select {
+	case res := <-req.ResponseCh:
+		e := res.Data.(*api.PeerEvent)
+		assert.Equal(e.Type, PEER_EVENT_NOTIFICATION_SENT)
+		assert.Equal(e.NeighborAddress, "10.0.0.1")
+		assert.Equal(e.PeerAs, uint32(65001))
+		assert.Equal(e.Notification.Code, uint32(bgp.BGP_ERROR_CEASE))
+		assert.Equal(e.Notification.SubcodeName, "ADMINISTRATIVE_SHUTDOWN")
+	case <-time.After(time.Second * 5):
+		t.Fatal("no event")
+	}
+}
+
+func TestPrefixLimit(t *testing.T) {
+	assert := assert.New(t)
+	bus := newEventBus(nil)
+	req := api.NewRestRequest(api.REQ_EVENTS, "", 0)
+	req.ResponseCh = make(chan *api.RestResponse, api.WATCH_QUEUE_SIZE)
+	req.Done = make(chan struct{})
+	bus.subscribeCh <- &restClient{req}
+	<-req.ResponseCh
+
+	a := config.AfiSafi{AfiSafiName: "ipv4-unicast"}
+	a.Ipv4Unicast.PrefixLimit = config.PrefixLimit{MaxPrefixes: 4, ShutdownThresholdPct: 50, RestartTimer: 60}
+	peer := &Peer{
+		peerConfig: config.Neighbor{
+			NeighborAddress: net.ParseIP("10.0.0.1"),
+			AfiSafiList:     []config.AfiSafi{a},
+		},
+		adjRib:            table.NewAdjRib([]bgp.RouteFamily{bgp.RF_IPv4_UC}),
+		outgoing:          make(chan *bgp.BGPMessage, 1),
+		prefixLimitEvents: make(map[bgp.RouteFamily]string),
+	}
+	peer.fsm = &FSM{peerConfig: &peer.peerConfig, events: bus}
+
+	peer.adjRib.UpdateIn([]table.Path{testPath(bgp.NewNLRInfo(24, "10.10.1.0"))})
+	peer.checkPrefixLimit()
+	peer.adjRib.UpdateIn([]table.Path{testPath(bgp.NewNLRInfo(24, "10.10.2.0"))})
+	peer.checkPrefixLimit()
+	e := recvEvent(t, req.ResponseCh)
+	assert.Equal(e.Type, PEER_EVENT_PREFIX_LIMIT_WARNING)
+	assert.Equal(e.PrefixLimit.Prefixes, 2)
+
+	// the warning is published once
+	peer.adjRib.UpdateIn([]table.Path{testPath(bgp.NewNLRInfo(24, "10.10.3.0"))})
+	peer.adjRib.UpdateIn([]table.Path{testPath(bgp.NewNLRInfo(24, "10.10.4.0"))})
+	peer.checkPrefixLimit()
+
+	peer.adjRib.UpdateIn([]table.Path{testPath(bgp.NewNLRInfo(24, "10.10.5.0"))})
+	peer.checkPrefixLimit()
+	e = recvEvent(t, req.ResponseCh)
+	assert.Equal(e.Type, PEER_EVENT_PREFIX_LIMIT_EXCEEDED)
+	assert.Equal(e.PrefixLimit.RouteFamily, bgp.RF_IPv4_UC.String())
+	assert.Equal(e.PrefixLimit.Prefixes, 5)
+	assert.Equal(e.PrefixLimit.MaxPrefixes, uint32(4))
+
+	// only the event is published; the session is kept
+	peer.adjRib.UpdateIn([]table.Path{testPath(bgp.NewNLRInfo(24, "10.10.6.0"))})
+	peer.checkPrefixLimit()
+	assert.Equal(len(peer.outgoing), 0)
+	assert.Equal(peer.fsm.idleHoldTime, float64(0))
+	select {
+	case res := <-req.ResponseCh:
+		t.Fatalf("unexpected event %s", res.Data)
+	case <-time.After(time.Millisecond * 100):
+	}
+}
@@ -73,6 +73,7 @@ type FSM struct {
 	negotiatedHoldTime float64
 	adminState         AdminState
 	adminStateCh       chan AdminState
+	events             *eventBus
 }
 
 func (fsm *FSM) bgpMessageStateUpdate(MessageType uint8, isIn bool) {
@@ -142,6 +143,17 @@ func (fsm *FSM) StateChange(nextState bgp.FSMState) {
 		"old":   fsm.state.String(),
 		"new":   nextState.String(),
 	}).Debug("state changed")
+	var e *peerEvent
+	if nextState == bgp.BGP_FSM_ESTABLISHED {
+		e = newPeerEvent(PEER_EVENT_UP, fsm.peerConfig)
+	} else if fsm.state == bgp.BGP_FSM_ESTABLISHED {
+		e = newPeerEvent(PEER_EVENT_DOWN, fsm.peerConfig)
+	}
+	if e != nil {
+		e.OldState = fsm.state.String()
+		e.State = nextState.String()
+		fsm.events.publish(e)
+	}
 	fsm.state = nextState
 }
 
@@ -171,6 +183,7 @@ func (fsm *FSM) sendNotificatonFromErrorMsg(conn net.Conn, e *bgp.MessageError)
 		"Key":   fsm.peerConfig.NeighborAddress,
 		"Data":  e,
 	}).Warn("sent notification")
+	fsm.events.publish(newNotificationEvent(PEER_EVENT_NOTIFICATION_SENT, fsm.peerConfig, e.TypeCode, e.SubTypeCode, e.Data))
 }
 
 func (fsm *FSM) sendNotification(conn net.Conn, code, subType uint8, data []byte, msg string) {
@@ -366,6 +379,10 @@ func (h *FSMHandler) recvMessageWithError() error {
 	m, err := bgp.ParseBGPBody(hd, bodyBuf)
 	if err == nil {
 		h.fsm.bgpMessageStateUpdate(m.Header.Type, true)
+		if m.Header.Type == bgp.BGP_MSG_NOTIFICATION {
+			body := m.Body.(*bgp.BGPNotification)
+			h.fsm.events.publish(newNotificationEvent(PEER_EVENT_NOTIFICATION_RECEIVED, h.fsm.peerConfig, body.ErrorCode, body.ErrorSubcode, body.Data))
+		}
 		err = bgp.ValidateBGPMessage(m)
 	} else {
 		h.fsm.bgpMessageStateUpdate(0, true)
@@ -619,6 +636,8 @@ func (h *FSMHandler) sendMessageloop() error {
 				"Key":   fsm.peerConfig.NeighborAddress,
 				"Data":  m,
 			}).Warn("sent notification")
+			body := m.Body.(*bgp.BGPNotification)
+			fsm.events.publish(newNotificationEvent(PEER_EVENT_NOTIFICATION_SENT, fsm.peerConfig, body.ErrorCode, body.ErrorSubcode, body.Data))
 
 			h.errorCh <- true
 			conn.Close()
@@ -773,6 +792,10 @@ func (h *FSMHandler) changeAdminState(s AdminState) error {
 		}).Debug("admin state changed")
 
 		fsm.adminState = s
+		e := newPeerEvent(PEER_EVENT_ADMIN_STATE_CHANGED, fsm.peerConfig)
+		e.State = fsm.state.String()
+		e.AdminState = s.String()
+		fsm.events.publish(e)
 
 		switch s {
 		case ADMIN_STATE_UP:
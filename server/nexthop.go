@@ -0,0 +1,337 @@
+// Copyright (C) 2015 Nippon Telegraph and Telephone Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"github.com/BurntSushi/toml"
+	log "github.com/Sirupsen/logrus"
+	"github.com/osrg/gobgp/config"
+	"github.com/osrg/gobgp/zebra"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	ZEBRA_DEFAULT_PATH    = "/var/run/quagga/zserv.api"
+	ZEBRA_LOOKUP_TIMEOUT  = time.Second * 5
+	ZEBRA_RECONNECT_TIME  = time.Second * 10
+	MIN_NEXTHOP_SCAN_TIME = 5
+	NEXTHOP_GC_TIME       = time.Minute
+)
+
+type nexthopState struct {
+	reachable bool
+	metric    uint32
+}
+
+// nexthopResolver caches the reachability of the nexthops. The nexthops
+// not in the cache are looked up in the background and regarded as
+// unreachable until resolved. They are looked up again when the routes
+// to them change. The nexthops whose state changed are notified to
+// notifyCh.
+type nexthopResolver struct {
+	mu     sync.Mutex
+	lookup func(net.IP) (bool, uint32, error)
+	// look up the nexthops not in the cache in Resolve
+	inline bool
+	cache  map[string]*nexthopState
+	// the nexthops to be looked up in the background
+	queue    map[string]bool
+	queuedCh chan struct{}
+	notifyCh chan []net.IP
+}
+
+func newResolver(lookup func(net.IP) (bool, uint32, error), notifyCh chan []net.IP) *nexthopResolver {
+	r := &nexthopResolver{
+		lookup:   lookup,
+		cache:    make(map[string]*nexthopState),
+		queue:    make(map[string]bool),
+		queuedCh: make(chan struct{}, 1),
+		notifyCh: notifyCh,
+	}
+	go r.loop()
+	return r
+}
+
+func newNexthopResolver(c config.NexthopResolver, notifyCh chan []net.IP) (*nexthopResolver, error) {
+	switch c.Type {
+	case config.NEXTHOP_RESOLVER_TYPE_STATIC:
+		s := &staticNexthopTable{path: c.Path}
+		if err := s.load(); err != nil {
+			return nil, err
+		}
+		r := newResolver(s.lookup, notifyCh)
+		// looking up the routes in memory doesn't block
+		r.inline = true
+		interval := c.ScanInterval
+		if interval < MIN_NEXTHOP_SCAN_TIME {
+			interval = MIN_NEXTHOP_SCAN_TIME
+		}
+		go func() {
+			ticker := time.NewTicker(time.Duration(interval) * time.Second)
+			for _ = range ticker.C {
+				if s.reload() {
+					r.invalidate(nil)
+				}
+			}
+		}()
+		return r, nil
+	case config.NEXTHOP_RESOLVER_TYPE_ZEBRA:
+		path := c.Path
+		if path == "" {
+			path = ZEBRA_DEFAULT_PATH
+		}
+		client := zebra.NewClient("unix", path, ZEBRA_LOOKUP_TIMEOUT)
+		r := newResolver(func(nexthop net.IP) (bool, uint32, error) {
+			reply, err := client.LookupNexthop(nexthop)
+			if err != nil {
+				return false, 0, err
+			}
+			return len(reply.Nexthops) > 0, reply.Metric, nil
+		}, notifyCh)
+		go r.watchZebra(path)
+		return r, nil
+	}
+	return nil, fmt.Errorf("unknown nexthop resolver type: %d", c.Type)
+}
+
+// look up the nexthops again whenever the routes in zebra's RIB
+// change. all the nexthops are looked up again on reconnection as the
+// changes in the meantime are lost.
+func (r *nexthopResolver) watchZebra(path string) {
+	ch := make(chan *net.IPNet, 64)
+	go func() {
+		for prefix := range ch {
+			r.invalidate(prefix)
+		}
+	}()
+	for {
+		r.invalidate(nil)
+		err := zebra.WatchRoutes("unix", path, ZEBRA_LOOKUP_TIMEOUT, ch)
+		log.WithFields(log.Fields{
+			"Topic": "Nexthop",
+			"Key":   path,
+			"Error": err,
+		}).Warn("lost the connection to zebra")
+		time.Sleep(ZEBRA_RECONNECT_TIME)
+	}
+}
+
+// the nexthop is regarded as unreachable when the lookup fails
+func (r *nexthopResolver) resolve(nexthop net.IP) *nexthopState {
+	reachable, metric, err := r.lookup(nexthop)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"Topic": "Nexthop",
+			"Key":   nexthop,
+			"Error": err,
+		}).Warn("failed to look up nexthop")
+		return &nexthopState{}
+	}
+	return &nexthopState{reachable: reachable, metric: metric}
+}
+
+// called in the best path selection. it doesn't block unless the
+// lookup is inline; the nexthop not resolved yet is unreachable.
+func (r *nexthopResolver) Resolve(nexthop net.IP) (bool, uint32) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := nexthop.String()
+	s, ok := r.cache[key]
+	if !ok {
+		if r.inline {
+			s = r.resolve(nexthop)
+		} else {
+			s = &nexthopState{}
+			r.enqueue(key)
+		}
+		r.cache[key] = s
+	}
+	return s.reachable, s.metric
+}
+
+// forget the nexthops not used by any path
+func (r *nexthopResolver) Retain(nexthops []net.IP) {
+	used := make(map[string]bool, len(nexthops))
+	for _, nexthop := range nexthops {
+		used[nexthop.String()] = true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, _ := range r.cache {
+		if !used[key] {
+			delete(r.cache, key)
+			delete(r.queue, key)
+		}
+	}
+}
+
+func (r *nexthopResolver) enqueue(key string) {
+	r.queue[key] = true
+	select {
+	case r.queuedCh <- struct{}{}:
+	default:
+	}
+}
+
+// look up the cached nexthops covered by the prefix again. all of
+// them if the prefix is nil.
+func (r *nexthopResolver) invalidate(prefix *net.IPNet) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, _ := range r.cache {
+		if prefix == nil || prefix.Contains(net.ParseIP(key)) {
+			r.enqueue(key)
+		}
+	}
+}
+
+// look up the queued nexthops without the lock and notify the ones
+// whose state changed. the unused nexthops are dropped from the cache
+// periodically.
+func (r *nexthopResolver) loop() {
+	gc := time.NewTicker(NEXTHOP_GC_TIME)
+	for {
+		select {
+		case <-r.queuedCh:
+		case <-gc.C:
+			// an empty notification makes the rib retain the
+			// nexthops in use
+			r.notifyCh <- []net.IP{}
+			continue
+		}
+		r.mu.Lock()
+		keys := make([]string, 0, len(r.queue))
+		for key, _ := range r.queue {
+			keys = append(keys, key)
+		}
+		r.queue = make(map[string]bool)
+		r.mu.Unlock()
+
+		changed := make([]net.IP, 0)
+		for _, key := range keys {
+			nexthop := net.ParseIP(key)
+			s := r.resolve(nexthop)
+			r.mu.Lock()
+			if old, ok := r.cache[key]; ok && *s != *old {
+				log.WithFields(log.Fields{
+					"Topic":     "Nexthop",
+					"Key":       key,
+					"Reachable": s.reachable,
+					"Metric":    s.metric,
+				}).Info("nexthop changed")
+				r.cache[key] = s
+				changed = append(changed, nexthop)
+			}
+			r.mu.Unlock()
+		}
+		if len(changed) > 0 {
+			r.notifyCh <- changed
+		}
+	}
+}
+
+type staticNexthopRoute struct {
+	Prefix string
+	Metric uint32
+}
+
+// staticNexthopTable is the IGP routes read from a TOML file like
+//
+//	[[RouteList]]
+//	Prefix = "10.0.0.0/24"
+//	Metric = 10
+//
+// The file is read again when it's modified.
+type staticNexthopTable struct {
+	mu      sync.RWMutex
+	path    string
+	modTime time.Time
+	routes  []*net.IPNet
+	metrics []uint32
+}
+
+func (s *staticNexthopTable) load() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+	var t struct {
+		RouteList []staticNexthopRoute
+	}
+	if _, err := toml.DecodeFile(s.path, &t); err != nil {
+		return err
+	}
+	routes := make([]*net.IPNet, 0, len(t.RouteList))
+	metrics := make([]uint32, 0, len(t.RouteList))
+	for _, r := range t.RouteList {
+		_, n, err := net.ParseCIDR(r.Prefix)
+		if err != nil {
+			return err
+		}
+		routes = append(routes, n)
+		metrics = append(metrics, r.Metric)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.modTime = info.ModTime()
+	s.routes = routes
+	s.metrics = metrics
+	return nil
+}
+
+// read the file again if modified. return true if reloaded.
+func (s *staticNexthopTable) reload() bool {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return false
+	}
+	s.mu.RLock()
+	modTime := s.modTime
+	s.mu.RUnlock()
+	if !info.ModTime().After(modTime) {
+		return false
+	}
+	if err := s.load(); err != nil {
+		log.WithFields(log.Fields{
+			"Topic": "Nexthop",
+			"Key":   s.path,
+			"Error": err,
+		}).Error("failed to reload static nexthop routes")
+		return false
+	}
+	log.WithFields(log.Fields{
+		"Topic": "Nexthop",
+		"Key":   s.path,
+	}).Info("static nexthop routes reloaded")
+	return true
+}
+
+// the longest match route decides the metric
+func (s *staticNexthopTable) lookup(nexthop net.IP) (bool, uint32, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	found, length, metric := false, -1, uint32(0)
+	for i, n := range s.routes {
+		if l, _ := n.Mask.Size(); n.Contains(nexthop) && l > length {
+			found, length, metric = true, l, s.metrics[i]
+		}
+	}
+	return found, metric, nil
+}
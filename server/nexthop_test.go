@@ -0,0 +1,115 @@
+// Copyright (C) 2015 Nippon Telegraph and Telephone Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStaticNexthopTable(t *testing.T) {
+	f, err := ioutil.TempFile("", "gobgp-nexthop")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(`
+[[RouteList]]
+Prefix = "10.0.0.0/8"
+Metric = 20
+
+[[RouteList]]
+Prefix = "10.0.0.0/24"
+Metric = 10
+`)
+	f.Close()
+
+	s := &staticNexthopTable{path: f.Name()}
+	assert.NoError(t, s.load())
+	reachable, metric, _ := s.lookup(net.ParseIP("10.0.0.1"))
+	assert.True(t, reachable)
+	assert.Equal(t, metric, uint32(10))
+	reachable, metric, _ = s.lookup(net.ParseIP("10.1.0.1"))
+	assert.True(t, reachable)
+	assert.Equal(t, metric, uint32(20))
+	reachable, _, _ = s.lookup(net.ParseIP("192.168.0.1"))
+	assert.False(t, reachable)
+}
+
+func recvNexthops(t *testing.T, ch chan []net.IP) []net.IP {
+	select {
+	case nexthops := <-ch:
+		return nexthops
+	case <-time.After(time.Second * 5):
+		t.Fatal("no nexthop notified")
+	}
+	return nil
+}
+
+func TestNexthopResolver(t *testing.T) {
+	assert := assert.New(t)
+	var mu sync.Mutex
+	routes := map[string]uint32{"10.0.0.1": 10}
+	notifyCh := make(chan []net.IP, 8)
+	r := newResolver(func(nexthop net.IP) (bool, uint32, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if nexthop.Equal(net.ParseIP("10.0.0.3")) {
+			return true, 0, fmt.Errorf("lookup failed")
+		}
+		metric, ok := routes[nexthop.String()]
+		return ok, metric, nil
+	}, notifyCh)
+
+	// unreachable until looked up in the background
+	nexthop := net.ParseIP("10.0.0.1")
+	reachable, _ := r.Resolve(nexthop)
+	assert.False(reachable)
+	assert.Equal(recvNexthops(t, notifyCh), []net.IP{nexthop})
+	reachable, metric := r.Resolve(nexthop)
+	assert.True(reachable)
+	assert.Equal(metric, uint32(10))
+
+	// the nexthop failed to be looked up is unreachable
+	r.Resolve(net.ParseIP("10.0.0.2"))
+	r.Resolve(net.ParseIP("10.0.0.3"))
+	r.Resolve(net.ParseIP("10.0.0.2"))
+	time.Sleep(time.Millisecond * 100)
+	assert.Equal(len(notifyCh), 0)
+	reachable, _ = r.Resolve(net.ParseIP("10.0.0.3"))
+	assert.False(reachable)
+
+	// looked up again when the route changes
+	mu.Lock()
+	routes["10.0.0.1"] = 20
+	mu.Unlock()
+	_, prefix, _ := net.ParseCIDR("192.168.0.0/16")
+	r.invalidate(prefix)
+	time.Sleep(time.Millisecond * 100)
+	assert.Equal(len(notifyCh), 0)
+	_, prefix, _ = net.ParseCIDR("10.0.0.0/24")
+	r.invalidate(prefix)
+	assert.Equal(recvNexthops(t, notifyCh), []net.IP{nexthop})
+	_, metric = r.Resolve(nexthop)
+	assert.Equal(metric, uint32(20))
+
+	r.Retain([]net.IP{nexthop})
+	assert.Equal(len(r.cache), 1)
+}
@@ -17,6 +17,7 @@ package server
 
 import (
 	"encoding/json"
+	"fmt"
 	log "github.com/Sirupsen/logrus"
 	"github.com/osrg/gobgp/api"
 	"github.com/osrg/gobgp/config"
@@ -41,8 +42,24 @@ const (
 	_ peerMsgType = iota
 	PEER_MSG_PATH
 	PEER_MSG_PEER_DOWN
+	PEER_MSG_PATH_FILTERED
+	PEER_MSG_SOFT_RECONFIG
+	PEER_MSG_PATH_REFRESH
 )
 
+// the result of the import policies of the global rib for a path
+// received from the peer
+type peerMsgDataFiltered struct {
+	path   table.Path
+	result *policyResult
+}
+
+// all the paths the sibling sends for the soft reconfiguration
+type peerMsgDataRefresh struct {
+	source   *table.PeerInfo
+	pathList []table.Path
+}
+
 type peerMsg struct {
 	msgType peerMsgType
 	msgData interface{}
@@ -60,36 +77,218 @@ type Peer struct {
 	adjRib       *table.AdjRib
 	// peer and rib are always not one-to-one so should not be
 	// here but it's the simplest and works our first target.
-	rib                 *table.TableManager
-	isGlobalRib         bool
-	rfMap               map[bgp.RouteFamily]bool
-	capMap              map[bgp.BGPCapabilityCode]bgp.ParameterCapabilityInterface
-	peerInfo            *table.PeerInfo
-	siblings            map[string]*serverMsgDataPeer
-	outgoing            chan *bgp.BGPMessage
-	importPolicies      []*policy.Policy
-	defaultImportPolicy config.DefaultPolicyType
-	exportPolicies      []*policy.Policy
-	defaultExportPolicy config.DefaultPolicyType
-}
-
-func NewPeer(g config.Global, peer config.Neighbor, serverMsgCh chan *serverMsg, peerMsgCh chan *peerMsg, peerList []*serverMsgDataPeer, isGlobalRib bool, policyMap map[string]*policy.Policy) *Peer {
+	rib            *table.TableManager
+	isGlobalRib    bool
+	rfMap          map[bgp.RouteFamily]bool
+	capMap         map[bgp.BGPCapabilityCode]bgp.ParameterCapabilityInterface
+	peerInfo       *table.PeerInfo
+	siblings       map[string]*serverMsgDataPeer
+	outgoing       chan *bgp.BGPMessage
+	policyMap      map[string]*policy.Policy
+	importPolicies map[bgp.RouteFamily]*appliedPolicies
+	exportPolicies map[bgp.RouteFamily]*appliedPolicies
+	importCounters policyCounters
+	exportCounters policyCounters
+	conditionals   []*conditionalAdvertisement
+	roaTable       *roaTable
+	watchers       []*bestPathWatcher
+	// the last prefix limit event published for each address family
+	prefixLimitEvents map[bgp.RouteFamily]string
+}
+
+const (
+	POLICY_RESULT_ACCEPT         = "accept"
+	POLICY_RESULT_REJECT         = "reject"
+	POLICY_RESULT_DEFAULT_ACCEPT = "default-accept"
+	POLICY_RESULT_DEFAULT_REJECT = "default-reject"
+	POLICY_RESULT_NONE           = "none"
+	// withdrawn by the conditional advertisement
+	POLICY_RESULT_SUPPRESSED = "suppressed"
+)
+
+// the result of applying the policies to a path
+type policyResult struct {
+	// the policy and the statement matching the path
+	Policy    string
+	Statement string
+	Action    string
+	// the path modified by the policies, nil if rejected
+	Path table.Path
+}
+
+func (r *policyResult) accepted() bool {
+	return r.Action == POLICY_RESULT_ACCEPT || r.Action == POLICY_RESULT_DEFAULT_ACCEPT
+}
+
+// the number of the paths accepted, rejected and modified by the
+// policies of a direction
+type policyCounters struct {
+	Accepted uint64
+	Rejected uint64
+	Modified uint64
+}
+
+// the number of the paths matching each statement of a policy
+type policyHits struct {
+	Name       string
+	Statements []statementHits
+}
+
+type statementHits struct {
+	Name string
+	Hits uint64
+}
+
+func newPolicyHits(pol *policy.Policy) *policyHits {
+	h := &policyHits{
+		Name:       pol.Name,
+		Statements: make([]statementHits, 0, len(pol.Statements)),
+	}
+	for i := range pol.Statements {
+		s := &pol.Statements[i]
+		h.Statements = append(h.Statements, statementHits{Name: s.Name, Hits: s.Hits()})
+	}
+	return h
+}
+
+func (h *policyHits) ToApiStruct() *api.Policy {
+	p := &api.Policy{
+		Name:       h.Name,
+		Statements: make([]*api.Statement, 0, len(h.Statements)),
+	}
+	for _, s := range h.Statements {
+		p.Statements = append(p.Statements, &api.Statement{Name: s.Name, Hits: s.Hits})
+	}
+	return p
+}
+
+// the policies applied to the paths of a route family and the default
+// policy for the paths that none of the policies is applied to
+type appliedPolicies struct {
+	policies      []*policy.Policy
+	defaultPolicy config.DefaultPolicyType
+	counters      *policyCounters
+}
+
+// apply the policies to the path. the statement matching the path
+// decides, or the default policy if no statement matches.
+func (a *appliedPolicies) evaluate(path table.Path, options *policy.PolicyOptions) *policyResult {
+	if a == nil {
+		return &policyResult{Action: POLICY_RESULT_DEFAULT_ACCEPT, Path: path}
+	}
+	for _, pol := range a.policies {
+		if r := evaluatePolicy(pol, path, options); r.Action != POLICY_RESULT_NONE {
+			return r
+		}
+	}
+	if a.defaultPolicy == config.DEFAULT_POLICY_TYPE_ACCEPT_ROUTE {
+		return &policyResult{Action: POLICY_RESULT_DEFAULT_ACCEPT, Path: path}
+	}
+	return &policyResult{Action: POLICY_RESULT_DEFAULT_REJECT}
+}
+
+// return true if the same policies are applied in the same order
+func (a *appliedPolicies) equal(b *appliedPolicies) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.defaultPolicy != b.defaultPolicy || len(a.policies) != len(b.policies) {
+		return false
+	}
+	for i, pol := range a.policies {
+		if pol != b.policies[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func evaluatePolicy(pol *policy.Policy, path table.Path, options *policy.PolicyOptions) *policyResult {
+	statement, routeType, newPath := pol.ApplyStatement(path, options)
+	if statement == nil {
+		return &policyResult{Policy: pol.Name, Action: POLICY_RESULT_NONE}
+	}
+	r := &policyResult{
+		Policy:    pol.Name,
+		Statement: statement.Name,
+		Action:    POLICY_RESULT_ACCEPT,
+		Path:      newPath,
+	}
+	if routeType == policy.ROUTE_TYPE_REJECT {
+		r.Action = POLICY_RESULT_REJECT
+	}
+	return r
+}
+
+// apply the policies to the path and count the result unless dry
+// run. nil is returned for the withdrawals and when no policy is
+// applied.
+func (a *appliedPolicies) applyPath(path table.Path, options *policy.PolicyOptions) *policyResult {
+	if path.IsWithdraw() || a == nil {
+		return nil
+	}
+	count := options == nil || !options.DryRun
+	if len(a.policies) == 0 {
+		if count {
+			a.counters.Accepted++
+		}
+		return nil
+	}
+	r := a.evaluate(path, options)
+	if !count {
+		return r
+	}
+	if r.accepted() {
+		a.counters.Accepted++
+		if r.Path != path {
+			a.counters.Modified++
+		}
+	} else {
+		a.counters.Rejected++
+	}
+	return r
+}
+
+// apply the policies to the paths. the rejected paths are removed, or
+// replaced with withdrawals if withdrawRejected is true.
+func (a *appliedPolicies) apply(pathList []table.Path, options *policy.PolicyOptions, withdrawRejected bool) []table.Path {
+	paths := make([]table.Path, 0, len(pathList))
+	for _, p := range pathList {
+		r := a.applyPath(p, options)
+		if r == nil {
+			paths = append(paths, p)
+		} else if r.accepted() {
+			log.Debug("path accepted: ", r.Path)
+			paths = append(paths, r.Path)
+		} else {
+			log.Debug("path was rejected: ", p)
+			if withdrawRejected {
+				paths = append(paths, p.Clone(true))
+			}
+		}
+	}
+	return paths
+}
+
+func NewPeer(g config.Global, peer config.Neighbor, serverMsgCh chan *serverMsg, peerMsgCh chan *peerMsg, peerList []*serverMsgDataPeer, isGlobalRib bool, policyMap map[string]*policy.Policy, events *eventBus) *Peer {
 	p := &Peer{
-		globalConfig: g,
-		peerConfig:   peer,
-		connCh:       make(chan net.Conn),
-		serverMsgCh:  serverMsgCh,
-		peerMsgCh:    peerMsgCh,
-		getActiveCh:  make(chan struct{}),
-		rfMap:        make(map[bgp.RouteFamily]bool),
-		capMap:       make(map[bgp.BGPCapabilityCode]bgp.ParameterCapabilityInterface),
-		isGlobalRib:  isGlobalRib,
+		globalConfig:      g,
+		peerConfig:        peer,
+		connCh:            make(chan net.Conn),
+		serverMsgCh:       serverMsgCh,
+		peerMsgCh:         peerMsgCh,
+		getActiveCh:       make(chan struct{}),
+		rfMap:             make(map[bgp.RouteFamily]bool),
+		capMap:            make(map[bgp.BGPCapabilityCode]bgp.ParameterCapabilityInterface),
+		isGlobalRib:       isGlobalRib,
+		prefixLimitEvents: make(map[bgp.RouteFamily]string),
 	}
 	p.siblings = make(map[string]*serverMsgDataPeer)
 	for _, s := range peerList {
 		p.siblings[s.address.String()] = s
 	}
 	p.fsm = NewFSM(&g, &peer, p.connCh)
+	p.fsm.events = events
 	peer.BgpNeighborCommonState.State = uint32(bgp.BGP_FSM_IDLE)
 	peer.BgpNeighborCommonState.Downtime = time.Now().Unix()
 	for _, rf := range peer.AfiSafiList {
@@ -104,6 +303,11 @@ func NewPeer(g config.Global, peer config.Neighbor, serverMsgCh chan *serverMsg,
 	rfList := p.configuredRFlist()
 	p.adjRib = table.NewAdjRib(rfList)
 	p.rib = table.NewTableManager(p.peerConfig.NeighborAddress.String(), rfList)
+	p.rib.SetRouteSelection(&g)
+	if isGlobalRib {
+		p.rib.SetAggregates(&g)
+		p.rib.SetBestPathWatcher(p.notifyWatchers)
+	}
 	p.setPolicy(policyMap)
 	p.t.Go(p.loop)
 	if !peer.TransportOptions.PassiveMode && !isGlobalRib {
@@ -112,37 +316,230 @@ func NewPeer(g config.Global, peer config.Neighbor, serverMsgCh chan *serverMsg,
 	return p
 }
 
-func (peer *Peer) setPolicy(policyMap map[string]*policy.Policy) {
-	// configure import policy
-	policyConfig := peer.peerConfig.ApplyPolicy
-	inPolicies := make([]*policy.Policy, 0)
-	for _, policyName := range policyConfig.ImportPolicies {
+func (peer *Peer) newAppliedPolicies(direction string, rf bgp.RouteFamily, names []string, defaultPolicy config.DefaultPolicyType) *appliedPolicies {
+	a := &appliedPolicies{
+		policies:      make([]*policy.Policy, 0, len(names)),
+		defaultPolicy: defaultPolicy,
+		counters:      &peer.exportCounters,
+	}
+	if direction == "import" {
+		a.counters = &peer.importCounters
+	}
+	for _, policyName := range names {
 		log.WithFields(log.Fields{
-			"Topic":      "Peer",
-			"Key":        peer.peerConfig.NeighborAddress,
-			"PolicyName": policyName,
-		}).Info("import policy installed")
-		if pol, ok := policyMap[policyName]; ok {
-			log.Debug("import policy : ", pol)
-			inPolicies = append(inPolicies, pol)
+			"Topic":       "Peer",
+			"Key":         peer.peerConfig.NeighborAddress,
+			"PolicyName":  policyName,
+			"RouteFamily": rf,
+		}).Infof("%s policy installed", direction)
+		if pol, ok := peer.policyMap[policyName]; ok {
+			log.Debugf("%s policy : %v", direction, pol)
+			a.policies = append(a.policies, pol)
 		}
 	}
-	peer.importPolicies = inPolicies
+	return a
+}
 
-	// configure export policy
-	outPolicies := make([]*policy.Policy, 0)
-	for _, policyName := range policyConfig.ExportPolicies {
-		log.WithFields(log.Fields{
-			"Topic":      "Peer",
-			"Key":        peer.peerConfig.NeighborAddress,
-			"PolicyName": policyName,
-		}).Info("export policy installed")
-		if pol, ok := policyMap[policyName]; ok {
-			log.Debug("export policy : ", pol)
-			outPolicies = append(outPolicies, pol)
+// resolve the policies for each route family. the apply-policy of the
+// address family takes precedence over the one of the neighbor. return
+// whether the import and export policies are updated.
+func (peer *Peer) setPolicy(policyMap map[string]*policy.Policy) (bool, bool) {
+	oldImport, oldExport := peer.importPolicies, peer.exportPolicies
+	peer.policyMap = policyMap
+	peer.importPolicies = make(map[bgp.RouteFamily]*appliedPolicies)
+	peer.exportPolicies = make(map[bgp.RouteFamily]*appliedPolicies)
+	for _, a := range peer.peerConfig.AfiSafiList {
+		rf, err := bgp.GetRouteFamily(a.AfiSafiName)
+		if err != nil {
+			continue
+		}
+		policyConfig := config.AfiSafiApplyPolicy(a)
+		importConfig, exportConfig := policyConfig, policyConfig
+		if len(policyConfig.ImportPolicies) == 0 {
+			importConfig = peer.peerConfig.ApplyPolicy
+		}
+		if len(policyConfig.ExportPolicies) == 0 {
+			exportConfig = peer.peerConfig.ApplyPolicy
 		}
+		peer.importPolicies[rf] = peer.newAppliedPolicies("import", rf, importConfig.ImportPolicies, importConfig.DefaultImportPolicy)
+		peer.exportPolicies[rf] = peer.newAppliedPolicies("export", rf, exportConfig.ExportPolicies, exportConfig.DefaultExportPolicy)
 	}
-	peer.exportPolicies = outPolicies
+
+	if !peer.isGlobalRib {
+		peer.setConditionalAdvertisements(policyMap)
+	}
+
+	importUpdated, exportUpdated := false, false
+	for rf, a := range peer.importPolicies {
+		if !a.equal(oldImport[rf]) {
+			importUpdated = true
+		}
+	}
+	for rf, a := range peer.exportPolicies {
+		if !a.equal(oldExport[rf]) {
+			exportUpdated = true
+		}
+	}
+	return importUpdated, exportUpdated
+}
+
+// apply the updated policies to the paths received and advertised
+// before. the paths before the import policies are in the adj-rib-in
+// of the peers sending them, so the siblings are asked to send all the
+// paths again. only the changes made by the policies are advertised.
+func (peer *Peer) softReconfigure(importUpdated, exportUpdated bool) {
+	if !importUpdated && !exportUpdated {
+		return
+	}
+	log.WithFields(log.Fields{
+		"Topic":  "Peer",
+		"Key":    peer.peerConfig.NeighborAddress,
+		"Import": importUpdated,
+		"Export": exportUpdated,
+	}).Info("soft reconfiguration for the updated policies")
+
+	request := &peerMsg{
+		msgType: PEER_MSG_SOFT_RECONFIG,
+		msgData: peer.peerInfo,
+	}
+	switch {
+	case peer.isGlobalRib:
+		// the global export policies are applied to the best
+		// paths sent to all the neighbors
+		if exportUpdated {
+			msg := peer.refreshMsg()
+			for _, s := range peer.siblings {
+				s.peerMsgCh <- msg
+			}
+		}
+		if importUpdated {
+			for _, s := range peer.siblings {
+				s.peerMsgCh <- request
+			}
+		}
+	case peer.peerConfig.RouteServer.RouteServerClient:
+		if importUpdated {
+			for _, s := range peer.siblings {
+				s.peerMsgCh <- request
+			}
+		}
+		if exportUpdated {
+			paths := make([]table.Path, 0)
+			for _, rf := range peer.configuredRFlist() {
+				paths = append(paths, peer.rib.GetPathList(rf)...)
+			}
+			peer.refreshAdjRibOut(paths, paths)
+		}
+	default:
+		// both policies are applied to the best paths of the
+		// global rib
+		for _, s := range peer.siblings {
+			s.peerMsgCh <- request
+		}
+	}
+}
+
+// the paths sent to the siblings for the soft reconfiguration, the
+// best paths of the global rib and the adj-rib-in of the others.
+func (peer *Peer) refreshMsg() *peerMsg {
+	pathList := make([]table.Path, 0)
+	// the paths evaluated again aren't counted
+	options := peer.policyOptions()
+	options.DryRun = true
+	for _, rf := range peer.configuredRFlist() {
+		if peer.isGlobalRib {
+			pathList = append(pathList, peer.exportPolicies[rf].apply(peer.rib.GetPathList(rf), options, false)...)
+		} else {
+			pathList = append(pathList, peer.adjRib.GetInPathList(rf)...)
+		}
+	}
+	return &peerMsg{
+		msgType: PEER_MSG_PATH_REFRESH,
+		msgData: &peerMsgDataRefresh{
+			source:   peer.peerInfo,
+			pathList: pathList,
+		},
+	}
+}
+
+// apply the import policies to all the paths from the source again.
+// the paths not in the RIB any more are withdrawn and the ones not
+// changed by the policies aren't processed.
+func (peer *Peer) reimportPaths(source *table.PeerInfo, pathList []table.Path) {
+	options := peer.policyOptions()
+	options.DryRun = true
+	var imported []table.Path
+	if peer.isGlobalRib {
+		imported = peer.applyImportPolicies(pathList, options)
+	} else {
+		imported = make([]table.Path, 0, len(pathList))
+		for _, p := range pathList {
+			imported = append(imported, peer.importPolicies[p.GetRouteFamily()].apply([]table.Path{p}, options, false)...)
+		}
+	}
+	newPaths := make(map[string]table.Path)
+	for _, p := range imported {
+		newPaths[pathKey(p)] = p
+	}
+	changed := make([]table.Path, 0)
+	for _, rf := range peer.configuredRFlist() {
+		for _, old := range peer.rib.GetPathListBySource(source, rf) {
+			key := pathKey(old)
+			if p, ok := newPaths[key]; !ok {
+				changed = append(changed, old.Clone(true))
+			} else if table.EqualPath(old, p) {
+				delete(newPaths, key)
+			}
+		}
+	}
+	for _, p := range newPaths {
+		changed = append(changed, p)
+	}
+	if len(changed) == 0 {
+		return
+	}
+	paths, _ := peer.rib.ProcessPaths(changed)
+	if peer.isGlobalRib {
+		peer.sendPathsToSiblings(paths)
+	} else {
+		peer.advertisePaths(paths, paths)
+	}
+}
+
+// advertise the difference between the adj-rib-out and the paths
+// exported from all the best paths. watchList is all the paths in the
+// RIB used to evaluate the advertise conditions.
+func (peer *Peer) refreshAdjRibOut(watchList []table.Path, pathList []table.Path) {
+	// the conditions are learned again from all the paths
+	for _, c := range peer.conditionals {
+		c.watched = make(map[string]bool)
+		c.paths = make(map[string]table.Path)
+	}
+	peer.updateConditions(watchList)
+
+	options := peer.policyOptions()
+	options.DryRun = true
+	exported := make(map[string]table.Path)
+	for _, p := range peer.exportPaths(pathList, options) {
+		if !p.IsWithdraw() {
+			exported[pathKey(p)] = p
+		}
+	}
+	paths := make([]table.Path, 0)
+	for _, rf := range peer.configuredRFlist() {
+		for _, old := range peer.adjRib.GetOutPathList(rf) {
+			key := pathKey(old)
+			if p, ok := exported[key]; !ok {
+				paths = append(paths, old.Clone(true))
+			} else if table.EqualPath(old, p) {
+				delete(exported, key)
+			}
+		}
+	}
+	for _, p := range exported {
+		paths = append(paths, p)
+	}
+	peer.sendPaths(paths)
 }
 
 func (peer *Peer) configuredRFlist() []bgp.RouteFamily {
@@ -155,6 +552,17 @@ func (peer *Peer) configuredRFlist() []bgp.RouteFamily {
 }
 
 func (peer *Peer) sendPathsToSiblings(pathList []table.Path) {
+	if peer.isGlobalRib {
+		// the global export policies are applied before the
+		// distribution to all neighbors. the rejected paths are
+		// withdrawn since the previous best paths might be sent.
+		paths := make([]table.Path, 0, len(pathList))
+		options := peer.policyOptions()
+		for _, p := range pathList {
+			paths = append(paths, peer.exportPolicies[p.GetRouteFamily()].apply([]table.Path{p}, options, true)...)
+		}
+		pathList = paths
+	}
 	if len(pathList) == 0 {
 		return
 	}
@@ -255,11 +663,55 @@ func (peer *Peer) handleBGPmessage(m *bgp.BGPMessage) {
 		table.UpdatePathAttrs4ByteAs(body)
 		msg := table.NewProcessMessage(m, peer.peerInfo)
 		pathList := msg.ToPathList()
+		table.UpdateInPathAttrs(pathList, &peer.peerConfig)
+		peer.validatePaths(pathList)
 		peer.adjRib.UpdateIn(pathList)
+		peer.checkPrefixLimit()
 		peer.sendPathsToSiblings(pathList)
 	}
 }
 
+// check the number of the paths received from the neighbor against
+// the prefix limit of each address family. the limit isn't enforced;
+// the warning is published once when the number reaches the threshold
+// and the event is published once when the number exceeds the limit.
+func (peer *Peer) checkPrefixLimit() {
+	for _, a := range peer.peerConfig.AfiSafiList {
+		limit := config.AfiSafiPrefixLimit(a)
+		if limit.MaxPrefixes == 0 {
+			continue
+		}
+		rf, _ := bgp.GetRouteFamily(a.AfiSafiName)
+		count := peer.adjRib.GetInCount(rf)
+		eventType := ""
+		threshold := int(limit.MaxPrefixes) * int(limit.ShutdownThresholdPct) / 100
+		switch {
+		case count > int(limit.MaxPrefixes):
+			eventType = PEER_EVENT_PREFIX_LIMIT_EXCEEDED
+		case limit.ShutdownThresholdPct > 0 && count >= threshold:
+			eventType = PEER_EVENT_PREFIX_LIMIT_WARNING
+		}
+		if eventType == "" {
+			delete(peer.prefixLimitEvents, rf)
+			continue
+		}
+		if peer.prefixLimitEvents[rf] == eventType {
+			continue
+		}
+		peer.prefixLimitEvents[rf] = eventType
+		if eventType == PEER_EVENT_PREFIX_LIMIT_EXCEEDED {
+			log.WithFields(log.Fields{
+				"Topic":       "Peer",
+				"Key":         peer.peerConfig.NeighborAddress,
+				"RouteFamily": rf,
+				"Prefixes":    count,
+				"MaxPrefixes": limit.MaxPrefixes,
+			}).Warn("prefix limit exceeded")
+		}
+		peer.fsm.events.publish(newPrefixLimitEvent(eventType, &peer.peerConfig, rf, count, limit))
+	}
+}
+
 func (peer *Peer) sendMessages(msgs []*bgp.BGPMessage) {
 	for _, m := range msgs {
 		if peer.peerConfig.BgpNeighborCommonState.State != uint32(bgp.BGP_FSM_ESTABLISHED) {
@@ -285,72 +737,97 @@ func (peer *Peer) sendMessages(msgs []*bgp.BGPMessage) {
 }
 
 func (peer *Peer) handleREST(restReq *api.RestRequest) {
+	if restReq.RequestType == api.REQ_GLOBAL_RIB_WATCH {
+		// the response channel is kept open to send the events
+		peer.addWatcher(&restClient{restReq}, restReq.RouteFamily, restReq.Snapshot)
+		return
+	}
 	result := &api.RestResponse{}
+	dump, err := peer.ribDump(restReq)
+	if err != nil {
+		result.ResponseErr = err
+		restReq.ResponseCh <- result
+		close(restReq.ResponseCh)
+		return
+	}
+	if dump != nil {
+		// large pages are marshaled without blocking the rib
+		go func() {
+			result.Data, result.ResponseErr = dump.Marshal()
+			restReq.ResponseCh <- result
+			close(restReq.ResponseCh)
+		}()
+		return
+	}
 	switch restReq.RequestType {
 	case api.REQ_LOCAL_RIB, api.REQ_GLOBAL_RIB:
-		// just empty so we use ipv4 for any route family
-		j, _ := json.Marshal(table.NewIPv4Table(0))
+		destList := make([]table.Destination, 0)
 		if peer.fsm.adminState != ADMIN_STATE_DOWN {
-			if t, ok := peer.rib.Tables[restReq.RouteFamily]; ok {
-				j, _ = json.Marshal(t)
+			destList, err = peer.rib.Lookup(restReq.RouteFamily, restReq.Prefix, table.LookupOption(restReq.Lookup))
+			if err != nil {
+				result.ResponseErr = err
+				break
 			}
 		}
+		j, _ := json.Marshal(struct {
+			Destinations []table.Destination
+		}{
+			Destinations: destList,
+		})
 		result.Data = j
-	case api.REQ_NEIGHBOR_SHUTDOWN:
-		peer.outgoing <- bgp.NewBGPNotificationMessage(bgp.BGP_ERROR_CEASE, bgp.BGP_ERROR_SUB_ADMINISTRATIVE_SHUTDOWN, nil)
-	case api.REQ_NEIGHBOR_RESET:
-		peer.fsm.idleHoldTime = peer.peerConfig.Timers.IdleHoldTimeAfterReset
-		peer.outgoing <- bgp.NewBGPNotificationMessage(bgp.BGP_ERROR_CEASE, bgp.BGP_ERROR_SUB_ADMINISTRATIVE_RESET, nil)
-	case api.REQ_NEIGHBOR_SOFT_RESET, api.REQ_NEIGHBOR_SOFT_RESET_IN:
-		// soft-reconfiguration inbound
-		peer.sendPathsToSiblings(peer.adjRib.GetInPathList(restReq.RouteFamily))
-		if restReq.RequestType == api.REQ_NEIGHBOR_SOFT_RESET_IN {
+	case api.REQ_LOCAL_RIB_EXPLAIN, api.REQ_GLOBAL_RIB_EXPLAIN:
+		e, err := peer.rib.Explain(restReq.RouteFamily, restReq.Prefix)
+		if err != nil {
+			result.ResponseErr = err
 			break
 		}
-		fallthrough
-	case api.REQ_NEIGHBOR_SOFT_RESET_OUT:
-		pathList := peer.adjRib.GetOutPathList(restReq.RouteFamily)
-		peer.sendMessages(table.CreateUpdateMsgFromPaths(pathList))
+		j, _ := json.Marshal(e)
+		result.Data = j
+	case api.REQ_ADJ_RIB_IN_FILTERED:
+		j, _ := json.Marshal(peer.adjRib.GetInFilteredList(restReq.RouteFamily))
+		result.Data = j
+	case api.REQ_NEIGHBOR_POLICY_COUNTERS:
+		j, _ := json.Marshal(struct {
+			Import policyCounters
+			Export policyCounters
+		}{
+			Import: peer.importCounters,
+			Export: peer.exportCounters,
+		})
+		result.Data = j
+	case api.REQ_NEIGHBOR_POLICY_TEST_IMPORT, api.REQ_NEIGHBOR_POLICY_TEST_EXPORT:
+		r, err := peer.testPolicy(restReq.Data, restReq.RequestType == api.REQ_NEIGHBOR_POLICY_TEST_IMPORT)
+		if err != nil {
+			result.ResponseErr = err
+			break
+		}
+		j, _ := json.Marshal(r)
+		result.Data = j
+	case api.REQ_NEIGHBOR_SHUTDOWN, api.REQ_NEIGHBOR_RESET,
+		api.REQ_NEIGHBOR_SOFT_RESET, api.REQ_NEIGHBOR_SOFT_RESET_IN, api.REQ_NEIGHBOR_SOFT_RESET_OUT:
+		peer.operate(restReq.RequestType, restReq.RouteFamily)
 	case api.REQ_ADJ_RIB_IN, api.REQ_ADJ_RIB_OUT:
 		adjrib := make(map[string][]table.Path)
 		rf := restReq.RouteFamily
+		lookup := peer.adjRib.LookupOut
 		if restReq.RequestType == api.REQ_ADJ_RIB_IN {
-			paths := peer.adjRib.GetInPathList(rf)
-			adjrib[rf.String()] = paths
-			log.Debugf("RouteFamily=%v adj-rib-in found : %d", rf.String(), len(paths))
-		} else {
-			paths := peer.adjRib.GetOutPathList(rf)
-			adjrib[rf.String()] = paths
-			log.Debugf("RouteFamily=%v adj-rib-out found : %d", rf.String(), len(paths))
+			lookup = peer.adjRib.LookupIn
 		}
+		paths, err := lookup(rf, restReq.Prefix, table.LookupOption(restReq.Lookup))
+		if err != nil {
+			result.ResponseErr = err
+			break
+		}
+		adjrib[rf.String()] = paths
 		j, _ := json.Marshal(adjrib)
 		result.Data = j
 	case api.REQ_NEIGHBOR_ENABLE, api.REQ_NEIGHBOR_DISABLE:
 		r := make(map[string]string)
-		if restReq.RequestType == api.REQ_NEIGHBOR_ENABLE {
-			select {
-			case peer.fsm.adminStateCh <- ADMIN_STATE_UP:
-				log.WithFields(log.Fields{
-					"Topic": "Peer",
-					"Key":   peer.peerConfig.NeighborAddress,
-				}).Debug("ADMIN_STATE_UP requested")
-				r["result"] = "ADMIN_STATE_UP"
-			default:
-				log.Warning("previous request is still remaining. : ", peer.peerConfig.NeighborAddress)
-				r["result"] = "previous request is still remaining"
-			}
+		state, err := peer.operate(restReq.RequestType, restReq.RouteFamily)
+		if err != nil {
+			r["result"] = err.Error()
 		} else {
-			select {
-			case peer.fsm.adminStateCh <- ADMIN_STATE_DOWN:
-				log.WithFields(log.Fields{
-					"Topic": "Peer",
-					"Key":   peer.peerConfig.NeighborAddress,
-				}).Debug("ADMIN_STATE_DOWN requested")
-				r["result"] = "ADMIN_STATE_DOWN"
-			default:
-				log.Warning("previous request is still remaining. : ", peer.peerConfig.NeighborAddress)
-				r["result"] = "previous request is still remaining"
-			}
+			r["result"] = state
 		}
 		j, _ := json.Marshal(r)
 		result.Data = j
@@ -359,44 +836,219 @@ func (peer *Peer) handleREST(restReq *api.RestRequest) {
 	close(restReq.ResponseCh)
 }
 
-func (peer *Peer) sendUpdateMsgFromPaths(pList []table.Path) {
-	pList = table.CloneAndUpdatePathAttrs(pList, &peer.globalConfig, &peer.peerConfig)
+func ribDumpOptions(o *api.RibDumpOptions) *table.RibDumpOptions {
+	return &table.RibDumpOptions{
+		Cursor:    o.Cursor,
+		Limit:     o.Limit,
+		Neighbor:  o.Neighbor,
+		Community: o.Community,
+		AsPath:    o.AsPath,
+		Nexthop:   o.Nexthop,
+		BestOnly:  o.BestOnly,
+	}
+}
 
-	paths := []table.Path{}
-	policies := peer.exportPolicies
-	log.WithFields(log.Fields{
-		"Topic": "Peer",
-		"Key":   peer.peerConfig.NeighborAddress,
-	}).Debug("Export Policies :", policies)
-	for _, p := range pList {
-		if p.IsWithdraw() {
-			paths = append(paths, p)
-			continue
+// the copy of the page of the rib dumped for the request, nil if the
+// request isn't for the whole rib
+func (peer *Peer) ribDump(req *api.RestRequest) (*table.RibDump, error) {
+	if req.Prefix != "" {
+		return nil, nil
+	}
+	o := ribDumpOptions(&req.Dump)
+	switch req.RequestType {
+	case api.REQ_LOCAL_RIB, api.REQ_GLOBAL_RIB:
+		var t table.Table
+		if peer.fsm.adminState != ADMIN_STATE_DOWN {
+			t = peer.rib.Tables[req.RouteFamily]
 		}
-		log.Debug("p: ", p)
-		if len(policies) != 0 {
-			applied, newPath := applyPolicies(policies, &p)
+		return table.NewRibDump(t, o)
+	case api.REQ_ADJ_RIB_IN:
+		return peer.adjRib.NewInDump(req.RouteFamily, o)
+	case api.REQ_ADJ_RIB_OUT:
+		return peer.adjRib.NewOutDump(req.RouteFamily, o)
+	}
+	return nil, nil
+}
 
-			if applied {
-				if newPath != nil {
-					log.Debug("path accepted")
-					paths = append(paths, *newPath)
-				} else {
-					log.Debug("path was rejected: ", p)
+func (peer *Peer) handleGrpc(grpcReq *api.GrpcRequest) {
+	if grpcReq.RequestType == api.REQ_GLOBAL_RIB_WATCH {
+		// the response channel is kept open to send the events
+		peer.addWatcher(&grpcClient{grpcReq}, grpcReq.RouteFamily, grpcReq.Snapshot)
+		return
+	}
+	rf := grpcReq.RouteFamily
+	results := make([]*api.GrpcResponse, 0)
+	switch grpcReq.RequestType {
+	case api.REQ_LOCAL_RIB, api.REQ_GLOBAL_RIB:
+		var t table.Table
+		if peer.fsm.adminState != ADMIN_STATE_DOWN {
+			t = peer.rib.Tables[rf]
+		}
+		dump, _ := table.NewRibDump(t, &table.RibDumpOptions{})
+		// the paths are copied in the loop and converted in the
+		// goroutine sending them
+		streamGrpcResponses(grpcReq, func(send func(*api.GrpcResponse)) {
+			dump.Visit(func(nlri bgp.AddrPrefixInterface, paths []table.Path, best table.Path) {
+				send(&api.GrpcResponse{Data: newApiDestination(nlri, paths, best)})
+			})
+		})
+		return
+	case api.REQ_ADJ_RIB_IN, api.REQ_ADJ_RIB_OUT:
+		var dump *table.RibDump
+		if grpcReq.RequestType == api.REQ_ADJ_RIB_IN {
+			dump, _ = peer.adjRib.NewInDump(rf, &table.RibDumpOptions{})
+		} else {
+			dump, _ = peer.adjRib.NewOutDump(rf, &table.RibDumpOptions{})
+		}
+		streamGrpcResponses(grpcReq, func(send func(*api.GrpcResponse)) {
+			dump.Visit(func(nlri bgp.AddrPrefixInterface, paths []table.Path, best table.Path) {
+				for _, p := range paths {
+					send(&api.GrpcResponse{Data: newApiPath(p)})
 				}
+			})
+		})
+		return
+	case api.REQ_NEIGHBOR_SHUTDOWN, api.REQ_NEIGHBOR_RESET,
+		api.REQ_NEIGHBOR_SOFT_RESET, api.REQ_NEIGHBOR_SOFT_RESET_IN, api.REQ_NEIGHBOR_SOFT_RESET_OUT,
+		api.REQ_NEIGHBOR_ENABLE, api.REQ_NEIGHBOR_DISABLE:
+		result := &api.Error{}
+		state, err := peer.operate(grpcReq.RequestType, rf)
+		if err != nil {
+			result.Code = api.Error_FAIL
+			result.Msg = err.Error()
+		} else {
+			result.Msg = state
+		}
+		results = append(results, &api.GrpcResponse{Data: result})
+	}
+	sendGrpcResponses(grpcReq, results)
+}
 
-			} else {
-				if peer.defaultExportPolicy == config.DEFAULT_POLICY_TYPE_ACCEPT_ROUTE {
-					paths = append(paths, p)
-					log.Debug("path is emitted by default export policy: ", p)
+func newApiPath(path table.Path) *api.Path {
+	afi, safi := bgp.RouteFamilyToAfiSafi(path.GetRouteFamily())
+	p := &api.Path{
+		Prefix:      path.GetPrefix(),
+		Family:      &api.AddressFamily{Afi: api.AFI(afi), Safi: api.SAFI(safi)},
+		Nexthop:     path.GetNexthop().String(),
+		IsWithdraw:  path.IsWithdraw(),
+		Age:         int64(time.Now().Sub(path.GetTimestamp()).Seconds()),
+		Validation:  api.ValidationResult(path.GetValidation()),
+		Communities: path.GetCommunities(),
+	}
+	if source := path.GetSource(); source != nil {
+		p.SourceAs = source.AS
+		if source.Address != nil {
+			p.SourceAddress = source.Address.String()
+		}
+	}
+	if origin, err := path.GetOrigin(); err == nil {
+		p.Origin = api.Origin(origin)
+	}
+	if med, err := path.GetMed(); err == nil {
+		p.Med = &med
+	}
+	if localPref, err := path.GetLocalPref(); err == nil {
+		p.LocalPref = &localPref
+	}
+	if nlri := path.GetNlri(); nlri != nil {
+		p.Nlri, _ = nlri.Serialize()
+	}
+	for _, a := range path.GetPathAttrs() {
+		if asPath, ok := a.(*bgp.PathAttributeAsPath); ok {
+			for _, param := range asPath.Value {
+				segment := &api.AsPathSegment{}
+				switch a := param.(type) {
+				case *bgp.AsPathParam:
+					segment.Type = uint32(a.Type)
+					for _, as := range a.AS {
+						segment.Asns = append(segment.Asns, uint32(as))
+					}
+				case *bgp.As4PathParam:
+					segment.Type = uint32(a.Type)
+					segment.Asns = a.AS
 				}
+				p.AsPath = append(p.AsPath, segment)
 			}
-		} else {
-			paths = append(paths, p)
 		}
+		if b, err := a.Serialize(); err == nil {
+			p.Pattrs = append(p.Pattrs, b)
+		}
+	}
+	return p
+}
 
+func newApiDestination(nlri bgp.AddrPrefixInterface, paths []table.Path, best table.Path) *api.Destination {
+	d := &api.Destination{
+		Prefix:      nlri.String(),
+		Paths:       make([]*api.Path, 0, len(paths)),
+		BestPathIdx: -1,
 	}
+	for i, p := range paths {
+		if p == best {
+			d.BestPathIdx = int32(i)
+		}
+		d.Paths = append(d.Paths, newApiPath(p))
+	}
+	return d
+}
 
+// the operations on the session requested through the apis. the admin
+// state requested is returned when enabling or disabling the neighbor.
+func (peer *Peer) operate(reqType int, rf bgp.RouteFamily) (string, error) {
+	switch reqType {
+	case api.REQ_NEIGHBOR_SHUTDOWN:
+		peer.outgoing <- bgp.NewBGPNotificationMessage(bgp.BGP_ERROR_CEASE, bgp.BGP_ERROR_SUB_ADMINISTRATIVE_SHUTDOWN, nil)
+	case api.REQ_NEIGHBOR_RESET:
+		peer.fsm.idleHoldTime = peer.peerConfig.Timers.IdleHoldTimeAfterReset
+		peer.outgoing <- bgp.NewBGPNotificationMessage(bgp.BGP_ERROR_CEASE, bgp.BGP_ERROR_SUB_ADMINISTRATIVE_RESET, nil)
+	case api.REQ_NEIGHBOR_SOFT_RESET, api.REQ_NEIGHBOR_SOFT_RESET_IN:
+		// soft-reconfiguration inbound
+		peer.sendPathsToSiblings(peer.adjRib.GetInPathList(rf))
+		if reqType == api.REQ_NEIGHBOR_SOFT_RESET_IN {
+			break
+		}
+		fallthrough
+	case api.REQ_NEIGHBOR_SOFT_RESET_OUT:
+		pathList := peer.adjRib.GetOutPathList(rf)
+		peer.sendMessages(table.CreateUpdateMsgFromPaths(pathList))
+	case api.REQ_NEIGHBOR_ENABLE, api.REQ_NEIGHBOR_DISABLE:
+		state := ADMIN_STATE_UP
+		if reqType == api.REQ_NEIGHBOR_DISABLE {
+			state = ADMIN_STATE_DOWN
+		}
+		select {
+		case peer.fsm.adminStateCh <- state:
+			log.WithFields(log.Fields{
+				"Topic": "Peer",
+				"Key":   peer.peerConfig.NeighborAddress,
+			}).Debug(state.String() + " requested")
+			return state.String(), nil
+		default:
+			log.Warning("previous request is still remaining. : ", peer.peerConfig.NeighborAddress)
+			return "", fmt.Errorf("previous request is still remaining")
+		}
+	}
+	return "", nil
+}
+
+func (peer *Peer) sendUpdateMsgFromPaths(pList []table.Path) {
+	peer.sendPaths(peer.exportPaths(pList, peer.policyOptions()))
+}
+
+// apply the advertise conditions and the export policies to the paths
+func (peer *Peer) exportPaths(pList []table.Path, options *policy.PolicyOptions) []table.Path {
+	pList = peer.applyConditions(pList)
+	pList = table.CloneAndUpdatePathAttrs(pList, &peer.globalConfig, &peer.peerConfig)
+
+	paths := []table.Path{}
+	for _, p := range pList {
+		paths = append(paths, peer.exportPolicies[p.GetRouteFamily()].apply([]table.Path{p}, options, false)...)
+	}
+	return paths
+}
+
+// update the adj-rib-out with the paths and send them
+func (peer *Peer) sendPaths(paths []table.Path) {
 	peer.adjRib.UpdateOut(paths)
 	sendpathList := []table.Path{}
 	for _, p := range paths {
@@ -431,20 +1083,20 @@ func (peer *Peer) sendUpdateMsgFromPaths(pList []table.Path) {
 // if no policy applied, return value that indicates 'not applied' to the caller of this function
 //
 // return values:
-//	bool -- indicates that any of policy applied to the path that is passed to this function
-//  table.Path -- indicates new path object that is the result of modification according to
-//                policy's action.
-//                If the applied policy doesn't have a modification action,
-//                then return the path itself that is passed to this function, otherwise return
-//                modified path.
-//                If action of the policy is 'reject', return nil
 //
-func applyPolicies(policies []*policy.Policy, original *table.Path) (bool, *table.Path) {
+//		bool -- indicates that any of policy applied to the path that is passed to this function
+//	 table.Path -- indicates new path object that is the result of modification according to
+//	               policy's action.
+//	               If the applied policy doesn't have a modification action,
+//	               then return the path itself that is passed to this function, otherwise return
+//	               modified path.
+//	               If action of the policy is 'reject', return nil
+func applyPolicies(policies []*policy.Policy, original *table.Path, options *policy.PolicyOptions) (bool, *table.Path) {
 
 	var applied bool = true
 
 	for _, pol := range policies {
-		if result, action, newpath := pol.Apply(*original); result {
+		if result, action, newpath := pol.Apply(*original, options); result {
 			log.Debug("newpath: ", newpath)
 			if action == policy.ROUTE_TYPE_REJECT {
 				log.Debug("path was rejected: ", original)
@@ -461,43 +1113,63 @@ func applyPolicies(policies []*policy.Policy, original *table.Path) (bool, *tabl
 	return !applied, original
 }
 
+// the session information referred by the policy actions
+func (peer *Peer) policyOptions() *policy.PolicyOptions {
+	return &policy.PolicyOptions{
+		LocalAs:         peer.globalConfig.As,
+		LocalAddress:    peer.peerConfig.LocalAddress,
+		NeighborAddress: peer.peerConfig.NeighborAddress,
+	}
+}
+
+// apply the import policies of the global rib to the paths, and tell
+// the results to the peers sending the paths to mark the paths
+// filtered in their adj-rib-in. the paths to the route server clients
+// aren't marked since each client has its own import policies.
+func (peer *Peer) applyImportPolicies(pList []table.Path, options *policy.PolicyOptions) []table.Path {
+	paths := make([]table.Path, 0, len(pList))
+	filtered := make(map[string][]*peerMsgDataFiltered)
+	for _, p := range pList {
+		if p.IsWithdraw() {
+			paths = append(paths, p)
+			continue
+		}
+		r := peer.importPolicies[p.GetRouteFamily()].applyPath(p, options)
+		if r == nil {
+			r = &policyResult{Action: POLICY_RESULT_DEFAULT_ACCEPT, Path: p}
+		}
+		if r.accepted() {
+			paths = append(paths, r.Path)
+		} else {
+			log.Debug("path was rejected: ", p)
+		}
+		if source := p.GetSource(); source != nil {
+			key := source.Address.String()
+			filtered[key] = append(filtered[key], &peerMsgDataFiltered{path: p, result: r})
+		}
+	}
+	for key, l := range filtered {
+		if s, ok := peer.siblings[key]; ok {
+			s.peerMsgCh <- &peerMsg{
+				msgType: PEER_MSG_PATH_FILTERED,
+				msgData: l,
+			}
+		}
+	}
+	return paths
+}
+
 func (peer *Peer) handlePeerMsg(m *peerMsg) {
 	switch m.msgType {
 	case PEER_MSG_PATH:
 		pList := m.msgData.([]table.Path)
 		paths := []table.Path{}
-
-		policies := peer.importPolicies
-		log.WithFields(log.Fields{
-			"Topic": "Peer",
-			"Key":   peer.peerConfig.NeighborAddress,
-		}).Debug("Import Policies :", policies)
-
-		for _, p := range pList {
-			log.Debug("p: ", p)
-			if !p.IsWithdraw() {
-				log.Debug("is not withdraw")
-
-				if len(policies) != 0 {
-					applied, newPath := applyPolicies(policies, &p)
-
-					if applied {
-						if newPath != nil {
-							log.Debug("path accepted")
-							paths = append(paths, *newPath)
-						}
-					} else {
-						if peer.defaultImportPolicy == config.DEFAULT_POLICY_TYPE_ACCEPT_ROUTE {
-							paths = append(paths, p)
-							log.Debug("path accepted by default import policy: ", p)
-						}
-					}
-				} else {
-					paths = append(paths, p)
-				}
-			} else {
-				log.Debug("is withdraw")
-				paths = append(paths, p)
+		options := peer.policyOptions()
+		if peer.isGlobalRib {
+			paths = peer.applyImportPolicies(pList, options)
+		} else {
+			for _, p := range pList {
+				paths = append(paths, peer.importPolicies[p.GetRouteFamily()].apply([]table.Path{p}, options, false)...)
 			}
 		}
 		log.Debug("length of paths: ", len(paths))
@@ -508,15 +1180,43 @@ func (peer *Peer) handlePeerMsg(m *peerMsg) {
 
 		if peer.isGlobalRib {
 			peer.sendPathsToSiblings(paths)
+		} else if peer.peerConfig.RouteServer.RouteServerClient {
+			peer.advertisePaths(paths, paths)
+		} else {
+			// the paths from the global rib are the best path
+			// changes before applying the policies
+			peer.advertisePaths(pList, paths)
+		}
+
+	case PEER_MSG_PATH_FILTERED:
+		for _, d := range m.msgData.([]*peerMsgDataFiltered) {
+			peer.adjRib.SetInFiltered(d.path, !d.result.accepted(), d.result.Policy, d.result.Statement)
+		}
+
+	case PEER_MSG_SOFT_RECONFIG:
+		d := m.msgData.(*table.PeerInfo)
+		if s, ok := peer.siblings[d.Address.String()]; ok {
+			s.peerMsgCh <- peer.refreshMsg()
+		}
+
+	case PEER_MSG_PATH_REFRESH:
+		d := m.msgData.(*peerMsgDataRefresh)
+		if peer.isGlobalRib || peer.peerConfig.RouteServer.RouteServerClient {
+			peer.reimportPaths(d.source, d.pathList)
 		} else {
-			peer.sendUpdateMsgFromPaths(paths)
+			paths := []table.Path{}
+			options := peer.policyOptions()
+			for _, p := range d.pathList {
+				paths = append(paths, peer.importPolicies[p.GetRouteFamily()].apply([]table.Path{p}, options, false)...)
+			}
+			peer.refreshAdjRibOut(d.pathList, paths)
 		}
 
 	case PEER_MSG_PEER_DOWN:
 		for _, rf := range peer.configuredRFlist() {
 			pList, _ := peer.rib.DeletePathsforPeer(m.msgData.(*table.PeerInfo), rf)
 			if peer.peerConfig.RouteServer.RouteServerClient {
-				peer.sendUpdateMsgFromPaths(pList)
+				peer.advertisePaths(pList, pList)
 			} else if peer.isGlobalRib {
 				peer.sendPathsToSiblings(pList)
 			}
@@ -524,6 +1224,39 @@ func (peer *Peer) handlePeerMsg(m *peerMsg) {
 	}
 }
 
+// set the RPKI validation results of the paths received from the
+// neighbor
+func (peer *Peer) validatePaths(pathList []table.Path) {
+	if peer.roaTable == nil {
+		return
+	}
+	for _, path := range pathList {
+		if !path.IsWithdraw() {
+			path.SetValidation(peer.roaTable.validate(path, peer.globalConfig.As))
+		}
+	}
+}
+
+// validate the adj-rib-in again after the ROAs are updated. the paths
+// are already shared with the siblings so the ones whose results
+// changed are cloned and sent again.
+func (peer *Peer) revalidatePaths() {
+	changed := make([]table.Path, 0)
+	for _, rf := range peer.configuredRFlist() {
+		for _, path := range peer.adjRib.GetInPathList(rf) {
+			if v := peer.roaTable.validate(path, peer.globalConfig.As); v != path.GetValidation() {
+				p := path.Clone(false)
+				p.SetValidation(v)
+				changed = append(changed, p)
+			}
+		}
+	}
+	if len(changed) > 0 {
+		peer.adjRib.UpdateIn(changed)
+		peer.sendPathsToSiblings(changed)
+	}
+}
+
 func (peer *Peer) handleServerMsg(m *serverMsg) {
 	switch m.msgType {
 	case SRV_MSG_PEER_ADDED:
@@ -543,7 +1276,7 @@ func (peer *Peer) handleServerMsg(m *serverMsg) {
 			for _, rf := range peer.configuredRFlist() {
 				pList, _ := peer.rib.DeletePathsforPeer(d, rf)
 				if peer.peerConfig.RouteServer.RouteServerClient {
-					peer.sendUpdateMsgFromPaths(pList)
+					peer.advertisePaths(pList, pList)
 				} else {
 					peer.sendPathsToSiblings(pList)
 				}
@@ -552,11 +1285,46 @@ func (peer *Peer) handleServerMsg(m *serverMsg) {
 			log.Warning("can not find peer: ", d.Address.String())
 		}
 	case SRV_MSG_API:
-		peer.handleREST(m.msgData.(*api.RestRequest))
+		switch req := m.msgData.(type) {
+		case *api.RestRequest:
+			peer.handleREST(req)
+		case *api.GrpcRequest:
+			peer.handleGrpc(req)
+		}
 	case SRV_MSG_POLICY_UPDATED:
 		log.Debug("policy updated")
 		d := m.msgData.(map[string]*policy.Policy)
-		peer.setPolicy(d)
+		peer.softReconfigure(peer.setPolicy(d))
+	case SRV_MSG_GLOBAL_POLICY_UPDATED:
+		d := m.msgData.(*serverMsgDataGlobalPolicy)
+		peer.peerConfig.ApplyPolicy = d.applyPolicy
+		peer.softReconfigure(peer.setPolicy(d.policyMap))
+	case SRV_MSG_AGGREGATES_UPDATED:
+		g := m.msgData.(config.Global)
+		peer.sendPathsToSiblings(peer.rib.SetAggregates(&g))
+	case SRV_MSG_ROUTE_SELECTION_UPDATED:
+		g := m.msgData.(config.Global)
+		pathList := peer.rib.SetRouteSelection(&g)
+		if peer.isGlobalRib {
+			peer.sendPathsToSiblings(pathList)
+		} else {
+			peer.advertisePaths(pathList, pathList)
+		}
+	case SRV_MSG_NEXTHOP_RESOLVER:
+		peer.sendPathsToSiblings(peer.rib.SetNexthopResolver(m.msgData.(table.NexthopResolver)))
+	case SRV_MSG_NEXTHOPS_UPDATED:
+		peer.sendPathsToSiblings(peer.rib.UpdateNexthops(m.msgData.([]net.IP)))
+	case SRV_MSG_RPKI_UPDATED:
+		peer.roaTable = m.msgData.(*roaTable)
+		peer.revalidatePaths()
+	case SRV_MSG_PEER_UPDATED:
+		c := m.msgData.(config.Neighbor)
+		peer.peerConfig.Description = c.Description
+		peer.peerConfig.ApplyPolicy = c.ApplyPolicy
+		peer.peerConfig.ConditionalAdvertisementList = c.ConditionalAdvertisementList
+		peer.peerConfig.AfiSafiList = c.AfiSafiList
+		peer.softReconfigure(peer.setPolicy(peer.policyMap))
+		peer.checkPrefixLimit()
 	default:
 		log.Fatal("unknown server msg type ", m.msgType)
 	}
@@ -671,6 +1439,7 @@ func (peer *Peer) loop() error {
 						for _, rf := range peer.configuredRFlist() {
 							peer.adjRib.DropAllIn(rf)
 						}
+						peer.prefixLimitEvents = make(map[bgp.RouteFamily]string)
 						pm := &peerMsg{
 							msgType: PEER_MSG_PEER_DOWN,
 							msgData: peer.peerInfo,
@@ -762,7 +1531,7 @@ func (peer *Peer) MarshalJSON() ([]byte, error) {
 		for _, rf := range peer.configuredRFlist() {
 			advertized += uint32(peer.adjRib.GetOutCount(rf))
 			received += uint32(peer.adjRib.GetInCount(rf))
-			accepted += uint32(peer.adjRib.GetInCount(rf))
+			accepted += uint32(peer.adjRib.GetInCount(rf) - peer.adjRib.GetInFilteredCount(rf))
 		}
 	}
 
@@ -822,3 +1591,76 @@ func (peer *Peer) MarshalJSON() ([]byte, error) {
 
 	return json.Marshal(p)
 }
+
+func (peer *Peer) ToApiStruct() *api.Peer {
+
+	f := peer.fsm
+	c := f.peerConfig
+
+	remoteCap := make([]uint32, 0, len(peer.capMap))
+	for k, _ := range peer.capMap {
+		remoteCap = append(remoteCap, uint32(k))
+	}
+
+	conf := &api.PeerConf{
+		RemoteIp:  c.NeighborAddress.String(),
+		Id:        peer.peerInfo.ID.To4().String(),
+		RemoteAs:  c.PeerAs,
+		RemoteCap: remoteCap,
+		LocalCap:  []uint32{uint32(bgp.BGP_CAP_MULTIPROTOCOL), uint32(bgp.BGP_CAP_ROUTE_REFRESH), uint32(bgp.BGP_CAP_FOUR_OCTET_AS_NUMBER)},
+	}
+
+	s := c.BgpNeighborCommonState
+
+	uptime := int64(0)
+	if s.Uptime != 0 {
+		uptime = int64(time.Now().Sub(time.Unix(s.Uptime, 0)).Seconds())
+	}
+	downtime := int64(0)
+	if s.Downtime != 0 {
+		downtime = int64(time.Now().Sub(time.Unix(s.Downtime, 0)).Seconds())
+	}
+
+	advertized := uint32(0)
+	received := uint32(0)
+	accepted := uint32(0)
+	if f.state == bgp.BGP_FSM_ESTABLISHED {
+		for _, rf := range peer.configuredRFlist() {
+			advertized += uint32(peer.adjRib.GetOutCount(rf))
+			received += uint32(peer.adjRib.GetInCount(rf))
+			accepted += uint32(peer.adjRib.GetInCount(rf) - peer.adjRib.GetInFilteredCount(rf))
+		}
+	}
+
+	info := &api.PeerInfo{
+		BgpState:                  f.state.String(),
+		AdminState:                f.adminState.String(),
+		FsmEstablishedTransitions: s.EstablishedCount,
+		TotalMessageOut:           s.TotalOut,
+		TotalMessageIn:            s.TotalIn,
+		UpdateMessageOut:          s.UpdateOut,
+		UpdateMessageIn:           s.UpdateIn,
+		KeepaliveMessageOut:       s.KeepaliveOut,
+		KeepaliveMessageIn:        s.KeepaliveIn,
+		OpenMessageOut:            s.OpenOut,
+		OpenMessageIn:             s.OpenIn,
+		NotificationOut:           s.NotifyOut,
+		NotificationIn:            s.NotifyIn,
+		RefreshMessageOut:         s.RefreshOut,
+		RefreshMessageIn:          s.RefreshIn,
+		DiscardedOut:              s.DiscardedOut,
+		DiscardedIn:               s.DiscardedIn,
+		Uptime:                    uptime,
+		Downtime:                  downtime,
+		Received:                  received,
+		Accepted:                  accepted,
+		Advertized:                advertized,
+		OutQ:                      uint32(len(peer.outgoing)),
+		Flops:                     s.Flops,
+	}
+
+	return &api.Peer{
+		Conf: conf,
+		Info: info,
+	}
+}
@@ -51,6 +51,33 @@ func createMpReach(nexthop string, prefix []bgp.AddrPrefixInterface) *bgp.PathAt
 	return mp_reach
 }
 
+// the path received from 10.0.0.1 in AS 65001. the IPv4 NLRI is sent
+// with NEXT_HOP and the others with MP_REACH_NLRI. the AS path in the
+// attributes replaces the default one.
+func testPath(nlri bgp.AddrPrefixInterface, attrs ...bgp.PathAttributeInterface) table.Path {
+	peer := &table.PeerInfo{AS: 65001, Address: net.ParseIP("10.0.0.1")}
+	aspath := createAsPathAttribute([]uint32{65001})
+	others := make([]bgp.PathAttributeInterface, 0, len(attrs))
+	for _, a := range attrs {
+		if p, ok := a.(*bgp.PathAttributeAsPath); ok {
+			aspath = p
+		} else {
+			others = append(others, a)
+		}
+	}
+	pathAttributes := []bgp.PathAttributeInterface{bgp.NewPathAttributeOrigin(0), aspath}
+	nlriList := []bgp.NLRInfo{}
+	if n, ok := nlri.(*bgp.NLRInfo); ok {
+		pathAttributes = append(pathAttributes, bgp.NewPathAttributeNextHop("10.0.0.1"))
+		nlriList = append(nlriList, *n)
+	} else {
+		pathAttributes = append(pathAttributes, createMpReach("2001::1", []bgp.AddrPrefixInterface{nlri}))
+	}
+	pathAttributes = append(pathAttributes, others...)
+	msg := table.NewProcessMessage(bgp.NewBGPUpdateMessage([]bgp.WithdrawnRoute{}, pathAttributes, nlriList), peer)
+	return msg.ToPathList()[0]
+}
+
 func update_fromRC3() *bgp.BGPMessage {
 	pathAttributes := []bgp.PathAttributeInterface{
 		bgp.NewPathAttributeOrigin(1),
@@ -532,3 +559,354 @@ func makePeer(globalConfig config.Global, peerConfig config.Neighbor) *Peer {
 
 	return p
 }
+
+func TestPeerSetPolicyPerRouteFamily(t *testing.T) {
+	assert := assert.New(t)
+	peer := &Peer{
+		peerConfig: config.Neighbor{
+			NeighborAddress: net.ParseIP("10.0.0.2"),
+			AfiSafiList: []config.AfiSafi{
+				config.AfiSafi{
+					AfiSafiName: "ipv4-unicast",
+					Ipv4Unicast: config.Ipv4Unicast{
+						ApplyPolicy: config.ApplyPolicy{
+							ImportPolicies:      []string{"primary"},
+							DefaultImportPolicy: config.DEFAULT_POLICY_TYPE_REJECT_ROUTE,
+						},
+					},
+				},
+				config.AfiSafi{AfiSafiName: "ipv6-unicast"},
+			},
+			ApplyPolicy: config.ApplyPolicy{
+				ImportPolicies: []string{"backup"},
+				ExportPolicies: []string{"backup"},
+			},
+		},
+	}
+	peer.setPolicy(conditionalPolicyMap())
+
+	// the apply-policy of the address family takes precedence
+	assert.Equal(len(peer.importPolicies[bgp.RF_IPv4_UC].policies), 1)
+	assert.Equal(peer.importPolicies[bgp.RF_IPv4_UC].policies[0].Name, "primary")
+	assert.Equal(peer.importPolicies[bgp.RF_IPv4_UC].defaultPolicy, config.DefaultPolicyType(config.DEFAULT_POLICY_TYPE_REJECT_ROUTE))
+	assert.Equal(peer.exportPolicies[bgp.RF_IPv4_UC].policies[0].Name, "backup")
+	assert.Equal(peer.importPolicies[bgp.RF_IPv6_UC].policies[0].Name, "backup")
+
+	options := peer.policyOptions()
+	paths := peer.importPolicies[bgp.RF_IPv4_UC].apply([]table.Path{
+		testPath(bgp.NewNLRInfo(0, "0.0.0.0")),
+		testPath(bgp.NewNLRInfo(24, "10.10.1.0")),
+	}, options, false)
+	assert.Equal(len(paths), 1)
+
+	// the rejected paths are withdrawn if requested
+	paths = peer.importPolicies[bgp.RF_IPv4_UC].apply([]table.Path{testPath(bgp.NewNLRInfo(24, "10.10.1.0"))}, options, true)
+	assert.Equal(len(paths), 1)
+	assert.Equal(paths[0].IsWithdraw(), true)
+}
+
+func TestPeerPolicyFilterAccounting(t *testing.T) {
+	assert := assert.New(t)
+	globalRib := &Peer{
+		peerConfig: config.Neighbor{
+			AfiSafiList: []config.AfiSafi{config.AfiSafi{AfiSafiName: "ipv4-unicast"}},
+			ApplyPolicy: config.ApplyPolicy{
+				ImportPolicies:      []string{"primary"},
+				DefaultImportPolicy: config.DEFAULT_POLICY_TYPE_REJECT_ROUTE,
+			},
+		},
+		isGlobalRib: true,
+	}
+	globalRib.setPolicy(conditionalPolicyMap())
+	pch := make(chan *peerMsg, 1)
+	globalRib.siblings = map[string]*serverMsgDataPeer{
+		"10.0.0.1": &serverMsgDataPeer{address: net.ParseIP("10.0.0.1"), peerMsgCh: pch},
+	}
+
+	pList := []table.Path{
+		testPath(bgp.NewNLRInfo(0, "0.0.0.0")),
+		testPath(bgp.NewNLRInfo(24, "10.10.1.0")),
+	}
+	paths := globalRib.applyImportPolicies(pList, globalRib.policyOptions())
+	assert.Equal(len(paths), 1)
+	assert.Equal(globalRib.importCounters, policyCounters{Accepted: 1, Rejected: 1})
+
+	// the peer sending the paths marks the rejected one
+	peer := &Peer{adjRib: table.NewAdjRib([]bgp.RouteFamily{bgp.RF_IPv4_UC})}
+	peer.adjRib.UpdateIn(pList)
+	peer.handlePeerMsg(<-pch)
+	filtered := peer.adjRib.GetInFilteredList(bgp.RF_IPv4_UC)
+	assert.Equal(len(filtered), 1)
+	j, _ := json.Marshal(filtered[0])
+	assert.Contains(string(j), `"Policy":"","Statement":""`)
+	assert.Equal(peer.adjRib.GetInFilteredCount(bgp.RF_IPv4_UC), 1)
+}
+
+func TestPeerSoftReconfigureImport(t *testing.T) {
+	assert := assert.New(t)
+	globalRib := &Peer{
+		peerConfig: config.Neighbor{
+			NeighborAddress: net.ParseIP("10.0.255.1"),
+			AfiSafiList:     []config.AfiSafi{config.AfiSafi{AfiSafiName: "ipv4-unicast"}},
+		},
+		peerInfo:    &table.PeerInfo{Address: net.ParseIP("10.0.255.1")},
+		isGlobalRib: true,
+		rib:         table.NewTableManager("global", []bgp.RouteFamily{bgp.RF_IPv4_UC}),
+	}
+	policyMap := conditionalPolicyMap()
+	globalRib.setPolicy(policyMap)
+	gch := make(chan *peerMsg, 8)
+
+	peer := &Peer{
+		peerConfig: config.Neighbor{
+			NeighborAddress: net.ParseIP("10.0.0.1"),
+			AfiSafiList:     []config.AfiSafi{config.AfiSafi{AfiSafiName: "ipv4-unicast"}},
+		},
+		peerInfo: &table.PeerInfo{AS: 65001, Address: net.ParseIP("10.0.0.1")},
+		adjRib:   table.NewAdjRib([]bgp.RouteFamily{bgp.RF_IPv4_UC}),
+		siblings: map[string]*serverMsgDataPeer{
+			"10.0.255.1": &serverMsgDataPeer{address: net.ParseIP("10.0.255.1"), peerMsgCh: gch},
+		},
+	}
+	pch := make(chan *peerMsg, 8)
+	globalRib.siblings = map[string]*serverMsgDataPeer{
+		"10.0.0.1": &serverMsgDataPeer{address: net.ParseIP("10.0.0.1"), peerMsgCh: pch},
+	}
+
+	pathAttributes := []bgp.PathAttributeInterface{
+		bgp.NewPathAttributeOrigin(0),
+		createAsPathAttribute([]uint32{65001}),
+		bgp.NewPathAttributeNextHop("10.0.0.1"),
+	}
+	nlri := []bgp.NLRInfo{*bgp.NewNLRInfo(0, "0.0.0.0"), *bgp.NewNLRInfo(24, "10.10.1.0")}
+	pList := table.NewProcessMessage(bgp.NewBGPUpdateMessage([]bgp.WithdrawnRoute{}, pathAttributes, nlri), peer.peerInfo).ToPathList()
+	peer.adjRib.UpdateIn(pList)
+	globalRib.handlePeerMsg(&peerMsg{msgType: PEER_MSG_PATH, msgData: pList})
+	for len(pch) > 0 {
+		<-pch
+	}
+	assert.Equal(len(globalRib.rib.GetPathList(bgp.RF_IPv4_UC)), 2)
+
+	// nothing is done if the policies applied aren't updated
+	globalRib.softReconfigure(globalRib.setPolicy(policyMap))
+	assert.Equal(len(pch), 0)
+
+	// the global rib asks the peer for the adj-rib-in
+	globalRib.peerConfig.ApplyPolicy = config.ApplyPolicy{
+		ImportPolicies:      []string{"primary"},
+		DefaultImportPolicy: config.DEFAULT_POLICY_TYPE_REJECT_ROUTE,
+	}
+	globalRib.softReconfigure(globalRib.setPolicy(policyMap))
+	m := <-pch
+	assert.Equal(m.msgType, PEER_MSG_SOFT_RECONFIG)
+	peer.handlePeerMsg(m)
+	m = <-gch
+	assert.Equal(m.msgType, PEER_MSG_PATH_REFRESH)
+	globalRib.handlePeerMsg(m)
+
+	// only the rejected path is withdrawn
+	assert.Equal(len(globalRib.rib.GetPathList(bgp.RF_IPv4_UC)), 1)
+	var paths []table.Path
+	for len(pch) > 0 {
+		if m := <-pch; m.msgType == PEER_MSG_PATH {
+			paths = append(paths, m.msgData.([]table.Path)...)
+		} else {
+			peer.handlePeerMsg(m)
+		}
+	}
+	assert.Equal(len(paths), 1)
+	assert.Equal(paths[0].IsWithdraw(), true)
+	assert.Equal(paths[0].GetNlri().String(), "10.10.1.0/24")
+	assert.Equal(peer.adjRib.GetInFilteredCount(bgp.RF_IPv4_UC), 1)
+
+	// the paths not changed aren't processed again
+	peer.handlePeerMsg(&peerMsg{msgType: PEER_MSG_SOFT_RECONFIG, msgData: globalRib.peerInfo})
+	globalRib.handlePeerMsg(<-gch)
+	for len(pch) > 0 {
+		assert.NotEqual((<-pch).msgType, PEER_MSG_PATH)
+	}
+}
+
+func TestPeerSoftReconfigureExport(t *testing.T) {
+	assert := assert.New(t)
+	peer := &Peer{
+		globalConfig: config.Global{As: 65000, RouterId: net.ParseIP("10.0.255.1")},
+		peerConfig: config.Neighbor{
+			NeighborAddress: net.ParseIP("10.0.0.2"),
+			LocalAddress:    net.ParseIP("10.0.0.254"),
+			PeerAs:          65002,
+			AfiSafiList:     []config.AfiSafi{config.AfiSafi{AfiSafiName: "ipv4-unicast"}},
+		},
+		peerInfo: &table.PeerInfo{AS: 65002, Address: net.ParseIP("10.0.0.2")},
+		adjRib:   table.NewAdjRib([]bgp.RouteFamily{bgp.RF_IPv4_UC}),
+		rfMap:    map[bgp.RouteFamily]bool{bgp.RF_IPv4_UC: true},
+		capMap:   make(map[bgp.BGPCapabilityCode]bgp.ParameterCapabilityInterface),
+		outgoing: make(chan *bgp.BGPMessage, 8),
+	}
+	peer.peerConfig.BgpNeighborCommonState.State = uint32(bgp.BGP_FSM_ESTABLISHED)
+	policyMap := conditionalPolicyMap()
+	peer.setPolicy(policyMap)
+	gch := make(chan *peerMsg, 8)
+	peer.siblings = map[string]*serverMsgDataPeer{
+		"10.0.255.1": &serverMsgDataPeer{address: net.ParseIP("10.0.255.1"), peerMsgCh: gch},
+	}
+
+	best := []table.Path{
+		testPath(bgp.NewNLRInfo(0, "0.0.0.0")),
+		testPath(bgp.NewNLRInfo(24, "10.10.1.0")),
+	}
+	peer.handlePeerMsg(&peerMsg{msgType: PEER_MSG_PATH, msgData: best})
+	assert.Equal(len(peer.adjRib.GetOutPathList(bgp.RF_IPv4_UC)), 2)
+	for len(peer.outgoing) > 0 {
+		<-peer.outgoing
+	}
+
+	// the peer asks the global rib for the best paths
+	peer.peerConfig.ApplyPolicy = config.ApplyPolicy{
+		ExportPolicies:      []string{"primary"},
+		DefaultExportPolicy: config.DEFAULT_POLICY_TYPE_REJECT_ROUTE,
+	}
+	peer.softReconfigure(peer.setPolicy(policyMap))
+	m := <-gch
+	assert.Equal(m.msgType, PEER_MSG_SOFT_RECONFIG)
+
+	refresh := &peerMsg{
+		msgType: PEER_MSG_PATH_REFRESH,
+		msgData: &peerMsgDataRefresh{pathList: best},
+	}
+	peer.handlePeerMsg(refresh)
+	assert.Equal(len(peer.adjRib.GetOutPathList(bgp.RF_IPv4_UC)), 1)
+	assert.Equal(len(peer.outgoing), 1)
+	update := (<-peer.outgoing).Body.(*bgp.BGPUpdate)
+	assert.Equal(len(update.WithdrawnRoutes), 1)
+	assert.Equal(update.WithdrawnRoutes[0].String(), "10.10.1.0/24")
+	assert.Equal(len(update.NLRI), 0)
+
+	// nothing is sent if the adj-rib-out isn't changed
+	peer.handlePeerMsg(refresh)
+	assert.Equal(len(peer.outgoing), 0)
+}
+
+func TestPeerUpdateConfig(t *testing.T) {
+	assert := assert.New(t)
+	c := config.Neighbor{
+		NeighborAddress: net.ParseIP("10.0.0.2"),
+		PeerAs:          65002,
+		AfiSafiList:     []config.AfiSafi{config.AfiSafi{AfiSafiName: "ipv4-unicast"}},
+	}
+	peer := &Peer{
+		peerConfig: c,
+		peerInfo:   &table.PeerInfo{AS: 65002, Address: net.ParseIP("10.0.0.2")},
+		adjRib:     table.NewAdjRib([]bgp.RouteFamily{bgp.RF_IPv4_UC}),
+	}
+	peer.setPolicy(conditionalPolicyMap())
+	gch := make(chan *peerMsg, 8)
+	peer.siblings = map[string]*serverMsgDataPeer{
+		"10.0.255.1": &serverMsgDataPeer{address: net.ParseIP("10.0.255.1"), peerMsgCh: gch},
+	}
+
+	// the policies are applied in place with the soft reconfiguration
+	c.Description = "updated"
+	c.ApplyPolicy = config.ApplyPolicy{
+		ImportPolicies:      []string{"primary"},
+		DefaultImportPolicy: config.DEFAULT_POLICY_TYPE_REJECT_ROUTE,
+	}
+	peer.handleServerMsg(&serverMsg{msgType: SRV_MSG_PEER_UPDATED, msgData: c})
+	assert.Equal(peer.peerConfig.Description, "updated")
+	assert.Equal(len(peer.importPolicies[bgp.RF_IPv4_UC].policies), 1)
+	assert.Equal(peer.importPolicies[bgp.RF_IPv4_UC].policies[0].Name, "primary")
+	assert.Equal(len(gch), 1)
+	assert.Equal((<-gch).msgType, PEER_MSG_SOFT_RECONFIG)
+
+	// nothing is reconfigured if the policies aren't changed
+	c.Description = "updated again"
+	peer.handleServerMsg(&serverMsg{msgType: SRV_MSG_PEER_UPDATED, msgData: c})
+	assert.Equal(peer.peerConfig.Description, "updated again")
+	assert.Equal(len(gch), 0)
+}
+
+func TestNewApiPath(t *testing.T) {
+	aspath := bgp.NewPathAttributeAsPath([]bgp.AsPathParamInterface{
+		bgp.NewAs4PathParam(bgp.BGP_ASPATH_ATTR_TYPE_SEQ, []uint32{65001, 65002}),
+		bgp.NewAs4PathParam(bgp.BGP_ASPATH_ATTR_TYPE_SET, []uint32{65003}),
+	})
+	attrs := []bgp.PathAttributeInterface{
+		bgp.NewPathAttributeOrigin(2),
+		aspath,
+		bgp.NewPathAttributeNextHop("10.0.0.1"),
+		bgp.NewPathAttributeMultiExitDisc(100),
+		bgp.NewPathAttributeCommunities([]uint32{100}),
+	}
+	// as received from the peer
+	received := &bgp.NLRInfo{}
+	assert.Nil(t, received.DecodeFromBytes([]byte{24, 10, 10, 10}))
+	peer := &table.PeerInfo{AS: 65001, Address: net.ParseIP("10.0.0.1")}
+	path := table.CreatePath(peer, received, attrs, false, time.Now())
+	path.SetValidation(config.RPKI_VALIDATION_RESULT_TYPE_VALID)
+
+	p := newApiPath(path)
+	assert.Equal(t, p.Prefix, "10.10.10.0/24")
+	assert.Equal(t, p.Family.Afi, api.AFI_IP)
+	assert.Equal(t, p.Family.Safi, api.SAFI_UNICAST)
+	assert.Equal(t, p.Nexthop, "10.0.0.1")
+	assert.Equal(t, p.SourceAddress, "10.0.0.1")
+	assert.Equal(t, p.SourceAs, uint32(65001))
+	assert.Equal(t, p.Validation, api.ValidationResult_VALIDATION_VALID)
+	assert.Equal(t, p.Origin, api.Origin_ORIGIN_INCOMPLETE)
+	assert.Equal(t, len(p.AsPath), 2)
+	assert.Equal(t, p.AsPath[0].Type, uint32(bgp.BGP_ASPATH_ATTR_TYPE_SEQ))
+	assert.Equal(t, p.AsPath[0].Asns, []uint32{65001, 65002})
+	assert.Equal(t, p.AsPath[1].Asns, []uint32{65003})
+	assert.Equal(t, p.GetMed(), uint32(100))
+	assert.Nil(t, p.LocalPref)
+	assert.Equal(t, p.Communities, []uint32{100})
+	assert.Equal(t, len(p.Pattrs), len(attrs))
+
+	// the nlri and the attributes in the wire format are decoded back
+	nlri := bgp.NewNLRInfo(0, "0.0.0.0")
+	assert.Nil(t, nlri.DecodeFromBytes(p.Nlri))
+	assert.Equal(t, nlri.String(), "10.10.10.0/24")
+	med := &bgp.PathAttributeMultiExitDisc{}
+	assert.Nil(t, med.DecodeFromBytes(p.Pattrs[3]))
+	assert.Equal(t, med.Value, uint32(100))
+}
+
+func TestPeerGrpcRib(t *testing.T) {
+	assert := assert.New(t)
+	peer := &Peer{
+		fsm:    &FSM{},
+		rib:    table.NewTableManager("global", []bgp.RouteFamily{bgp.RF_IPv4_UC}),
+		adjRib: table.NewAdjRib([]bgp.RouteFamily{bgp.RF_IPv4_UC}),
+	}
+	paths := []table.Path{
+		testPath(bgp.NewNLRInfo(24, "10.10.2.0")),
+		testPath(bgp.NewNLRInfo(24, "10.10.1.0")),
+	}
+	peer.rib.ProcessPaths(paths)
+	peer.adjRib.UpdateIn(paths)
+
+	request := func(reqType int) []*api.GrpcResponse {
+		req := api.NewGrpcRequest(reqType, "", bgp.RF_IPv4_UC)
+		peer.handleGrpc(req)
+		results := make([]*api.GrpcResponse, 0)
+		for r := range req.ResponseCh {
+			results = append(results, r)
+		}
+		return results
+	}
+	results := request(api.REQ_GLOBAL_RIB)
+	assert.Equal(len(results), 2)
+	dest := results[0].Data.(*api.Destination)
+	assert.Equal(dest.Prefix, "10.10.1.0/24")
+	assert.Equal(len(dest.Paths), 1)
+	assert.Equal(dest.BestPathIdx, int32(0))
+	assert.Equal(results[1].Data.(*api.Destination).Prefix, "10.10.2.0/24")
+
+	results = request(api.REQ_ADJ_RIB_IN)
+	assert.Equal(len(results), 2)
+	assert.Equal(results[0].Data.(*api.Path).Prefix, "10.10.1.0/24")
+	assert.Equal(len(request(api.REQ_ADJ_RIB_OUT)), 0)
+
+	peer.fsm.adminState = ADMIN_STATE_DOWN
+	assert.Equal(len(request(api.REQ_GLOBAL_RIB)), 0)
+}
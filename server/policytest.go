@@ -0,0 +1,111 @@
+// Copyright (C) 2015 Nippon Telegraph and Telephone Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/osrg/gobgp/config"
+	"github.com/osrg/gobgp/policy"
+	"github.com/osrg/gobgp/table"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the path given to the policy test endpoints
+type policyTestPath struct {
+	// "<address>/<masklength>"
+	Prefix         string
+	Nexthop        net.IP
+	Origin         config.BgpOriginAttrType
+	AsPath         []uint32
+	Med            uint32
+	LocalPref      uint32
+	Communities    []string
+	ExtCommunities []string
+	// the neighbor the path is received from. the path is locally
+	// originated if not specified.
+	Neighbor net.IP
+	// the AS of the neighbor
+	NeighborAs uint32
+}
+
+func newPolicyTestPath(data []byte) (table.Path, error) {
+	t := &policyTestPath{}
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("invalid path: %s", err)
+	}
+	elems := strings.Split(t.Prefix, "/")
+	if len(elems) != 2 {
+		return nil, fmt.Errorf("invalid prefix: %s", t.Prefix)
+	}
+	masklen, err := strconv.ParseUint(elems[1], 10, 8)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prefix: %s", t.Prefix)
+	}
+	n := config.Network{
+		Address:       net.ParseIP(elems[0]),
+		Masklength:    uint8(masklen),
+		NextHop:       t.Nexthop,
+		Origin:        t.Origin,
+		Med:           t.Med,
+		LocalPref:     t.LocalPref,
+		CommunityList: t.Communities,
+	}
+	var source *table.PeerInfo
+	if t.Neighbor != nil {
+		source = &table.PeerInfo{
+			AS:      t.NeighborAs,
+			Address: t.Neighbor,
+		}
+	}
+	return table.CreateTestPath(source, n, t.AsPath, t.ExtCommunities, time.Now())
+}
+
+// apply the import or export policies of the peer to the path as
+// handlePeerMsg() and sendUpdateMsgFromPaths() do.
+func (peer *Peer) testPolicy(data []byte, isImport bool) (*policyResult, error) {
+	path, err := newPolicyTestPath(data)
+	if err != nil {
+		return nil, err
+	}
+	options := peer.policyOptions()
+	options.DryRun = true
+	if isImport {
+		table.UpdateInPathAttrs([]table.Path{path}, &peer.peerConfig)
+		return peer.importPolicies[path.GetRouteFamily()].evaluate(path, options), nil
+	}
+	if peer.suppressedByConditions(path) {
+		return &policyResult{Action: POLICY_RESULT_SUPPRESSED}, nil
+	}
+	path = table.CloneAndUpdatePathAttrs([]table.Path{path}, &peer.globalConfig, &peer.peerConfig)[0]
+	return peer.exportPolicies[path.GetRouteFamily()].evaluate(path, options), nil
+}
+
+// apply the named policy to the path
+func (server *BgpServer) testPolicy(name string, data []byte) (*policyResult, error) {
+	pol, ok := server.policyMap[name]
+	if !ok {
+		return nil, fmt.Errorf("policy %s isn't defined", name)
+	}
+	path, err := newPolicyTestPath(data)
+	if err != nil {
+		return nil, err
+	}
+	return evaluatePolicy(pol, path, &policy.PolicyOptions{LocalAs: server.bgpConfig.Global.As, DryRun: true}), nil
+}
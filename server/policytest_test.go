@@ -0,0 +1,79 @@
+// Copyright (C) 2015 Nippon Telegraph and Telephone Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"github.com/osrg/gobgp/config"
+	"github.com/osrg/gobgp/packet"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+)
+
+func TestPeerTestPolicy(t *testing.T) {
+	assert := assert.New(t)
+	peer := &Peer{
+		peerConfig: config.Neighbor{
+			NeighborAddress: net.ParseIP("10.0.0.2"),
+			PeerType:        config.PEER_TYPE_EXTERNAL,
+			Weight:          10,
+			AfiSafiList:     []config.AfiSafi{config.AfiSafi{AfiSafiName: "ipv4-unicast"}},
+			ApplyPolicy: config.ApplyPolicy{
+				ImportPolicies:      []string{"backup"},
+				DefaultImportPolicy: config.DEFAULT_POLICY_TYPE_REJECT_ROUTE,
+			},
+			ConditionalAdvertisementList: []config.ConditionalAdvertisement{
+				config.ConditionalAdvertisement{
+					AdvertisePolicy: "backup",
+					ExistPolicy:     "primary",
+				},
+			},
+		},
+	}
+	peer.setPolicy(conditionalPolicyMap())
+	peer.setConditionalAdvertisements(conditionalPolicyMap())
+
+	r, err := peer.testPolicy([]byte(`{"Prefix": "10.10.1.0/24", "AsPath": [65001], "Neighbor": "10.0.0.1", "NeighborAs": 65001}`), true)
+	assert.Nil(err)
+	assert.Equal(r.Action, POLICY_RESULT_ACCEPT)
+	assert.Equal(r.Policy, "backup")
+	assert.Equal(r.Statement, "backup")
+	assert.Equal(r.Path.GetNlri().String(), "10.10.1.0/24")
+	assert.Equal(r.Path.GetRouteFamily(), bgp.RF_IPv4_UC)
+	// the local attributes are set as received from the peer
+	assert.Equal(r.Path.GetWeight(), uint32(10))
+	localPref, _ := r.Path.GetLocalPref()
+	assert.Equal(localPref, uint32(config.DEFAULT_LOCAL_PREF))
+
+	r, err = peer.testPolicy([]byte(`{"Prefix": "10.20.1.0/24"}`), true)
+	assert.Nil(err)
+	assert.Equal(r.Action, POLICY_RESULT_DEFAULT_REJECT)
+	assert.Nil(r.Path)
+
+	// no export policy is applied
+	r, err = peer.testPolicy([]byte(`{"Prefix": "10.20.1.0/24"}`), false)
+	assert.Nil(err)
+	assert.Equal(r.Action, POLICY_RESULT_DEFAULT_ACCEPT)
+
+	// the path is withdrawn while the primary path doesn't exist
+	r, err = peer.testPolicy([]byte(`{"Prefix": "10.10.1.0/24"}`), false)
+	assert.Nil(err)
+	assert.Equal(r.Action, POLICY_RESULT_SUPPRESSED)
+	assert.Equal(len(peer.conditionals[0].pathList()), 0)
+
+	_, err = peer.testPolicy([]byte(`{"Prefix": "10.20.1.0"}`), true)
+	assert.NotNil(err)
+}
@@ -0,0 +1,595 @@
+// Copyright (C) 2015 Nippon Telegraph and Telephone Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	log "github.com/Sirupsen/logrus"
+	"github.com/osrg/gobgp/config"
+	"github.com/osrg/gobgp/packet"
+	"github.com/osrg/gobgp/table"
+	"github.com/tchap/go-patricia/patricia"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	RPKI_CONNECT_TIMEOUT = time.Second * 10
+	RPKI_RETRY_TIME      = time.Second * 30
+	ROA_FILE_SCAN_TIME   = time.Second * 5
+)
+
+var (
+	errRoaMatched     = errors.New("roa matched")
+	errRtrDowngrade   = errors.New("RTR protocol version downgraded")
+	errRtrNoDataReady = errors.New("RTR cache has no data available")
+)
+
+// the validated ROA payload
+type roa struct {
+	Prefix    net.IP
+	PrefixLen uint8
+	MaxLen    uint8
+	AS        uint32
+}
+
+func (r *roa) key() string {
+	return fmt.Sprintf("%s/%d-%d:%d", r.Prefix, r.PrefixLen, r.MaxLen, r.AS)
+}
+
+func (r *roa) routeFamily() bgp.RouteFamily {
+	if r.Prefix.To4() != nil {
+		return bgp.RF_IPv4_UC
+	}
+	return bgp.RF_IPv6_UC
+}
+
+// roaTable is the ROAs of all the sources, the RPKI caches and the
+// static ROA file, indexed by the prefix to find the ROAs covering a
+// path quickly. It's shared by the peers.
+type roaTable struct {
+	mu      sync.RWMutex
+	sources map[string][]*roa
+	tries   map[bgp.RouteFamily]*patricia.Trie
+}
+
+func newRoaTable() *roaTable {
+	return &roaTable{
+		sources: make(map[string][]*roa),
+		tries:   make(map[bgp.RouteFamily]*patricia.Trie),
+	}
+}
+
+// replace the ROAs of the source and build the tries again
+func (t *roaTable) replace(source string, roas []*roa) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(roas) == 0 {
+		delete(t.sources, source)
+	} else {
+		t.sources[source] = roas
+	}
+	tries := make(map[bgp.RouteFamily]*patricia.Trie)
+	for _, l := range t.sources {
+		for _, r := range l {
+			rf := r.routeFamily()
+			trie, ok := tries[rf]
+			if !ok {
+				trie = patricia.NewTrie()
+				tries[rf] = trie
+			}
+			key := table.PrefixKey(r.Prefix, r.PrefixLen)
+			if item := trie.Get(key); item != nil {
+				trie.Set(key, append(item.([]*roa), r))
+			} else {
+				trie.Insert(key, []*roa{r})
+			}
+		}
+	}
+	t.tries = tries
+}
+
+func (t *roaTable) count() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	n := 0
+	for _, l := range t.sources {
+		n += len(l)
+	}
+	return n
+}
+
+func roaPathPrefix(path table.Path) (net.IP, uint8, bool) {
+	switch path.GetRouteFamily() {
+	case bgp.RF_IPv4_UC:
+		prefix := path.GetNlri().(*bgp.NLRInfo).IPAddrPrefix
+		return prefix.Prefix, prefix.Length, true
+	case bgp.RF_IPv6_UC:
+		prefix := path.GetNlri().(*bgp.IPv6AddrPrefix)
+		return prefix.Prefix, prefix.Length, true
+	}
+	return nil, 0, false
+}
+
+// validate the origin of the path (RFC 6811). The path originated in
+// the local AS has the empty AS_PATH. The path ending with an AS_SET
+// has no origin and never matches a ROA, nor does a ROA of AS 0.
+func (t *roaTable) validate(path table.Path, localAs uint32) config.RpkiValidationResultType {
+	addr, length, ok := roaPathPrefix(path)
+	if !ok {
+		return config.RPKI_VALIDATION_RESULT_TYPE_NONE
+	}
+	as := localAs
+	if path.GetAsPathLen() > 0 {
+		as, _ = path.GetOriginAs()
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	trie, ok := t.tries[path.GetRouteFamily()]
+	if !ok {
+		return config.RPKI_VALIDATION_RESULT_TYPE_NOT_FOUND
+	}
+	var result config.RpkiValidationResultType = config.RPKI_VALIDATION_RESULT_TYPE_NOT_FOUND
+	trie.VisitPrefixes(table.PrefixKey(addr, length), func(prefix patricia.Prefix, item patricia.Item) error {
+		for _, r := range item.([]*roa) {
+			if as != 0 && r.AS == as && length <= r.MaxLen {
+				result = config.RPKI_VALIDATION_RESULT_TYPE_VALID
+				return errRoaMatched
+			}
+			result = config.RPKI_VALIDATION_RESULT_TYPE_INVALID
+		}
+		return nil
+	})
+	return result
+}
+
+// rtrClient keeps the ROAs of an RPKI cache with the RPKI-to-Router
+// protocol (RFC 6810 and RFC 8210). Version 1 is tried first and
+// version 0 is used if the cache doesn't support it.
+type rtrClient struct {
+	mu         sync.Mutex
+	address    string
+	table      *roaTable
+	notify     func()
+	version    uint8
+	sessionID  uint16
+	serial     uint32
+	hasSerial  bool
+	records    map[string]*roa
+	up         bool
+	uptime     time.Time
+	lastUpdate time.Time
+	refresh    time.Duration
+	retry      time.Duration
+	expire     time.Duration
+	// the refresh interval is given by the cache unless configured
+	fixedRefresh bool
+}
+
+func newRtrClient(c config.RpkiServer, t *roaTable, notify func()) *rtrClient {
+	port := c.Port
+	if port == 0 {
+		port = bgp.RTR_PORT
+	}
+	client := &rtrClient{
+		address: net.JoinHostPort(c.Address.String(), strconv.Itoa(int(port))),
+		table:   t,
+		notify:  notify,
+		version: bgp.RTR_PROTOCOL_VERSION1,
+		records: make(map[string]*roa),
+		refresh: time.Second * bgp.RTR_DEFAULT_REFRESH_INTERVAL,
+		retry:   RPKI_RETRY_TIME,
+		expire:  time.Second * bgp.RTR_DEFAULT_EXPIRE_INTERVAL,
+	}
+	if c.RefreshTime != 0 {
+		client.refresh = time.Second * time.Duration(c.RefreshTime)
+		client.fixedRefresh = true
+	}
+	return client
+}
+
+func (c *rtrClient) loop() {
+	for {
+		conn, err := net.DialTimeout("tcp", c.address, RPKI_CONNECT_TIMEOUT)
+		if err == nil {
+			err = c.session(conn)
+			conn.Close()
+		}
+
+		c.mu.Lock()
+		wasUp := c.up
+		c.up = false
+		expired := len(c.records) > 0 && time.Since(c.lastUpdate) > c.expire
+		if expired {
+			c.records = make(map[string]*roa)
+			c.hasSerial = false
+		}
+		retry := c.retry
+		c.mu.Unlock()
+
+		if wasUp {
+			log.WithFields(log.Fields{
+				"Topic": "Rpki",
+				"Key":   c.address,
+				"Error": err,
+			}).Warn("RPKI cache session down")
+		}
+		if expired {
+			log.WithFields(log.Fields{
+				"Topic": "Rpki",
+				"Key":   c.address,
+			}).Warn("ROAs of RPKI cache expired")
+			c.table.replace(c.address, nil)
+			c.notify()
+		}
+		if err != errRtrDowngrade {
+			time.Sleep(retry)
+		}
+	}
+}
+
+func (c *rtrClient) send(conn net.Conn, msg bgp.RTRMessage) error {
+	buf, err := msg.Serialize()
+	if err != nil {
+		return err
+	}
+	_, err = conn.Write(buf)
+	return err
+}
+
+// send the serial query if the cache has sent the data before, the
+// reset query otherwise.
+func (c *rtrClient) query(conn net.Conn) error {
+	c.mu.Lock()
+	var msg bgp.RTRMessage
+	if c.hasSerial {
+		msg = bgp.NewRTRSerialQuery(c.version, c.sessionID, c.serial)
+	} else {
+		msg = bgp.NewRTRResetQuery(c.version)
+	}
+	c.mu.Unlock()
+	return c.send(conn, msg)
+}
+
+// run the session until the connection is closed or an error is
+// reported by the cache.
+func (c *rtrClient) session(conn net.Conn) error {
+	msgCh := make(chan bgp.RTRMessage)
+	errCh := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		scanner := bufio.NewScanner(conn)
+		scanner.Split(bgp.SplitRTR)
+		for scanner.Scan() {
+			msg, err := bgp.ParseRTR(scanner.Bytes())
+			if err != nil {
+				errCh <- err
+				return
+			}
+			select {
+			case msgCh <- msg:
+			case <-done:
+				return
+			}
+		}
+		err := scanner.Err()
+		if err == nil {
+			err = io.EOF
+		}
+		errCh <- err
+	}()
+
+	c.mu.Lock()
+	c.up = true
+	c.uptime = time.Now()
+	c.mu.Unlock()
+	log.WithFields(log.Fields{
+		"Topic": "Rpki",
+		"Key":   c.address,
+	}).Info("RPKI cache session up")
+
+	if err := c.query(conn); err != nil {
+		return err
+	}
+	timer := time.NewTimer(c.refresh)
+	defer timer.Stop()
+	// the records being updated between the cache response and the
+	// end of data
+	var pending map[string]*roa
+	for {
+		select {
+		case err := <-errCh:
+			return err
+		case <-timer.C:
+			if pending == nil {
+				if err := c.query(conn); err != nil {
+					return err
+				}
+			}
+			timer.Reset(c.refresh)
+		case msg := <-msgCh:
+			switch m := msg.(type) {
+			case *bgp.RTRSerial:
+				if m.Type == bgp.RTR_SERIAL_NOTIFY && pending == nil {
+					if err := c.query(conn); err != nil {
+						return err
+					}
+				}
+			case *bgp.RTRHeaderOnly:
+				switch m.Type {
+				case bgp.RTR_CACHE_RESPONSE:
+					c.mu.Lock()
+					pending = make(map[string]*roa)
+					if c.hasSerial && c.sessionID == m.SessionID {
+						for k, r := range c.records {
+							pending[k] = r
+						}
+					}
+					c.sessionID = m.SessionID
+					c.mu.Unlock()
+				case bgp.RTR_CACHE_RESET:
+					c.mu.Lock()
+					c.hasSerial = false
+					c.mu.Unlock()
+					if err := c.query(conn); err != nil {
+						return err
+					}
+				}
+			case *bgp.RTRIPPrefix:
+				if pending == nil {
+					return fmt.Errorf("RTR prefix PDU received out of cache response")
+				}
+				r := &roa{
+					Prefix:    m.Prefix.Mask(net.CIDRMask(int(m.PrefixLen), len(m.Prefix)*8)),
+					PrefixLen: m.PrefixLen,
+					MaxLen:    m.MaxLen,
+					AS:        m.AS,
+				}
+				if m.Flags&bgp.RTR_PREFIX_FLAG_ANNOUNCE != 0 {
+					pending[r.key()] = r
+				} else {
+					delete(pending, r.key())
+				}
+			case *bgp.RTREndOfData:
+				if pending == nil {
+					return fmt.Errorf("RTR end of data PDU received out of cache response")
+				}
+				roas := make([]*roa, 0, len(pending))
+				for _, r := range pending {
+					roas = append(roas, r)
+				}
+				c.mu.Lock()
+				c.records = pending
+				c.serial = m.Serial
+				c.hasSerial = true
+				c.lastUpdate = time.Now()
+				if m.Version != bgp.RTR_PROTOCOL_VERSION0 {
+					if !c.fixedRefresh && m.RefreshInterval != 0 {
+						c.refresh = time.Second * time.Duration(m.RefreshInterval)
+					}
+					if m.RetryInterval != 0 {
+						c.retry = time.Second * time.Duration(m.RetryInterval)
+					}
+					if m.ExpireInterval != 0 {
+						c.expire = time.Second * time.Duration(m.ExpireInterval)
+					}
+				}
+				refresh := c.refresh
+				c.mu.Unlock()
+				pending = nil
+				timer.Reset(refresh)
+
+				log.WithFields(log.Fields{
+					"Topic":  "Rpki",
+					"Key":    c.address,
+					"Serial": m.Serial,
+					"Roas":   len(roas),
+				}).Info("ROAs of RPKI cache updated")
+				c.table.replace(c.address, roas)
+				c.notify()
+			case *bgp.RTRErrorReport:
+				c.mu.Lock()
+				downgrade := m.ErrorCode() == bgp.RTR_UNSUPPORTED_PROTOCOL_VERSION && m.Version < c.version
+				if downgrade {
+					c.version = m.Version
+				}
+				c.mu.Unlock()
+				switch {
+				case downgrade:
+					return errRtrDowngrade
+				case m.ErrorCode() == bgp.RTR_NO_DATA_AVAILABLE:
+					return errRtrNoDataReady
+				}
+				return fmt.Errorf("RTR error report %d: %s", m.ErrorCode(), m.Text)
+			}
+		}
+	}
+}
+
+func (c *rtrClient) MarshalJSON() ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	uptime := int64(0)
+	if c.up {
+		uptime = int64(time.Since(c.uptime).Seconds())
+	}
+	return json.Marshal(struct {
+		Address    string
+		Up         bool
+		Uptime     int64
+		Version    uint8
+		SessionId  uint16
+		Serial     uint32
+		Roas       int
+		LastUpdate int64
+	}{
+		Address:    c.address,
+		Up:         c.up,
+		Uptime:     uptime,
+		Version:    c.version,
+		SessionId:  c.sessionID,
+		Serial:     c.serial,
+		Roas:       len(c.records),
+		LastUpdate: c.lastUpdate.Unix(),
+	})
+}
+
+type staticRoa struct {
+	Prefix string
+	MaxLen uint8
+	As     uint32
+}
+
+// staticRoaTable is the ROAs read from a TOML file like
+//
+//	[[RoaList]]
+//	Prefix = "192.168.0.0/16"
+//	MaxLen = 24
+//	As = 65001
+//
+// The max length is the prefix length if not specified. The file is
+// read again when it's modified.
+type staticRoaTable struct {
+	path    string
+	modTime time.Time
+	roas    []*roa
+}
+
+func (s *staticRoaTable) load() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+	var t struct {
+		RoaList []staticRoa
+	}
+	if _, err := toml.DecodeFile(s.path, &t); err != nil {
+		return err
+	}
+	roas := make([]*roa, 0, len(t.RoaList))
+	for _, r := range t.RoaList {
+		_, n, err := net.ParseCIDR(r.Prefix)
+		if err != nil {
+			return err
+		}
+		ones, bits := n.Mask.Size()
+		maxLen := r.MaxLen
+		if maxLen == 0 {
+			maxLen = uint8(ones)
+		}
+		if int(maxLen) < ones || int(maxLen) > bits {
+			return fmt.Errorf("invalid max length %d of %s", maxLen, r.Prefix)
+		}
+		roas = append(roas, &roa{
+			Prefix:    n.IP,
+			PrefixLen: uint8(ones),
+			MaxLen:    maxLen,
+			AS:        r.As,
+		})
+	}
+	s.modTime = info.ModTime()
+	s.roas = roas
+	return nil
+}
+
+// read the file again if modified. return true if reloaded.
+func (s *staticRoaTable) reload() bool {
+	info, err := os.Stat(s.path)
+	if err != nil || !info.ModTime().After(s.modTime) {
+		return false
+	}
+	if err := s.load(); err != nil {
+		log.WithFields(log.Fields{
+			"Topic": "Rpki",
+			"Key":   s.path,
+			"Error": err,
+		}).Error("failed to reload static ROAs")
+		return false
+	}
+	log.WithFields(log.Fields{
+		"Topic": "Rpki",
+		"Key":   s.path,
+	}).Info("static ROAs reloaded")
+	return true
+}
+
+// roaManager runs the RTR clients and watches the static ROA file.
+// The table is notified to notifyCh when the ROAs are updated.
+type roaManager struct {
+	table    *roaTable
+	clients  []*rtrClient
+	roaFile  string
+	notifyCh chan *roaTable
+}
+
+func newRoaManager(c config.RpkiValidation, notifyCh chan *roaTable) (*roaManager, error) {
+	m := &roaManager{
+		table:    newRoaTable(),
+		roaFile:  c.RoaFile,
+		notifyCh: notifyCh,
+	}
+	if c.RoaFile != "" {
+		s := &staticRoaTable{path: c.RoaFile}
+		if err := s.load(); err != nil {
+			return nil, err
+		}
+		m.table.replace(s.path, s.roas)
+		go func() {
+			for _ = range time.Tick(ROA_FILE_SCAN_TIME) {
+				if s.reload() {
+					m.table.replace(s.path, s.roas)
+					m.notify()
+				}
+			}
+		}()
+	}
+	for _, s := range c.RpkiServerList {
+		client := newRtrClient(s, m.table, m.notify)
+		m.clients = append(m.clients, client)
+		go client.loop()
+	}
+	return m, nil
+}
+
+// the updates while the server is busy are coalesced into one.
+func (m *roaManager) notify() {
+	select {
+	case m.notifyCh <- m.table:
+	default:
+	}
+}
+
+func (m *roaManager) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Servers []*rtrClient
+		RoaFile string
+		Roas    int
+	}{
+		Servers: m.clients,
+		RoaFile: m.roaFile,
+		Roas:    m.table.count(),
+	})
+}
@@ -0,0 +1,327 @@
+// Copyright (C) 2015 Nippon Telegraph and Telephone Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bufio"
+	"github.com/osrg/gobgp/config"
+	"github.com/osrg/gobgp/packet"
+	"github.com/osrg/gobgp/table"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func rpkiTestPath(prefix string, masklen uint8, asPath []uint32) table.Path {
+	nexthop := net.ParseIP("10.0.0.1")
+	if net.ParseIP(prefix).To4() == nil {
+		nexthop = net.ParseIP("2001::1")
+	}
+	n := config.Network{
+		Address:    net.ParseIP(prefix),
+		Masklength: masklen,
+		NextHop:    nexthop,
+	}
+	source := &table.PeerInfo{AS: 65001, Address: net.ParseIP("10.0.0.1")}
+	path, _ := table.CreateTestPath(source, n, asPath, nil, time.Now())
+	return path
+}
+
+func TestRoaTableValidate(t *testing.T) {
+	rt := newRoaTable()
+	rt.replace("test", []*roa{
+		&roa{Prefix: net.ParseIP("10.0.0.0").To4(), PrefixLen: 16, MaxLen: 24, AS: 65001},
+		&roa{Prefix: net.ParseIP("10.0.0.0").To4(), PrefixLen: 16, MaxLen: 16, AS: 65002},
+		&roa{Prefix: net.ParseIP("192.168.0.0").To4(), PrefixLen: 16, MaxLen: 16, AS: 0},
+		&roa{Prefix: net.ParseIP("2001:db8::"), PrefixLen: 32, MaxLen: 48, AS: 65001},
+	})
+	assert.Equal(t, 4, rt.count())
+
+	tests := []struct {
+		prefix  string
+		masklen uint8
+		asPath  []uint32
+		result  config.RpkiValidationResultType
+	}{
+		{"10.0.0.0", 16, []uint32{65000, 65001}, config.RPKI_VALIDATION_RESULT_TYPE_VALID},
+		{"10.0.1.0", 24, []uint32{65001}, config.RPKI_VALIDATION_RESULT_TYPE_VALID},
+		{"10.0.0.0", 16, []uint32{65002}, config.RPKI_VALIDATION_RESULT_TYPE_VALID},
+		// longer than the max length
+		{"10.0.1.0", 24, []uint32{65002}, config.RPKI_VALIDATION_RESULT_TYPE_INVALID},
+		{"10.0.1.128", 25, []uint32{65001}, config.RPKI_VALIDATION_RESULT_TYPE_INVALID},
+		{"10.0.0.0", 16, []uint32{65003}, config.RPKI_VALIDATION_RESULT_TYPE_INVALID},
+		// the origin is the local AS
+		{"10.0.0.0", 16, []uint32{}, config.RPKI_VALIDATION_RESULT_TYPE_VALID},
+		// no ROA covers it
+		{"10.0.0.0", 8, []uint32{65001}, config.RPKI_VALIDATION_RESULT_TYPE_NOT_FOUND},
+		{"172.16.0.0", 16, []uint32{65001}, config.RPKI_VALIDATION_RESULT_TYPE_NOT_FOUND},
+		// AS 0
+		{"192.168.0.0", 16, []uint32{65001}, config.RPKI_VALIDATION_RESULT_TYPE_INVALID},
+		{"2001:db8:1::", 48, []uint32{65001}, config.RPKI_VALIDATION_RESULT_TYPE_VALID},
+		{"2001:db8:1::", 64, []uint32{65001}, config.RPKI_VALIDATION_RESULT_TYPE_INVALID},
+		{"2001:db9::", 32, []uint32{65001}, config.RPKI_VALIDATION_RESULT_TYPE_NOT_FOUND},
+	}
+	for _, test := range tests {
+		path := rpkiTestPath(test.prefix, test.masklen, test.asPath)
+		assert.Equal(t, test.result, rt.validate(path, 65001), "%s/%d %v", test.prefix, test.masklen, test.asPath)
+	}
+
+	rt.replace("test", nil)
+	assert.Equal(t, 0, rt.count())
+	path := rpkiTestPath("10.0.0.0", 16, []uint32{65001})
+	assert.Equal(t, config.RpkiValidationResultType(config.RPKI_VALIDATION_RESULT_TYPE_NOT_FOUND), rt.validate(path, 65001))
+}
+
+func TestStaticRoaTable(t *testing.T) {
+	f, err := ioutil.TempFile("", "gobgp-roa")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(`
+[[RoaList]]
+Prefix = "10.0.0.0/16"
+MaxLen = 24
+As = 65001
+
+[[RoaList]]
+Prefix = "2001:db8::/32"
+As = 65002
+`)
+	f.Close()
+
+	ch := make(chan *roaTable, 1)
+	m, err := newRoaManager(config.RpkiValidation{RoaFile: f.Name()}, ch)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, m.table.count())
+	path := rpkiTestPath("10.0.1.0", 24, []uint32{65001})
+	assert.Equal(t, config.RpkiValidationResultType(config.RPKI_VALIDATION_RESULT_TYPE_VALID), m.table.validate(path, 65000))
+	path = rpkiTestPath("2001:db8:1::", 48, []uint32{65002})
+	assert.Equal(t, config.RpkiValidationResultType(config.RPKI_VALIDATION_RESULT_TYPE_INVALID), m.table.validate(path, 65000))
+
+	s := &staticRoaTable{path: f.Name()}
+	assert.NoError(t, s.load())
+	assert.False(t, s.reload())
+	ioutil.WriteFile(f.Name(), []byte("[[RoaList]]\nPrefix = \"10.0.0.0/16\"\nMaxLen = 8\nAs = 65001\n"), 0644)
+	os.Chtimes(f.Name(), time.Now(), s.modTime.Add(time.Second))
+	// the invalid file is ignored
+	assert.False(t, s.reload())
+	assert.Equal(t, 2, len(s.roas))
+}
+
+// rtrTestServer is a stand-in RPKI cache. It sends all the prefixes
+// to the reset query and the changes after the serial to the serial
+// query.
+type rtrTestServer struct {
+	mu        sync.Mutex
+	l         net.Listener
+	version   uint8
+	sessionID uint16
+	serial    uint32
+	prefixes  map[string]*bgp.RTRIPPrefix
+	changes   map[uint32][]*bgp.RTRIPPrefix
+	conns     []net.Conn
+}
+
+func newRtrTestServer(t *testing.T, version uint8, prefixes []*bgp.RTRIPPrefix) *rtrTestServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	s := &rtrTestServer{
+		l:         l,
+		version:   version,
+		sessionID: 100,
+		prefixes:  make(map[string]*bgp.RTRIPPrefix),
+		changes:   make(map[uint32][]*bgp.RTRIPPrefix),
+	}
+	for _, p := range prefixes {
+		s.prefixes[p.Prefix.String()+strconv.Itoa(int(p.PrefixLen))] = p
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.mu.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *rtrTestServer) port() uint32 {
+	return uint32(s.l.Addr().(*net.TCPAddr).Port)
+}
+
+func (s *rtrTestServer) close() {
+	s.l.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+}
+
+func (s *rtrTestServer) write(conn net.Conn, msgs ...bgp.RTRMessage) {
+	for _, m := range msgs {
+		b, _ := m.Serialize()
+		conn.Write(b)
+	}
+}
+
+func (s *rtrTestServer) serve(conn net.Conn) {
+	scanner := bufio.NewScanner(conn)
+	scanner.Split(bgp.SplitRTR)
+	for scanner.Scan() {
+		msg, err := bgp.ParseRTR(scanner.Bytes())
+		if err != nil {
+			conn.Close()
+			return
+		}
+		s.mu.Lock()
+		version := s.version
+		if q, ok := msg.(*bgp.RTRHeaderOnly); ok && q.Version > version {
+			s.write(conn, bgp.NewRTRErrorReport(version, bgp.RTR_UNSUPPORTED_PROTOCOL_VERSION, scanner.Bytes(), ""))
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		msgs := []bgp.RTRMessage{bgp.NewRTRCacheResponse(version, s.sessionID)}
+		switch m := msg.(type) {
+		case *bgp.RTRHeaderOnly:
+			for _, p := range s.prefixes {
+				msgs = append(msgs, bgp.NewRTRIPPrefix(version, p.Prefix, p.PrefixLen, p.MaxLen, p.AS, true))
+			}
+		case *bgp.RTRSerial:
+			for serial := m.Serial + 1; serial <= s.serial; serial++ {
+				for _, p := range s.changes[serial] {
+					msgs = append(msgs, bgp.NewRTRIPPrefix(version, p.Prefix, p.PrefixLen, p.MaxLen, p.AS, p.Flags == bgp.RTR_PREFIX_FLAG_ANNOUNCE))
+				}
+			}
+		}
+		msgs = append(msgs, bgp.NewRTREndOfData(version, s.sessionID, s.serial))
+		s.write(conn, msgs...)
+		s.mu.Unlock()
+	}
+}
+
+// apply the changes and notify the connected routers
+func (s *rtrTestServer) update(changes []*bgp.RTRIPPrefix) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.serial++
+	s.changes[s.serial] = changes
+	for _, p := range changes {
+		key := p.Prefix.String() + strconv.Itoa(int(p.PrefixLen))
+		if p.Flags == bgp.RTR_PREFIX_FLAG_ANNOUNCE {
+			s.prefixes[key] = p
+		} else {
+			delete(s.prefixes, key)
+		}
+	}
+	for _, conn := range s.conns {
+		s.write(conn, bgp.NewRTRSerialNotify(s.version, s.sessionID, s.serial))
+	}
+}
+
+func waitRoaUpdate(t *testing.T, ch chan struct{}) {
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatal("ROAs not updated")
+	}
+}
+
+func TestRtrClient(t *testing.T) {
+	for _, version := range []uint8{bgp.RTR_PROTOCOL_VERSION1, bgp.RTR_PROTOCOL_VERSION0} {
+		s := newRtrTestServer(t, version, []*bgp.RTRIPPrefix{
+			bgp.NewRTRIPPrefix(version, net.ParseIP("10.0.0.0").To4(), 16, 24, 65001, true),
+			bgp.NewRTRIPPrefix(version, net.ParseIP("2001:db8::"), 32, 32, 65001, true),
+		})
+		defer s.close()
+
+		rt := newRoaTable()
+		ch := make(chan struct{}, 8)
+		c := newRtrClient(config.RpkiServer{Address: net.ParseIP("127.0.0.1"), Port: s.port()}, rt, func() {
+			ch <- struct{}{}
+		})
+		go c.loop()
+		waitRoaUpdate(t, ch)
+		assert.Equal(t, 2, rt.count())
+		c.mu.Lock()
+		assert.Equal(t, version, c.version)
+		assert.True(t, c.up)
+		c.mu.Unlock()
+
+		path := rpkiTestPath("10.0.1.0", 24, []uint32{65001})
+		assert.Equal(t, config.RpkiValidationResultType(config.RPKI_VALIDATION_RESULT_TYPE_VALID), rt.validate(path, 65000))
+		path2 := rpkiTestPath("172.16.0.0", 16, []uint32{65002})
+		assert.Equal(t, config.RpkiValidationResultType(config.RPKI_VALIDATION_RESULT_TYPE_NOT_FOUND), rt.validate(path2, 65000))
+
+		// the serial notify triggers the serial query
+		s.update([]*bgp.RTRIPPrefix{
+			bgp.NewRTRIPPrefix(version, net.ParseIP("10.0.0.0").To4(), 16, 24, 65001, false),
+			bgp.NewRTRIPPrefix(version, net.ParseIP("10.0.0.0").To4(), 16, 16, 65001, true),
+			bgp.NewRTRIPPrefix(version, net.ParseIP("172.16.0.0").To4(), 12, 16, 65003, true),
+		})
+		waitRoaUpdate(t, ch)
+		assert.Equal(t, 3, rt.count())
+		assert.Equal(t, config.RpkiValidationResultType(config.RPKI_VALIDATION_RESULT_TYPE_INVALID), rt.validate(path, 65000))
+		assert.Equal(t, config.RpkiValidationResultType(config.RPKI_VALIDATION_RESULT_TYPE_INVALID), rt.validate(path2, 65000))
+		c.mu.Lock()
+		assert.Equal(t, uint32(1), c.serial)
+		c.mu.Unlock()
+	}
+}
+
+func TestPeerRevalidatePaths(t *testing.T) {
+	p := &Peer{
+		globalConfig: config.Global{As: 65000},
+		peerConfig: config.Neighbor{
+			AfiSafiList: []config.AfiSafi{config.AfiSafi{AfiSafiName: "ipv4-unicast"}},
+		},
+		adjRib:   table.NewAdjRib([]bgp.RouteFamily{bgp.RF_IPv4_UC}),
+		siblings: make(map[string]*serverMsgDataPeer),
+	}
+	pch := make(chan *peerMsg, 8)
+	p.siblings["10.0.0.254"] = &serverMsgDataPeer{address: net.ParseIP("10.0.0.254"), peerMsgCh: pch}
+
+	rt := newRoaTable()
+	p.roaTable = rt
+	path := rpkiTestPath("10.0.0.0", 24, []uint32{65001})
+	p.validatePaths([]table.Path{path})
+	p.adjRib.UpdateIn([]table.Path{path})
+	assert.Equal(t, config.RpkiValidationResultType(config.RPKI_VALIDATION_RESULT_TYPE_NOT_FOUND), path.GetValidation())
+
+	rt.replace("test", []*roa{&roa{Prefix: net.ParseIP("10.0.0.0").To4(), PrefixLen: 16, MaxLen: 24, AS: 65001}})
+	p.revalidatePaths()
+	// the path shared with the siblings isn't modified
+	assert.Equal(t, config.RpkiValidationResultType(config.RPKI_VALIDATION_RESULT_TYPE_NOT_FOUND), path.GetValidation())
+	m := <-pch
+	paths := m.msgData.([]table.Path)
+	assert.Equal(t, 1, len(paths))
+	assert.Equal(t, config.RpkiValidationResultType(config.RPKI_VALIDATION_RESULT_TYPE_VALID), paths[0].GetValidation())
+	assert.Equal(t, config.RpkiValidationResultType(config.RPKI_VALIDATION_RESULT_TYPE_VALID), p.adjRib.GetInPathList(bgp.RF_IPv4_UC)[0].GetValidation())
+
+	// nothing changed
+	p.revalidatePaths()
+	assert.Equal(t, 0, len(pch))
+}
@@ -22,10 +22,13 @@ import (
 	"github.com/osrg/gobgp/api"
 	"github.com/osrg/gobgp/config"
 	"github.com/osrg/gobgp/policy"
+	"github.com/osrg/gobgp/table"
 	"net"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type serverMsgType int
@@ -36,6 +39,13 @@ const (
 	SRV_MSG_PEER_DELETED
 	SRV_MSG_API
 	SRV_MSG_POLICY_UPDATED
+	SRV_MSG_AGGREGATES_UPDATED
+	SRV_MSG_NEXTHOP_RESOLVER
+	SRV_MSG_NEXTHOPS_UPDATED
+	SRV_MSG_ROUTE_SELECTION_UPDATED
+	SRV_MSG_GLOBAL_POLICY_UPDATED
+	SRV_MSG_RPKI_UPDATED
+	SRV_MSG_PEER_UPDATED
 )
 
 type serverMsg struct {
@@ -48,6 +58,11 @@ type serverMsgDataPeer struct {
 	address   net.IP
 }
 
+type serverMsgDataGlobalPolicy struct {
+	applyPolicy config.ApplyPolicy
+	policyMap   map[string]*policy.Policy
+}
+
 type peerMapInfo struct {
 	peer                *Peer
 	serverMsgCh         chan *serverMsg
@@ -57,16 +72,30 @@ type peerMapInfo struct {
 }
 
 type BgpServer struct {
-	bgpConfig      config.Bgp
-	globalTypeCh   chan config.Global
-	addedPeerCh    chan config.Neighbor
-	deletedPeerCh  chan config.Neighbor
-	RestReqCh      chan *api.RestRequest
-	listenPort     int
-	peerMap        map[string]peerMapInfo
-	globalRib      *Peer
-	policyUpdateCh chan config.RoutingPolicy
-	policyMap      map[string]*policy.Policy
+	bgpConfig        config.Bgp
+	globalTypeCh     chan config.Global
+	addedPeerCh      chan config.Neighbor
+	deletedPeerCh    chan config.Neighbor
+	updatedPeerCh    chan config.Neighbor
+	RestReqCh        chan *api.RestRequest
+	GrpcReqCh        chan *api.GrpcRequest
+	listenPort       int
+	peerMap          map[string]peerMapInfo
+	globalRib        *Peer
+	policyUpdateCh   chan config.RoutingPolicy
+	policyMap        map[string]*policy.Policy
+	policyConfig     config.RoutingPolicy
+	addedNetworkCh   chan config.Network
+	deletedNetworkCh chan config.Network
+	networkMap       map[string]config.Network
+	aggregateCh      chan config.Global
+	nexthopCh        chan []net.IP
+	rpkiCh           chan *roaTable
+	roaManager       *roaManager
+	routeSelectionCh chan config.Global
+	globalPolicyCh   chan config.ApplyPolicy
+	webhookCh        chan []config.EventWebhook
+	events           *eventBus
 }
 
 func NewBgpServer(port int) *BgpServer {
@@ -74,8 +103,19 @@ func NewBgpServer(port int) *BgpServer {
 	b.globalTypeCh = make(chan config.Global)
 	b.addedPeerCh = make(chan config.Neighbor)
 	b.deletedPeerCh = make(chan config.Neighbor)
+	b.updatedPeerCh = make(chan config.Neighbor)
 	b.RestReqCh = make(chan *api.RestRequest, 1)
+	b.GrpcReqCh = make(chan *api.GrpcRequest, 1)
 	b.policyUpdateCh = make(chan config.RoutingPolicy)
+	b.addedNetworkCh = make(chan config.Network)
+	b.deletedNetworkCh = make(chan config.Network)
+	b.networkMap = make(map[string]config.Network)
+	b.aggregateCh = make(chan config.Global)
+	b.nexthopCh = make(chan []net.IP)
+	b.rpkiCh = make(chan *roaTable, 1)
+	b.routeSelectionCh = make(chan config.Global)
+	b.globalPolicyCh = make(chan config.ApplyPolicy)
+	b.webhookCh = make(chan []config.EventWebhook)
 	b.listenPort = port
 	return &b
 }
@@ -117,7 +157,35 @@ func (server *BgpServer) Serve() {
 		NeighborAddress: g.RouterId,
 		AfiSafiList:     g.AfiSafiList,
 	}
-	server.globalRib = NewPeer(g, neighConf, globalSch, globalPch, nil, true, make(map[string]*policy.Policy))
+	server.events = newEventBus(g.EventWebhookList)
+	server.globalRib = NewPeer(g, neighConf, globalSch, globalPch, nil, true, make(map[string]*policy.Policy), nil)
+
+	if g.NexthopResolver.Type != config.NEXTHOP_RESOLVER_TYPE_NONE {
+		resolver, err := newNexthopResolver(g.NexthopResolver, server.nexthopCh)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"Topic": "Nexthop",
+				"Error": err,
+			}).Error("failed to start nexthop resolver")
+		} else {
+			globalSch <- &serverMsg{
+				msgType: SRV_MSG_NEXTHOP_RESOLVER,
+				msgData: resolver,
+			}
+		}
+	}
+
+	if rv := g.RpkiValidation; len(rv.RpkiServerList) > 0 || rv.RoaFile != "" {
+		m, err := newRoaManager(rv, server.rpkiCh)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"Topic": "Rpki",
+				"Error": err,
+			}).Error("failed to start RPKI validation")
+		} else {
+			server.roaManager = m
+		}
+	}
 
 	listenerMap := make(map[string]*net.TCPListener)
 	acceptCh := make(chan *net.TCPConn)
@@ -180,7 +248,13 @@ func (server *BgpServer) Serve() {
 				}
 				l = []*serverMsgDataPeer{globalRib}
 			}
-			p := NewPeer(server.bgpConfig.Global, peer, sch, pch, l, false, server.policyMap)
+			p := NewPeer(server.bgpConfig.Global, peer, sch, pch, l, false, server.policyMap, server.events)
+			if server.roaManager != nil {
+				sch <- &serverMsg{
+					msgType: SRV_MSG_RPKI_UPDATED,
+					msgData: server.roaManager.table,
+				}
+			}
 			d := &serverMsgDataPeer{
 				address:   peer.NeighborAddress,
 				peerMsgCh: pch,
@@ -222,15 +296,79 @@ func (server *BgpServer) Serve() {
 			} else {
 				log.Info("Can't delete a peer configuration for ", addr)
 			}
+		case peer := <-server.updatedPeerCh:
+			addr := peer.NeighborAddress.String()
+			if info, found := server.peerMap[addr]; found {
+				log.Info("Update a peer configuration for ", addr)
+				info.serverMsgCh <- &serverMsg{
+					msgType: SRV_MSG_PEER_UPDATED,
+					msgData: peer,
+				}
+			} else {
+				log.Info("Can't update a peer configuration for ", addr)
+			}
+		case n := <-server.addedNetworkCh:
+			server.networkMap[config.NetworkKey(n)] = n
+			sendNetworkPaths(globalPch, []table.Path{server.networkPath(n, false)})
+		case n := <-server.deletedNetworkCh:
+			key := config.NetworkKey(n)
+			if _, found := server.networkMap[key]; found {
+				delete(server.networkMap, key)
+				sendNetworkPaths(globalPch, []table.Path{server.networkPath(n, true)})
+			} else {
+				log.Info("Can't delete a network configuration for ", key)
+			}
+		case g := <-server.aggregateCh:
+			globalSch <- &serverMsg{
+				msgType: SRV_MSG_AGGREGATES_UPDATED,
+				msgData: g,
+			}
+		case g := <-server.routeSelectionCh:
+			msg := &serverMsg{
+				msgType: SRV_MSG_ROUTE_SELECTION_UPDATED,
+				msgData: g,
+			}
+			globalSch <- msg
+			sendServerMsgToRSClients(server.peerMap, msg)
+		case p := <-server.globalPolicyCh:
+			server.bgpConfig.ApplyPolicy = p
+			globalSch <- &serverMsg{
+				msgType: SRV_MSG_GLOBAL_POLICY_UPDATED,
+				msgData: &serverMsgDataGlobalPolicy{
+					applyPolicy: p,
+					policyMap:   server.policyMap,
+				},
+			}
+		case hooks := <-server.webhookCh:
+			server.events.updateWebhooks(hooks)
+		case nexthops := <-server.nexthopCh:
+			globalSch <- &serverMsg{
+				msgType: SRV_MSG_NEXTHOPS_UPDATED,
+				msgData: nexthops,
+			}
+		case t := <-server.rpkiCh:
+			sendServerMsgToAll(server.peerMap, &serverMsg{
+				msgType: SRV_MSG_RPKI_UPDATED,
+				msgData: t,
+			})
 		case restReq := <-server.RestReqCh:
 			server.handleRest(restReq)
+		case grpcReq := <-server.GrpcReqCh:
+			server.handleGrpc(grpcReq)
 		case pl := <-server.policyUpdateCh:
 			server.SetPolicy(pl)
 			msg := &serverMsg{
 				msgType: SRV_MSG_POLICY_UPDATED,
 				msgData: server.policyMap,
 			}
+			globalSch <- msg
 			sendServerMsgToAll(server.peerMap, msg)
+			// the policies of networks might be changed
+			pathList := make([]table.Path, 0, len(server.networkMap))
+			for _, n := range server.networkMap {
+				pathList = append(pathList, server.networkPath(n, false))
+			}
+			sendNetworkPaths(globalPch, pathList)
 		}
 	}
 }
@@ -261,17 +399,99 @@ func (server *BgpServer) PeerDelete(peer config.Neighbor) {
 	server.deletedPeerCh <- peer
 }
 
+// apply the configuration of the neighbor which doesn't need a new
+// session, see config.NeighborNeedsReset.
+func (server *BgpServer) PeerUpdate(peer config.Neighbor) {
+	server.updatedPeerCh <- peer
+}
+
+func (server *BgpServer) NetworkAdd(n config.Network) {
+	server.addedNetworkCh <- n
+}
+
+func (server *BgpServer) NetworkDelete(n config.Network) {
+	server.deletedNetworkCh <- n
+}
+
+func (server *BgpServer) UpdateAggregates(g config.Global) {
+	server.aggregateCh <- g
+}
+
+func (server *BgpServer) UpdateRouteSelection(g config.Global) {
+	server.routeSelectionCh <- g
+}
+
+func (server *BgpServer) UpdateGlobalPolicy(p config.ApplyPolicy) {
+	server.globalPolicyCh <- p
+}
+
+func (server *BgpServer) UpdateEventWebhooks(g config.Global) {
+	server.webhookCh <- g.EventWebhookList
+}
+
+// create the path for a network configuration. If the network's import
+// policies reject it, the withdrawn path is returned instead.
+func (server *BgpServer) networkPath(n config.Network, isWithdraw bool) table.Path {
+	path, err := table.CreateNetworkPath(n, isWithdraw, time.Now())
+	if err != nil {
+		log.WithFields(log.Fields{
+			"Topic": "Server",
+			"Key":   config.NetworkKey(n),
+			"Error": err,
+		}).Error("invalid network configuration")
+		return nil
+	}
+	if isWithdraw {
+		return path
+	}
+
+	policies := make([]*policy.Policy, 0)
+	for _, name := range n.ApplyPolicy.ImportPolicies {
+		if pol, ok := server.policyMap[name]; ok {
+			policies = append(policies, pol)
+		}
+	}
+	if len(policies) == 0 {
+		return path
+	}
+
+	applied, newPath := applyPolicies(policies, &path, &policy.PolicyOptions{LocalAs: server.bgpConfig.Global.As})
+	if (applied && newPath != nil) || (!applied && n.ApplyPolicy.DefaultImportPolicy == config.DEFAULT_POLICY_TYPE_ACCEPT_ROUTE) {
+		return *newPath
+	}
+	log.WithFields(log.Fields{
+		"Topic": "Server",
+		"Key":   config.NetworkKey(n),
+	}).Info("network is rejected by policy")
+	path, _ = table.CreateNetworkPath(n, true, time.Now())
+	return path
+}
+
+func sendNetworkPaths(ch chan *peerMsg, pathList []table.Path) {
+	paths := make([]table.Path, 0, len(pathList))
+	for _, p := range pathList {
+		if p != nil {
+			paths = append(paths, p)
+		}
+	}
+	if len(paths) == 0 {
+		return
+	}
+	ch <- &peerMsg{
+		msgType: PEER_MSG_PATH,
+		msgData: paths,
+	}
+}
+
 func (server *BgpServer) UpdatePolicy(policy config.RoutingPolicy) {
 	server.policyUpdateCh <- policy
 }
 
+// the policies not updated are kept so that the peers reconfigure
+// only the paths the updated policies are applied to
 func (server *BgpServer) SetPolicy(pl config.RoutingPolicy) {
-	pMap := make(map[string]*policy.Policy)
-	df := pl.DefinedSets
-	for _, p := range pl.PolicyDefinitionList {
-		pMap[p.Name] = policy.NewPolicy(p.Name, p, df)
-	}
-	server.policyMap = pMap
+	server.policyMap = policy.UpdatePolicyMap(server.policyMap, server.policyConfig, pl)
+	server.policyConfig = pl
 }
 
 func (server *BgpServer) handleRest(restReq *api.RestRequest) {
@@ -300,16 +520,61 @@ func (server *BgpServer) handleRest(restReq *api.RestRequest) {
 		}
 		restReq.ResponseCh <- result
 		close(restReq.ResponseCh)
-	case api.REQ_GLOBAL_RIB:
+	case api.REQ_GLOBAL_RIB, api.REQ_GLOBAL_RIB_EXPLAIN, api.REQ_GLOBAL_RIB_WATCH:
 		msg := &serverMsg{
 			msgType: SRV_MSG_API,
 			msgData: restReq,
 		}
 		server.globalRib.serverMsgCh <- msg
+	case api.REQ_EVENTS:
+		server.events.subscribeCh <- &restClient{restReq}
+	case api.REQ_POLICIES, api.REQ_POLICY:
+		result := &api.RestResponse{}
+		if restReq.RequestType == api.REQ_POLICY {
+			if pol, ok := server.policyMap[restReq.Name]; ok {
+				result.Data, _ = json.Marshal(newPolicyHits(pol))
+			} else {
+				result.ResponseErr = fmt.Errorf("policy %s isn't defined", restReq.Name)
+			}
+		} else {
+			names := make([]string, 0, len(server.policyMap))
+			for name, _ := range server.policyMap {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			l := make([]*policyHits, 0, len(names))
+			for _, name := range names {
+				l = append(l, newPolicyHits(server.policyMap[name]))
+			}
+			result.Data, _ = json.Marshal(l)
+		}
+		restReq.ResponseCh <- result
+		close(restReq.ResponseCh)
+	case api.REQ_RPKI:
+		result := &api.RestResponse{}
+		if server.roaManager != nil {
+			result.Data, _ = json.Marshal(server.roaManager)
+		} else {
+			result.ResponseErr = fmt.Errorf("RPKI validation isn't configured")
+		}
+		restReq.ResponseCh <- result
+		close(restReq.ResponseCh)
+	case api.REQ_POLICY_TEST:
+		result := &api.RestResponse{}
+		r, err := server.testPolicy(restReq.Name, restReq.Data)
+		if err != nil {
+			result.ResponseErr = err
+		} else {
+			result.Data, _ = json.Marshal(r)
+		}
+		restReq.ResponseCh <- result
+		close(restReq.ResponseCh)
 	case api.REQ_LOCAL_RIB, api.REQ_NEIGHBOR_SHUTDOWN, api.REQ_NEIGHBOR_RESET,
 		api.REQ_NEIGHBOR_SOFT_RESET, api.REQ_NEIGHBOR_SOFT_RESET_IN, api.REQ_NEIGHBOR_SOFT_RESET_OUT,
-		api.REQ_ADJ_RIB_IN, api.REQ_ADJ_RIB_OUT,
-		api.REQ_NEIGHBOR_ENABLE, api.REQ_NEIGHBOR_DISABLE:
+		api.REQ_ADJ_RIB_IN, api.REQ_ADJ_RIB_OUT, api.REQ_LOCAL_RIB_EXPLAIN,
+		api.REQ_NEIGHBOR_ENABLE, api.REQ_NEIGHBOR_DISABLE,
+		api.REQ_NEIGHBOR_POLICY_TEST_IMPORT, api.REQ_NEIGHBOR_POLICY_TEST_EXPORT,
+		api.REQ_ADJ_RIB_IN_FILTERED, api.REQ_NEIGHBOR_POLICY_COUNTERS:
 
 		remoteAddr := restReq.RemoteAddr
 		result := &api.RestResponse{}
@@ -327,3 +592,88 @@ func (server *BgpServer) handleRest(restReq *api.RestRequest) {
 		}
 	}
 }
+
+// the responses of a streaming call are sent in another goroutine not
+// to block the server on the client
+func sendGrpcResponses(req *api.GrpcRequest, results []*api.GrpcResponse) {
+	streamGrpcResponses(req, func(send func(*api.GrpcResponse)) {
+		for _, r := range results {
+			send(r)
+		}
+	})
+}
+
+// the responses are built and sent by the function in another goroutine,
+// so large responses like the ribs are converted without blocking the
+// server
+func streamGrpcResponses(req *api.GrpcRequest, stream func(send func(*api.GrpcResponse))) {
+	go func() {
+		stream(func(r *api.GrpcResponse) {
+			req.ResponseCh <- r
+		})
+		close(req.ResponseCh)
+	}()
+}
+
+func (server *BgpServer) handleGrpc(grpcReq *api.GrpcRequest) {
+	switch grpcReq.RequestType {
+	case api.REQ_NEIGHBORS:
+		results := make([]*api.GrpcResponse, 0, len(server.peerMap))
+		for _, info := range server.peerMap {
+			results = append(results, &api.GrpcResponse{Data: info.peer.ToApiStruct()})
+		}
+		sendGrpcResponses(grpcReq, results)
+	case api.REQ_NEIGHBOR:
+		result := &api.GrpcResponse{}
+		if info, found := server.peerMap[grpcReq.RemoteAddr]; found {
+			result.Data = info.peer.ToApiStruct()
+		} else {
+			result.ResponseErr = fmt.Errorf("Neighbor that has %v does not exist.", grpcReq.RemoteAddr)
+		}
+		sendGrpcResponses(grpcReq, []*api.GrpcResponse{result})
+	case api.REQ_GLOBAL_RIB, api.REQ_GLOBAL_RIB_WATCH:
+		server.globalRib.serverMsgCh <- &serverMsg{
+			msgType: SRV_MSG_API,
+			msgData: grpcReq,
+		}
+	case api.REQ_EVENTS:
+		server.events.subscribeCh <- &grpcClient{grpcReq}
+	case api.REQ_POLICIES:
+		names := make([]string, 0, len(server.policyMap))
+		for name, _ := range server.policyMap {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		results := make([]*api.GrpcResponse, 0, len(names))
+		for _, name := range names {
+			results = append(results, &api.GrpcResponse{Data: newPolicyHits(server.policyMap[name]).ToApiStruct()})
+		}
+		sendGrpcResponses(grpcReq, results)
+	case api.REQ_POLICY:
+		result := &api.GrpcResponse{}
+		if pol, ok := server.policyMap[grpcReq.Name]; ok {
+			result.Data = newPolicyHits(pol).ToApiStruct()
+		} else {
+			result.ResponseErr = fmt.Errorf("policy %s isn't defined", grpcReq.Name)
+		}
+		sendGrpcResponses(grpcReq, []*api.GrpcResponse{result})
+	case api.REQ_LOCAL_RIB, api.REQ_ADJ_RIB_IN, api.REQ_ADJ_RIB_OUT,
+		api.REQ_NEIGHBOR_SHUTDOWN, api.REQ_NEIGHBOR_RESET,
+		api.REQ_NEIGHBOR_SOFT_RESET, api.REQ_NEIGHBOR_SOFT_RESET_IN, api.REQ_NEIGHBOR_SOFT_RESET_OUT,
+		api.REQ_NEIGHBOR_ENABLE, api.REQ_NEIGHBOR_DISABLE:
+		if info, found := server.peerMap[grpcReq.RemoteAddr]; found {
+			info.peer.serverMsgCh <- &serverMsg{
+				msgType: SRV_MSG_API,
+				msgData: grpcReq,
+			}
+		} else {
+			sendGrpcResponses(grpcReq, []*api.GrpcResponse{&api.GrpcResponse{
+				ResponseErr: fmt.Errorf("Neighbor that has %v does not exist.", grpcReq.RemoteAddr),
+			}})
+		}
+	default:
+		sendGrpcResponses(grpcReq, []*api.GrpcResponse{&api.GrpcResponse{
+			ResponseErr: fmt.Errorf("unsupported request type: %d", grpcReq.RequestType),
+		}})
+	}
+}
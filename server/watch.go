@@ -0,0 +1,112 @@
+// Copyright (C) 2015 Nippon Telegraph and Telephone Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"github.com/osrg/gobgp/api"
+	"github.com/osrg/gobgp/packet"
+	"github.com/osrg/gobgp/table"
+)
+
+const (
+	WATCH_EVENT_ADD          = "add"
+	WATCH_EVENT_WITHDRAW     = "withdraw"
+	WATCH_EVENT_BEST_CHANGED = "best-changed"
+)
+
+// the best path change of a destination sent to the watchers. Path is
+// the withdrawn best path for the withdraw event.
+type watchEvent struct {
+	Type   string
+	Prefix string
+	Path   table.Path
+}
+
+func newWatchEvent(c *table.BestPathChange) *watchEvent {
+	switch {
+	case c.New == nil:
+		return &watchEvent{Type: WATCH_EVENT_WITHDRAW, Prefix: c.Old.GetNlri().String(), Path: c.Old}
+	case c.Old == nil:
+		return &watchEvent{Type: WATCH_EVENT_ADD, Prefix: c.New.GetNlri().String(), Path: c.New}
+	}
+	return &watchEvent{Type: WATCH_EVENT_BEST_CHANGED, Prefix: c.New.GetNlri().String(), Path: c.New}
+}
+
+func (e *watchEvent) ToApiStruct() *api.BestPathEvent {
+	return &api.BestPathEvent{
+		Type:   e.Type,
+		Prefix: e.Prefix,
+		Path:   newApiPath(e.Path),
+	}
+}
+
+// the client watching the best path changes of a route family in the
+// global rib. the events are sent in a batch per calculation of the
+// best paths not to block the global rib.
+type bestPathWatcher struct {
+	rf     bgp.RouteFamily
+	client streamClient
+}
+
+// start sending the best path changes to the client. the current best
+// paths are sent as the add events first if the snapshot is requested.
+func (peer *Peer) addWatcher(c streamClient, rf bgp.RouteFamily, snapshot bool) {
+	if _, ok := peer.rib.Tables[rf]; !ok {
+		c.fail(fmt.Errorf("address family %s isn't configured", rf))
+		return
+	}
+	if snapshot {
+		events := make([]*watchEvent, 0)
+		for _, p := range peer.rib.GetPathList(rf) {
+			events = append(events, newWatchEvent(&table.BestPathChange{New: p}))
+		}
+		if !c.send(events) {
+			c.close()
+			return
+		}
+	} else {
+		c.start()
+	}
+	peer.watchers = append(peer.watchers, &bestPathWatcher{rf: rf, client: c})
+}
+
+func (peer *Peer) notifyWatchers(changes []*table.BestPathChange) {
+	if len(peer.watchers) == 0 {
+		return
+	}
+	events := make(map[bgp.RouteFamily][]*watchEvent)
+	for _, c := range changes {
+		e := newWatchEvent(c)
+		rf := e.Path.GetRouteFamily()
+		events[rf] = append(events[rf], e)
+	}
+	watchers := make([]*bestPathWatcher, 0, len(peer.watchers))
+	for _, w := range peer.watchers {
+		alive := false
+		if e, ok := events[w.rf]; ok {
+			alive = w.client.send(e)
+		} else {
+			alive = !w.client.canceled()
+		}
+		if alive {
+			watchers = append(watchers, w)
+		} else {
+			w.client.close()
+		}
+	}
+	peer.watchers = watchers
+}
@@ -0,0 +1,130 @@
+// Copyright (C) 2015 Nippon Telegraph and Telephone Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"github.com/osrg/gobgp/api"
+	"github.com/osrg/gobgp/packet"
+	"github.com/osrg/gobgp/table"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func watchRequest(rf bgp.RouteFamily, snapshot bool, size int) *api.RestRequest {
+	req := api.NewRestRequest(api.REQ_GLOBAL_RIB_WATCH, "", rf)
+	req.Snapshot = snapshot
+	req.ResponseCh = make(chan *api.RestResponse, size)
+	req.Done = make(chan struct{})
+	return req
+}
+
+func watchEvents(assert *assert.Assertions, req *api.RestRequest) []map[string]interface{} {
+	res := <-req.ResponseCh
+	assert.Nil(res.Err())
+	events := make([]map[string]interface{}, 0)
+	assert.Nil(json.Unmarshal(res.Data, &events))
+	return events
+}
+
+func TestBestPathWatcher(t *testing.T) {
+	assert := assert.New(t)
+	globalRib := &Peer{
+		isGlobalRib: true,
+		rib:         table.NewTableManager("global", []bgp.RouteFamily{bgp.RF_IPv4_UC}),
+	}
+	addWatcher := func(req *api.RestRequest) {
+		globalRib.addWatcher(&restClient{req}, req.RouteFamily, req.Snapshot)
+	}
+	globalRib.rib.SetBestPathWatcher(globalRib.notifyWatchers)
+	path := testPath(bgp.NewNLRInfo(24, "10.10.1.0"))
+	globalRib.rib.ProcessPaths([]table.Path{path})
+
+	// not configured address family
+	req := watchRequest(bgp.RF_IPv6_UC, false, api.WATCH_QUEUE_SIZE)
+	addWatcher(req)
+	assert.NotNil((<-req.ResponseCh).Err())
+	_, ok := <-req.ResponseCh
+	assert.False(ok)
+
+	snapshot := watchRequest(bgp.RF_IPv4_UC, true, api.WATCH_QUEUE_SIZE)
+	addWatcher(snapshot)
+	events := watchEvents(assert, snapshot)
+	assert.Equal(len(events), 1)
+	assert.Equal(events[0]["Type"], WATCH_EVENT_ADD)
+	assert.Equal(events[0]["Prefix"], "10.10.1.0/24")
+
+	req = watchRequest(bgp.RF_IPv4_UC, false, api.WATCH_QUEUE_SIZE)
+	addWatcher(req)
+	assert.Nil((<-req.ResponseCh).Data)
+	slow := watchRequest(bgp.RF_IPv4_UC, false, 1)
+	addWatcher(slow)
+	assert.Equal(len(globalRib.watchers), 3)
+
+	added := testPath(bgp.NewNLRInfo(24, "10.10.2.0"))
+	globalRib.rib.ProcessPaths([]table.Path{added, path.Clone(false)})
+	events = watchEvents(assert, req)
+	assert.Equal(len(events), 2)
+	assert.Equal(events[0]["Type"], WATCH_EVENT_ADD)
+	assert.Equal(events[0]["Prefix"], "10.10.2.0/24")
+	assert.Equal(events[1]["Type"], WATCH_EVENT_BEST_CHANGED)
+	assert.Equal(events[1]["Prefix"], "10.10.1.0/24")
+
+	// the slow watcher is dropped and the canceled one is removed
+	close(snapshot.Done)
+	globalRib.rib.ProcessPaths([]table.Path{added.Clone(true)})
+	events = watchEvents(assert, req)
+	assert.Equal(len(events), 1)
+	assert.Equal(events[0]["Type"], WATCH_EVENT_WITHDRAW)
+	assert.Equal(events[0]["Prefix"], "10.10.2.0/24")
+	assert.Equal(len(globalRib.watchers), 1)
+	assert.Equal(len(slow.ResponseCh), 1)
+	<-slow.ResponseCh
+	_, ok = <-slow.ResponseCh
+	assert.False(ok)
+}
+
+func TestBestPathWatcherGrpc(t *testing.T) {
+	assert := assert.New(t)
+	globalRib := &Peer{
+		isGlobalRib: true,
+		rib:         table.NewTableManager("global", []bgp.RouteFamily{bgp.RF_IPv4_UC}),
+	}
+	globalRib.rib.SetBestPathWatcher(globalRib.notifyWatchers)
+	path := testPath(bgp.NewNLRInfo(24, "10.10.1.0"))
+	globalRib.rib.ProcessPaths([]table.Path{path})
+
+	req := api.NewGrpcRequest(api.REQ_GLOBAL_RIB_WATCH, "", bgp.RF_IPv4_UC)
+	req.Snapshot = true
+	req.ResponseCh = make(chan *api.GrpcResponse, api.WATCH_QUEUE_SIZE)
+	req.Done = make(chan struct{})
+	globalRib.handleGrpc(req)
+	events := (<-req.ResponseCh).Data.([]*api.BestPathEvent)
+	assert.Equal(len(events), 1)
+	assert.Equal(events[0].Type, WATCH_EVENT_ADD)
+	assert.Equal(events[0].Prefix, "10.10.1.0/24")
+	assert.Equal(events[0].Path.Prefix, "10.10.1.0/24")
+
+	globalRib.rib.ProcessPaths([]table.Path{path.Clone(true)})
+	events = (<-req.ResponseCh).Data.([]*api.BestPathEvent)
+	assert.Equal(len(events), 1)
+	assert.Equal(events[0].Type, WATCH_EVENT_WITHDRAW)
+
+	close(req.Done)
+	globalRib.rib.ProcessPaths([]table.Path{testPath(bgp.NewNLRInfo(24, "10.10.2.0"))})
+	_, ok := <-req.ResponseCh
+	assert.False(ok)
+}
@@ -0,0 +1,320 @@
+// Copyright (C) 2015 Nippon Telegraph and Telephone Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package table
+
+import (
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/osrg/gobgp/config"
+	"github.com/osrg/gobgp/packet"
+	"net"
+	"reflect"
+	"sort"
+	"time"
+)
+
+type aggregate struct {
+	config     config.AggregateAddress
+	rf         bgp.RouteFamily
+	prefix     *net.IPNet
+	aggregator *bgp.PathAttributeAggregator
+	// the best paths of more specific prefixes in the loc-RIB
+	contributors map[string]Path
+	// the aggregate path installed in the table. nil if no
+	// contributor exists.
+	path Path
+}
+
+func newAggregate(rf bgp.RouteFamily, c config.AggregateAddress, as uint32, routerId net.IP) (*aggregate, error) {
+	var bits int
+	switch rf {
+	case bgp.RF_IPv4_UC:
+		bits = 32
+	case bgp.RF_IPv6_UC:
+		bits = 128
+	default:
+		return nil, fmt.Errorf("aggregate address isn't supported for %s", rf)
+	}
+	if c.Address == nil || int(c.Masklength) > bits || (c.Address.To4() != nil) != (bits == 32) {
+		return nil, fmt.Errorf("invalid aggregate address: %s/%d", c.Address, c.Masklength)
+	}
+	mask := net.CIDRMask(int(c.Masklength), bits)
+	return &aggregate{
+		config:       c,
+		rf:           rf,
+		prefix:       &net.IPNet{IP: c.Address.Mask(mask), Mask: mask},
+		aggregator:   bgp.NewPathAttributeAggregator(as, routerId.String()),
+		contributors: make(map[string]Path),
+	}, nil
+}
+
+// return true if the prefix of the path is more specific than the
+// aggregate address
+func (a *aggregate) isContributor(path Path) bool {
+	if path.GetRouteFamily() != a.rf {
+		return false
+	}
+	_, n, err := net.ParseCIDR(path.getPrefix())
+	if err != nil {
+		return false
+	}
+	l1, _ := n.Mask.Size()
+	l2, _ := a.prefix.Mask.Size()
+	return l1 > l2 && a.prefix.Contains(n.IP)
+}
+
+func (a *aggregate) createPath() Path {
+	// RFC 4271 9.2.2.2
+	// the ORIGIN of the aggregated route is INCOMPLETE if at least
+	// one route has INCOMPLETE, EGP if at least one route has EGP
+	// and IGP otherwise.
+	origin := uint8(bgp.BGP_ORIGIN_ATTR_TYPE_IGP)
+	asMap := make(map[uint32]bool)
+	for _, p := range a.contributors {
+		if _, attr := p.getPathAttr(bgp.BGP_ATTR_TYPE_ORIGIN); attr != nil {
+			if o := attr.(*bgp.PathAttributeOrigin).Value[0]; o > origin {
+				origin = o
+			}
+		}
+		if _, attr := p.getPathAttr(bgp.BGP_ATTR_TYPE_AS_PATH); attr != nil {
+			for _, param := range attr.(*bgp.PathAttributeAsPath).Value {
+				for _, as := range param.(*bgp.As4PathParam).AS {
+					asMap[as] = true
+				}
+			}
+		}
+	}
+
+	attrs := []bgp.PathAttributeInterface{bgp.NewPathAttributeOrigin(origin)}
+	asParams := []bgp.AsPathParamInterface{}
+	if a.config.AsSet {
+		asList := make([]uint32, 0, len(asMap))
+		for as, _ := range asMap {
+			asList = append(asList, as)
+		}
+		sort.Slice(asList, func(i, j int) bool { return asList[i] < asList[j] })
+		if len(asList) > 0 {
+			asParams = append(asParams, bgp.NewAs4PathParam(bgp.BGP_ASPATH_ATTR_TYPE_SET, asList))
+		}
+	}
+	attrs = append(attrs, bgp.NewPathAttributeAsPath(asParams))
+	if !a.config.AsSet {
+		// the AS_PATH information of the contributors is lost
+		attrs = append(attrs, bgp.NewPathAttributeAtomicAggregate())
+	}
+	attrs = append(attrs, a.aggregator)
+
+	var nlri bgp.AddrPrefixInterface
+	length, _ := a.prefix.Mask.Size()
+	if a.rf == bgp.RF_IPv4_UC {
+		nlri = bgp.NewNLRInfo(uint8(length), a.prefix.IP.String())
+		attrs = append(attrs, bgp.NewPathAttributeNextHop("0.0.0.0"))
+	} else {
+		nlri = bgp.NewIPv6AddrPrefix(uint8(length), a.prefix.IP.String())
+		mpreach := bgp.NewPathAttributeMpReachNLRI("::", []bgp.AddrPrefixInterface{nlri})
+		attrs = append([]bgp.PathAttributeInterface{mpreach}, attrs...)
+	}
+	return CreatePath(nil, nlri, attrs, false, time.Now())
+}
+
+// configure the aggregate addresses. return the paths that have to be
+// advertised or withdrawn due to the change.
+func (manager *TableManager) SetAggregates(g *config.Global) []Path {
+	aggregates := make(map[bgp.RouteFamily][]*aggregate)
+	added := make([]*aggregate, 0)
+	for _, a := range g.AfiSafiList {
+		rf, _ := bgp.GetRouteFamily(a.AfiSafiName)
+		if _, ok := manager.Tables[rf]; !ok {
+			continue
+		}
+	next:
+		for _, c := range a.AggregateAddressList {
+			for _, agg := range manager.aggregates[rf] {
+				if reflect.DeepEqual(agg.config, c) {
+					aggregates[rf] = append(aggregates[rf], agg)
+					continue next
+				}
+			}
+			agg, err := newAggregate(rf, c, g.As, g.RouterId)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"Topic": "table",
+					"Owner": manager.owner,
+					"Error": err,
+				}).Error("failed to configure aggregate address")
+				continue
+			}
+			aggregates[rf] = append(aggregates[rf], agg)
+			added = append(added, agg)
+		}
+	}
+
+	deleted := make([]*aggregate, 0)
+	for rf, list := range manager.aggregates {
+	found:
+		for _, agg := range list {
+			for _, a := range aggregates[rf] {
+				if a == agg {
+					continue found
+				}
+			}
+			deleted = append(deleted, agg)
+		}
+	}
+	old := manager.aggregates
+	manager.aggregates = aggregates
+
+	paths := make([]Path, 0)
+	advertised := make(map[string]bool)
+	for _, agg := range deleted {
+		log.WithFields(log.Fields{
+			"Topic": "table",
+			"Owner": manager.owner,
+			"Key":   agg.prefix.String(),
+		}).Info("aggregate address removed")
+		contributors := agg.contributors
+		agg.contributors = make(map[string]Path)
+		paths = append(paths, manager.updateAggregate(agg)...)
+		if agg.config.SummaryOnly {
+			for _, p := range contributors {
+				if !manager.isSuppressed(p) && !advertised[p.getPrefix()] {
+					advertised[p.getPrefix()] = true
+					paths = append(paths, p)
+				}
+			}
+		}
+	}
+
+	withdrawn := make(map[string]bool)
+	for _, agg := range added {
+		log.WithFields(log.Fields{
+			"Topic": "table",
+			"Owner": manager.owner,
+			"Key":   agg.prefix.String(),
+		}).Info("aggregate address added")
+		for _, dest := range manager.Tables[agg.rf].getDestinations() {
+			if p := dest.getBestPath(); p != nil && agg.isContributor(p) {
+				agg.contributors[p.getPrefix()] = p
+				// withdraw the path only if it was advertised, that
+				// is, not suppressed yet by any other aggregate.
+				if agg.config.SummaryOnly && !suppressed(old, p) && !withdrawn[p.getPrefix()] {
+					withdrawn[p.getPrefix()] = true
+					paths = append(paths, p.Clone(true))
+				}
+			}
+		}
+		paths = append(paths, manager.updateAggregate(agg)...)
+	}
+	return paths
+}
+
+// return true if the path is suppressed by a summary-only aggregate.
+// no path, like the one of a destination without the best path, is
+// never suppressed.
+func (manager *TableManager) isSuppressed(path Path) bool {
+	if path == nil {
+		return false
+	}
+	return suppressed(manager.aggregates, path)
+}
+
+func suppressed(aggregates map[bgp.RouteFamily][]*aggregate, path Path) bool {
+	for _, agg := range aggregates[path.GetRouteFamily()] {
+		if agg.config.SummaryOnly && agg.isContributor(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// install or withdraw the aggregate path according to the current
+// contributors.
+func (manager *TableManager) updateAggregate(agg *aggregate) []Path {
+	var path Path
+	if len(agg.contributors) == 0 {
+		if agg.path == nil {
+			return []Path{}
+		}
+		log.WithFields(log.Fields{
+			"Topic": "table",
+			"Owner": manager.owner,
+			"Key":   agg.prefix.String(),
+		}).Info("aggregate address deactivated")
+		path = agg.path.Clone(true)
+		agg.path = nil
+	} else {
+		path = agg.createPath()
+		if agg.path != nil && reflect.DeepEqual(agg.path.getPathAttrs(), path.getPathAttrs()) {
+			return []Path{}
+		}
+		if agg.path == nil {
+			log.WithFields(log.Fields{
+				"Topic":        "table",
+				"Owner":        manager.owner,
+				"Key":          agg.prefix.String(),
+				"Contributors": len(agg.contributors),
+			}).Info("aggregate address activated")
+		}
+		agg.path = path
+	}
+	dest := insert(manager.Tables[agg.rf], path)
+	paths, _ := manager.calculate([]Destination{dest})
+	return manager.processAggregates(paths)
+}
+
+// update the contributors of the aggregates with the best path
+// changes and filter out the paths suppressed by summary-only
+// aggregates. the changes of the aggregate paths are appended.
+func (manager *TableManager) processAggregates(pathList []Path) []Path {
+	if len(manager.aggregates) == 0 {
+		return pathList
+	}
+	paths := make([]Path, 0, len(pathList))
+	changed := make([]*aggregate, 0)
+	for _, path := range pathList {
+		suppressed := false
+		for _, agg := range manager.aggregates[path.GetRouteFamily()] {
+			if !agg.isContributor(path) {
+				continue
+			}
+			if path.IsWithdraw() {
+				delete(agg.contributors, path.getPrefix())
+			} else {
+				agg.contributors[path.getPrefix()] = path
+			}
+			found := false
+			for _, c := range changed {
+				if c == agg {
+					found = true
+					break
+				}
+			}
+			if !found {
+				changed = append(changed, agg)
+			}
+			if agg.config.SummaryOnly {
+				suppressed = true
+			}
+		}
+		if !suppressed {
+			paths = append(paths, path)
+		}
+	}
+	for _, agg := range changed {
+		paths = append(paths, manager.updateAggregate(agg)...)
+	}
+	return paths
+}
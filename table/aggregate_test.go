@@ -0,0 +1,128 @@
+// Copyright (C) 2015 Nippon Telegraph and Telephone Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package table
+
+import (
+	"github.com/osrg/gobgp/config"
+	"github.com/osrg/gobgp/packet"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+)
+
+func aggregateGlobal(c config.AggregateAddress) *config.Global {
+	return &config.Global{
+		As:       65001,
+		RouterId: net.ParseIP("10.0.0.1"),
+		AfiSafiList: []config.AfiSafi{
+			config.AfiSafi{
+				AfiSafiName:          "ipv4-unicast",
+				AggregateAddressList: []config.AggregateAddress{c},
+			},
+		},
+	}
+}
+
+func TestAggregateAtomicAggregate(t *testing.T) {
+	tm := NewTableManager("TestAggregateAtomicAggregate", []bgp.RouteFamily{bgp.RF_IPv4_UC})
+	paths := tm.SetAggregates(aggregateGlobal(config.AggregateAddress{
+		Address:    net.ParseIP("10.10.0.0"),
+		Masklength: 16,
+	}))
+	assert.Equal(t, len(paths), 0)
+
+	peer := peerR1()
+	pList, err := tm.ProcessUpdate(peer, update_fromR1())
+	assert.NoError(t, err)
+	assert.Equal(t, len(pList), 2)
+	assert.Equal(t, pList[0].getPrefix(), "10.10.10.0/24")
+	agg := pList[1]
+	assert.Equal(t, agg.getPrefix(), "10.10.0.0/16")
+	assert.Equal(t, agg.IsWithdraw(), false)
+	assert.Nil(t, agg.GetSource())
+	_, attr := agg.getPathAttr(bgp.BGP_ATTR_TYPE_ATOMIC_AGGREGATE)
+	assert.NotNil(t, attr)
+	_, attr = agg.getPathAttr(bgp.BGP_ATTR_TYPE_AGGREGATOR)
+	assert.Equal(t, attr.(*bgp.PathAttributeAggregator).Value.AS, uint32(65001))
+	_, attr = agg.getPathAttr(bgp.BGP_ATTR_TYPE_AS_PATH)
+	assert.Equal(t, len(attr.(*bgp.PathAttributeAsPath).Value), 0)
+
+	// withdraw the only contributor
+	withdrawn := []bgp.WithdrawnRoute{bgp.WithdrawnRoute{IPAddrPrefix: *bgp.NewIPAddrPrefix(24, "10.10.10.0")}}
+	pList, err = tm.ProcessUpdate(peer, bgp.NewBGPUpdateMessage(withdrawn, []bgp.PathAttributeInterface{}, []bgp.NLRInfo{}))
+	assert.NoError(t, err)
+	assert.Equal(t, len(pList), 2)
+	assert.Equal(t, pList[1].getPrefix(), "10.10.0.0/16")
+	assert.Equal(t, pList[1].IsWithdraw(), true)
+}
+
+func TestAggregateSummaryOnlyAsSet(t *testing.T) {
+	tm := NewTableManager("TestAggregateSummaryOnlyAsSet", []bgp.RouteFamily{bgp.RF_IPv4_UC})
+	_, err := tm.ProcessUpdate(peerR1(), update_fromR1())
+	assert.NoError(t, err)
+
+	c := config.AggregateAddress{
+		Address:     net.ParseIP("10.10.0.0"),
+		Masklength:  16,
+		AsSet:       true,
+		SummaryOnly: true,
+	}
+	paths := tm.SetAggregates(aggregateGlobal(c))
+	assert.Equal(t, len(paths), 2)
+	// the contributor is suppressed
+	assert.Equal(t, paths[0].getPrefix(), "10.10.10.0/24")
+	assert.Equal(t, paths[0].IsWithdraw(), true)
+	agg := paths[1]
+	assert.Equal(t, agg.getPrefix(), "10.10.0.0/16")
+	assert.Equal(t, agg.IsWithdraw(), false)
+	_, attr := agg.getPathAttr(bgp.BGP_ATTR_TYPE_ATOMIC_AGGREGATE)
+	assert.Nil(t, attr)
+	_, attr = agg.getPathAttr(bgp.BGP_ATTR_TYPE_AS_PATH)
+	param := attr.(*bgp.PathAttributeAsPath).Value[0].(*bgp.As4PathParam)
+	assert.Equal(t, param.Type, uint8(bgp.BGP_ASPATH_ATTR_TYPE_SET))
+	assert.Equal(t, param.AS, []uint32{65000})
+
+	assert.Equal(t, len(tm.GetPathList(bgp.RF_IPv4_UC)), 1)
+	assert.False(t, tm.isSuppressed(nil))
+
+	// the contributor suppressed already isn't withdrawn again
+	g := aggregateGlobal(c)
+	g.AfiSafiList[0].AggregateAddressList = append(g.AfiSafiList[0].AggregateAddressList, config.AggregateAddress{
+		Address:     net.ParseIP("10.0.0.0"),
+		Masklength:  8,
+		SummaryOnly: true,
+	})
+	paths = tm.SetAggregates(g)
+	assert.Equal(t, len(paths), 2)
+	assert.Equal(t, paths[0].getPrefix(), "10.10.0.0/16")
+	assert.Equal(t, paths[0].IsWithdraw(), true)
+	assert.Equal(t, paths[1].getPrefix(), "10.0.0.0/8")
+	assert.Equal(t, paths[1].IsWithdraw(), false)
+	paths = tm.SetAggregates(aggregateGlobal(c))
+	assert.Equal(t, len(paths), 2)
+	assert.Equal(t, paths[0].getPrefix(), "10.0.0.0/8")
+	assert.Equal(t, paths[0].IsWithdraw(), true)
+	assert.Equal(t, paths[1].getPrefix(), "10.10.0.0/16")
+	assert.Equal(t, paths[1].IsWithdraw(), false)
+
+	// removing the aggregate address advertises the contributor again
+	paths = tm.SetAggregates(&config.Global{As: 65001, RouterId: net.ParseIP("10.0.0.1")})
+	assert.Equal(t, len(paths), 2)
+	assert.Equal(t, paths[0].getPrefix(), "10.10.0.0/16")
+	assert.Equal(t, paths[0].IsWithdraw(), true)
+	assert.Equal(t, paths[1].getPrefix(), "10.10.10.0/24")
+	assert.Equal(t, paths[1].IsWithdraw(), false)
+}
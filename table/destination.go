@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"fmt"
 	log "github.com/Sirupsen/logrus"
+	"github.com/osrg/gobgp/config"
 	"github.com/osrg/gobgp/packet"
 	"net"
 	"reflect"
@@ -40,6 +41,15 @@ const (
 	BPR_ROUTER_ID          = "Router ID"
 )
 
+// SelectionOptions is the parameters of the best path selection.
+type SelectionOptions struct {
+	config.RouteSelectionOptions
+	// asn of local bgpspeaker
+	LocalAsn uint32
+	// resolves the reachability and the IGP cost of nexthops
+	Resolver NexthopResolver
+}
+
 type PeerInfo struct {
 	AS      uint32
 	ID      net.IP
@@ -47,8 +57,18 @@ type PeerInfo struct {
 	Address net.IP
 }
 
+// return the address of the peer. nil PeerInfo stands for
+// locally originated paths.
+func (i *PeerInfo) getAddress() net.IP {
+	if i == nil {
+		return nil
+	}
+	return i.Address
+}
+
 type Destination interface {
-	Calculate(localAsn uint32) (Path, string, error)
+	Calculate(options *SelectionOptions) (Path, string, error)
+	computeKnownBestPath(options *SelectionOptions, trace *BestPathExplanation) (Path, string, error)
 	getRouteFamily() bgp.RouteFamily
 	setRouteFamily(ROUTE_FAMILY bgp.RouteFamily)
 	getNlri() bgp.AddrPrefixInterface
@@ -102,11 +122,8 @@ func (dd *DestinationDefault) MarshalJSON() ([]byte, error) {
 				return i
 			}
 		}
-		log.WithFields(log.Fields{
-			"Topic": "Table",
-			"Key":   prefix.String(),
-		}).Panic("no best path")
-		return 0
+		// none of the paths has a reachable nexthop
+		return -1
 	}()
 	return json.Marshal(struct {
 		Prefix      string
@@ -210,7 +227,7 @@ func (dd *DestinationDefault) validatePath(path Path) {
 //
 // Modifies destination's state related to stored paths. Removes withdrawn
 // paths from known paths. Also, adds new paths to known paths.
-func (dest *DestinationDefault) Calculate(localAsn uint32) (Path, string, error) {
+func (dest *DestinationDefault) Calculate(options *SelectionOptions) (Path, string, error) {
 
 	// First remove the withdrawn paths.
 	// Note: If we want to support multiple paths per destination we may
@@ -224,6 +241,9 @@ func (dest *DestinationDefault) Calculate(localAsn uint32) (Path, string, error)
 		// it becomes best path.
 		dest.knownPathList = append(dest.knownPathList, dest.newPathList[0])
 		dest.newPathList, _ = deleteAt(dest.newPathList, 0)
+		if reachable, _ := resolveNexthop(options.Resolver, dest.knownPathList[0]); !reachable {
+			return nil, BPR_REACHABLE_NEXT_HOP, nil
+		}
 		log.WithFields(log.Fields{
 			"Topic":  "Table",
 			"Key":    dest.getNlri().String(),
@@ -251,7 +271,7 @@ func (dest *DestinationDefault) Calculate(localAsn uint32) (Path, string, error)
 	}
 
 	// Compute new best path
-	currentBestPath, reason, e := dest.computeKnownBestPath(localAsn)
+	currentBestPath, reason, e := dest.computeKnownBestPath(options, nil)
 	if e != nil {
 		log.Error(e)
 	}
@@ -355,7 +375,7 @@ func (dest *DestinationDefault) removeWithdrawls() {
 	}
 }
 
-func (dest *DestinationDefault) computeKnownBestPath(localAsn uint32) (Path, string, error) {
+func (dest *DestinationDefault) computeKnownBestPath(options *SelectionOptions, trace *BestPathExplanation) (Path, string, error) {
 
 	//	"""Computes the best path among known paths.
 	//
@@ -366,21 +386,65 @@ func (dest *DestinationDefault) computeKnownBestPath(localAsn uint32) (Path, str
 
 	log.Debugf("computeKnownBestPath known pathlist: %d", len(dest.knownPathList))
 
+	// The paths with unreachable nexthop can't be the best path.
+	pathList := make([]Path, 0, len(dest.knownPathList))
+	for _, path := range dest.knownPathList {
+		if reachable, _ := resolveNexthop(options.Resolver, path); reachable {
+			pathList = append(pathList, path)
+		} else {
+			trace.unreachable(path)
+		}
+	}
+	if len(pathList) == 0 {
+		return nil, BPR_REACHABLE_NEXT_HOP, nil
+	}
+
+	// With deterministic MED, the best path is selected among the
+	// paths from the same neighbor AS first, then the winners of the
+	// groups are compared. The result doesn't depend on the order in
+	// which the paths were received.
+	if options.DeterministicMed && len(pathList) > 1 {
+		groups := make(map[uint32][]Path)
+		asList := make([]uint32, 0)
+		for _, path := range pathList {
+			asn := getNeighborAs(options.LocalAsn, path)
+			if _, ok := groups[asn]; !ok {
+				asList = append(asList, asn)
+			}
+			groups[asn] = append(groups[asn], path)
+		}
+		if len(groups) > 1 {
+			winners := make([]Path, 0, len(groups))
+			for _, asn := range asList {
+				best, _ := selectBestPath(options, groups[asn], trace)
+				winners = append(winners, best)
+			}
+			pathList = winners
+		}
+	}
+
+	currentBestPath, bestPathReason := selectBestPath(options, pathList, trace)
+	if len(pathList) == 1 && len(dest.knownPathList) > 1 {
+		bestPathReason = BPR_REACHABLE_NEXT_HOP
+	}
+	return currentBestPath, bestPathReason, nil
+}
+
+func selectBestPath(options *SelectionOptions, pathList []Path, trace *BestPathExplanation) (Path, string) {
 	// We pick the first path as current best path. This helps in breaking
 	// tie between two new paths learned in one cycle for which best-path
 	// calculation steps lead to tie.
-	currentBestPath := dest.knownPathList[0]
+	currentBestPath := pathList[0]
 	bestPathReason := BPR_ONLY_PATH
-	for _, nextPath := range dest.knownPathList[1:] {
+	for _, nextPath := range pathList[1:] {
 		// Compare next path with current best path.
-		// TODO make interface to get Local AS number
-		newBestPath, reason := computeBestPath(localAsn, currentBestPath, nextPath)
+		newBestPath, reason := computeBestPath(options, currentBestPath, nextPath, trace)
 		bestPathReason = reason
 		if newBestPath != nil {
 			currentBestPath = newBestPath
 		}
 	}
-	return currentBestPath, bestPathReason, nil
+	return currentBestPath, bestPathReason
 }
 
 func (dest *DestinationDefault) removeOldPaths() {
@@ -446,14 +510,16 @@ func removeWithPath(list []Path, path Path) ([]Path, bool) {
 	return list, false
 }
 
-func computeBestPath(localAsn uint32, path1, path2 Path) (Path, string) {
+func computeBestPath(options *SelectionOptions, path1, path2 Path, trace *BestPathExplanation) (Path, string) {
 
 	//Compares given paths and returns best path.
 	//
 	//Parameters:
-	//	-`localAsn`: asn of local bgpspeaker
+	//	-`options`: asn of local bgpspeaker, nexthop resolver and
+	//	route selection options
 	//	-`path1`: first path to compare
 	//	-`path2`: second path to compare
+	//	-`trace`: records the result of each step if not nil
 	//
 	//	Best path processing will involve following steps:
 	//	1.  Select a path with a reachable next hop.
@@ -462,17 +528,21 @@ func computeBestPath(localAsn uint32, path1, path2 Path) (Path, string) {
 	//	local preference value.
 	//	4.  Prefer locally originated routes (network routes, redistributed
 	//	routes, or aggregated routes) over received routes.
-	//	5.  Select the route with the shortest AS-path length.
+	//	5.  Select the route with the shortest AS-path length unless
+	//	ignore-as-path-length is set.
 	//	6.  If all paths have the same AS-path length, select the path based
 	//	on origin: IGP is preferred over EGP; EGP is preferred over
 	//	Incomplete.
 	//	7.  If the origins are the same, select the path with lowest MED
-	//	value.
+	//	value. MED is compared only among the paths from the same
+	//	neighbor AS unless always-compare-med is set.
 	//	8.  If the paths have the same MED values, select the path learned
 	//	via EBGP over one learned via IBGP.
-	//	9.  Select the route with the lowest IGP cost to the next hop.
+	//	9.  Select the route with the lowest IGP cost to the next hop
+	//	unless ignore-next-hop-igp-metric is set.
 	//	10. Select the route received from the peer with the lowest BGP
-	//	router ID.
+	//	router ID. eBGP paths are compared only when
+	//	external-compare-router-id is set.
 	//
 	//	Returns None if best-path among given paths cannot be computed else best
 	//	path.
@@ -481,68 +551,78 @@ func computeBestPath(localAsn uint32, path1, path2 Path) (Path, string) {
 
 	var bestPath Path
 	bestPathReason := BPR_UNKNOWN
+	comparison := trace.compare(path1, path2)
 
-	// Follow best path calculation algorithm steps.
-	// compare by reachability
-	if bestPath == nil {
-		bestPath = compareByReachableNexthop(path1, path2)
-		bestPathReason = BPR_REACHABLE_NEXT_HOP
-	}
-
-	if bestPath == nil {
-		bestPath = compareByHighestWeight(path1, path2)
-		bestPathReason = BPR_HIGHEST_WEIGHT
+	// Follow best path calculation algorithm steps. The steps disabled
+	// by the route selection options are skipped.
+	step := func(reason string, enabled bool, compare func() Path) {
+		if bestPath != nil {
+			return
+		}
+		if !enabled {
+			comparison.skip(reason)
+			return
+		}
+		bestPath = compare()
+		bestPathReason = reason
+		comparison.step(reason, bestPath)
 	}
 
-	if bestPath == nil {
-		bestPath = compareByLocalPref(path1, path2)
-		bestPathReason = BPR_LOCAL_PREF
-	}
-	if bestPath == nil {
-		bestPath = compareByLocalOrigin(path1, path2)
-		bestPathReason = BPR_LOCAL_ORIGIN
-	}
-	if bestPath == nil {
-		bestPath = compareByASPath(path1, path2)
-		bestPathReason = BPR_ASPATH
-	}
-	if bestPath == nil {
-		bestPath = compareByOrigin(path1, path2)
-		bestPathReason = BPR_ORIGIN
-	}
-	if bestPath == nil {
-		bestPath = compareByMED(path1, path2)
-		bestPathReason = BPR_MED
-	}
-	if bestPath == nil {
-		bestPath = compareByASNumber(localAsn, path1, path2)
-		bestPathReason = BPR_ASN
-	}
-	if bestPath == nil {
-		bestPath = compareByIGPCost(path1, path2)
-		bestPathReason = BPR_IGP_COST
-	}
-	if bestPath == nil {
-		var e error = nil
-		bestPath, e = compareByRouterID(localAsn, path1, path2)
+	step(BPR_REACHABLE_NEXT_HOP, true, func() Path {
+		return compareByReachableNexthop(options.Resolver, path1, path2)
+	})
+	step(BPR_HIGHEST_WEIGHT, true, func() Path {
+		return compareByHighestWeight(path1, path2)
+	})
+	step(BPR_LOCAL_PREF, true, func() Path {
+		return compareByLocalPref(path1, path2)
+	})
+	step(BPR_LOCAL_ORIGIN, true, func() Path {
+		return compareByLocalOrigin(path1, path2)
+	})
+	step(BPR_ASPATH, !options.IgnoreAsPathLength, func() Path {
+		return compareByASPath(path1, path2)
+	})
+	step(BPR_ORIGIN, true, func() Path {
+		return compareByOrigin(path1, path2)
+	})
+	step(BPR_MED, true, func() Path {
+		return compareByMED(options, path1, path2)
+	})
+	step(BPR_ASN, true, func() Path {
+		return compareByASNumber(options.LocalAsn, path1, path2)
+	})
+	step(BPR_IGP_COST, !options.IgnoreNextHopIgpMetric, func() Path {
+		return compareByIGPCost(options.Resolver, path1, path2)
+	})
+	step(BPR_ROUTER_ID, true, func() Path {
+		path, e := compareByRouterID(options, path1, path2)
 		if e != nil {
 			log.Error(e)
 		}
-		bestPathReason = BPR_ROUTER_ID
-	}
+		return path
+	})
 	if bestPath == nil {
 		bestPathReason = BPR_UNKNOWN
 	}
+	comparison.result(bestPath, bestPathReason)
 
 	return bestPath, bestPathReason
 }
 
-func compareByReachableNexthop(path1, path2 Path) Path {
+func compareByReachableNexthop(resolver NexthopResolver, path1, path2 Path) Path {
 	//	Compares given paths and selects best path based on reachable next-hop.
 	//
 	//	If no path matches this criteria, return None.
 	//  However RouteServer doesn't need to check reachability, so return nil.
 	log.Debugf("enter compareByReachableNexthop -- path1: %s, path2: %s", path1, path2)
+	reachable1, _ := resolveNexthop(resolver, path1)
+	reachable2, _ := resolveNexthop(resolver, path2)
+	if reachable1 && !reachable2 {
+		return path1
+	} else if !reachable1 && reachable2 {
+		return path2
+	}
 	return nil
 }
 
@@ -554,6 +634,13 @@ func compareByHighestWeight(path1, path2 Path) Path {
 	//	Return:
 	//	nil if best path among given paths cannot be decided, else best path.
 	log.Debugf("enter compareByHighestWeight -- path1: %s, path2: %s", path1, path2)
+	weight1 := path1.GetWeight()
+	weight2 := path2.GetWeight()
+	if weight1 > weight2 {
+		return path1
+	} else if weight1 < weight2 {
+		return path2
+	}
 	return nil
 }
 
@@ -687,16 +774,39 @@ func compareByOrigin(path1, path2 Path) Path {
 	}
 }
 
-func compareByMED(path1, path2 Path) Path {
+// return the leftmost AS in AS_PATH. the local AS is returned for the
+// paths originated in the local AS.
+func getNeighborAs(localAsn uint32, path Path) uint32 {
+	_, attr := path.getPathAttr(bgp.BGP_ATTR_TYPE_AS_PATH)
+	if attr == nil || len(attr.(*bgp.PathAttributeAsPath).Value) == 0 {
+		return localAsn
+	}
+	switch param := attr.(*bgp.PathAttributeAsPath).Value[0].(type) {
+	case *bgp.As4PathParam:
+		if param.Type == bgp.BGP_ASPATH_ATTR_TYPE_SEQ && len(param.AS) > 0 {
+			return param.AS[0]
+		}
+	case *bgp.AsPathParam:
+		if param.Type == bgp.BGP_ASPATH_ATTR_TYPE_SEQ && len(param.AS) > 0 {
+			return uint32(param.AS[0])
+		}
+	}
+	return localAsn
+}
+
+func compareByMED(options *SelectionOptions, path1, path2 Path) Path {
 	//	Select the path based with lowest MED value.
 	//
 	//	If both paths have same MED, return None.
 	//	By default, a route that arrives with no MED value is treated as if it
 	//	had a MED of 0, the most preferred value.
 	//	RFC says lower MED is preferred over higher MED value.
-	//  compare MED among not only same AS path but also all path,
-	//  like bgp always-compare-med
+	//	MED is compared only among the paths from the same neighbor AS
+	//	unless always-compare-med is set.
 	log.Debugf("enter compareByMED")
+	if !options.AlwaysCompareMed && getNeighborAs(options.LocalAsn, path1) != getNeighborAs(options.LocalAsn, path2) {
+		return nil
+	}
 	getMed := func(path Path) uint32 {
 		_, attribute := path.getPathAttr(bgp.BGP_ATTR_TYPE_MULTI_EXIT_DISC)
 		if attribute == nil {
@@ -751,23 +861,32 @@ func compareByASNumber(localAsn uint32, path1, path2 Path) Path {
 	return nil
 }
 
-func compareByIGPCost(path1, path2 Path) Path {
+func compareByIGPCost(resolver NexthopResolver, path1, path2 Path) Path {
 	//	Select the route with the lowest IGP cost to the next hop.
 	//
 	//	Return None if igp cost is same.
-	// Currently BGPS has no concept of IGP and IGP cost.
+	//	The IGP cost is provided by the nexthop resolver.
 	log.Debugf("enter compareByIGPCost -- path1: %v, path2: %v", path1, path2)
+	_, metric1 := resolveNexthop(resolver, path1)
+	_, metric2 := resolveNexthop(resolver, path2)
+	if metric1 < metric2 {
+		return path1
+	} else if metric1 > metric2 {
+		return path2
+	}
 	return nil
 }
 
-func compareByRouterID(localAsn uint32, path1, path2 Path) (Path, error) {
+func compareByRouterID(options *SelectionOptions, path1, path2 Path) (Path, error) {
 	//	Select the route received from the peer with the lowest BGP router ID.
 	//
 	//	If both paths are eBGP paths, then we do not do any tie breaking, i.e we do
-	//	not pick best-path based on this criteria.
+	//	not pick best-path based on this criteria unless
+	//	external-compare-router-id is set.
 	//	RFC: http://tools.ietf.org/html/rfc5004
 	//	We pick best path between two iBGP paths as usual.
 	log.Debugf("enter compareByRouterID")
+	localAsn := options.LocalAsn
 	getAsn := func(pathSource *PeerInfo) uint32 {
 		if pathSource == nil {
 			return localAsn
@@ -801,7 +920,7 @@ func compareByRouterID(localAsn uint32, path1, path2 Path) (Path, error) {
 	isEbgp2 := asn2 != localAsn
 	// If both paths are from eBGP peers, then according to RFC we need
 	// not tie break using router id.
-	if isEbgp1 && isEbgp2 {
+	if isEbgp1 && isEbgp2 && !options.ExternalCompareRouterId {
 		return nil, nil
 	}
 
@@ -920,11 +1039,8 @@ func (ipv6d *IPv6Destination) MarshalJSON() ([]byte, error) {
 				return i
 			}
 		}
-		log.WithFields(log.Fields{
-			"Topic": "Table",
-			"Key":   prefix.String(),
-		}).Panic("no best path")
-		return 0
+		// none of the paths has a reachable nexthop
+		return -1
 	}()
 	return json.Marshal(struct {
 		Prefix      string
@@ -976,11 +1092,8 @@ func (ipv4vpnd *IPv4VPNDestination) MarshalJSON() ([]byte, error) {
 				return i
 			}
 		}
-		log.WithFields(log.Fields{
-			"Topic": "Table",
-			"Key":   prefix.String(),
-		}).Panic("no best path")
-		return 0
+		// none of the paths has a reachable nexthop
+		return -1
 	}()
 	return json.Marshal(struct {
 		Prefix      string
@@ -17,9 +17,10 @@ package table
 
 import (
 	//"fmt"
+	"github.com/osrg/gobgp/config"
 	"github.com/osrg/gobgp/packet"
 	"github.com/stretchr/testify/assert"
-	//"net"
+	"net"
 	"testing"
 	"time"
 )
@@ -107,7 +108,7 @@ func TestDestinationCalculate(t *testing.T) {
 	ipv4d.addNewPath(pathD[1])
 	ipv4d.addNewPath(pathD[2])
 	ipv4d.addWithdraw(pathD[2])
-	_, _, e := ipv4d.Calculate(uint32(100))
+	_, _, e := ipv4d.Calculate(&SelectionOptions{LocalAsn: 100})
 	assert.Nil(t, e)
 }
 
@@ -198,3 +199,157 @@ func updateMsgD3() *bgp.BGPMessage {
 	withdrawnRoutes := []bgp.WithdrawnRoute{w1}
 	return bgp.NewBGPUpdateMessage(withdrawnRoutes, pathAttributes, nlri)
 }
+
+func TestDestinationCalculateWeight(t *testing.T) {
+	peerD := DestCreatePeer()
+	attrs := func(localPref uint32) []bgp.PathAttributeInterface {
+		return []bgp.PathAttributeInterface{
+			bgp.NewPathAttributeOrigin(0),
+			bgp.NewPathAttributeAsPath([]bgp.AsPathParamInterface{bgp.NewAs4PathParam(2, []uint32{65000})}),
+			bgp.NewPathAttributeNextHop("192.168.50.1"),
+			bgp.NewPathAttributeLocalPref(localPref),
+		}
+	}
+	nlri := bgp.NewNLRInfo(24, "10.10.10.0")
+	path1 := CreatePath(peerD[0], nlri, attrs(200), false, time.Now())
+	path2 := CreatePath(peerD[1], nlri, attrs(100), false, time.Now())
+	path2.SetWeight(100)
+	ipv4d := NewIPv4Destination(nlri)
+	ipv4d.addNewPath(path1)
+	ipv4d.addNewPath(path2)
+	best, reason, e := ipv4d.Calculate(&SelectionOptions{LocalAsn: 100})
+	assert.Nil(t, e)
+	assert.Equal(t, best, path2)
+	assert.Equal(t, reason, BPR_HIGHEST_WEIGHT)
+}
+
+func selectionTestPath(as uint32, id string, asPath []uint32, med uint32) Path {
+	peer := &PeerInfo{
+		AS:      as,
+		ID:      net.ParseIP(id).To4(),
+		LocalID: net.ParseIP("10.0.0.1").To4(),
+	}
+	attrs := []bgp.PathAttributeInterface{
+		bgp.NewPathAttributeOrigin(0),
+		bgp.NewPathAttributeAsPath([]bgp.AsPathParamInterface{bgp.NewAs4PathParam(2, asPath)}),
+		bgp.NewPathAttributeNextHop("192.168.50.1"),
+		bgp.NewPathAttributeMultiExitDisc(med),
+		bgp.NewPathAttributeLocalPref(100),
+	}
+	return CreatePath(peer, bgp.NewNLRInfo(24, "10.10.10.0"), attrs, false, time.Now())
+}
+
+func calculateBestPath(options *SelectionOptions, paths ...Path) (Path, string) {
+	d := NewIPv4Destination(paths[0].GetNlri())
+	for _, path := range paths {
+		d.addNewPath(path)
+	}
+	best, reason, _ := d.Calculate(options)
+	return best, reason
+}
+
+func TestDestinationCalculateMED(t *testing.T) {
+	path1 := selectionTestPath(65000, "10.0.0.2", []uint32{100}, 200)
+	path2 := selectionTestPath(65000, "10.0.0.3", []uint32{200}, 100)
+	// MED isn't compared between the paths from different AS
+	options := &SelectionOptions{LocalAsn: 65000}
+	best, reason := calculateBestPath(options, path1, path2)
+	assert.Equal(t, best, path1)
+	assert.Equal(t, reason, BPR_ROUTER_ID)
+
+	options.AlwaysCompareMed = true
+	best, reason = calculateBestPath(options, path1, path2)
+	assert.Equal(t, best, path2)
+	assert.Equal(t, reason, BPR_MED)
+}
+
+func TestDestinationCalculateDeterministicMED(t *testing.T) {
+	pathA := selectionTestPath(65000, "10.0.0.2", []uint32{100}, 200)
+	pathB := selectionTestPath(65000, "10.0.0.3", []uint32{200}, 150)
+	pathC := selectionTestPath(65000, "10.0.0.4", []uint32{100}, 100)
+
+	// the result depends on the order of the paths
+	options := &SelectionOptions{LocalAsn: 65000}
+	best, _ := calculateBestPath(options, pathC, pathB, pathA)
+	assert.Equal(t, best, pathA)
+	best, _ = calculateBestPath(options, pathA, pathB, pathC)
+	assert.Equal(t, best, pathC)
+
+	options.DeterministicMed = true
+	best, _ = calculateBestPath(options, pathC, pathB, pathA)
+	assert.Equal(t, best, pathB)
+	best, _ = calculateBestPath(options, pathA, pathB, pathC)
+	assert.Equal(t, best, pathB)
+}
+
+func TestDestinationCalculateIgnoreAsPathLength(t *testing.T) {
+	path1 := selectionTestPath(65000, "10.0.0.2", []uint32{100, 300}, 100)
+	path2 := selectionTestPath(65000, "10.0.0.3", []uint32{100}, 200)
+	best, reason := calculateBestPath(&SelectionOptions{LocalAsn: 65000}, path1, path2)
+	assert.Equal(t, best, path2)
+	assert.Equal(t, reason, BPR_ASPATH)
+
+	options := &SelectionOptions{LocalAsn: 65000}
+	options.IgnoreAsPathLength = true
+	best, reason = calculateBestPath(options, path1, path2)
+	assert.Equal(t, best, path1)
+	assert.Equal(t, reason, BPR_MED)
+}
+
+func TestDestinationCalculateExternalRouterId(t *testing.T) {
+	path1 := selectionTestPath(65001, "10.0.0.3", []uint32{65001}, 0)
+	path2 := selectionTestPath(65002, "10.0.0.2", []uint32{65002}, 0)
+	best, reason := calculateBestPath(&SelectionOptions{LocalAsn: 65000}, path1, path2)
+	assert.Equal(t, best, path1)
+	assert.Equal(t, reason, BPR_UNKNOWN)
+
+	options := &SelectionOptions{LocalAsn: 65000}
+	options.ExternalCompareRouterId = true
+	best, reason = calculateBestPath(options, path1, path2)
+	assert.Equal(t, best, path2)
+	assert.Equal(t, reason, BPR_ROUTER_ID)
+}
+
+func TestTableManagerExplain(t *testing.T) {
+	tm := NewTableManager("TestTableManagerExplain", []bgp.RouteFamily{bgp.RF_IPv4_UC})
+	tm.SetRouteSelection(&config.Global{
+		As: 65000,
+		AfiSafiList: []config.AfiSafi{
+			config.AfiSafi{
+				AfiSafiName:           "ipv4-unicast",
+				RouteSelectionOptions: config.RouteSelectionOptions{IgnoreAsPathLength: true},
+			},
+		},
+	})
+	path1 := selectionTestPath(65000, "10.0.0.3", []uint32{100}, 100)
+	path2 := selectionTestPath(65000, "10.0.0.2", []uint32{100, 200}, 100)
+	_, err := tm.ProcessPaths([]Path{path1, path2})
+	assert.NoError(t, err)
+
+	e, err := tm.Explain(bgp.RF_IPv4_UC, "10.10.10.1/24")
+	assert.NoError(t, err)
+	assert.Equal(t, e.Prefix, "10.10.10.0/24")
+	assert.Equal(t, len(e.Paths), 2)
+	assert.Equal(t, e.Paths[e.BestPathIdx], path2)
+	assert.Equal(t, e.Reason, BPR_ROUTER_ID)
+	assert.Equal(t, len(e.Comparisons), 1)
+	c := e.Comparisons[0]
+	assert.Equal(t, c.Winner, e.BestPathIdx)
+	for _, s := range c.Steps {
+		switch s.Reason {
+		case BPR_ASPATH:
+			assert.True(t, s.Skipped)
+		case BPR_ROUTER_ID:
+			assert.Equal(t, s.Winner, e.BestPathIdx)
+		default:
+			assert.Equal(t, s.Winner, -1)
+		}
+	}
+	// the state of the destination isn't changed
+	assert.Equal(t, len(tm.GetPathList(bgp.RF_IPv4_UC)), 1)
+
+	_, err = tm.Explain(bgp.RF_IPv4_UC, "20.20.20.0/24")
+	assert.Error(t, err)
+	_, err = tm.Explain(bgp.RF_IPv6_UC, "2001::/64")
+	assert.Error(t, err)
+}
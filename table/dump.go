@@ -0,0 +1,360 @@
+// Copyright (C) 2015 Nippon Telegraph and Telephone Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package table
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/osrg/gobgp/packet"
+	"github.com/tchap/go-patricia/patricia"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// the paths of a prefix in the dump
+type ribEntry struct {
+	name  string
+	nlri  bgp.AddrPrefixInterface
+	paths []Path
+	best  Path
+}
+
+// the key of a prefix in the sorted index of a rib
+type ribKey struct {
+	key  patricia.Prefix
+	name string
+}
+
+// in the order of the prefixes in the patricia trie
+func ribEntryLess(key1 patricia.Prefix, name1 string, key2 patricia.Prefix, name2 string) bool {
+	if string(key1) != string(key2) {
+		return string(key1) < string(key2)
+	}
+	return name1 < name2
+}
+
+// the parser of the keys of the rib of the route family, nil if the
+// keys are only sorted by the names
+func prefixParser(rf bgp.RouteFamily) func(string) patricia.Prefix {
+	switch rf {
+	case bgp.RF_IPv4_UC, bgp.RF_IPv6_UC:
+		return cidr2prefix
+	case bgp.RF_IPv4_VPN:
+		return ParseLabbelledVpnPrefix
+	case bgp.RF_EVPN:
+		return ParseEVPNPrefix
+	}
+	return nil
+}
+
+// the keys of the rib after the cursor in the order of the prefixes.
+// the keys are sorted again only when a dump starts after the rib has
+// changed; the following pages of the dump are taken from the same
+// snapshot, and the prefixes added in the meantime aren't dumped.
+func (idx *ribIndex) after(cursor string, names func() []string) ([]*ribKey, error) {
+	var key patricia.Prefix
+	if cursor != "" {
+		var err error
+		if key, err = idx.parseKey(cursor); err != nil {
+			return nil, fmt.Errorf("invalid cursor: %s", cursor)
+		}
+	}
+	if idx.dirty && (cursor == "" || idx.sorted == nil) {
+		sorted := make([]*ribKey, 0, len(idx.sorted))
+		for _, name := range names() {
+			// the keys which can't be parsed are sorted by the names
+			k, _ := idx.parseKey(name)
+			sorted = append(sorted, &ribKey{key: k, name: name})
+		}
+		sort.Slice(sorted, func(i, j int) bool {
+			return ribEntryLess(sorted[i].key, sorted[i].name, sorted[j].key, sorted[j].name)
+		})
+		idx.sorted = sorted
+		idx.dirty = false
+	}
+	if cursor == "" {
+		return idx.sorted, nil
+	}
+	i := sort.Search(len(idx.sorted), func(i int) bool {
+		return ribEntryLess(key, cursor, idx.sorted[i].key, idx.sorted[i].name)
+	})
+	return idx.sorted[i:], nil
+}
+
+// RibDump is a page of the paths in a rib copied in the goroutine owning
+// the rib. It's marshaled later in another goroutine not to block the
+// rib while a large page is serialized.
+type RibDump struct {
+	rf      bgp.RouteFamily
+	entries []*ribEntry
+	// the cursor of the next page if any
+	next string
+	// the paths of adj-rib are dumped without the destinations
+	adj bool
+}
+
+// copy the page of the destinations of the table selected by the
+// options. nil table is dumped as an empty one.
+func NewRibDump(t Table, o *RibDumpOptions) (*RibDump, error) {
+	d := &RibDump{entries: make([]*ribEntry, 0)}
+	f, err := newRibFilter(o)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return d, nil
+	}
+	keys, err := t.getIndex().after(o.Cursor, func() []string {
+		names := make([]string, 0, len(t.getDestinations()))
+		for name := range t.getDestinations() {
+			names = append(names, name)
+		}
+		return names
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range keys {
+		dest := t.getDestination(k.name)
+		if dest == nil {
+			continue
+		}
+		if !d.add(o, &ribEntry{
+			name:  k.name,
+			nlri:  dest.getNlri(),
+			paths: f.apply(dest.getKnownPathList(), dest.getBestPath()),
+			best:  dest.getBestPath(),
+		}) {
+			break
+		}
+	}
+	return d, nil
+}
+
+// add the entry of the prefix unless no path is selected. false if the
+// page is already full.
+func (d *RibDump) add(o *RibDumpOptions, e *ribEntry) bool {
+	if len(e.paths) == 0 {
+		return true
+	}
+	if o.Limit > 0 && len(d.entries) == o.Limit {
+		d.next = d.entries[len(d.entries)-1].name
+		return false
+	}
+	d.entries = append(d.entries, e)
+	return true
+}
+
+// call the function with the NLRI, the paths and the best path of each
+// prefix in the dump in order. the best path may be filtered out of the
+// paths.
+func (d *RibDump) Visit(f func(nlri bgp.AddrPrefixInterface, paths []Path, best Path)) {
+	for _, e := range d.entries {
+		f(e.nlri, e.paths, e.best)
+	}
+}
+
+func (adj *AdjRib) newDump(rib map[bgp.RouteFamily]map[string]*ReceivedRoute, index map[bgp.RouteFamily]*ribIndex, rf bgp.RouteFamily, o *RibDumpOptions) (*RibDump, error) {
+	d := &RibDump{
+		rf:      rf,
+		entries: make([]*ribEntry, 0),
+		adj:     true,
+	}
+	f, err := newRibFilter(o)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := rib[rf]; !ok {
+		return d, nil
+	}
+	keys, err := index[rf].after(o.Cursor, func() []string {
+		names := make([]string, 0, len(rib[rf]))
+		for name := range rib[rf] {
+			names = append(names, name)
+		}
+		return names
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range keys {
+		rr, ok := rib[rf][k.name]
+		if !ok {
+			continue
+		}
+		if !d.add(o, &ribEntry{
+			name:  k.name,
+			nlri:  rr.path.GetNlri(),
+			paths: f.apply([]Path{rr.path}, rr.path),
+			best:  rr.path,
+		}) {
+			break
+		}
+	}
+	return d, nil
+}
+
+// copy the page of the paths in adj-rib-in of the route family selected
+// by the options
+func (adj *AdjRib) NewInDump(rf bgp.RouteFamily, o *RibDumpOptions) (*RibDump, error) {
+	return adj.newDump(adj.adjRibIn, adj.inIndex, rf, o)
+}
+
+func (adj *AdjRib) NewOutDump(rf bgp.RouteFamily, o *RibDumpOptions) (*RibDump, error) {
+	return adj.newDump(adj.adjRibOut, adj.outIndex, rf, o)
+}
+
+// the page and the filters of the paths in a dump. the page starts after
+// the prefix of the cursor and has up to Limit prefixes; all the prefixes
+// if Limit is zero.
+type RibDumpOptions struct {
+	Cursor    string
+	Limit     int
+	Neighbor  string
+	Community string
+	AsPath    string
+	Nexthop   string
+	BestOnly  bool
+}
+
+// ribFilter selects the paths in the dump. the zero value selects all.
+type ribFilter struct {
+	neighbor     net.IP
+	community    uint32
+	hasCommunity bool
+	asPath       *regexp.Regexp
+	nexthop      net.IP
+	bestOnly     bool
+}
+
+func newRibFilter(o *RibDumpOptions) (*ribFilter, error) {
+	f := &ribFilter{bestOnly: o.BestOnly}
+	if o.Neighbor != "" {
+		if f.neighbor = net.ParseIP(o.Neighbor); f.neighbor == nil {
+			return nil, fmt.Errorf("invalid neighbor: %s", o.Neighbor)
+		}
+	}
+	if o.Community != "" {
+		c, err := ParseCommunity(o.Community)
+		if err != nil {
+			return nil, err
+		}
+		f.community = c
+		f.hasCommunity = true
+	}
+	if o.AsPath != "" {
+		r, err := ParseAsPathRegexp(o.AsPath)
+		if err != nil {
+			return nil, fmt.Errorf("invalid AS path regular expression: %s", o.AsPath)
+		}
+		f.asPath = r
+	}
+	if o.Nexthop != "" {
+		if f.nexthop = net.ParseIP(o.Nexthop); f.nexthop == nil {
+			return nil, fmt.Errorf("invalid nexthop: %s", o.Nexthop)
+		}
+	}
+	return f, nil
+}
+
+func (f *ribFilter) match(path Path) bool {
+	if f.neighbor != nil && (path.GetSource() == nil || !path.GetSource().Address.Equal(f.neighbor)) {
+		return false
+	}
+	if f.hasCommunity {
+		found := false
+		for _, c := range path.GetCommunities() {
+			if c == f.community {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.asPath != nil && !f.asPath.MatchString(path.GetAsString()) {
+		return false
+	}
+	if f.nexthop != nil && !path.GetNexthop().Equal(f.nexthop) {
+		return false
+	}
+	return true
+}
+
+// the paths selected by the filter
+func (f *ribFilter) apply(paths []Path, best Path) []Path {
+	selected := make([]Path, 0, len(paths))
+	for _, p := range paths {
+		if f.bestOnly && p != best {
+			continue
+		}
+		if f.match(p) {
+			selected = append(selected, p)
+		}
+	}
+	return selected
+}
+
+// the destinations in the dump as the JSON of the table, or the paths
+// as the JSON of adj-rib. Next is the cursor of the next page if any.
+func (d *RibDump) Marshal() ([]byte, error) {
+	if d.adj {
+		paths := make([]Path, 0, len(d.entries))
+		for _, e := range d.entries {
+			paths = append(paths, e.paths...)
+		}
+		r := map[string]interface{}{d.rf.String(): paths}
+		if d.next != "" {
+			r["Next"] = d.next
+		}
+		return json.Marshal(r)
+	}
+
+	type destination struct {
+		Prefix      string
+		Paths       []Path
+		BestPathIdx int
+	}
+	destList := make([]*destination, 0, len(d.entries))
+	for _, e := range d.entries {
+		// the address of the prefix like the JSON of the destinations
+		prefix := e.name
+		if i := strings.Index(prefix, "/"); i >= 0 {
+			prefix = prefix[:i]
+		}
+		dest := &destination{
+			Prefix:      prefix,
+			Paths:       e.paths,
+			BestPathIdx: -1,
+		}
+		for i, p := range e.paths {
+			if p == e.best {
+				dest.BestPathIdx = i
+			}
+		}
+		destList = append(destList, dest)
+	}
+	return json.Marshal(struct {
+		Destinations []*destination
+		Next         string `json:",omitempty"`
+	}{
+		Destinations: destList,
+		Next:         d.next,
+	})
+}
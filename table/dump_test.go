@@ -0,0 +1,188 @@
+// Copyright (C) 2015 Nippon Telegraph and Telephone Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package table
+
+import (
+	"encoding/json"
+	"github.com/osrg/gobgp/packet"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type testRibDump struct {
+	Destinations []struct {
+		Prefix      string
+		Paths       []json.RawMessage
+		BestPathIdx int
+	}
+	Next string
+}
+
+func (d *testRibDump) prefixes() []string {
+	prefixes := make([]string, 0, len(d.Destinations))
+	for _, dest := range d.Destinations {
+		prefixes = append(prefixes, dest.Prefix)
+	}
+	return prefixes
+}
+
+func TestRibDump(t *testing.T) {
+	assert := assert.New(t)
+	tm := NewTableManager("TestRibDump", []bgp.RouteFamily{bgp.RF_IPv4_UC})
+	attrs := []bgp.PathAttributeInterface{
+		bgp.NewPathAttributeOrigin(0),
+		bgp.NewPathAttributeAsPath([]bgp.AsPathParamInterface{bgp.NewAs4PathParam(2, []uint32{65100, 65200})}),
+		bgp.NewPathAttributeNextHop("192.168.50.2"),
+		bgp.NewPathAttributeCommunities([]uint32{65100<<16 | 1}),
+	}
+	path := CreatePath(peerR2(), bgp.NewNLRInfo(24, "10.1.1.0"), attrs, false, time.Now())
+	tm.ProcessPaths(append(lookupTestPaths(), path))
+
+	marshal := func(o *RibDumpOptions) *testRibDump {
+		dump, err := NewRibDump(tm.Tables[bgp.RF_IPv4_UC], o)
+		assert.Nil(err)
+		j, err := dump.Marshal()
+		assert.Nil(err)
+		d := &testRibDump{}
+		assert.Nil(json.Unmarshal(j, d))
+		return d
+	}
+	d := marshal(&RibDumpOptions{})
+	assert.Equal(d.prefixes(), []string{"10.0.0.0", "10.1.0.0", "10.1.1.0", "10.1.2.0", "192.168.0.0"})
+	assert.Equal(len(d.Destinations[2].Paths), 2)
+	assert.Equal(d.Next, "")
+
+	// pages
+	d = marshal(&RibDumpOptions{Limit: 2})
+	assert.Equal(d.prefixes(), []string{"10.0.0.0", "10.1.0.0"})
+	assert.Equal(d.Next, "10.1.0.0/16")
+	d = marshal(&RibDumpOptions{Limit: 2, Cursor: d.Next})
+	assert.Equal(d.prefixes(), []string{"10.1.1.0", "10.1.2.0"})
+	assert.Equal(d.Next, "10.1.2.0/24")
+	d = marshal(&RibDumpOptions{Limit: 2, Cursor: d.Next})
+	assert.Equal(d.prefixes(), []string{"192.168.0.0"})
+	assert.Equal(d.Next, "")
+	// the cursor doesn't need to be in the rib
+	d = marshal(&RibDumpOptions{Cursor: "10.1.1.128/25"})
+	assert.Equal(d.prefixes(), []string{"10.1.2.0", "192.168.0.0"})
+
+	// the following pages are taken from the snapshot of the first one
+	d = marshal(&RibDumpOptions{Limit: 2})
+	path2 := CreatePath(peerR2(), bgp.NewNLRInfo(24, "10.1.3.0"), attrs, false, time.Now())
+	tm.ProcessPaths([]Path{path2})
+	d = marshal(&RibDumpOptions{Limit: 2, Cursor: d.Next})
+	assert.Equal(d.prefixes(), []string{"10.1.1.0", "10.1.2.0"})
+	d = marshal(&RibDumpOptions{Limit: 2, Cursor: d.Next})
+	assert.Equal(d.prefixes(), []string{"192.168.0.0"})
+	d = marshal(&RibDumpOptions{})
+	assert.Equal(d.prefixes(), []string{"10.0.0.0", "10.1.0.0", "10.1.1.0", "10.1.2.0", "10.1.3.0", "192.168.0.0"})
+	tm.ProcessPaths([]Path{path2.Clone(true)})
+
+	// filters
+	for _, o := range []*RibDumpOptions{
+		&RibDumpOptions{Neighbor: "10.0.0.2"},
+		&RibDumpOptions{Community: "65100:1"},
+		&RibDumpOptions{AsPath: "_65200$"},
+		&RibDumpOptions{Nexthop: "192.168.50.2"},
+	} {
+		d = marshal(o)
+		assert.Equal(d.prefixes(), []string{"10.1.1.0"})
+		assert.Equal(len(d.Destinations[0].Paths), 1)
+		// the best path is from 10.0.0.1 with the shorter AS path
+		assert.Equal(d.Destinations[0].BestPathIdx, -1)
+	}
+	d = marshal(&RibDumpOptions{BestOnly: true})
+	assert.Equal(len(d.Destinations), 5)
+	for _, dest := range d.Destinations {
+		assert.Equal(len(dest.Paths), 1)
+		assert.Equal(dest.BestPathIdx, 0)
+	}
+
+	for _, o := range []*RibDumpOptions{
+		&RibDumpOptions{Cursor: "10.1.1.0"},
+		&RibDumpOptions{Neighbor: "peer"},
+		&RibDumpOptions{Community: "65100:1:1"},
+		&RibDumpOptions{AsPath: "("},
+	} {
+		_, err := NewRibDump(tm.Tables[bgp.RF_IPv4_UC], o)
+		assert.NotNil(err)
+	}
+	// the community is a 32bit integer
+	d = marshal(&RibDumpOptions{Community: "4266393601"})
+	assert.Equal(d.prefixes(), []string{"10.1.1.0"})
+
+	dump, err := NewRibDump(nil, &RibDumpOptions{})
+	assert.Nil(err)
+	j, err := dump.Marshal()
+	assert.Nil(err)
+	assert.Equal(string(j), `{"Destinations":[]}`)
+}
+
+func TestAdjRibDump(t *testing.T) {
+	assert := assert.New(t)
+	adj := NewAdjRib([]bgp.RouteFamily{bgp.RF_IPv4_UC})
+	adj.UpdateIn(lookupTestPaths())
+
+	dump, err := adj.NewInDump(bgp.RF_IPv4_UC, &RibDumpOptions{Limit: 3})
+	assert.Nil(err)
+	j, err := dump.Marshal()
+	assert.Nil(err)
+	d := make(map[string]json.RawMessage)
+	assert.Nil(json.Unmarshal(j, &d))
+	paths := make([]struct{ Network string }, 0)
+	assert.Nil(json.Unmarshal(d["RF_IPv4_UC"], &paths))
+	assert.Equal(len(paths), 3)
+	assert.Equal(paths[2].Network, "10.1.1.0/24")
+	assert.Equal(string(d["Next"]), `"10.1.1.0/24"`)
+
+	dump, err = adj.NewOutDump(bgp.RF_IPv4_UC, &RibDumpOptions{})
+	assert.Nil(err)
+	j, err = dump.Marshal()
+	assert.Nil(err)
+	assert.Equal(string(j), `{"RF_IPv4_UC":[]}`)
+}
+
+func TestAdjRibDumpVPN(t *testing.T) {
+	assert := assert.New(t)
+	adj := NewAdjRib([]bgp.RouteFamily{bgp.RF_IPv4_VPN})
+	attrs := []bgp.PathAttributeInterface{
+		bgp.NewPathAttributeOrigin(0),
+		bgp.NewPathAttributeAsPath([]bgp.AsPathParamInterface{bgp.NewAs4PathParam(2, []uint32{65000})}),
+	}
+	paths := make([]Path, 0)
+	for _, prefix := range []string{"10.1.0.0", "10.0.0.0"} {
+		nlri := bgp.NewLabelledVPNIPAddrPrefix(16, prefix, *bgp.NewLabel(100), bgp.NewRouteDistinguisherTwoOctetAS(65000, 1))
+		mpreach := bgp.NewPathAttributeMpReachNLRI("192.168.50.1", []bgp.AddrPrefixInterface{nlri})
+		paths = append(paths, CreatePath(peerR1(), nlri, append(attrs, mpreach), false, time.Now()))
+	}
+	adj.UpdateIn(paths)
+
+	dump, err := adj.NewInDump(bgp.RF_IPv4_VPN, &RibDumpOptions{Limit: 1})
+	assert.Nil(err)
+	j, err := dump.Marshal()
+	assert.Nil(err)
+	d := make(map[string]json.RawMessage)
+	assert.Nil(json.Unmarshal(j, &d))
+	assert.Equal(string(d["Next"]), `"`+paths[1].getPrefix()+`"`)
+
+	dump, err = adj.NewInDump(bgp.RF_IPv4_VPN, &RibDumpOptions{Cursor: paths[1].getPrefix()})
+	assert.Nil(err)
+	assert.Equal(len(dump.entries), 1)
+	assert.Equal(dump.entries[0].name, paths[0].getPrefix())
+	_, err = adj.NewInDump(bgp.RF_IPv4_VPN, &RibDumpOptions{Cursor: "10.0.0.0/16"})
+	assert.NotNil(err)
+}
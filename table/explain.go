@@ -0,0 +1,155 @@
+// Copyright (C) 2015 Nippon Telegraph and Telephone Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package table
+
+import (
+	"fmt"
+	"github.com/osrg/gobgp/packet"
+	"net"
+)
+
+// BestPathStep is the result of a step of the comparison between two
+// paths. Winner is the index of the preferred path or -1 if the step
+// can't decide.
+type BestPathStep struct {
+	Reason  string
+	Winner  int
+	Skipped bool
+}
+
+// BestPathComparison is a comparison between two paths. Path1, Path2
+// and Winner are the indexes of the paths in BestPathExplanation.
+type BestPathComparison struct {
+	Path1       int
+	Path2       int
+	Winner      int
+	Reason      string
+	Steps       []*BestPathStep
+	explanation *BestPathExplanation
+}
+
+// BestPathExplanation tells how the best path of a destination is
+// selected; the known paths, the ones with an unreachable nexthop and
+// the comparisons made in order.
+type BestPathExplanation struct {
+	Prefix      string
+	Paths       []Path
+	BestPathIdx int
+	Reason      string
+	Unreachable []int
+	Comparisons []*BestPathComparison
+}
+
+func (e *BestPathExplanation) index(path Path) int {
+	for i, p := range e.Paths {
+		if p == path {
+			return i
+		}
+	}
+	return -1
+}
+
+// the methods below are called with nil receivers when the best path
+// selection isn't traced.
+
+func (e *BestPathExplanation) unreachable(path Path) {
+	if e == nil {
+		return
+	}
+	e.Unreachable = append(e.Unreachable, e.index(path))
+}
+
+func (e *BestPathExplanation) compare(path1, path2 Path) *BestPathComparison {
+	if e == nil {
+		return nil
+	}
+	c := &BestPathComparison{
+		Path1:       e.index(path1),
+		Path2:       e.index(path2),
+		Winner:      -1,
+		Steps:       make([]*BestPathStep, 0),
+		explanation: e,
+	}
+	e.Comparisons = append(e.Comparisons, c)
+	return c
+}
+
+func (c *BestPathComparison) step(reason string, winner Path) {
+	if c == nil {
+		return
+	}
+	c.Steps = append(c.Steps, &BestPathStep{
+		Reason: reason,
+		Winner: c.explanation.index(winner),
+	})
+}
+
+func (c *BestPathComparison) skip(reason string) {
+	if c == nil {
+		return
+	}
+	c.Steps = append(c.Steps, &BestPathStep{
+		Reason:  reason,
+		Winner:  -1,
+		Skipped: true,
+	})
+}
+
+func (c *BestPathComparison) result(winner Path, reason string) {
+	if c == nil {
+		return
+	}
+	c.Winner = c.explanation.index(winner)
+	c.Reason = reason
+}
+
+// run the best path selection of the destination again and return
+// how the best path is selected. the state of the destination isn't
+// changed.
+func (manager *TableManager) Explain(rf bgp.RouteFamily, prefix string) (*BestPathExplanation, error) {
+	t, ok := manager.Tables[rf]
+	if !ok {
+		return nil, fmt.Errorf("address family %s isn't configured", rf)
+	}
+	key := prefix
+	switch rf {
+	case bgp.RF_IPv4_UC, bgp.RF_IPv6_UC:
+		_, n, err := net.ParseCIDR(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prefix: %s", prefix)
+		}
+		key = n.String()
+	}
+	dest := t.getDestination(key)
+	if dest == nil || len(dest.getKnownPathList()) == 0 {
+		return nil, fmt.Errorf("%s isn't found in the rib", prefix)
+	}
+
+	e := &BestPathExplanation{
+		Prefix:      key,
+		Paths:       dest.getKnownPathList(),
+		BestPathIdx: -1,
+		Unreachable: make([]int, 0),
+		Comparisons: make([]*BestPathComparison, 0),
+	}
+	best, reason, err := dest.computeKnownBestPath(manager.selectionOptions(rf), e)
+	if err != nil {
+		return nil, err
+	}
+	e.BestPathIdx = e.index(best)
+	e.Reason = reason
+	return e, nil
+}
@@ -0,0 +1,203 @@
+// Copyright (C) 2015 Nippon Telegraph and Telephone Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package table
+
+import (
+	"fmt"
+	"github.com/osrg/gobgp/packet"
+	"github.com/tchap/go-patricia/patricia"
+	"net"
+	"strings"
+)
+
+type LookupOption string
+
+const (
+	// the prefix itself
+	LOOKUP_EXACT LookupOption = "exact"
+	// the most specific prefix covering the address or the prefix
+	LOOKUP_LONGEST LookupOption = "longest"
+	// the prefix and its more-specifics
+	LOOKUP_LONGER LookupOption = "longer"
+	// the prefix and its less-specifics
+	LOOKUP_SHORTER LookupOption = "shorter"
+)
+
+// parse the prefix or the address looked up in the rib of the route
+// family. an address is a host prefix. the name is the key of the prefix
+// in the rib.
+func lookupKey(rf bgp.RouteFamily, prefix string) (patricia.Prefix, string, error) {
+	var addrlen int
+	switch rf {
+	case bgp.RF_IPv4_UC:
+		addrlen = net.IPv4len
+	case bgp.RF_IPv6_UC:
+		addrlen = net.IPv6len
+	default:
+		return nil, "", fmt.Errorf("lookup by prefix isn't supported for %s", rf)
+	}
+	if !strings.Contains(prefix, "/") {
+		addr := net.ParseIP(prefix)
+		if addr == nil || (addr.To4() != nil) != (addrlen == net.IPv4len) {
+			return nil, "", fmt.Errorf("invalid address for %s: %s", rf, prefix)
+		}
+		prefix = fmt.Sprintf("%s/%d", addr, addrlen*8)
+	}
+	_, n, err := net.ParseCIDR(prefix)
+	if err != nil || len(n.IP) != addrlen {
+		return nil, "", fmt.Errorf("invalid prefix for %s: %s", rf, prefix)
+	}
+	ones, _ := n.Mask.Size()
+	return PrefixKey(n.IP, uint8(ones)), n.String(), nil
+}
+
+// ribIndex keeps the prefixes of a rib in a patricia trie updated with
+// the rib not to build the trie for each lookup. the items are the keys
+// of the rib. the keys are also sorted for the dumps when the rib has
+// changed.
+type ribIndex struct {
+	rf    bgp.RouteFamily
+	parse func(string) patricia.Prefix
+	trie  *patricia.Trie
+	// the snapshot of the keys in the order of the prefixes
+	sorted []*ribKey
+	dirty  bool
+}
+
+func newRibIndex(rf bgp.RouteFamily) *ribIndex {
+	idx := &ribIndex{
+		rf:    rf,
+		parse: prefixParser(rf),
+		dirty: true,
+	}
+	switch rf {
+	case bgp.RF_IPv4_UC, bgp.RF_IPv6_UC:
+		idx.trie = patricia.NewTrie()
+	}
+	return idx
+}
+
+// the key of the prefix in the patricia trie, an error if the key of
+// the rib can't be parsed
+func (idx *ribIndex) parseKey(name string) (key patricia.Prefix, err error) {
+	if idx.parse == nil {
+		return nil, nil
+	}
+	defer func() {
+		if recover() != nil {
+			err = fmt.Errorf("invalid prefix for %s: %s", idx.rf, name)
+		}
+	}()
+	return idx.parse(name), nil
+}
+
+// add the key of a new prefix in the rib
+func (idx *ribIndex) add(name string) {
+	idx.dirty = true
+	if idx.trie != nil {
+		if key, err := idx.parseKey(name); err == nil {
+			idx.trie.Set(key, name)
+		}
+	}
+}
+
+// remove the key of a prefix deleted from the rib
+func (idx *ribIndex) remove(name string) {
+	idx.dirty = true
+	if idx.trie != nil {
+		if key, err := idx.parseKey(name); err == nil && idx.trie.Get(key) == name {
+			idx.trie.Delete(key)
+		}
+	}
+}
+
+// the keys of the prefixes matching the prefix or the address with the
+// lookup option, in the order of the prefixes. the exact match is only
+// the key and may be missing in the rib.
+func (idx *ribIndex) lookup(prefix string, option LookupOption) ([]string, error) {
+	key, name, err := lookupKey(idx.rf, prefix)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0)
+	visit := func(prefix patricia.Prefix, item patricia.Item) error {
+		names = append(names, item.(string))
+		return nil
+	}
+	switch option {
+	case LOOKUP_EXACT, "":
+		names = append(names, name)
+	case LOOKUP_LONGEST:
+		idx.trie.VisitPrefixes(key, visit)
+		if len(names) > 0 {
+			names = names[len(names)-1:]
+		}
+	case LOOKUP_LONGER:
+		idx.trie.VisitSubtree(key, visit)
+	case LOOKUP_SHORTER:
+		idx.trie.VisitPrefixes(key, visit)
+	default:
+		return nil, fmt.Errorf("invalid lookup option: %s", option)
+	}
+	return names, nil
+}
+
+// return the destinations in the rib matching the prefix or the address
+// with the lookup option
+func (manager *TableManager) Lookup(rf bgp.RouteFamily, prefix string, option LookupOption) ([]Destination, error) {
+	t, ok := manager.Tables[rf]
+	if !ok {
+		return nil, fmt.Errorf("address family %s isn't configured", rf)
+	}
+	names, err := t.getIndex().lookup(prefix, option)
+	if err != nil {
+		return nil, err
+	}
+	destList := make([]Destination, 0, len(names))
+	for _, name := range names {
+		if dest := t.getDestination(name); dest != nil {
+			destList = append(destList, dest)
+		}
+	}
+	return destList, nil
+}
+
+func (adj *AdjRib) lookup(rib map[bgp.RouteFamily]map[string]*ReceivedRoute, index map[bgp.RouteFamily]*ribIndex, rf bgp.RouteFamily, prefix string, option LookupOption) ([]Path, error) {
+	if _, ok := rib[rf]; !ok {
+		return nil, fmt.Errorf("address family %s isn't configured", rf)
+	}
+	names, err := index[rf].lookup(prefix, option)
+	if err != nil {
+		return nil, err
+	}
+	pathList := make([]Path, 0, len(names))
+	for _, name := range names {
+		if rr, ok := rib[rf][name]; ok {
+			pathList = append(pathList, rr.path)
+		}
+	}
+	return pathList, nil
+}
+
+// return the paths in adj-rib-in matching the prefix or the address
+// with the lookup option
+func (adj *AdjRib) LookupIn(rf bgp.RouteFamily, prefix string, option LookupOption) ([]Path, error) {
+	return adj.lookup(adj.adjRibIn, adj.inIndex, rf, prefix, option)
+}
+
+func (adj *AdjRib) LookupOut(rf bgp.RouteFamily, prefix string, option LookupOption) ([]Path, error) {
+	return adj.lookup(adj.adjRibOut, adj.outIndex, rf, prefix, option)
+}
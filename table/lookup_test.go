@@ -0,0 +1,117 @@
+// Copyright (C) 2015 Nippon Telegraph and Telephone Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package table
+
+import (
+	"github.com/osrg/gobgp/packet"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func lookupTestPaths() []Path {
+	peer := peerR1()
+	attrs := []bgp.PathAttributeInterface{
+		bgp.NewPathAttributeOrigin(0),
+		bgp.NewPathAttributeAsPath([]bgp.AsPathParamInterface{bgp.NewAs4PathParam(2, []uint32{65000})}),
+		bgp.NewPathAttributeNextHop("192.168.50.1"),
+	}
+	paths := make([]Path, 0)
+	for _, n := range []*bgp.NLRInfo{
+		bgp.NewNLRInfo(8, "10.0.0.0"),
+		bgp.NewNLRInfo(16, "10.1.0.0"),
+		bgp.NewNLRInfo(24, "10.1.1.0"),
+		bgp.NewNLRInfo(24, "10.1.2.0"),
+		bgp.NewNLRInfo(16, "192.168.0.0"),
+	} {
+		paths = append(paths, CreatePath(peer, n, attrs, false, time.Now()))
+	}
+	return paths
+}
+
+func lookupPrefixes(paths []Path) []string {
+	prefixes := make([]string, 0, len(paths))
+	for _, p := range paths {
+		prefixes = append(prefixes, p.GetNlri().String())
+	}
+	return prefixes
+}
+
+func TestTableManagerLookup(t *testing.T) {
+	assert := assert.New(t)
+	tm := NewTableManager("TestTableManagerLookup", []bgp.RouteFamily{bgp.RF_IPv4_UC})
+	pathList := lookupTestPaths()
+	tm.ProcessPaths(pathList)
+
+	lookup := func(prefix string, option LookupOption) []string {
+		destList, err := tm.Lookup(bgp.RF_IPv4_UC, prefix, option)
+		assert.Nil(err)
+		paths := make([]Path, 0, len(destList))
+		for _, d := range destList {
+			paths = append(paths, d.getBestPath())
+		}
+		return lookupPrefixes(paths)
+	}
+	assert.Equal(lookup("10.1.0.0/16", LOOKUP_EXACT), []string{"10.1.0.0/16"})
+	assert.Equal(lookup("10.1.0.0/17", LOOKUP_EXACT), []string{})
+	assert.Equal(lookup("10.1.1.1", LOOKUP_LONGEST), []string{"10.1.1.0/24"})
+	assert.Equal(lookup("10.1.3.1", LOOKUP_LONGEST), []string{"10.1.0.0/16"})
+	assert.Equal(lookup("10.1.0.0/16", LOOKUP_LONGER), []string{"10.1.0.0/16", "10.1.1.0/24", "10.1.2.0/24"})
+	assert.Equal(lookup("10.1.1.0/24", LOOKUP_SHORTER), []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.1.0/24"})
+	assert.Equal(lookup("172.16.0.1", LOOKUP_LONGEST), []string{})
+
+	_, err := tm.Lookup(bgp.RF_IPv4_UC, "10.1.0.0/16", "any")
+	assert.NotNil(err)
+	_, err = tm.Lookup(bgp.RF_IPv4_UC, "2001:db8::/32", LOOKUP_EXACT)
+	assert.NotNil(err)
+	_, err = tm.Lookup(bgp.RF_IPv6_UC, "2001:db8::/32", LOOKUP_EXACT)
+	assert.NotNil(err)
+
+	// the withdrawn prefixes aren't looked up any more
+	withdrawn := make([]Path, 0)
+	for _, p := range pathList[1:3] {
+		withdrawn = append(withdrawn, p.Clone(true))
+	}
+	tm.ProcessPaths(withdrawn)
+	assert.Equal(lookup("10.1.1.1", LOOKUP_LONGEST), []string{"10.0.0.0/8"})
+	assert.Equal(lookup("10.0.0.0/8", LOOKUP_LONGER), []string{"10.0.0.0/8", "10.1.2.0/24"})
+	assert.Equal(lookup("10.1.0.0/16", LOOKUP_EXACT), []string{})
+}
+
+func TestAdjRibLookup(t *testing.T) {
+	assert := assert.New(t)
+	adj := NewAdjRib([]bgp.RouteFamily{bgp.RF_IPv4_UC})
+	adj.UpdateIn(lookupTestPaths())
+
+	paths, err := adj.LookupIn(bgp.RF_IPv4_UC, "10.0.0.0/8", LOOKUP_LONGER)
+	assert.Nil(err)
+	assert.Equal(lookupPrefixes(paths), []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.1.0/24", "10.1.2.0/24"})
+	paths, err = adj.LookupIn(bgp.RF_IPv4_UC, "192.168.1.1", LOOKUP_LONGEST)
+	assert.Nil(err)
+	assert.Equal(lookupPrefixes(paths), []string{"192.168.0.0/16"})
+	paths, err = adj.LookupOut(bgp.RF_IPv4_UC, "10.0.0.0/8", LOOKUP_LONGER)
+	assert.Nil(err)
+	assert.Equal(len(paths), 0)
+
+	adj.UpdateIn([]Path{lookupTestPaths()[1].Clone(true)})
+	paths, err = adj.LookupIn(bgp.RF_IPv4_UC, "10.1.1.0/24", LOOKUP_SHORTER)
+	assert.Nil(err)
+	assert.Equal(lookupPrefixes(paths), []string{"10.0.0.0/8", "10.1.1.0/24"})
+	adj.DropAllIn(bgp.RF_IPv4_UC)
+	paths, err = adj.LookupIn(bgp.RF_IPv4_UC, "10.0.0.0/8", LOOKUP_LONGER)
+	assert.Nil(err)
+	assert.Equal(len(paths), 0)
+}
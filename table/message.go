@@ -23,12 +23,17 @@ import (
 
 func UpdatePathAttrs2ByteAs(msg *bgp.BGPUpdate) error {
 	var asAttr *bgp.PathAttributeAsPath
+	var aggAttr *bgp.PathAttributeAggregator
 	idx := 0
+	aggIdx := 0
 	for i, attr := range msg.PathAttributes {
 		switch attr.(type) {
 		case *bgp.PathAttributeAsPath:
 			asAttr = attr.(*bgp.PathAttributeAsPath)
 			idx = i
+		case *bgp.PathAttributeAggregator:
+			aggAttr = attr.(*bgp.PathAttributeAggregator)
+			aggIdx = i
 		}
 	}
 
@@ -37,6 +42,21 @@ func UpdatePathAttrs2ByteAs(msg *bgp.BGPUpdate) error {
 	}
 
 	msg.PathAttributes = cloneAttrSlice(msg.PathAttributes)
+
+	// RFC 6793 4.2.2
+	// the AGGREGATOR attribute is sent with AS_TRANS and the
+	// AS4_AGGREGATOR attribute if the AS doesn't fit in two octets.
+	if aggAttr != nil {
+		as := aggAttr.Value.AS
+		addr := aggAttr.Value.Address.String()
+		if as > (1<<16)-1 {
+			msg.PathAttributes[aggIdx] = bgp.NewPathAttributeAggregator(uint16(bgp.AS_TRANS), addr)
+			msg.PathAttributes = append(msg.PathAttributes, bgp.NewPathAttributeAs4Aggregator(as, addr))
+		} else {
+			msg.PathAttributes[aggIdx] = bgp.NewPathAttributeAggregator(uint16(as), addr)
+		}
+	}
+
 	asAttr = msg.PathAttributes[idx].(*bgp.PathAttributeAsPath)
 	as4pathParam := make([]*bgp.As4PathParam, 0)
 	newASparams := make([]bgp.AsPathParamInterface, len(asAttr.Value))
@@ -131,13 +151,21 @@ func cloneAttrSlice(attrs []bgp.PathAttributeInterface) []bgp.PathAttributeInter
 func CloneAndUpdatePathAttrs(pathList []Path, global *config.Global, peer *config.Neighbor) []Path {
 	newPathList := make([]Path, 0, len(pathList))
 	for _, p := range pathList {
-		clone := p.clone(p.IsWithdraw())
+		clone := p.Clone(p.IsWithdraw())
 		clone.updatePathAttrs(global, peer)
 		newPathList = append(newPathList, clone)
 	}
 	return newPathList
 }
 
+// set the local attributes such as weight and default local-pref of
+// the paths received from the peer
+func UpdateInPathAttrs(pathList []Path, peer *config.Neighbor) {
+	for _, p := range pathList {
+		p.updateInPathAttrs(peer)
+	}
+}
+
 func createUpdateMsgFromPath(path Path, msg *bgp.BGPMessage) *bgp.BGPMessage {
 	rf := path.GetRouteFamily()
 
@@ -99,3 +99,19 @@ func TestAs4PathUnchanged(t *testing.T) {
 		}
 	}
 }
+
+func TestAggregator2ByteAs(t *testing.T) {
+	m := updateMsg1([]uint16{}).Body.(*bgp.BGPUpdate)
+	aspathParam := []bgp.AsPathParamInterface{bgp.NewAs4PathParam(2, []uint32{65000})}
+	m.PathAttributes[1] = bgp.NewPathAttributeAsPath(aspathParam)
+	m.PathAttributes = append(m.PathAttributes, bgp.NewPathAttributeAggregator(uint32(400000), "10.0.0.1"))
+	UpdatePathAttrs2ByteAs(m)
+	assert.Equal(t, len(m.PathAttributes), 6)
+
+	attr := m.PathAttributes[4].(*bgp.PathAttributeAggregator)
+	assert.Equal(t, attr.Value.AS, uint32(bgp.AS_TRANS))
+	assert.Equal(t, attr.Value.Address.String(), "10.0.0.1")
+	attr2 := m.PathAttributes[5].(*bgp.PathAttributeAs4Aggregator)
+	assert.Equal(t, attr2.Value.AS, uint32(400000))
+	assert.Equal(t, attr2.Value.Address.String(), "10.0.0.1")
+}
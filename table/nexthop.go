@@ -0,0 +1,95 @@
+// Copyright (C) 2015 Nippon Telegraph and Telephone Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package table
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"net"
+)
+
+// NexthopResolver tells the reachability of nexthops and the IGP
+// metric to them. The paths with unreachable nexthop aren't selected
+// as the best path.
+type NexthopResolver interface {
+	Resolve(nexthop net.IP) (reachable bool, metric uint32)
+	// forget the nexthops other than the ones used by the paths
+	Retain(nexthops []net.IP)
+}
+
+// all the nexthops are reachable with metric 0 without the
+// resolver. locally originated paths are always reachable.
+func resolveNexthop(resolver NexthopResolver, path Path) (bool, uint32) {
+	if resolver == nil || path.GetSource() == nil {
+		return true, 0
+	}
+	nexthop := path.GetNexthop()
+	if nexthop == nil || nexthop.IsUnspecified() {
+		return true, 0
+	}
+	return resolver.Resolve(nexthop)
+}
+
+// set the nexthop resolver and return the best path changes
+func (manager *TableManager) SetNexthopResolver(resolver NexthopResolver) []Path {
+	log.WithFields(log.Fields{
+		"Topic": "table",
+		"Owner": manager.owner,
+	}).Info("nexthop resolver configured")
+	manager.resolver = resolver
+	paths, _ := manager.calculate(manager.getDestinations())
+	return manager.processAggregates(paths)
+}
+
+// re-run the best path selection of the destinations having the
+// paths via the nexthops whose reachability or metric changed. the
+// resolver is told the nexthops still in use.
+func (manager *TableManager) UpdateNexthops(nexthops []net.IP) []Path {
+	changed := make(map[string]bool)
+	for _, nexthop := range nexthops {
+		changed[nexthop.String()] = true
+	}
+	used := make(map[string]net.IP)
+	destinationList := make([]Destination, 0)
+	for _, dest := range manager.getDestinations() {
+		found := false
+		for _, path := range dest.getKnownPathList() {
+			nexthop := path.GetNexthop()
+			used[nexthop.String()] = nexthop
+			if changed[nexthop.String()] && !found {
+				destinationList = append(destinationList, dest)
+				found = true
+			}
+		}
+	}
+	if manager.resolver != nil {
+		inUse := make([]net.IP, 0, len(used))
+		for _, nexthop := range used {
+			inUse = append(inUse, nexthop)
+		}
+		manager.resolver.Retain(inUse)
+	}
+	if len(nexthops) == 0 {
+		return []Path{}
+	}
+	log.WithFields(log.Fields{
+		"Topic":        "table",
+		"Owner":        manager.owner,
+		"Nexthops":     nexthops,
+		"Destinations": len(destinationList),
+	}).Info("nexthops updated")
+	paths, _ := manager.calculate(destinationList)
+	return manager.processAggregates(paths)
+}
@@ -0,0 +1,110 @@
+// Copyright (C) 2015 Nippon Telegraph and Telephone Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package table
+
+import (
+	"github.com/osrg/gobgp/packet"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+	"time"
+)
+
+// the nexthops not in the map are unreachable
+type testResolver map[string]uint32
+
+func (r testResolver) Resolve(nexthop net.IP) (bool, uint32) {
+	metric, ok := r[nexthop.String()]
+	return ok, metric
+}
+
+func (r testResolver) Retain(nexthops []net.IP) {
+	used := make(map[string]bool)
+	for _, nexthop := range nexthops {
+		used[nexthop.String()] = true
+	}
+	for key, _ := range r {
+		if !used[key] {
+			delete(r, key)
+		}
+	}
+}
+
+func nexthopTestPath(peer *PeerInfo, nexthop string) Path {
+	attrs := []bgp.PathAttributeInterface{
+		bgp.NewPathAttributeOrigin(0),
+		bgp.NewPathAttributeAsPath([]bgp.AsPathParamInterface{bgp.NewAs4PathParam(2, []uint32{65000})}),
+		bgp.NewPathAttributeNextHop(nexthop),
+	}
+	return CreatePath(peer, bgp.NewNLRInfo(24, "10.10.10.0"), attrs, false, time.Now())
+}
+
+func TestDestinationCalculateNexthop(t *testing.T) {
+	peerD := DestCreatePeer()
+	nlri := bgp.NewNLRInfo(24, "10.10.10.0")
+	path1 := nexthopTestPath(peerD[0], "192.168.50.1")
+	path2 := nexthopTestPath(peerD[1], "192.168.50.2")
+	ipv4d := NewIPv4Destination(nlri)
+	ipv4d.addNewPath(path1)
+	ipv4d.addNewPath(path2)
+
+	best, reason, e := ipv4d.Calculate(&SelectionOptions{LocalAsn: 100, Resolver: testResolver{"192.168.50.2": 10}})
+	assert.Nil(t, e)
+	assert.Equal(t, best, path2)
+	assert.Equal(t, reason, BPR_REACHABLE_NEXT_HOP)
+
+	best, reason, e = ipv4d.Calculate(&SelectionOptions{LocalAsn: 100, Resolver: testResolver{"192.168.50.1": 20, "192.168.50.2": 10}})
+	assert.Nil(t, e)
+	assert.Equal(t, best, path2)
+	assert.Equal(t, reason, BPR_IGP_COST)
+
+	best, reason, e = ipv4d.Calculate(&SelectionOptions{LocalAsn: 100, Resolver: testResolver{}})
+	assert.Nil(t, e)
+	assert.Nil(t, best)
+	assert.Equal(t, reason, BPR_REACHABLE_NEXT_HOP)
+}
+
+func TestTableManagerUpdateNexthops(t *testing.T) {
+	tm := NewTableManager("TestTableManagerUpdateNexthops", []bgp.RouteFamily{bgp.RF_IPv4_UC})
+	resolver := testResolver{}
+	paths := tm.SetNexthopResolver(resolver)
+	assert.Equal(t, len(paths), 0)
+
+	// the nexthop of the path is unreachable
+	pList, err := tm.ProcessUpdate(peerR1(), update_fromR1())
+	assert.NoError(t, err)
+	assert.Equal(t, len(pList), 0)
+	assert.Equal(t, len(tm.GetPathList(bgp.RF_IPv4_UC)), 0)
+
+	nexthop := net.ParseIP("192.168.50.1")
+	resolver[nexthop.String()] = 10
+	pList = tm.UpdateNexthops([]net.IP{nexthop})
+	assert.Equal(t, len(pList), 1)
+	assert.Equal(t, pList[0].IsWithdraw(), false)
+	assert.Equal(t, len(tm.GetPathList(bgp.RF_IPv4_UC)), 1)
+
+	// the nexthops not used are forgotten
+	resolver["10.0.0.1"] = 10
+	pList = tm.UpdateNexthops([]net.IP{})
+	assert.Equal(t, len(pList), 0)
+	assert.Equal(t, resolver, testResolver{nexthop.String(): 10})
+
+	delete(resolver, nexthop.String())
+	pList = tm.UpdateNexthops([]net.IP{nexthop})
+	assert.Equal(t, len(pList), 1)
+	assert.Equal(t, pList[0].IsWithdraw(), true)
+	assert.Equal(t, len(tm.GetPathList(bgp.RF_IPv4_UC)), 0)
+}
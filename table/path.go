@@ -16,21 +16,28 @@
 package table
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	log "github.com/Sirupsen/logrus"
 	"github.com/osrg/gobgp/config"
 	"github.com/osrg/gobgp/packet"
+	"math"
 	"net"
 	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type Path interface {
 	String() string
 	getPathAttrs() []bgp.PathAttributeInterface
+	GetPathAttrs() []bgp.PathAttributeInterface
 	getPathAttr(bgp.BGPAttrType) (int, bgp.PathAttributeInterface)
 	updatePathAttrs(global *config.Global, peer *config.Neighbor)
+	updateInPathAttrs(peer *config.Neighbor)
 	GetRouteFamily() bgp.RouteFamily
 	setSource(source *PeerInfo)
 	GetSource() *PeerInfo
@@ -40,11 +47,34 @@ type Path interface {
 	IsWithdraw() bool
 	GetNlri() bgp.AddrPrefixInterface
 	getPrefix() string
+	GetPrefix() string
 	setMedSetByTargetNeighbor(medSetByTargetNeighbor bool)
 	getMedSetByTargetNeighbor() bool
-	clone(IsWithdraw bool) Path
+	Clone(IsWithdraw bool) Path
 	getTimestamp() time.Time
+	GetTimestamp() time.Time
 	setTimestamp(t time.Time)
+	GetWeight() uint32
+	SetWeight(weight uint32)
+	GetIgpTag() string
+	SetIgpTag(tag string)
+	GetValidation() config.RpkiValidationResultType
+	SetValidation(v config.RpkiValidationResultType)
+	GetOriginAs() (uint32, bool)
+	GetCommunities() []uint32
+	SetCommunities(communities []uint32, doReplace bool)
+	GetExtCommunities() []bgp.ExtendedCommunityInterface
+	SetExtCommunities(communities []bgp.ExtendedCommunityInterface, doReplace bool)
+	GetAsPathLen() int
+	GetAsString() string
+	GetOrigin() (uint8, error)
+	GetInstallProtocol() config.InstallProtocolType
+	GetMed() (uint32, error)
+	SetMed(med int64, doReplace bool) error
+	GetLocalPref() (uint32, error)
+	SetLocalPref(localPref uint32)
+	SetNexthop(nexthop net.IP)
+	PrependAsn(asn uint32, repeat uint8)
 	MarshalJSON() ([]byte, error)
 }
 
@@ -57,6 +87,10 @@ type PathDefault struct {
 	pathAttrs              []bgp.PathAttributeInterface
 	medSetByTargetNeighbor bool
 	timestamp              time.Time
+	// local to this router and never sent to the peers
+	weight     uint32
+	igpTag     string
+	validation config.RpkiValidationResultType
 }
 
 func NewPathDefault(rf bgp.RouteFamily, source *PeerInfo, nlri bgp.AddrPrefixInterface, nexthop net.IP, isWithdraw bool, pattrs []bgp.PathAttributeInterface, medSetByTargetNeighbor bool, now time.Time) *PathDefault {
@@ -93,10 +127,27 @@ func cloneAsPath(asAttr *bgp.PathAttributeAsPath) *bgp.PathAttributeAsPath {
 	return bgp.NewPathAttributeAsPath(newASparams)
 }
 
+// replace the nexthop attribute of the path. the nexthop of
+// multiprotocol families lives in MP_REACH_NLRI.
+func (pd *PathDefault) updateNexthopAttr(nexthop net.IP) {
+	if idx, _ := pd.getPathAttr(bgp.BGP_ATTR_TYPE_NEXT_HOP); idx >= 0 {
+		pd.pathAttrs[idx] = bgp.NewPathAttributeNextHop(nexthop.String())
+	} else if idx, attr := pd.getPathAttr(bgp.BGP_ATTR_TYPE_MP_REACH_NLRI); idx >= 0 {
+		reach := attr.(*bgp.PathAttributeMpReachNLRI)
+		pd.pathAttrs[idx] = bgp.NewPathAttributeMpReachNLRI(nexthop.String(), reach.Value)
+	} else {
+		log.Fatal("missing NEXTHOP mandatory attribute")
+	}
+}
+
 func (pd *PathDefault) updatePathAttrs(global *config.Global, peer *config.Neighbor) {
-	newPathAttrs := make([]bgp.PathAttributeInterface, len(pd.pathAttrs))
-	for i, v := range pd.pathAttrs {
-		newPathAttrs[i] = v
+	newPathAttrs := make([]bgp.PathAttributeInterface, 0, len(pd.pathAttrs))
+	for _, v := range pd.pathAttrs {
+		// LOCAL_PREF must not be sent to external peers
+		if _, ok := v.(*bgp.PathAttributeLocalPref); ok && peer.PeerType == config.PEER_TYPE_EXTERNAL && !peer.RouteServer.RouteServerClient {
+			continue
+		}
+		newPathAttrs = append(newPathAttrs, v)
 	}
 	pd.pathAttrs = newPathAttrs
 
@@ -106,12 +157,7 @@ func (pd *PathDefault) updatePathAttrs(global *config.Global, peer *config.Neigh
 
 	if peer.PeerType == config.PEER_TYPE_EXTERNAL {
 		// NEXTHOP handling
-		idx, _ := pd.getPathAttr(bgp.BGP_ATTR_TYPE_NEXT_HOP)
-		if idx < 0 {
-			log.Fatal("missing NEXTHOP mandatory attribute")
-		}
-		newNexthop := bgp.NewPathAttributeNextHop(peer.LocalAddress.String())
-		newPathAttrs[idx] = newNexthop
+		pd.updateNexthopAttr(peer.LocalAddress)
 
 		// AS_PATH handling
 		//
@@ -141,9 +187,9 @@ func (pd *PathDefault) updatePathAttrs(global *config.Global, peer *config.Neigh
 		}
 		asPath := cloneAsPath(originalAsPath.(*bgp.PathAttributeAsPath))
 		newPathAttrs[idx] = asPath
-		fst := asPath.Value[0].(*bgp.As4PathParam)
-		if len(asPath.Value) > 0 && fst.Type == bgp.BGP_ASPATH_ATTR_TYPE_SEQ &&
-			fst.ASLen() < 255 {
+		if len(asPath.Value) > 0 && asPath.Value[0].(*bgp.As4PathParam).Type == bgp.BGP_ASPATH_ATTR_TYPE_SEQ &&
+			asPath.Value[0].(*bgp.As4PathParam).ASLen() < 255 {
+			fst := asPath.Value[0].(*bgp.As4PathParam)
 			fst.AS = append([]uint32{global.As}, fst.AS...)
 			fst.Num += 1
 		} else {
@@ -152,20 +198,29 @@ func (pd *PathDefault) updatePathAttrs(global *config.Global, peer *config.Neigh
 		}
 
 		// MED Handling
+		//
+		// the MED received from a neighbor isn't propagated to other
+		// ASes, while the MED configured for the locally originated
+		// paths is advertised to the external peers.
 		idx, _ = pd.getPathAttr(bgp.BGP_ATTR_TYPE_MULTI_EXIT_DISC)
-		if idx >= 0 {
+		if idx >= 0 && pd.source != nil {
 			newPathAttrs = append(newPathAttrs[:idx], newPathAttrs[idx+1:]...)
+			pd.pathAttrs = newPathAttrs
 		}
 	} else if peer.PeerType == config.PEER_TYPE_INTERNAL {
+		// locally originated paths without explicit nexthop are
+		// advertised with our address.
+		if pd.source == nil && (pd.nexthop == nil || pd.nexthop.IsUnspecified()) {
+			pd.updateNexthopAttr(peer.LocalAddress)
+		}
+
 		// For iBGP peers we are required to send local-pref attribute
 		// for connected or local prefixes.
-		// We set default local-pref 100.
-		p := bgp.NewPathAttributeLocalPref(100)
+		// We set the default local-pref of the peer if the path
+		// doesn't have one.
 		idx, _ := pd.getPathAttr(bgp.BGP_ATTR_TYPE_LOCAL_PREF)
 		if idx < 0 {
-			newPathAttrs = append(newPathAttrs, p)
-		} else {
-			newPathAttrs[idx] = p
+			pd.pathAttrs = append(pd.pathAttrs, bgp.NewPathAttributeLocalPref(defaultLocalPref(peer)))
 		}
 	} else {
 		log.WithFields(log.Fields{
@@ -175,30 +230,357 @@ func (pd *PathDefault) updatePathAttrs(global *config.Global, peer *config.Neigh
 	}
 }
 
+// set the local attributes of the path received from the peer
+func (pd *PathDefault) updateInPathAttrs(peer *config.Neighbor) {
+	pd.weight = peer.Weight
+
+	// the route server passes the paths through without change
+	if pd.withdraw || peer.RouteServer.RouteServerClient {
+		return
+	}
+	// RFC 4271 5.1.5. LOCAL_PREF received from external peers is
+	// ignored. the default one is set instead, which the import
+	// policies can override.
+	idx, _ := pd.getPathAttr(bgp.BGP_ATTR_TYPE_LOCAL_PREF)
+	if idx >= 0 && peer.PeerType != config.PEER_TYPE_EXTERNAL {
+		return
+	}
+	newPathAttrs := make([]bgp.PathAttributeInterface, 0, len(pd.pathAttrs)+1)
+	for i, v := range pd.pathAttrs {
+		if i != idx {
+			newPathAttrs = append(newPathAttrs, v)
+		}
+	}
+	pd.pathAttrs = append(newPathAttrs, bgp.NewPathAttributeLocalPref(defaultLocalPref(peer)))
+}
+
+func defaultLocalPref(peer *config.Neighbor) uint32 {
+	if peer.DefaultLocalPref == 0 {
+		return config.DEFAULT_LOCAL_PREF
+	}
+	return peer.DefaultLocalPref
+}
+
+func (pd *PathDefault) GetWeight() uint32 {
+	return pd.weight
+}
+
+func (pd *PathDefault) SetWeight(weight uint32) {
+	pd.weight = weight
+}
+
+// the IGP tag of the locally originated path. like the weight, it's
+// never sent to the peers.
+func (pd *PathDefault) GetIgpTag() string {
+	return pd.igpTag
+}
+
+func (pd *PathDefault) SetIgpTag(tag string) {
+	pd.igpTag = tag
+}
+
+// the result of the RPKI origin validation, local to this router too
+func (pd *PathDefault) GetValidation() config.RpkiValidationResultType {
+	return pd.validation
+}
+
+func (pd *PathDefault) SetValidation(v config.RpkiValidationResultType) {
+	pd.validation = v
+}
+
+func validationString(v config.RpkiValidationResultType) string {
+	switch v {
+	case config.RPKI_VALIDATION_RESULT_TYPE_NOT_FOUND:
+		return "not-found"
+	case config.RPKI_VALIDATION_RESULT_TYPE_VALID:
+		return "valid"
+	case config.RPKI_VALIDATION_RESULT_TYPE_INVALID:
+		return "invalid"
+	}
+	return ""
+}
+
+// replace the attribute of the same type or add the attribute. the
+// attribute list is copied as it might be shared with the other paths.
+func (pd *PathDefault) setPathAttr(attr bgp.PathAttributeInterface) {
+	newPathAttrs := make([]bgp.PathAttributeInterface, len(pd.pathAttrs), len(pd.pathAttrs)+1)
+	copy(newPathAttrs, pd.pathAttrs)
+	for i, a := range newPathAttrs {
+		if reflect.TypeOf(a) == reflect.TypeOf(attr) {
+			newPathAttrs[i] = attr
+			pd.pathAttrs = newPathAttrs
+			return
+		}
+	}
+	pd.pathAttrs = append(newPathAttrs, attr)
+}
+
+func (pd *PathDefault) removePathAttr(pattrType bgp.BGPAttrType) {
+	idx, _ := pd.getPathAttr(pattrType)
+	if idx < 0 {
+		return
+	}
+	newPathAttrs := make([]bgp.PathAttributeInterface, 0, len(pd.pathAttrs)-1)
+	newPathAttrs = append(newPathAttrs, pd.pathAttrs[:idx]...)
+	pd.pathAttrs = append(newPathAttrs, pd.pathAttrs[idx+1:]...)
+}
+
+func (pd *PathDefault) GetCommunities() []uint32 {
+	communities := make([]uint32, 0)
+	if _, attr := pd.getPathAttr(bgp.BGP_ATTR_TYPE_COMMUNITIES); attr != nil {
+		communities = append(communities, attr.(*bgp.PathAttributeCommunities).Value...)
+	}
+	return communities
+}
+
+// add the communities to the path, or replace the communities of the
+// path if doReplace is true. the attribute is removed when the path
+// has no community.
+func (pd *PathDefault) SetCommunities(communities []uint32, doReplace bool) {
+	newCommunities := make([]uint32, 0)
+	if !doReplace {
+		newCommunities = pd.GetCommunities()
+	}
+	for _, c := range communities {
+		found := false
+		for _, v := range newCommunities {
+			if c == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			newCommunities = append(newCommunities, c)
+		}
+	}
+	if len(newCommunities) == 0 {
+		pd.removePathAttr(bgp.BGP_ATTR_TYPE_COMMUNITIES)
+		return
+	}
+	pd.setPathAttr(bgp.NewPathAttributeCommunities(newCommunities))
+}
+
+func (pd *PathDefault) GetExtCommunities() []bgp.ExtendedCommunityInterface {
+	communities := make([]bgp.ExtendedCommunityInterface, 0)
+	if _, attr := pd.getPathAttr(bgp.BGP_ATTR_TYPE_EXTENDED_COMMUNITIES); attr != nil {
+		communities = append(communities, attr.(*bgp.PathAttributeExtendedCommunities).Value...)
+	}
+	return communities
+}
+
+// add the extended communities to the path, or replace the extended
+// communities of the path if doReplace is true. the attribute is
+// removed when the path has no extended community.
+func (pd *PathDefault) SetExtCommunities(communities []bgp.ExtendedCommunityInterface, doReplace bool) {
+	newCommunities := make([]bgp.ExtendedCommunityInterface, 0)
+	if !doReplace {
+		newCommunities = pd.GetExtCommunities()
+	}
+	for _, c := range communities {
+		found := false
+		for _, v := range newCommunities {
+			if EqualExtCommunity(c, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			newCommunities = append(newCommunities, c)
+		}
+	}
+	if len(newCommunities) == 0 {
+		pd.removePathAttr(bgp.BGP_ATTR_TYPE_EXTENDED_COMMUNITIES)
+		return
+	}
+	pd.setPathAttr(bgp.NewPathAttributeExtendedCommunities(newCommunities))
+}
+
+func (pd *PathDefault) getAsPathParams() [][]uint32 {
+	params := make([][]uint32, 0)
+	_, attr := pd.getPathAttr(bgp.BGP_ATTR_TYPE_AS_PATH)
+	if attr == nil {
+		return params
+	}
+	for _, param := range attr.(*bgp.PathAttributeAsPath).Value {
+		switch p := param.(type) {
+		case *bgp.As4PathParam:
+			params = append(params, append([]uint32{uint32(p.Type)}, p.AS...))
+		case *bgp.AsPathParam:
+			l := []uint32{uint32(p.Type)}
+			for _, as := range p.AS {
+				l = append(l, uint32(as))
+			}
+			params = append(params, l)
+		}
+	}
+	return params
+}
+
+// return the origin AS, the rightmost AS of AS_PATH. false is returned
+// when AS_PATH is empty or ends with an AS_SET.
+func (pd *PathDefault) GetOriginAs() (uint32, bool) {
+	params := pd.getAsPathParams()
+	if len(params) == 0 {
+		return 0, false
+	}
+	last := params[len(params)-1]
+	if last[0] == bgp.BGP_ASPATH_ATTR_TYPE_SET || len(last) < 2 {
+		return 0, false
+	}
+	return last[len(last)-1], true
+}
+
+// return the length of AS_PATH. an AS_SET is counted as one AS.
+func (pd *PathDefault) GetAsPathLen() int {
+	length := 0
+	for _, param := range pd.getAsPathParams() {
+		if param[0] == bgp.BGP_ASPATH_ATTR_TYPE_SET {
+			length++
+		} else {
+			length += len(param) - 1
+		}
+	}
+	return length
+}
+
+// return AS_PATH as a string like "65001 65002 {65003,65004}".
+func (pd *PathDefault) GetAsString() string {
+	segments := make([]string, 0)
+	for _, param := range pd.getAsPathParams() {
+		asList := make([]string, 0, len(param)-1)
+		for _, as := range param[1:] {
+			asList = append(asList, strconv.FormatUint(uint64(as), 10))
+		}
+		if param[0] == bgp.BGP_ASPATH_ATTR_TYPE_SET {
+			segments = append(segments, "{"+strings.Join(asList, ",")+"}")
+		} else if len(asList) > 0 {
+			segments = append(segments, strings.Join(asList, " "))
+		}
+	}
+	return strings.Join(segments, " ")
+}
+
+func (pd *PathDefault) GetOrigin() (uint8, error) {
+	_, attr := pd.getPathAttr(bgp.BGP_ATTR_TYPE_ORIGIN)
+	if attr == nil {
+		return 0, fmt.Errorf("no origin path attr")
+	}
+	return attr.(*bgp.PathAttributeOrigin).Value[0], nil
+}
+
+// return the protocol that the path is installed by. the locally
+// originated paths are the static networks or the aggregated paths
+// which have the AGGREGATOR attribute.
+func (pd *PathDefault) GetInstallProtocol() config.InstallProtocolType {
+	if pd.source != nil {
+		return config.INSTALL_PROTOCOL_TYPE_BGP
+	}
+	if _, attr := pd.getPathAttr(bgp.BGP_ATTR_TYPE_AGGREGATOR); attr != nil {
+		return config.INSTALL_PROTOCOL_TYPE_LOCAL_AGGREGATE
+	}
+	return config.INSTALL_PROTOCOL_TYPE_STATIC
+}
+
+func (pd *PathDefault) GetMed() (uint32, error) {
+	_, attr := pd.getPathAttr(bgp.BGP_ATTR_TYPE_MULTI_EXIT_DISC)
+	if attr == nil {
+		return 0, fmt.Errorf("no med path attr")
+	}
+	return attr.(*bgp.PathAttributeMultiExitDisc).Value, nil
+}
+
+// set the MED of the path, or add med to the current MED if doReplace
+// is false. the path without MED is handled as MED 0.
+func (pd *PathDefault) SetMed(med int64, doReplace bool) error {
+	if !doReplace {
+		if _, attr := pd.getPathAttr(bgp.BGP_ATTR_TYPE_MULTI_EXIT_DISC); attr != nil {
+			med += int64(attr.(*bgp.PathAttributeMultiExitDisc).Value)
+		}
+	}
+	if med < 0 || med > math.MaxUint32 {
+		return fmt.Errorf("MED is out of range: %d", med)
+	}
+	pd.setPathAttr(bgp.NewPathAttributeMultiExitDisc(uint32(med)))
+	return nil
+}
+
+func (pd *PathDefault) GetLocalPref() (uint32, error) {
+	_, attr := pd.getPathAttr(bgp.BGP_ATTR_TYPE_LOCAL_PREF)
+	if attr == nil {
+		return 0, fmt.Errorf("no local-pref path attr")
+	}
+	return attr.(*bgp.PathAttributeLocalPref).Value, nil
+}
+
+func (pd *PathDefault) SetLocalPref(localPref uint32) {
+	pd.setPathAttr(bgp.NewPathAttributeLocalPref(localPref))
+}
+
+// set the nexthop of the path and the attribute carrying it. the
+// nexthop of multiprotocol families lives in MP_REACH_NLRI.
+func (pd *PathDefault) SetNexthop(nexthop net.IP) {
+	pd.nexthop = nexthop
+	if _, attr := pd.getPathAttr(bgp.BGP_ATTR_TYPE_NEXT_HOP); attr != nil {
+		pd.setPathAttr(bgp.NewPathAttributeNextHop(nexthop.String()))
+	} else if _, attr := pd.getPathAttr(bgp.BGP_ATTR_TYPE_MP_REACH_NLRI); attr != nil {
+		reach := attr.(*bgp.PathAttributeMpReachNLRI)
+		pd.setPathAttr(bgp.NewPathAttributeMpReachNLRI(nexthop.String(), reach.Value))
+	}
+}
+
+// prepend the AS to AS_PATH repeat times. a new AS_SEQUENCE segment is
+// added when the first segment isn't AS_SEQUENCE or overflows.
+func (pd *PathDefault) PrependAsn(asn uint32, repeat uint8) {
+	prepend := make([]uint32, repeat)
+	for i := range prepend {
+		prepend[i] = asn
+	}
+	params := make([]bgp.AsPathParamInterface, 0)
+	for _, param := range pd.getAsPathParams() {
+		params = append(params, bgp.NewAs4PathParam(uint8(param[0]), param[1:]))
+	}
+	if len(params) > 0 {
+		fst := params[0].(*bgp.As4PathParam)
+		if fst.Type == bgp.BGP_ASPATH_ATTR_TYPE_SEQ && fst.ASLen()+len(prepend) <= 255 {
+			params[0] = bgp.NewAs4PathParam(bgp.BGP_ASPATH_ATTR_TYPE_SEQ, append(prepend, fst.AS...))
+			pd.setPathAttr(bgp.NewPathAttributeAsPath(params))
+			return
+		}
+	}
+	params = append([]bgp.AsPathParamInterface{bgp.NewAs4PathParam(bgp.BGP_ASPATH_ATTR_TYPE_SEQ, prepend)}, params...)
+	pd.setPathAttr(bgp.NewPathAttributeAsPath(params))
+}
+
 func (pd *PathDefault) getTimestamp() time.Time {
 	return pd.timestamp
 }
 
+func (pd *PathDefault) GetTimestamp() time.Time {
+	return pd.timestamp
+}
+
 func (pd *PathDefault) setTimestamp(t time.Time) {
 	pd.timestamp = t
 }
 
 func (pd *PathDefault) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
-		Network string
-		Nexthop string
-		Attrs   []bgp.PathAttributeInterface
-		Age     float64
+		Network    string
+		Nexthop    string
+		Attrs      []bgp.PathAttributeInterface
+		Age        float64
+		Validation string
 	}{
-		Network: pd.getPrefix(),
-		Nexthop: pd.nexthop.String(),
-		Attrs:   pd.getPathAttrs(),
-		Age:     time.Now().Sub(pd.timestamp).Seconds(),
+		Network:    pd.getPrefix(),
+		Nexthop:    pd.nexthop.String(),
+		Attrs:      pd.getPathAttrs(),
+		Age:        time.Now().Sub(pd.timestamp).Seconds(),
+		Validation: validationString(pd.validation),
 	})
 }
 
 // create new PathAttributes
-func (pd *PathDefault) clone(isWithdraw bool) Path {
+func (pd *PathDefault) Clone(isWithdraw bool) Path {
 	nlri := pd.nlri
 	if isWithdraw {
 		if pd.IsWithdraw() {
@@ -207,7 +589,11 @@ func (pd *PathDefault) clone(isWithdraw bool) Path {
 			nlri = &bgp.WithdrawnRoute{pd.nlri.(*bgp.NLRInfo).IPAddrPrefix}
 		}
 	}
-	return CreatePath(pd.source, nlri, pd.pathAttrs, isWithdraw, pd.timestamp)
+	path := CreatePath(pd.source, nlri, pd.pathAttrs, isWithdraw, pd.timestamp)
+	path.SetWeight(pd.weight)
+	path.SetIgpTag(pd.igpTag)
+	path.SetValidation(pd.validation)
+	return path
 }
 
 func (pd *PathDefault) GetRouteFamily() bgp.RouteFamily {
@@ -253,6 +639,10 @@ func (pd *PathDefault) getPathAttrs() []bgp.PathAttributeInterface {
 	return pd.pathAttrs
 }
 
+func (pd *PathDefault) GetPathAttrs() []bgp.PathAttributeInterface {
+	return pd.pathAttrs
+}
+
 func (pd *PathDefault) getPathAttr(pattrType bgp.BGPAttrType) (int, bgp.PathAttributeInterface) {
 	attrMap := [bgp.BGP_ATTR_TYPE_AS4_AGGREGATOR + 1]reflect.Type{}
 	attrMap[bgp.BGP_ATTR_TYPE_ORIGIN] = reflect.TypeOf(&bgp.PathAttributeOrigin{})
@@ -290,6 +680,10 @@ func (pi *PathDefault) String() string {
 	return str
 }
 
+func (pi *PathDefault) GetPrefix() string {
+	return pi.getPrefix()
+}
+
 func (pi *PathDefault) getPrefix() string {
 	switch nlri := pi.nlri.(type) {
 	case *bgp.NLRInfo:
@@ -325,6 +719,235 @@ func CreatePath(source *PeerInfo, nlri bgp.AddrPrefixInterface, attrs []bgp.Path
 	return path
 }
 
+var wellKnownCommunities = map[string]uint32{
+	"no-export":           0xffffff01,
+	"no-advertise":        0xffffff02,
+	"no-export-subconfed": 0xffffff03,
+	"no-peer":             0xffffff04,
+}
+
+// "_" in AS path regular expressions matches the beginning or the end
+// of the AS path, or a delimiter between ASes like Cisco's.
+var asPathRegexpBoundary = "(^|[ ,{}()]|$)"
+
+// compile the regular expression matched against the AS path like
+// "65001 65002 {65003,65004}".
+func ParseAsPathRegexp(s string) (*regexp.Regexp, error) {
+	return regexp.Compile(strings.Replace(s, "_", asPathRegexpBoundary, -1))
+}
+
+// parse the string representation of a standard community.
+// "<as>:<value>", a 32bit integer and well-known names are accepted.
+func ParseCommunity(s string) (uint32, error) {
+	if v, ok := wellKnownCommunities[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	elems := strings.Split(s, ":")
+	switch len(elems) {
+	case 1:
+		v, err := strconv.ParseUint(elems[0], 10, 32)
+		if err == nil {
+			return uint32(v), nil
+		}
+	case 2:
+		as, err1 := strconv.ParseUint(elems[0], 10, 16)
+		v, err2 := strconv.ParseUint(elems[1], 10, 16)
+		if err1 == nil && err2 == nil {
+			return uint32(as<<16 | v), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid community: %s", s)
+}
+
+const (
+	EXT_COMMUNITY_ROUTE_TARGET = "route-target"
+	EXT_COMMUNITY_ROUTE_ORIGIN = "route-origin"
+)
+
+var extCommunitySubTypes = map[string]uint8{
+	EXT_COMMUNITY_ROUTE_TARGET: bgp.EC_SUBTYPE_ROUTE_TARGET,
+	EXT_COMMUNITY_ROUTE_ORIGIN: bgp.EC_SUBTYPE_ROUTE_ORIGIN,
+}
+
+// parse the string representation of a route target or route origin
+// extended community like "route-target:65000:100". the global
+// administrator is a 2 octet AS, an IPv4 address, or a 4 octet AS in
+// the plain or "<high>.<low>" form.
+func ParseExtCommunity(s string) (bgp.ExtendedCommunityInterface, error) {
+	elems := strings.SplitN(s, ":", 2)
+	if len(elems) != 2 {
+		return nil, fmt.Errorf("invalid extended community: %s", s)
+	}
+	subType, ok := extCommunitySubTypes[elems[0]]
+	if !ok {
+		return nil, fmt.Errorf("unsupported extended community type: %s", s)
+	}
+	idx := strings.LastIndex(elems[1], ":")
+	if idx < 0 {
+		return nil, fmt.Errorf("invalid extended community: %s", s)
+	}
+	global, local := elems[1][:idx], elems[1][idx+1:]
+	if ip := net.ParseIP(global).To4(); ip != nil {
+		v, err := strconv.ParseUint(local, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid extended community: %s", s)
+		}
+		return &bgp.IPv4AddressSpecificExtended{SubType: subType, IPv4: ip, LocalAdmin: uint16(v)}, nil
+	}
+	var as uint64
+	var err error
+	isFourOctet := false
+	if dot := strings.Index(global, "."); dot >= 0 {
+		high, err1 := strconv.ParseUint(global[:dot], 10, 16)
+		low, err2 := strconv.ParseUint(global[dot+1:], 10, 16)
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("invalid extended community: %s", s)
+		}
+		as = high<<16 | low
+		isFourOctet = true
+	} else if as, err = strconv.ParseUint(global, 10, 32); err != nil {
+		return nil, fmt.Errorf("invalid extended community: %s", s)
+	}
+	if !isFourOctet && as <= math.MaxUint16 {
+		v, err := strconv.ParseUint(local, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid extended community: %s", s)
+		}
+		return &bgp.TwoOctetAsSpecificExtended{SubType: subType, AS: uint16(as), LocalAdmin: uint32(v)}, nil
+	}
+	v, err := strconv.ParseUint(local, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid extended community: %s", s)
+	}
+	return &bgp.FourOctetAsSpecificExtended{SubType: subType, AS: uint32(as), LocalAdmin: uint16(v)}, nil
+}
+
+// return the string representation of the extended community with the
+// type like "route-target:65000:100". the other extended communities
+// than route targets and route origins are represented without the
+// type.
+func ExtCommunityString(e bgp.ExtendedCommunityInterface) string {
+	var subType uint8
+	switch c := e.(type) {
+	case *bgp.TwoOctetAsSpecificExtended:
+		subType = c.SubType
+	case *bgp.IPv4AddressSpecificExtended:
+		subType = c.SubType
+	case *bgp.FourOctetAsSpecificExtended:
+		subType = c.SubType
+	default:
+		return e.String()
+	}
+	for name, t := range extCommunitySubTypes {
+		if t == subType {
+			return fmt.Sprintf("%s:%s", name, e.String())
+		}
+	}
+	return e.String()
+}
+
+// return true if the paths have the same prefix, source and path
+// attributes. the timestamps aren't compared.
+func EqualPath(a, b Path) bool {
+	return a.GetRouteFamily() == b.GetRouteFamily() && a.getPrefix() == b.getPrefix() &&
+		a.GetSource() == b.GetSource() && a.IsWithdraw() == b.IsWithdraw() &&
+		a.GetWeight() == b.GetWeight() && a.GetValidation() == b.GetValidation() &&
+		reflect.DeepEqual(a.getPathAttrs(), b.getPathAttrs())
+}
+
+// compare the extended communities in the wire format
+func EqualExtCommunity(a, b bgp.ExtendedCommunityInterface) bool {
+	bufA, errA := a.Serialize()
+	bufB, errB := b.Serialize()
+	return errA == nil && errB == nil && bytes.Equal(bufA, bufB)
+}
+
+// create a locally originated path from the network configuration.
+// locally originated paths don't have the source.
+func CreateNetworkPath(n config.Network, isWithdraw bool, now time.Time) (Path, error) {
+	origin := bgp.NewPathAttributeOrigin(uint8(n.Origin))
+	aspath := bgp.NewPathAttributeAsPath([]bgp.AsPathParamInterface{})
+	attrs := []bgp.PathAttributeInterface{origin, aspath}
+
+	var nlri bgp.AddrPrefixInterface
+	if addr := n.Address.To4(); addr != nil {
+		if n.Masklength > 32 {
+			return nil, fmt.Errorf("invalid masklength: %s/%d", n.Address, n.Masklength)
+		}
+		prefix := addr.Mask(net.CIDRMask(int(n.Masklength), 32)).String()
+		if isWithdraw {
+			nlri = &bgp.WithdrawnRoute{IPAddrPrefix: *bgp.NewIPAddrPrefix(n.Masklength, prefix)}
+		} else {
+			nlri = bgp.NewNLRInfo(n.Masklength, prefix)
+		}
+		nexthop := "0.0.0.0"
+		if n.NextHop != nil {
+			nexthop = n.NextHop.String()
+		}
+		attrs = append(attrs, bgp.NewPathAttributeNextHop(nexthop))
+	} else if n.Address.To16() != nil {
+		if n.Masklength > 128 {
+			return nil, fmt.Errorf("invalid masklength: %s/%d", n.Address, n.Masklength)
+		}
+		prefix := n.Address.Mask(net.CIDRMask(int(n.Masklength), 128)).String()
+		nlri = bgp.NewIPv6AddrPrefix(n.Masklength, prefix)
+		nexthop := "::"
+		if n.NextHop != nil {
+			nexthop = n.NextHop.String()
+		}
+		mpreach := bgp.NewPathAttributeMpReachNLRI(nexthop, []bgp.AddrPrefixInterface{nlri})
+		attrs = append([]bgp.PathAttributeInterface{mpreach}, attrs...)
+	} else {
+		return nil, fmt.Errorf("invalid network address: %s", n.Address)
+	}
+
+	if n.Med != 0 {
+		attrs = append(attrs, bgp.NewPathAttributeMultiExitDisc(n.Med))
+	}
+	if n.LocalPref != 0 {
+		attrs = append(attrs, bgp.NewPathAttributeLocalPref(n.LocalPref))
+	}
+	if len(n.CommunityList) > 0 {
+		communities := make([]uint32, 0, len(n.CommunityList))
+		for _, c := range n.CommunityList {
+			v, err := ParseCommunity(c)
+			if err != nil {
+				return nil, err
+			}
+			communities = append(communities, v)
+		}
+		attrs = append(attrs, bgp.NewPathAttributeCommunities(communities))
+	}
+	path := CreatePath(nil, nlri, attrs, isWithdraw, now)
+	path.SetIgpTag(string(n.Tag))
+	return path, nil
+}
+
+// create the path to test the policies with. the path is created from
+// the network configuration with the AS path, the extended communities
+// and the source neighbor.
+func CreateTestPath(source *PeerInfo, n config.Network, asPath []uint32, extCommunities []string, now time.Time) (Path, error) {
+	path, err := CreateNetworkPath(n, false, now)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(asPath) - 1; i >= 0; i-- {
+		path.PrependAsn(asPath[i], 1)
+	}
+	if len(extCommunities) > 0 {
+		communities := make([]bgp.ExtendedCommunityInterface, 0, len(extCommunities))
+		for _, s := range extCommunities {
+			c, err := ParseExtCommunity(s)
+			if err != nil {
+				return nil, err
+			}
+			communities = append(communities, c)
+		}
+		path.SetExtCommunities(communities, true)
+	}
+	return CreatePath(source, path.GetNlri(), path.getPathAttrs(), false, now), nil
+}
+
 /*
 * 	Definition of inherited Path  interface
  */
@@ -363,9 +986,13 @@ func NewIPv6Path(source *PeerInfo, nlri bgp.AddrPrefixInterface, isWithdraw bool
 	return ipv6Path
 }
 
-func (ipv6p *IPv6Path) clone(isWithdraw bool) Path {
+func (ipv6p *IPv6Path) Clone(isWithdraw bool) Path {
 	nlri := ipv6p.nlri
-	return CreatePath(ipv6p.source, nlri, ipv6p.pathAttrs, isWithdraw, ipv6p.PathDefault.timestamp)
+	path := CreatePath(ipv6p.source, nlri, ipv6p.pathAttrs, isWithdraw, ipv6p.PathDefault.timestamp)
+	path.SetWeight(ipv6p.weight)
+	path.SetIgpTag(ipv6p.igpTag)
+	path.SetValidation(ipv6p.validation)
+	return path
 }
 
 func (ipv6p *IPv6Path) setPathDefault(pd *PathDefault) {
@@ -381,6 +1008,10 @@ func (ipv6p *IPv6Path) getPrefix() string {
 	return addrPrefix.IPAddrPrefixDefault.String()
 }
 
+func (ipv6p *IPv6Path) GetPrefix() string {
+	return ipv6p.getPrefix()
+}
+
 // return IPv6Path's string representation
 func (ipv6p *IPv6Path) String() string {
 	str := fmt.Sprintf("IPv6Path Source: %v, ", ipv6p.GetSource())
@@ -393,15 +1024,17 @@ func (ipv6p *IPv6Path) String() string {
 
 func (ipv6p *IPv6Path) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
-		Network string
-		Nexthop string
-		Attrs   []bgp.PathAttributeInterface
-		Age     float64
+		Network    string
+		Nexthop    string
+		Attrs      []bgp.PathAttributeInterface
+		Age        float64
+		Validation string
 	}{
-		Network: ipv6p.getPrefix(),
-		Nexthop: ipv6p.PathDefault.nexthop.String(),
-		Attrs:   ipv6p.PathDefault.getPathAttrs(),
-		Age:     time.Now().Sub(ipv6p.PathDefault.timestamp).Seconds(),
+		Network:    ipv6p.getPrefix(),
+		Nexthop:    ipv6p.PathDefault.nexthop.String(),
+		Attrs:      ipv6p.PathDefault.getPathAttrs(),
+		Age:        time.Now().Sub(ipv6p.PathDefault.timestamp).Seconds(),
+		Validation: validationString(ipv6p.PathDefault.validation),
 	})
 }
 
@@ -419,9 +1052,13 @@ func NewIPv4VPNPath(source *PeerInfo, nlri bgp.AddrPrefixInterface, isWithdraw b
 	return ipv4VPNPath
 }
 
-func (ipv4vpnp *IPv4VPNPath) clone(isWithdraw bool) Path {
+func (ipv4vpnp *IPv4VPNPath) Clone(isWithdraw bool) Path {
 	nlri := ipv4vpnp.nlri
-	return CreatePath(ipv4vpnp.source, nlri, ipv4vpnp.pathAttrs, isWithdraw, ipv4vpnp.PathDefault.timestamp)
+	path := CreatePath(ipv4vpnp.source, nlri, ipv4vpnp.pathAttrs, isWithdraw, ipv4vpnp.PathDefault.timestamp)
+	path.SetWeight(ipv4vpnp.weight)
+	path.SetIgpTag(ipv4vpnp.igpTag)
+	path.SetValidation(ipv4vpnp.validation)
+	return path
 }
 
 func (ipv4vpnp *IPv4VPNPath) setPathDefault(pd *PathDefault) {
@@ -437,6 +1074,10 @@ func (ipv4vpnp *IPv4VPNPath) getPrefix() string {
 	return addrPrefix.IPAddrPrefixDefault.String()
 }
 
+func (ipv4vpnp *IPv4VPNPath) GetPrefix() string {
+	return ipv4vpnp.getPrefix()
+}
+
 // return IPv4VPNPath's string representation
 func (ipv4vpnp *IPv4VPNPath) String() string {
 	str := fmt.Sprintf("IPv4VPNPath Source: %v, ", ipv4vpnp.GetSource())
@@ -449,15 +1090,17 @@ func (ipv4vpnp *IPv4VPNPath) String() string {
 
 func (ipv4vpnp *IPv4VPNPath) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
-		Network string
-		Nexthop string
-		Attrs   []bgp.PathAttributeInterface
-		Age     float64
+		Network    string
+		Nexthop    string
+		Attrs      []bgp.PathAttributeInterface
+		Age        float64
+		Validation string
 	}{
-		Network: ipv4vpnp.getPrefix(),
-		Nexthop: ipv4vpnp.PathDefault.nexthop.String(),
-		Attrs:   ipv4vpnp.PathDefault.getPathAttrs(),
-		Age:     time.Now().Sub(ipv4vpnp.PathDefault.timestamp).Seconds(),
+		Network:    ipv4vpnp.getPrefix(),
+		Nexthop:    ipv4vpnp.PathDefault.nexthop.String(),
+		Attrs:      ipv4vpnp.PathDefault.getPathAttrs(),
+		Age:        time.Now().Sub(ipv4vpnp.PathDefault.timestamp).Seconds(),
+		Validation: validationString(ipv4vpnp.PathDefault.validation),
 	})
 }
 
@@ -476,9 +1119,13 @@ func NewEVPNPath(source *PeerInfo, nlri bgp.AddrPrefixInterface, isWithdraw bool
 	return EVPNPath
 }
 
-func (evpnp *EVPNPath) clone(isWithdraw bool) Path {
+func (evpnp *EVPNPath) Clone(isWithdraw bool) Path {
 	nlri := evpnp.nlri
-	return CreatePath(evpnp.source, nlri, evpnp.pathAttrs, isWithdraw, evpnp.PathDefault.timestamp)
+	path := CreatePath(evpnp.source, nlri, evpnp.pathAttrs, isWithdraw, evpnp.PathDefault.timestamp)
+	path.SetWeight(evpnp.weight)
+	path.SetIgpTag(evpnp.igpTag)
+	path.SetValidation(evpnp.validation)
+	return path
 }
 
 func (evpnp *EVPNPath) setPathDefault(pd *PathDefault) {
@@ -494,6 +1141,10 @@ func (evpnp *EVPNPath) getPrefix() string {
 	return addrPrefix.String()
 }
 
+func (evpnp *EVPNPath) GetPrefix() string {
+	return evpnp.getPrefix()
+}
+
 // return EVPNPath's string representation
 func (evpnp *EVPNPath) String() string {
 	str := fmt.Sprintf("EVPNPath Source: %v, ", evpnp.GetSource())
@@ -506,14 +1157,16 @@ func (evpnp *EVPNPath) String() string {
 
 func (evpnp *EVPNPath) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
-		Network string
-		Nexthop string
-		Attrs   []bgp.PathAttributeInterface
-		Age     float64
+		Network    string
+		Nexthop    string
+		Attrs      []bgp.PathAttributeInterface
+		Age        float64
+		Validation string
 	}{
-		Network: evpnp.getPrefix(),
-		Nexthop: evpnp.PathDefault.nexthop.String(),
-		Attrs:   evpnp.PathDefault.getPathAttrs(),
-		Age:     time.Now().Sub(evpnp.PathDefault.timestamp).Seconds(),
+		Network:    evpnp.getPrefix(),
+		Nexthop:    evpnp.PathDefault.nexthop.String(),
+		Attrs:      evpnp.PathDefault.getPathAttrs(),
+		Age:        time.Now().Sub(evpnp.PathDefault.timestamp).Seconds(),
+		Validation: validationString(evpnp.PathDefault.validation),
 	})
 }
@@ -3,6 +3,7 @@ package table
 
 import (
 	//"fmt"
+	"github.com/osrg/gobgp/config"
 	"github.com/osrg/gobgp/packet"
 	"github.com/stretchr/testify/assert"
 	"net"
@@ -166,6 +167,159 @@ func TestPathGetAttribute(t *testing.T) {
 	assert.Equal(t, r_nh, nh)
 }
 
+func TestPathCreateNetworkPath(t *testing.T) {
+	n := config.Network{
+		Address:       net.ParseIP("10.10.1.1"),
+		Masklength:    24,
+		Med:           10,
+		CommunityList: []string{"65000:100", "no-export"},
+	}
+	path, err := CreateNetworkPath(n, false, time.Now())
+	assert.Nil(t, err)
+	assert.Nil(t, path.GetSource())
+	assert.Equal(t, path.GetRouteFamily(), bgp.RF_IPv4_UC)
+	assert.Equal(t, path.getPrefix(), "10.10.1.0/24")
+	assert.Equal(t, path.GetNexthop().String(), "0.0.0.0")
+	_, attr := path.getPathAttr(bgp.BGP_ATTR_TYPE_MULTI_EXIT_DISC)
+	assert.Equal(t, attr.(*bgp.PathAttributeMultiExitDisc).Value, uint32(10))
+	_, attr = path.getPathAttr(bgp.BGP_ATTR_TYPE_COMMUNITIES)
+	assert.Equal(t, attr.(*bgp.PathAttributeCommunities).Value, []uint32{65000<<16 | 100, 0xffffff01})
+
+	withdraw, err := CreateNetworkPath(n, true, time.Now())
+	assert.Nil(t, err)
+	assert.True(t, withdraw.IsWithdraw())
+	assert.Equal(t, withdraw.getPrefix(), "10.10.1.0/24")
+
+	n6 := config.Network{
+		Address:    net.ParseIP("2001:db8::"),
+		Masklength: 32,
+	}
+	path, err = CreateNetworkPath(n6, false, time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, path.GetRouteFamily(), bgp.RF_IPv6_UC)
+	assert.Equal(t, path.getPrefix(), "2001:db8::/32")
+
+	n.Masklength = 33
+	_, err = CreateNetworkPath(n, false, time.Now())
+	assert.NotNil(t, err)
+
+	n.Masklength = 24
+	n.CommunityList = []string{"65000:xxx"}
+	_, err = CreateNetworkPath(n, false, time.Now())
+	assert.NotNil(t, err)
+}
+
+func TestPathParseExtCommunity(t *testing.T) {
+	for _, c := range []struct {
+		s     string
+		ec    bgp.ExtendedCommunityInterface
+		isErr bool
+	}{
+		{"route-target:65000:100", &bgp.TwoOctetAsSpecificExtended{SubType: 0x02, AS: 65000, LocalAdmin: 100}, false},
+		{"route-origin:10.0.0.1:100", &bgp.IPv4AddressSpecificExtended{SubType: 0x03, IPv4: net.ParseIP("10.0.0.1").To4(), LocalAdmin: 100}, false},
+		{"route-target:4200000000:100", &bgp.FourOctetAsSpecificExtended{SubType: 0x02, AS: 4200000000, LocalAdmin: 100}, false},
+		{"route-target:1.2:100", &bgp.FourOctetAsSpecificExtended{SubType: 0x02, AS: 1<<16 | 2, LocalAdmin: 100}, false},
+		{"65000:100", nil, true},
+		{"route-target:65000", nil, true},
+		{"route-target:4200000000:65536", nil, true},
+		{"encapsulation:65000:100", nil, true},
+	} {
+		ec, err := ParseExtCommunity(c.s)
+		if c.isErr {
+			assert.NotNil(t, err, c.s)
+			continue
+		}
+		assert.Nil(t, err, c.s)
+		assert.True(t, EqualExtCommunity(ec, c.ec), c.s)
+	}
+	ec, _ := ParseExtCommunity("route-origin:10.0.0.1:100")
+	assert.Equal(t, ExtCommunityString(ec), "route-origin:10.0.0.1:100")
+}
+
+func TestPathUpdatePathAttrsLocalPath(t *testing.T) {
+	n := config.Network{
+		Address:       net.ParseIP("10.10.1.0"),
+		Masklength:    24,
+		Med:           10,
+		CommunityList: []string{"65000:1"},
+	}
+	global := &config.Global{As: 65000}
+	path, _ := CreateNetworkPath(n, false, time.Now())
+
+	ebgp := &config.Neighbor{
+		PeerType:     config.PEER_TYPE_EXTERNAL,
+		LocalAddress: net.ParseIP("192.168.0.1"),
+	}
+	clone := path.Clone(false)
+	clone.updatePathAttrs(global, ebgp)
+	_, attr := clone.getPathAttr(bgp.BGP_ATTR_TYPE_NEXT_HOP)
+	assert.Equal(t, attr.(*bgp.PathAttributeNextHop).Value.String(), "192.168.0.1")
+	_, attr = clone.getPathAttr(bgp.BGP_ATTR_TYPE_AS_PATH)
+	assert.Equal(t, attr.(*bgp.PathAttributeAsPath).Value[0].(*bgp.As4PathParam).AS, []uint32{65000})
+	// the MED configured for the network is advertised to the external
+	// peers
+	assert.Equal(t, pathAttrTypes(clone), []bgp.BGPAttrType{
+		bgp.BGP_ATTR_TYPE_ORIGIN,
+		bgp.BGP_ATTR_TYPE_AS_PATH,
+		bgp.BGP_ATTR_TYPE_NEXT_HOP,
+		bgp.BGP_ATTR_TYPE_MULTI_EXIT_DISC,
+		bgp.BGP_ATTR_TYPE_COMMUNITIES,
+	})
+
+	ibgp := &config.Neighbor{
+		PeerType:     config.PEER_TYPE_INTERNAL,
+		LocalAddress: net.ParseIP("192.168.0.2"),
+	}
+	clone = path.Clone(false)
+	clone.updatePathAttrs(global, ibgp)
+	_, attr = clone.getPathAttr(bgp.BGP_ATTR_TYPE_NEXT_HOP)
+	assert.Equal(t, attr.(*bgp.PathAttributeNextHop).Value.String(), "192.168.0.2")
+	_, attr = clone.getPathAttr(bgp.BGP_ATTR_TYPE_LOCAL_PREF)
+	assert.Equal(t, attr.(*bgp.PathAttributeLocalPref).Value, uint32(100))
+
+	// the original path must not be modified
+	_, attr = path.getPathAttr(bgp.BGP_ATTR_TYPE_NEXT_HOP)
+	assert.Equal(t, attr.(*bgp.PathAttributeNextHop).Value.String(), "0.0.0.0")
+}
+
+func TestPathUpdatePathAttrsReceivedMed(t *testing.T) {
+	peer := &PeerInfo{AS: 65001, Address: net.ParseIP("10.0.0.1")}
+	attrs := []bgp.PathAttributeInterface{
+		bgp.NewPathAttributeOrigin(0),
+		bgp.NewPathAttributeAsPath([]bgp.AsPathParamInterface{bgp.NewAs4PathParam(2, []uint32{65001})}),
+		bgp.NewPathAttributeNextHop("10.0.0.1"),
+		bgp.NewPathAttributeMultiExitDisc(10),
+		bgp.NewPathAttributeCommunities([]uint32{65000<<16 | 1}),
+	}
+	path := CreatePath(peer, bgp.NewNLRInfo(24, "10.10.1.0"), attrs, false, time.Now())
+
+	ebgp := &config.Neighbor{
+		PeerType:     config.PEER_TYPE_EXTERNAL,
+		LocalAddress: net.ParseIP("192.168.0.1"),
+	}
+	clone := path.Clone(false)
+	clone.updatePathAttrs(&config.Global{As: 65000}, ebgp)
+	// the MED received from a neighbor isn't sent to another AS
+	assert.Equal(t, pathAttrTypes(clone), []bgp.BGPAttrType{
+		bgp.BGP_ATTR_TYPE_ORIGIN,
+		bgp.BGP_ATTR_TYPE_AS_PATH,
+		bgp.BGP_ATTR_TYPE_NEXT_HOP,
+		bgp.BGP_ATTR_TYPE_COMMUNITIES,
+	})
+	// the original path must not be modified
+	assert.Equal(t, len(path.getPathAttrs()), 5)
+}
+
+func pathAttrTypes(path Path) []bgp.BGPAttrType {
+	types := make([]bgp.BGPAttrType, 0)
+	for _, a := range path.getPathAttrs() {
+		// the type code follows the flags
+		buf, _ := a.Serialize()
+		types = append(types, bgp.BGPAttrType(buf[1]))
+	}
+	return types
+}
+
 func PathCreatePeer() []*PeerInfo {
 	peerP1 := &PeerInfo{AS: 65000}
 	peerP2 := &PeerInfo{AS: 65001}
@@ -253,3 +407,67 @@ func updateMsgP3() *bgp.BGPMessage {
 	withdrawnRoutes := []bgp.WithdrawnRoute{w1}
 	return bgp.NewBGPUpdateMessage(withdrawnRoutes, pathAttributes, nlri)
 }
+
+func TestPathUpdateInPathAttrs(t *testing.T) {
+	peerP := PathCreatePeer()
+	msg := updateMsgP1()
+	UpdatePathAttrs4ByteAs(msg.Body.(*bgp.BGPUpdate))
+	pathList := NewProcessMessage(msg, peerP[0]).ToPathList()
+	peer := &config.Neighbor{
+		PeerType:         config.PEER_TYPE_EXTERNAL,
+		Weight:           10,
+		DefaultLocalPref: 50,
+	}
+	UpdateInPathAttrs(pathList, peer)
+	path := pathList[0]
+	assert.Equal(t, path.GetWeight(), uint32(10))
+	_, attr := path.getPathAttr(bgp.BGP_ATTR_TYPE_LOCAL_PREF)
+	assert.Equal(t, attr.(*bgp.PathAttributeLocalPref).Value, uint32(50))
+	// the message must not be modified
+	assert.Equal(t, len(msg.Body.(*bgp.BGPUpdate).PathAttributes), 4)
+	assert.Equal(t, path.Clone(false).GetWeight(), uint32(10))
+
+	// the local-pref is kept for iBGP and removed for eBGP
+	global := &config.Global{As: 65000}
+	clone := path.Clone(false)
+	clone.updatePathAttrs(global, &config.Neighbor{PeerType: config.PEER_TYPE_INTERNAL})
+	_, attr = clone.getPathAttr(bgp.BGP_ATTR_TYPE_LOCAL_PREF)
+	assert.Equal(t, attr.(*bgp.PathAttributeLocalPref).Value, uint32(50))
+	clone = path.Clone(false)
+	clone.updatePathAttrs(global, &config.Neighbor{PeerType: config.PEER_TYPE_EXTERNAL, LocalAddress: net.ParseIP("192.168.0.1")})
+	idx, _ := clone.getPathAttr(bgp.BGP_ATTR_TYPE_LOCAL_PREF)
+	assert.Equal(t, idx, -1)
+
+	// the local-pref received from an external peer is replaced with
+	// the default one
+	msg = updateMsgP1()
+	body := msg.Body.(*bgp.BGPUpdate)
+	body.PathAttributes = append(body.PathAttributes, bgp.NewPathAttributeLocalPref(200))
+	pathList = NewProcessMessage(msg, peerP[0]).ToPathList()
+	UpdateInPathAttrs(pathList, peer)
+	localPref, _ := pathList[0].GetLocalPref()
+	assert.Equal(t, localPref, uint32(50))
+	assert.Equal(t, len(pathList[0].getPathAttrs()), 5)
+	pathList = NewProcessMessage(msg, peerP[0]).ToPathList()
+	UpdateInPathAttrs(pathList, &config.Neighbor{PeerType: config.PEER_TYPE_INTERNAL, DefaultLocalPref: 50})
+	localPref, _ = pathList[0].GetLocalPref()
+	assert.Equal(t, localPref, uint32(200))
+}
+
+func TestPathGetAsString(t *testing.T) {
+	aspath := bgp.NewPathAttributeAsPath([]bgp.AsPathParamInterface{
+		bgp.NewAs4PathParam(bgp.BGP_ASPATH_ATTR_TYPE_SEQ, []uint32{65001, 65002}),
+		bgp.NewAs4PathParam(bgp.BGP_ASPATH_ATTR_TYPE_SET, []uint32{65003, 65004}),
+		bgp.NewAs4PathParam(bgp.BGP_ASPATH_ATTR_TYPE_SEQ, []uint32{65005}),
+	})
+	attrs := []bgp.PathAttributeInterface{bgp.NewPathAttributeOrigin(0), aspath, bgp.NewPathAttributeNextHop("10.0.0.1")}
+	path := CreatePath(PathCreatePeer()[0], bgp.NewNLRInfo(24, "10.10.10.0"), attrs, false, time.Now())
+	assert.Equal(t, path.GetAsString(), "65001 65002 {65003,65004} 65005")
+	assert.Equal(t, path.GetAsPathLen(), 4)
+
+	aspath = bgp.NewPathAttributeAsPath([]bgp.AsPathParamInterface{})
+	attrs = []bgp.PathAttributeInterface{bgp.NewPathAttributeOrigin(0), aspath, bgp.NewPathAttributeNextHop("10.0.0.1")}
+	path = CreatePath(PathCreatePeer()[0], bgp.NewNLRInfo(24, "10.10.10.0"), attrs, false, time.Now())
+	assert.Equal(t, path.GetAsString(), "")
+	assert.Equal(t, path.GetAsPathLen(), 0)
+}
@@ -34,16 +34,21 @@ type Table interface {
 	setDestinations(destinations map[string]Destination)
 	getDestination(key string) Destination
 	setDestination(key string, dest Destination)
+	deleteDestination(key string)
+	getIndex() *ribIndex
 	tableKey(nlri bgp.AddrPrefixInterface) string
 	validatePath(path Path)
 	validateNlri(nlri bgp.AddrPrefixInterface)
 	DeleteDestByPeer(*PeerInfo) []Destination
+	GetSortedDestinations() []Destination
 	MarshalJSON() ([]byte, error)
 }
 
 type TableDefault struct {
 	ROUTE_FAMILY bgp.RouteFamily
 	destinations map[string]Destination
+	// the prefixes of the destinations for the lookups
+	index *ribIndex
 	//need SignalBus
 }
 
@@ -65,10 +70,31 @@ func cidr2prefix(cidr string) patricia.Prefix {
 	return patricia.Prefix(buffer.String()[:ones])
 }
 
-func (td *TableDefault) MarshalJSON() ([]byte, error) {
+// the key of the prefix in the patricia trie, the bits of the address
+// in '0' and '1' up to the mask length
+func PrefixKey(addr net.IP, masklen uint8) patricia.Prefix {
+	if a := addr.To4(); a != nil {
+		addr = a
+	}
+	if int(masklen) > len(addr)*8 {
+		masklen = uint8(len(addr) * 8)
+	}
+	key := make(patricia.Prefix, masklen)
+	for i := range key {
+		if addr[i/8]&(0x80>>uint(i%8)) != 0 {
+			key[i] = '1'
+		} else {
+			key[i] = '0'
+		}
+	}
+	return key
+}
+
+// the destinations in the order of the prefixes parsed from the keys
+func sortDestinations(destinations map[string]Destination, parse func(string) patricia.Prefix) []Destination {
 	trie := patricia.NewTrie()
-	for key, dest := range td.destinations {
-		trie.Insert(cidr2prefix(key), dest)
+	for key, dest := range destinations {
+		trie.Insert(parse(key), dest)
 	}
 
 	destList := make([]Destination, 0)
@@ -77,11 +103,18 @@ func (td *TableDefault) MarshalJSON() ([]byte, error) {
 		destList = append(destList, dest)
 		return nil
 	})
+	return destList
+}
 
+func (td *TableDefault) GetSortedDestinations() []Destination {
+	return sortDestinations(td.destinations, cidr2prefix)
+}
+
+func (td *TableDefault) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
 		Destinations []Destination
 	}{
-		Destinations: destList,
+		Destinations: td.GetSortedDestinations(),
 	})
 }
 
@@ -153,14 +186,13 @@ func deleteDestByNlri(table Table, nlri bgp.AddrPrefixInterface) Destination {
 	destinations := table.getDestinations()
 	dest := destinations[table.tableKey(nlri)]
 	if dest != nil {
-		delete(destinations, table.tableKey(nlri))
+		table.deleteDestination(table.tableKey(nlri))
 	}
 	return dest
 }
 
 func deleteDest(table Table, dest Destination) {
-	destinations := table.getDestinations()
-	delete(destinations, table.tableKey(dest.getNlri()))
+	table.deleteDestination(table.tableKey(dest.getNlri()))
 }
 
 func (td *TableDefault) validatePath(path Path) {
@@ -232,6 +264,7 @@ func (td *TableDefault) getDestinations() map[string]Destination {
 }
 func (td *TableDefault) setDestinations(destinations map[string]Destination) {
 	td.destinations = destinations
+	td.index = nil
 }
 func (td *TableDefault) getDestination(key string) Destination {
 	dest, ok := td.destinations[key]
@@ -243,9 +276,31 @@ func (td *TableDefault) getDestination(key string) Destination {
 }
 
 func (td *TableDefault) setDestination(key string, dest Destination) {
+	if _, ok := td.destinations[key]; !ok {
+		td.getIndex().add(key)
+	}
 	td.destinations[key] = dest
 }
 
+func (td *TableDefault) deleteDestination(key string) {
+	if _, ok := td.destinations[key]; ok {
+		td.getIndex().remove(key)
+		delete(td.destinations, key)
+	}
+}
+
+// the index of the destinations, built from the destinations at the
+// first use and updated with them
+func (td *TableDefault) getIndex() *ribIndex {
+	if td.index == nil {
+		td.index = newRibIndex(td.ROUTE_FAMILY)
+		for key := range td.destinations {
+			td.index.add(key)
+		}
+	}
+	return td.index
+}
+
 //Implements interface
 func (td *TableDefault) tableKey(nlri bgp.AddrPrefixInterface) string {
 	//need Inheritance over ride
@@ -360,26 +415,16 @@ func ParseLabbelledVpnPrefix(key string) patricia.Prefix {
 
 }
 
-func (ipv4vpnt *IPv4VPNTable) MarshalJSON() ([]byte, error) {
-
-	trie := patricia.NewTrie()
-	for key, dest := range ipv4vpnt.destinations {
-		trie.Insert(ParseLabbelledVpnPrefix(key), dest)
-	}
-
-	destList := make([]Destination, 0)
-	trie.Visit(func(prefix patricia.Prefix, item patricia.Item) error {
-		dest, _ := item.(Destination)
-		destList = append(destList, dest)
-		return nil
-	})
+func (ipv4vpnt *IPv4VPNTable) GetSortedDestinations() []Destination {
+	return sortDestinations(ipv4vpnt.destinations, ParseLabbelledVpnPrefix)
+}
 
+func (ipv4vpnt *IPv4VPNTable) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
 		Destinations []Destination
 	}{
-		Destinations: destList,
+		Destinations: ipv4vpnt.GetSortedDestinations(),
 	})
-
 }
 
 type EVPNTable struct {
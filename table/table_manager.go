@@ -16,7 +16,9 @@
 package table
 
 import (
+	"encoding/json"
 	log "github.com/Sirupsen/logrus"
+	"github.com/osrg/gobgp/config"
 	"github.com/osrg/gobgp/packet"
 	"github.com/tchap/go-patricia/patricia"
 	"reflect"
@@ -123,9 +125,21 @@ func (p *ProcessMessage) ToPathList() []Path {
 }
 
 type TableManager struct {
-	Tables   map[bgp.RouteFamily]Table
-	localAsn uint32
-	owner    string
+	Tables     map[bgp.RouteFamily]Table
+	localAsn   uint32
+	owner      string
+	aggregates map[bgp.RouteFamily][]*aggregate
+	resolver   NexthopResolver
+	selection  map[bgp.RouteFamily]config.RouteSelectionOptions
+	watcher    func([]*BestPathChange)
+}
+
+// the change of the best path of a destination. Old is nil if the
+// destination didn't have the best path and New is nil if it doesn't
+// have one any more.
+type BestPathChange struct {
+	Old Path
+	New Path
 }
 
 func NewTableManager(owner string, rfList []bgp.RouteFamily) *TableManager {
@@ -146,11 +160,77 @@ func NewTableManager(owner string, rfList []bgp.RouteFamily) *TableManager {
 		}
 	}
 	t.owner = owner
+	t.aggregates = make(map[bgp.RouteFamily][]*aggregate)
+	t.selection = make(map[bgp.RouteFamily]config.RouteSelectionOptions)
 	return t
 }
 
+func (manager *TableManager) getDestinations() []Destination {
+	destinationList := make([]Destination, 0)
+	for _, t := range manager.Tables {
+		for _, dest := range t.getDestinations() {
+			destinationList = append(destinationList, dest)
+		}
+	}
+	return destinationList
+}
+
+// configure the local AS and the route selection options of each
+// address family, and return the best path changes.
+func (manager *TableManager) SetRouteSelection(g *config.Global) []Path {
+	selection := make(map[bgp.RouteFamily]config.RouteSelectionOptions)
+	for _, a := range g.AfiSafiList {
+		rf, err := bgp.GetRouteFamily(a.AfiSafiName)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"Topic": "table",
+				"Owner": manager.owner,
+				"Key":   a.AfiSafiName,
+			}).Error("unknown address family")
+			continue
+		}
+		o := a.RouteSelectionOptions
+		// the best paths aren't installed into the FIB, so there are
+		// no inactive routes, and the AIGP attribute isn't supported.
+		if o.AdvertiseInactiveRoutes || o.EnableAigp {
+			log.WithFields(log.Fields{
+				"Topic": "table",
+				"Owner": manager.owner,
+				"Key":   a.AfiSafiName,
+			}).Warn("advertise-inactive-routes and enable-aigp aren't supported")
+		}
+		selection[rf] = o
+	}
+	if manager.localAsn == g.As && reflect.DeepEqual(manager.selection, selection) {
+		return []Path{}
+	}
+	log.WithFields(log.Fields{
+		"Topic": "table",
+		"Owner": manager.owner,
+	}).Info("route selection options configured")
+	manager.localAsn = g.As
+	manager.selection = selection
+	paths, _ := manager.calculate(manager.getDestinations())
+	return manager.processAggregates(paths)
+}
+
+// the watcher is called with the best path changes every time the
+// best paths are calculated.
+func (manager *TableManager) SetBestPathWatcher(watcher func([]*BestPathChange)) {
+	manager.watcher = watcher
+}
+
+func (manager *TableManager) selectionOptions(rf bgp.RouteFamily) *SelectionOptions {
+	return &SelectionOptions{
+		RouteSelectionOptions: manager.selection[rf],
+		LocalAsn:              manager.localAsn,
+		Resolver:              manager.resolver,
+	}
+}
+
 func (manager *TableManager) calculate(destinationList []Destination) ([]Path, error) {
 	newPaths := make([]Path, 0)
+	changes := make([]*BestPathChange, 0)
 
 	for _, destination := range destinationList {
 		// compute best path
@@ -161,7 +241,7 @@ func (manager *TableManager) calculate(destinationList []Destination) ([]Path, e
 			"Key":   destination.getNlri().String(),
 		}).Info("Processing destination")
 
-		newBestPath, reason, err := destination.Calculate(manager.localAsn)
+		newBestPath, reason, err := destination.Calculate(manager.selectionOptions(destination.getRouteFamily()))
 
 		if err != nil {
 			log.Error(err)
@@ -177,7 +257,7 @@ func (manager *TableManager) calculate(destinationList []Destination) ([]Path, e
 				"Topic":    "table",
 				"Owner":    manager.owner,
 				"Key":      destination.getNlri().String(),
-				"peer":     newBestPath.GetSource().Address,
+				"peer":     newBestPath.GetSource().getAddress(),
 				"next_hop": newBestPath.GetNexthop().String(),
 				"reason":   reason,
 			}).Debug("best path is not changed")
@@ -191,41 +271,36 @@ func (manager *TableManager) calculate(destinationList []Destination) ([]Path, e
 				"Key":   destination.getNlri().String(),
 			}).Debug("best path is nil")
 
-			if len(destination.getKnownPathList()) == 0 {
+			// the known paths might remain when none of them
+			// has a reachable nexthop
+			if currentBestPath != nil {
 				// create withdraw path
-				if currentBestPath != nil {
-					log.WithFields(log.Fields{
-						"Topic":    "table",
-						"Owner":    manager.owner,
-						"Key":      destination.getNlri().String(),
-						"peer":     currentBestPath.GetSource().Address,
-						"next_hop": currentBestPath.GetNexthop().String(),
-					}).Debug("best path is lost")
-
-					p := destination.getBestPath()
-					destination.setOldBestPath(p)
-					newPaths = append(newPaths, p.clone(true))
-				}
-				destination.setBestPath(nil)
-			} else {
-
 				log.WithFields(log.Fields{
-					"Topic": "table",
-					"Owner": manager.owner,
-					"Key":   destination.getNlri().String(),
-				}).Error("known path list is not empty")
+					"Topic":    "table",
+					"Owner":    manager.owner,
+					"Key":      destination.getNlri().String(),
+					"peer":     currentBestPath.GetSource().getAddress(),
+					"next_hop": currentBestPath.GetNexthop().String(),
+					"reason":   reason,
+				}).Debug("best path is lost")
+
+				destination.setOldBestPath(currentBestPath)
+				newPaths = append(newPaths, currentBestPath.Clone(true))
+				changes = append(changes, &BestPathChange{Old: currentBestPath})
 			}
+			destination.setBestPath(nil)
 		} else {
 			log.WithFields(log.Fields{
 				"Topic":    "table",
 				"Owner":    manager.owner,
 				"Key":      newBestPath.GetNlri().String(),
-				"peer":     newBestPath.GetSource().Address,
+				"peer":     newBestPath.GetSource().getAddress(),
 				"next_hop": newBestPath.GetNexthop(),
 				"reason":   reason,
 			}).Debug("new best path")
 
 			newPaths = append(newPaths, newBestPath)
+			changes = append(changes, &BestPathChange{Old: currentBestPath, New: newBestPath})
 			destination.setBestPath(newBestPath)
 		}
 
@@ -241,13 +316,17 @@ func (manager *TableManager) calculate(destinationList []Destination) ([]Path, e
 			}).Debug("destination removed")
 		}
 	}
+	if manager.watcher != nil && len(changes) > 0 {
+		manager.watcher(changes)
+	}
 	return newPaths, nil
 }
 
 func (manager *TableManager) DeletePathsforPeer(peerInfo *PeerInfo, rf bgp.RouteFamily) ([]Path, error) {
 	if _, ok := manager.Tables[rf]; ok {
 		destinationList := manager.Tables[rf].DeleteDestByPeer(peerInfo)
-		return manager.calculate(destinationList)
+		paths, err := manager.calculate(destinationList)
+		return manager.processAggregates(paths), err
 	}
 	return []Path{}, nil
 }
@@ -261,7 +340,8 @@ func (manager *TableManager) ProcessPaths(pathList []Path) ([]Path, error) {
 			destinationList = append(destinationList, destination)
 		}
 	}
-	return manager.calculate(destinationList)
+	paths, err := manager.calculate(destinationList)
+	return manager.processAggregates(paths), err
 }
 
 func (manager *TableManager) GetPathList(rf bgp.RouteFamily) []Path {
@@ -270,7 +350,26 @@ func (manager *TableManager) GetPathList(rf bgp.RouteFamily) []Path {
 	}
 	var paths []Path
 	for _, dest := range manager.Tables[rf].getDestinations() {
-		paths = append(paths, dest.getBestPath())
+		if path := dest.getBestPath(); path != nil && !manager.isSuppressed(path) {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// return the paths from the source in the RIB including the ones not
+// selected as the best
+func (manager *TableManager) GetPathListBySource(source *PeerInfo, rf bgp.RouteFamily) []Path {
+	paths := make([]Path, 0)
+	if _, ok := manager.Tables[rf]; !ok {
+		return paths
+	}
+	for _, dest := range manager.Tables[rf].getDestinations() {
+		for _, path := range dest.getKnownPathList() {
+			if path.GetSource() == source {
+				paths = append(paths, path)
+			}
+		}
 	}
 	return paths
 }
@@ -300,21 +399,28 @@ func (manager *TableManager) ProcessUpdate(fromPeer *PeerInfo, message *bgp.BGPM
 type AdjRib struct {
 	adjRibIn  map[bgp.RouteFamily]map[string]*ReceivedRoute
 	adjRibOut map[bgp.RouteFamily]map[string]*ReceivedRoute
+	// the prefixes in adj-rib-in and adj-rib-out for the lookups
+	inIndex  map[bgp.RouteFamily]*ribIndex
+	outIndex map[bgp.RouteFamily]*ribIndex
 }
 
 func NewAdjRib(rfList []bgp.RouteFamily) *AdjRib {
 	r := &AdjRib{
 		adjRibIn:  make(map[bgp.RouteFamily]map[string]*ReceivedRoute),
 		adjRibOut: make(map[bgp.RouteFamily]map[string]*ReceivedRoute),
+		inIndex:   make(map[bgp.RouteFamily]*ribIndex),
+		outIndex:  make(map[bgp.RouteFamily]*ribIndex),
 	}
 	for _, rf := range rfList {
 		r.adjRibIn[rf] = make(map[string]*ReceivedRoute)
 		r.adjRibOut[rf] = make(map[string]*ReceivedRoute)
+		r.inIndex[rf] = newRibIndex(rf)
+		r.outIndex[rf] = newRibIndex(rf)
 	}
 	return r
 }
 
-func (adj *AdjRib) update(rib map[bgp.RouteFamily]map[string]*ReceivedRoute, pathList []Path) {
+func (adj *AdjRib) update(rib map[bgp.RouteFamily]map[string]*ReceivedRoute, index map[bgp.RouteFamily]*ribIndex, pathList []Path) {
 	for _, path := range pathList {
 		rf := path.GetRouteFamily()
 		key := path.getPrefix()
@@ -322,22 +428,26 @@ func (adj *AdjRib) update(rib map[bgp.RouteFamily]map[string]*ReceivedRoute, pat
 		if path.IsWithdraw() {
 			if found {
 				delete(rib[rf], key)
+				index[rf].remove(key)
 			}
 		} else {
 			if found && reflect.DeepEqual(old.path.getPathAttrs(), path.getPathAttrs()) {
 				path.setTimestamp(old.path.getTimestamp())
 			}
+			if !found {
+				index[rf].add(key)
+			}
 			rib[rf][key] = NewReceivedRoute(path, false)
 		}
 	}
 }
 
 func (adj *AdjRib) UpdateIn(pathList []Path) {
-	adj.update(adj.adjRibIn, pathList)
+	adj.update(adj.adjRibIn, adj.inIndex, pathList)
 }
 
 func (adj *AdjRib) UpdateOut(pathList []Path) {
-	adj.update(adj.adjRibOut, pathList)
+	adj.update(adj.adjRibOut, adj.outIndex, pathList)
 }
 
 func (adj *AdjRib) getPathList(rib map[string]*ReceivedRoute) []Path {
@@ -370,6 +480,50 @@ func (adj *AdjRib) GetOutPathList(rf bgp.RouteFamily) []Path {
 	return adj.getPathList(adj.adjRibOut[rf])
 }
 
+// mark the path in adj-rib-in as filtered by the policies, or clear the
+// mark. the names tell the policy and the statement rejecting the path,
+// and are empty for the default policy.
+func (adj *AdjRib) SetInFiltered(path Path, filtered bool, policyName, statementName string) {
+	rr, ok := adj.adjRibIn[path.GetRouteFamily()][path.getPrefix()]
+	if !ok || rr.path != path {
+		// replaced or withdrawn in the meantime
+		return
+	}
+	rr.filtered = filtered
+	rr.policyName = ""
+	rr.statementName = ""
+	if filtered {
+		rr.policyName = policyName
+		rr.statementName = statementName
+	}
+}
+
+func (adj *AdjRib) GetInFilteredList(rf bgp.RouteFamily) []*ReceivedRoute {
+	trie := patricia.NewTrie()
+	for _, rr := range adj.adjRibIn[rf] {
+		if rr.filtered {
+			trie.Insert(cidr2prefix(rr.path.GetNlri().String()), rr)
+		}
+	}
+
+	rrList := []*ReceivedRoute{}
+	trie.Visit(func(prefix patricia.Prefix, item patricia.Item) error {
+		rrList = append(rrList, item.(*ReceivedRoute))
+		return nil
+	})
+	return rrList
+}
+
+func (adj *AdjRib) GetInFilteredCount(rf bgp.RouteFamily) int {
+	count := 0
+	for _, rr := range adj.adjRibIn[rf] {
+		if rr.filtered {
+			count++
+		}
+	}
+	return count
+}
+
 func (adj *AdjRib) GetInCount(rf bgp.RouteFamily) int {
 	if _, ok := adj.adjRibIn[rf]; !ok {
 		return 0
@@ -388,12 +542,28 @@ func (adj *AdjRib) DropAllIn(rf bgp.RouteFamily) {
 	if _, ok := adj.adjRibIn[rf]; ok {
 		// replace old one
 		adj.adjRibIn[rf] = make(map[string]*ReceivedRoute)
+		adj.inIndex[rf] = newRibIndex(rf)
 	}
 }
 
 type ReceivedRoute struct {
 	path     Path
 	filtered bool
+	// the policy and the statement filtering the path
+	policyName    string
+	statementName string
+}
+
+func (rr *ReceivedRoute) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Path      Path
+		Policy    string
+		Statement string
+	}{
+		Path:      rr.path,
+		Policy:    rr.policyName,
+		Statement: rr.statementName,
+	})
 }
 
 func (rr *ReceivedRoute) String() string {
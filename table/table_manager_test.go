@@ -18,6 +18,7 @@ package table
 import (
 	_ "fmt"
 	log "github.com/Sirupsen/logrus"
+	"github.com/osrg/gobgp/config"
 	"github.com/osrg/gobgp/packet"
 	"github.com/stretchr/testify/assert"
 	"net"
@@ -808,6 +809,9 @@ func TestProcessBGPUpdate_4_select_low_origin_ipv6(t *testing.T) {
 func TestProcessBGPUpdate_5_select_low_med_ipv4(t *testing.T) {
 
 	tm := NewTableManager("TestProcessBGPUpdate_5_select_low_med_ipv4", []bgp.RouteFamily{bgp.RF_IPv4_UC})
+	// the paths are from the different neighbor AS, and MED is compared
+	// only within the same neighbor AS by default
+	tm.selection[bgp.RF_IPv4_UC] = config.RouteSelectionOptions{AlwaysCompareMed: true}
 	var err error
 
 	// low origin message
@@ -892,6 +896,9 @@ func TestProcessBGPUpdate_5_select_low_med_ipv4(t *testing.T) {
 func TestProcessBGPUpdate_5_select_low_med_ipv6(t *testing.T) {
 
 	tm := NewTableManager("TestProcessBGPUpdate_5_select_low_med_ipv6", []bgp.RouteFamily{bgp.RF_IPv6_UC})
+	// the paths are from the different neighbor AS, and MED is compared
+	// only within the same neighbor AS by default
+	tm.selection[bgp.RF_IPv6_UC] = config.RouteSelectionOptions{AlwaysCompareMed: true}
 	var err error
 
 	origin1 := bgp.NewPathAttributeOrigin(0)
@@ -2395,3 +2402,29 @@ func update_fromR2viaR1_ipv6() *bgp.BGPMessage {
 	return bgp.NewBGPUpdateMessage(withdrawnRoutes, pathAttributes, nlri)
 
 }
+
+func TestBestPathWatcher(t *testing.T) {
+	assert := assert.New(t)
+	tm := NewTableManager("TestBestPathWatcher", []bgp.RouteFamily{bgp.RF_IPv4_UC})
+	changes := make([]*BestPathChange, 0)
+	tm.SetBestPathWatcher(func(c []*BestPathChange) {
+		changes = append(changes, c...)
+	})
+
+	peer := peerR1()
+	pList, _ := tm.ProcessUpdate(peer, update_fromR1())
+	assert.Equal(len(changes), 1)
+	assert.Nil(changes[0].Old)
+	assert.Equal(changes[0].New, pList[0])
+
+	// replaced by the path from the same peer
+	newList, _ := tm.ProcessUpdate(peer, update_fromR1())
+	assert.Equal(len(changes), 2)
+	assert.Equal(changes[1].Old, pList[0])
+	assert.Equal(changes[1].New, newList[0])
+
+	tm.ProcessPaths([]Path{newList[0].Clone(true)})
+	assert.Equal(len(changes), 3)
+	assert.Equal(changes[2].Old, newList[0])
+	assert.Nil(changes[2].New)
+}
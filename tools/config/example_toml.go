@@ -13,6 +13,19 @@ func main() {
 		Global: config.Global{
 			As:       12332,
 			RouterId: net.ParseIP("10.0.0.1"),
+			AfiSafiList: []config.AfiSafi{
+				config.AfiSafi{
+					AfiSafiName: "ipv4-unicast",
+					NetworkList: []config.Network{
+						config.Network{
+							Address:       net.ParseIP("10.1.0.0"),
+							Masklength:    16,
+							CommunityList: []string{"12332:100"},
+						},
+					},
+				},
+				config.AfiSafi{AfiSafiName: "ipv6-unicast"},
+			},
 		},
 		NeighborList: []config.Neighbor{
 			config.Neighbor{
@@ -0,0 +1,382 @@
+// Copyright (C) 2015 Nippon Telegraph and Telephone Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zebra
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	HEADER_SIZE   = 6
+	HEADER_MARKER = 255
+	VERSION       = 2
+)
+
+type API_TYPE uint16
+
+const (
+	_ API_TYPE = iota
+	INTERFACE_ADD
+	INTERFACE_DELETE
+	INTERFACE_ADDRESS_ADD
+	INTERFACE_ADDRESS_DELETE
+	INTERFACE_UP
+	INTERFACE_DOWN
+	IPV4_ROUTE_ADD
+	IPV4_ROUTE_DELETE
+	IPV6_ROUTE_ADD
+	IPV6_ROUTE_DELETE
+	REDISTRIBUTE_ADD
+	REDISTRIBUTE_DELETE
+	REDISTRIBUTE_DEFAULT_ADD
+	REDISTRIBUTE_DEFAULT_DELETE
+	IPV4_NEXTHOP_LOOKUP
+	IPV6_NEXTHOP_LOOKUP
+	IPV4_IMPORT_LOOKUP
+	IPV6_IMPORT_LOOKUP
+	INTERFACE_RENAME
+	ROUTER_ID_ADD
+	ROUTER_ID_DELETE
+	ROUTER_ID_UPDATE
+	HELLO
+)
+
+type ROUTE_TYPE uint8
+
+const (
+	ROUTE_SYSTEM ROUTE_TYPE = iota
+	ROUTE_KERNEL
+	ROUTE_CONNECT
+	ROUTE_STATIC
+	ROUTE_RIP
+	ROUTE_RIPNG
+	ROUTE_OSPF
+	ROUTE_OSPF6
+	ROUTE_ISIS
+	ROUTE_BGP
+	ROUTE_HSLS
+	ROUTE_OLSR
+	ROUTE_BABEL
+	ROUTE_MAX
+)
+
+type NEXTHOP_FLAG uint8
+
+const (
+	_ NEXTHOP_FLAG = iota
+	NEXTHOP_IFINDEX
+	NEXTHOP_IFNAME
+	NEXTHOP_IPV4
+	NEXTHOP_IPV4_IFINDEX
+	NEXTHOP_IPV4_IFNAME
+	NEXTHOP_IPV6
+	NEXTHOP_IPV6_IFINDEX
+	NEXTHOP_IPV6_IFNAME
+	NEXTHOP_BLACKHOLE
+)
+
+type Header struct {
+	Len     uint16
+	Marker  uint8
+	Version uint8
+	Command API_TYPE
+}
+
+func (h *Header) Serialize() ([]byte, error) {
+	buf := make([]byte, HEADER_SIZE)
+	binary.BigEndian.PutUint16(buf[0:2], h.Len)
+	buf[2] = h.Marker
+	buf[3] = h.Version
+	binary.BigEndian.PutUint16(buf[4:6], uint16(h.Command))
+	return buf, nil
+}
+
+func (h *Header) DecodeFromBytes(data []byte) error {
+	if len(data) < HEADER_SIZE {
+		return fmt.Errorf("Not all ZAPI message header")
+	}
+	h.Len = binary.BigEndian.Uint16(data[0:2])
+	h.Marker = data[2]
+	h.Version = data[3]
+	h.Command = API_TYPE(binary.BigEndian.Uint16(data[4:6]))
+	if h.Marker != HEADER_MARKER || h.Version != VERSION {
+		return fmt.Errorf("unsupported ZAPI header, marker: %d, version: %d", h.Marker, h.Version)
+	}
+	if h.Len < HEADER_SIZE {
+		return fmt.Errorf("invalid ZAPI message length: %d", h.Len)
+	}
+	return nil
+}
+
+type Nexthop struct {
+	Type    NEXTHOP_FLAG
+	Addr    net.IP
+	Ifindex uint32
+}
+
+// the body of IPV4_NEXTHOP_LOOKUP and IPV6_NEXTHOP_LOOKUP. the request
+// has only the address.
+type NexthopLookupBody struct {
+	Addr     net.IP
+	Metric   uint32
+	Nexthops []Nexthop
+}
+
+func (b *NexthopLookupBody) Serialize() ([]byte, error) {
+	if addr := b.Addr.To4(); addr != nil {
+		return []byte(addr), nil
+	}
+	if addr := b.Addr.To16(); addr != nil {
+		return []byte(addr), nil
+	}
+	return nil, fmt.Errorf("invalid address: %s", b.Addr)
+}
+
+func (b *NexthopLookupBody) DecodeFromBytes(data []byte, command API_TYPE) error {
+	addrLen := net.IPv4len
+	if command == IPV6_NEXTHOP_LOOKUP {
+		addrLen = net.IPv6len
+	}
+	if len(data) < addrLen+5 {
+		return fmt.Errorf("Not all nexthop lookup message bytes available")
+	}
+	b.Addr = net.IP(data[:addrLen]).To16()
+	b.Metric = binary.BigEndian.Uint32(data[addrLen : addrLen+4])
+	num := int(data[addrLen+4])
+	data = data[addrLen+5:]
+	b.Nexthops = make([]Nexthop, 0, num)
+	for i := 0; i < num; i++ {
+		if len(data) < 1 {
+			return fmt.Errorf("Not all nexthop bytes available")
+		}
+		n := Nexthop{Type: NEXTHOP_FLAG(data[0])}
+		data = data[1:]
+		gwLen, hasIfindex := 0, false
+		switch n.Type {
+		case NEXTHOP_IPV4:
+			gwLen = net.IPv4len
+		case NEXTHOP_IPV4_IFINDEX:
+			gwLen, hasIfindex = net.IPv4len, true
+		case NEXTHOP_IPV6:
+			gwLen = net.IPv6len
+		case NEXTHOP_IPV6_IFINDEX, NEXTHOP_IPV6_IFNAME:
+			gwLen, hasIfindex = net.IPv6len, true
+		case NEXTHOP_IFINDEX, NEXTHOP_IFNAME:
+			hasIfindex = true
+		}
+		if hasIfindex {
+			if len(data) < gwLen+4 {
+				return fmt.Errorf("Not all nexthop bytes available")
+			}
+			n.Ifindex = binary.BigEndian.Uint32(data[gwLen : gwLen+4])
+		} else if len(data) < gwLen {
+			return fmt.Errorf("Not all nexthop bytes available")
+		}
+		if gwLen > 0 {
+			n.Addr = net.IP(data[:gwLen]).To16()
+		}
+		if hasIfindex {
+			data = data[gwLen+4:]
+		} else {
+			data = data[gwLen:]
+		}
+		b.Nexthops = append(b.Nexthops, n)
+	}
+	return nil
+}
+
+// the prefix of IPV4_ROUTE_ADD, IPV4_ROUTE_DELETE, IPV6_ROUTE_ADD and
+// IPV6_ROUTE_DELETE sent by zebra. the nexthops and the rest of the
+// body aren't decoded.
+type RouteBody struct {
+	Type    ROUTE_TYPE
+	Flags   uint8
+	Message uint8
+	Prefix  *net.IPNet
+}
+
+func (b *RouteBody) DecodeFromBytes(data []byte, command API_TYPE) error {
+	addrLen := net.IPv4len
+	if command == IPV6_ROUTE_ADD || command == IPV6_ROUTE_DELETE {
+		addrLen = net.IPv6len
+	}
+	if len(data) < 4 {
+		return fmt.Errorf("Not all route message bytes available")
+	}
+	b.Type = ROUTE_TYPE(data[0])
+	b.Flags = data[1]
+	b.Message = data[2]
+	length := int(data[3])
+	if length > addrLen*8 {
+		return fmt.Errorf("invalid prefix length: %d", length)
+	}
+	byteLen := (length + 7) / 8
+	if len(data) < 4+byteLen {
+		return fmt.Errorf("Not all route message bytes available")
+	}
+	addr := make([]byte, addrLen)
+	copy(addr, data[4:4+byteLen])
+	b.Prefix = &net.IPNet{
+		IP:   net.IP(addr),
+		Mask: net.CIDRMask(length, addrLen*8),
+	}
+	return nil
+}
+
+func writeMessage(conn net.Conn, command API_TYPE, body []byte) error {
+	h := &Header{
+		Len:     uint16(HEADER_SIZE + len(body)),
+		Marker:  HEADER_MARKER,
+		Version: VERSION,
+		Command: command,
+	}
+	buf, _ := h.Serialize()
+	_, err := conn.Write(append(buf, body...))
+	return err
+}
+
+func readMessage(conn net.Conn) (*Header, []byte, error) {
+	buf := make([]byte, HEADER_SIZE)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return nil, nil, err
+	}
+	h := &Header{}
+	if err := h.DecodeFromBytes(buf); err != nil {
+		return nil, nil, err
+	}
+	buf = make([]byte, h.Len-HEADER_SIZE)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return nil, nil, err
+	}
+	return h, buf, nil
+}
+
+// subscribe to the routes of zebra's RIB and send the prefixes of the
+// routes added or deleted to the channel. zebra sends all the routes
+// when subscribed. it returns only when the connection fails.
+func WatchRoutes(network, address string, timeout time.Duration, ch chan<- *net.IPNet) error {
+	conn, err := net.DialTimeout(network, address, timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	for t := ROUTE_KERNEL; t < ROUTE_MAX; t++ {
+		// the routes installed by ourselves aren't interesting
+		if t == ROUTE_BGP {
+			continue
+		}
+		conn.SetWriteDeadline(time.Now().Add(timeout))
+		if err := writeMessage(conn, REDISTRIBUTE_ADD, []byte{byte(t)}); err != nil {
+			return err
+		}
+	}
+	for {
+		h, body, err := readMessage(conn)
+		if err != nil {
+			return err
+		}
+		switch h.Command {
+		case IPV4_ROUTE_ADD, IPV4_ROUTE_DELETE, IPV6_ROUTE_ADD, IPV6_ROUTE_DELETE:
+			route := &RouteBody{}
+			if err := route.DecodeFromBytes(body, h.Command); err != nil {
+				return err
+			}
+			ch <- route.Prefix
+		}
+	}
+}
+
+// Client talks to zebra to look up the nexthops. The requests are
+// serialized as zebra answers them in order.
+type Client struct {
+	network string
+	address string
+	timeout time.Duration
+	conn    net.Conn
+	mu      sync.Mutex
+}
+
+func NewClient(network, address string, timeout time.Duration) *Client {
+	return &Client{
+		network: network,
+		address: address,
+		timeout: timeout,
+	}
+}
+
+func (c *Client) close() {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
+
+func (c *Client) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.close()
+}
+
+func (c *Client) lookup(addr net.IP) (*NexthopLookupBody, error) {
+	if c.conn == nil {
+		conn, err := net.DialTimeout(c.network, c.address, c.timeout)
+		if err != nil {
+			return nil, err
+		}
+		c.conn = conn
+	}
+	command := IPV4_NEXTHOP_LOOKUP
+	if addr.To4() == nil {
+		command = IPV6_NEXTHOP_LOOKUP
+	}
+	body, err := (&NexthopLookupBody{Addr: addr}).Serialize()
+	if err != nil {
+		return nil, err
+	}
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	if err := writeMessage(c.conn, command, body); err != nil {
+		return nil, err
+	}
+	h, buf, err := readMessage(c.conn)
+	if err != nil {
+		return nil, err
+	}
+	if h.Command != command {
+		return nil, fmt.Errorf("unexpected ZAPI message: %d", h.Command)
+	}
+	reply := &NexthopLookupBody{}
+	if err := reply.DecodeFromBytes(buf, command); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// look up the route to the address in the zebra's RIB. the
+// connection is re-established on the next lookup after an error.
+func (c *Client) LookupNexthop(addr net.IP) (*NexthopLookupBody, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	reply, err := c.lookup(addr)
+	if err != nil {
+		c.close()
+	}
+	return reply, err
+}
@@ -0,0 +1,84 @@
+// Copyright (C) 2015 Nippon Telegraph and Telephone Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zebra
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+)
+
+func TestHeaderSerialize(t *testing.T) {
+	h := &Header{
+		Len:     10,
+		Marker:  HEADER_MARKER,
+		Version: VERSION,
+		Command: IPV4_NEXTHOP_LOOKUP,
+	}
+	buf, err := h.Serialize()
+	assert.NoError(t, err)
+	assert.Equal(t, buf, []byte{0, 10, 255, 2, 0, 15})
+
+	h2 := &Header{}
+	assert.NoError(t, h2.DecodeFromBytes(buf))
+	assert.Equal(t, h, h2)
+
+	buf[3] = 1
+	assert.Error(t, h2.DecodeFromBytes(buf))
+}
+
+func TestNexthopLookupBody(t *testing.T) {
+	b := &NexthopLookupBody{Addr: net.ParseIP("192.168.0.1")}
+	buf, err := b.Serialize()
+	assert.NoError(t, err)
+	assert.Equal(t, buf, []byte{192, 168, 0, 1})
+
+	buf = append(buf, []byte{
+		0, 0, 0, 20, // metric
+		2,                               // number of nexthops
+		byte(NEXTHOP_IPV4), 10, 0, 0, 1, // gateway
+		byte(NEXTHOP_IFINDEX), 0, 0, 0, 3, // ifindex
+	}...)
+	reply := &NexthopLookupBody{}
+	assert.NoError(t, reply.DecodeFromBytes(buf, IPV4_NEXTHOP_LOOKUP))
+	assert.True(t, reply.Addr.Equal(net.ParseIP("192.168.0.1")))
+	assert.Equal(t, reply.Metric, uint32(20))
+	assert.Equal(t, len(reply.Nexthops), 2)
+	assert.True(t, reply.Nexthops[0].Addr.Equal(net.ParseIP("10.0.0.1")))
+	assert.Equal(t, reply.Nexthops[1].Ifindex, uint32(3))
+
+	assert.Error(t, reply.DecodeFromBytes(buf[:len(buf)-1], IPV4_NEXTHOP_LOOKUP))
+}
+
+func TestRouteBody(t *testing.T) {
+	buf := []byte{
+		byte(ROUTE_OSPF), 0, 0x03, // type, flags and message
+		22, 10, 1, 4, // prefix
+		1, byte(NEXTHOP_IPV4), 10, 0, 0, 1, // nexthops aren't decoded
+	}
+	b := &RouteBody{}
+	assert.NoError(t, b.DecodeFromBytes(buf, IPV4_ROUTE_ADD))
+	assert.Equal(t, b.Type, ROUTE_OSPF)
+	assert.Equal(t, b.Prefix.String(), "10.1.4.0/22")
+
+	buf = []byte{byte(ROUTE_STATIC), 0, 0, 32, 0x20, 0x01, 0x0d, 0xb8}
+	assert.NoError(t, b.DecodeFromBytes(buf, IPV6_ROUTE_DELETE))
+	assert.Equal(t, b.Prefix.String(), "2001:db8::/32")
+
+	assert.Error(t, b.DecodeFromBytes(buf[:6], IPV6_ROUTE_DELETE))
+	buf[3] = 129
+	assert.Error(t, b.DecodeFromBytes(buf, IPV6_ROUTE_DELETE))
+}